@@ -2,7 +2,9 @@ package main
 
 import (
 	"backend-chat-app/initial"
+	"backend-chat-app/internal/domain/phone"
 	"log"
+	_ "time/tzdata" // profile timezones must resolve in minimal containers
 
 	"github.com/gin-gonic/gin"
 )
@@ -10,15 +12,30 @@ import (
 func main() {
 	cfg := initial.LoadConfig()
 
-	client := initial.NewMongoConnection(cfg.DBUrl)
+	// Shared by the phone migration and the services, so stored and looked
+	// up numbers are normalized the same way
+	phones, err := phone.NewNormalizer(cfg.PhoneRegion)
+	if err != nil {
+		log.Fatalf("Invalid PHONE_DEFAULT_REGION: %v", err)
+	}
+
+	client := initial.NewMongoConnection(cfg, phones)
 
 	r := gin.Default()
+	// Without this gin believes X-Forwarded-For from any client, which would
+	// let anyone pick the IP that login throttling counts against
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 
-	router, hub := initial.SetupRouter(r, cfg.JWTKey, client)
+	router, hub, workers := initial.SetupRouter(r, cfg, client, phones)
 
 	go hub.Run()
 	log.Println("WebSocket hub started")
 
+	workers.Start()
+	log.Println("Background workers started")
+
 	log.Printf("Server is running on PORT %s", cfg.Port)
 	log.Fatal(router.Run(":" + cfg.Port))
 }
@@ -2,23 +2,93 @@ package initial
 
 import (
 	"fmt"
+	"log"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Port   string
-	DBUrl  string
-	JWTKey string
+	Port       string
+	DBUrl      string
+	JWTKey     string
+	AppBaseURL string
+	Mail       MailConfig
+	OIDC       OIDCConfig
+	Password   PasswordConfig
+	StorageDir string
+	// StorageBackend is "local" to keep files in StorageDir or "gridfs" to keep them in MongoDB
+	StorageBackend string
+	// PhoneRegion is the ISO country code national phone numbers belong to
+	PhoneRegion string
+	// AccountDeletionGraceDays is how long a deleted account can still be restored
+	AccountDeletionGraceDays int
+	// TrustedProxies are the addresses or CIDR ranges allowed to set
+	// X-Forwarded-For. When empty the client IP is the peer address.
+	TrustedProxies []string
+}
+
+type PasswordConfig struct {
+	Hasher       string
+	MinLength    int
+	MaxLength    int
+	BreachedList string
+}
+
+// MailConfig falls back to a local outbox when SMTPHost is empty.
+type MailConfig struct {
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	From         string
+	OutboxDir    string
+}
+
+// OIDCConfig enables single sign-on when Issuer is set.
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
 }
 
 func LoadConfig() *Config {
 	_ = godotenv.Load(".env")
 	config := &Config{
-		Port:   getEnv("PORT", "8080"),
-		DBUrl:  getEnv("MONGO_URL", "mongodb://localhost:27017/chat-app"),
-		JWTKey: getEnv("JWT_SECRET", "default-jwt-secret"),
+		Port:       getEnv("PORT", "8080"),
+		DBUrl:      getEnv("MONGO_URL", "mongodb://localhost:27017/chat-app"),
+		JWTKey:     getEnv("JWT_SECRET", "default-jwt-secret"),
+		AppBaseURL: getEnv("APP_BASE_URL", "https://kitdev.vercel.app"),
+		Mail: MailConfig{
+			SMTPHost:     getEnv("SMTP_HOST", ""),
+			SMTPPort:     getEnv("SMTP_PORT", "587"),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			From:         getEnv("MAIL_FROM", "no-reply@kitdev.vercel.app"),
+			OutboxDir:    getEnv("MAIL_OUTBOX_DIR", ""),
+		},
+		OIDC: OIDCConfig{
+			Issuer:       getEnv("OIDC_ISSUER", ""),
+			ClientID:     getEnv("OIDC_CLIENT_ID", ""),
+			ClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+			RedirectURL:  getEnv("OIDC_REDIRECT_URL", "https://kitdev.vercel.app/sso/callback"),
+			Scopes:       strings.Fields(getEnv("OIDC_SCOPES", "openid profile email")),
+		},
+		StorageDir:               getEnv("STORAGE_DIR", "./data"),
+		StorageBackend:           getEnv("STORAGE_BACKEND", "local"),
+		PhoneRegion:              getEnv("PHONE_DEFAULT_REGION", "VN"),
+		AccountDeletionGraceDays: getEnvInt("ACCOUNT_DELETION_GRACE_DAYS", 30),
+		TrustedProxies:           strings.Fields(strings.ReplaceAll(getEnv("TRUSTED_PROXIES", ""), ",", " ")),
+		Password: PasswordConfig{
+			Hasher:       getEnv("PASSWORD_HASHER", "argon2id"),
+			MinLength:    getEnvInt("PASSWORD_MIN_LENGTH", 8),
+			MaxLength:    getEnvInt("PASSWORD_MAX_LENGTH", 128),
+			BreachedList: getEnv("PASSWORD_BREACHED_LIST", ""),
+		},
 	}
 	fmt.Println(config.DBUrl)
 	return config
@@ -30,3 +100,13 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+		log.Printf("Invalid value for %s, using default %d", key, defaultValue)
+	}
+	return defaultValue
+}
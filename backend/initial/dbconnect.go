@@ -1,6 +1,8 @@
 package initial
 
 import (
+	"backend-chat-app/internal/domain/phone"
+	"backend-chat-app/internal/infrastructure/database"
 	"backend-chat-app/internal/infrastructure/database/registry"
 	"context"
 	"log"
@@ -10,11 +12,11 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func NewMongoConnection(mongoURI string) *mongo.Client {
+func NewMongoConnection(cfg *Config, phones *phone.Normalizer) *mongo.Client {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	clientOptions := options.Client().ApplyURI(mongoURI).SetServerSelectionTimeout(30 * time.Second).SetConnectTimeout(30 * time.Second).SetTLSConfig(nil)
+	clientOptions := options.Client().ApplyURI(cfg.DBUrl).SetServerSelectionTimeout(30 * time.Second).SetConnectTimeout(30 * time.Second).SetTLSConfig(nil)
 
 	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
@@ -28,6 +30,8 @@ func NewMongoConnection(mongoURI string) *mongo.Client {
 
 	log.Println("MongoDB connected successfully!")
 
+	database.RegisterPhoneMigration(phones)
+
 	err = registry.SetupAllIndexes(client, "chat-app")
 	if err != nil {
 		log.Fatal("Failed to setup MongoDB indexes:", err)
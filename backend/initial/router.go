@@ -1,45 +1,129 @@
 package initial
 
 import (
+	"backend-chat-app/internal/application/account"
+	"backend-chat-app/internal/application/attachment"
 	"backend-chat-app/internal/application/auth"
+	"backend-chat-app/internal/application/bot"
 	"backend-chat-app/internal/application/chat"
+	"backend-chat-app/internal/application/contact"
+	"backend-chat-app/internal/application/linkpreview"
 	"backend-chat-app/internal/application/user"
+	"backend-chat-app/internal/domain/mail"
+	"backend-chat-app/internal/domain/media"
+	"backend-chat-app/internal/domain/phone"
+	"backend-chat-app/internal/infrastructure/audio"
 	"backend-chat-app/internal/infrastructure/database"
+	"backend-chat-app/internal/infrastructure/imaging"
+	"backend-chat-app/internal/infrastructure/mailer"
+	"backend-chat-app/internal/infrastructure/oidc"
+	"backend-chat-app/internal/infrastructure/password"
+	"backend-chat-app/internal/infrastructure/storage"
+	"backend-chat-app/internal/infrastructure/unfurl"
 	ws "backend-chat-app/internal/infrastructure/websocket"
 	"backend-chat-app/internal/interface/http"
 	"backend-chat-app/internal/interface/http/middleware"
+	"log"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func SetupRouter(r *gin.Engine, JWTSecret string, client *mongo.Client) (*gin.Engine, *ws.Hub) {
-	hub := ws.NewHub()
+// Workers are the background loops of the services, started once the
+// router is set up.
+type Workers struct {
+	account     *account.AccountService
+	attachment  *attachment.AttachmentService
+	linkPreview *linkpreview.LinkPreviewService
+	schedule    *chat.ScheduleService
+	expiry      *chat.ExpiryService
+	pollCloser  *chat.PollCloser
+}
+
+func (w *Workers) Start() {
+	go w.account.RunWorker()
+	go w.attachment.RunWorker()
+	go w.linkPreview.RunWorker()
+	go w.schedule.RunWorker()
+	go w.expiry.RunWorker()
+	go w.pollCloser.RunWorker()
+}
 
+func SetupRouter(r *gin.Engine, cfg *Config, client *mongo.Client, phones *phone.Normalizer) (*gin.Engine, *ws.Hub, *Workers) {
 	userRepo := database.NewMongoUserRepository(client, "chat-app")
 	conversationRepo := database.NewMongoConversationRepository(client, "chat-app")
 	messageRepo := database.NewMongoMessageRepository(client, "chat-app")
+	tokenRepo := database.NewMongoTokenRepository(client, "chat-app")
+	loginAttemptRepo := database.NewMongoLoginAttemptRepository(client, "chat-app")
+	securityEventRepo := database.NewMongoSecurityEventRepository(client, "chat-app")
+	loginStateRepo := database.NewMongoLoginStateRepository(client, "chat-app")
+	apiKeyRepo := database.NewMongoAPIKeyRepository(client, "chat-app")
+	contactRepo := database.NewMongoContactRepository(client, "chat-app")
+	contactRequestRepo := database.NewMongoContactRequestRepository(client, "chat-app")
+	blockRepo := database.NewMongoBlockRepository(client, "chat-app")
+	exportJobRepo := database.NewMongoExportJobRepository(client, "chat-app")
+	conversationSettingsRepo := database.NewMongoConversationSettingsRepository(client, "chat-app")
+	conversationInviteRepo := database.NewMongoConversationInviteRepository(client, "chat-app")
+	attachmentRepo := database.NewMongoAttachmentRepository(client, "chat-app")
+	linkPreviewRepo := database.NewMongoLinkPreviewRepository(client, "chat-app")
+	pinnedMessageRepo := database.NewMongoPinnedMessageRepository(client, "chat-app")
+	scheduledMessageRepo := database.NewMongoScheduledMessageRepository(client, "chat-app")
+
+	hub := ws.NewHub(blockRepo)
 
-	authService := auth.NewService(userRepo, JWTSecret)
-	userService := user.NewUserService(userRepo, conversationRepo)
-	chatService := chat.NewChatService(messageRepo, conversationRepo, userRepo)
+	mailSender := newMailer(cfg.Mail)
+
+	loginGuard := auth.NewLoginGuard(loginAttemptRepo, securityEventRepo, auth.DefaultAccountPolicy(), auth.DefaultIPPolicy())
+
+	passwordHasher, err := password.NewHasher(cfg.Password.Hasher)
+	if err != nil {
+		log.Fatal("Failed to set up password hashing: ", err)
+	}
+	passwordPolicy, err := auth.NewPasswordPolicy(cfg.Password.MinLength, cfg.Password.MaxLength, cfg.Password.BreachedList)
+	if err != nil {
+		log.Fatal("Failed to load password policy: ", err)
+	}
 
-	authHandle := http.NewAuthHandle(authService, JWTSecret)
+	authService := auth.NewService(userRepo, tokenRepo, mailSender, loginGuard, passwordHasher, passwordPolicy, phones, cfg.JWTKey, cfg.AppBaseURL)
+	blobStore := newBlobStore(cfg, client)
+	imageProcessor := imaging.NewProcessor(40_000_000)
+
+	attachmentService := attachment.NewAttachmentService(attachmentRepo, conversationRepo, blobStore, imageProcessor, audio.NewAnalyzer())
+	linkPreviewService := linkpreview.NewLinkPreviewService(messageRepo, linkPreviewRepo, unfurl.NewFetcher(), http.BroadcastLinkPreviews(hub))
+	userService := user.NewUserService(userRepo, conversationRepo, blobStore, imageProcessor, contactRepo, blockRepo, phones, conversationSettingsRepo)
+	chatService := chat.NewChatService(messageRepo, conversationRepo, userRepo, contactRepo, blockRepo, phones, conversationSettingsRepo, conversationInviteRepo, pinnedMessageRepo, scheduledMessageRepo, attachmentService, linkPreviewService)
+	pollCloser := chat.NewPollCloser(chatService, http.BroadcastPollUpdates(hub))
+	expiryService := chat.NewExpiryService(chatService, http.BroadcastMessagesExpired(hub))
+	scheduleService := chat.NewScheduleService(chatService, scheduledMessageRepo, http.BroadcastNewMessage(hub), http.NotifyScheduleFailed(hub))
+	botService := bot.NewBotService(userRepo, conversationRepo, apiKeyRepo)
+	contactService := contact.NewContactService(contactRepo, contactRequestRepo, blockRepo, userRepo, phones)
+	deletionGrace := time.Duration(cfg.AccountDeletionGraceDays) * 24 * time.Hour
+	accountService := account.NewAccountService(userRepo, conversationRepo, messageRepo, contactRepo, contactRequestRepo, blockRepo, tokenRepo, apiKeyRepo, exportJobRepo, scheduledMessageRepo, blobStore, userService, attachmentService, passwordHasher, deletionGrace)
+
+	authHandle := http.NewAuthHandle(authService, cfg.JWTKey)
 	userHandle := http.NewUserHandle(userService)
 	chatHandle := http.NewChatHandle(chatService, hub)
+	attachmentHandle := http.NewAttachmentHandle(attachmentService)
+	scheduleHandle := http.NewScheduleHandle(scheduleService)
+	botHandle := http.NewBotHandle(botService)
+	contactHandle := http.NewContactHandle(contactService, hub)
+	accountHandle := http.NewAccountHandle(accountService)
 
-	wsHandle := http.NewWebSocketHandle(hub, chatService)
+	wsHandle := http.NewWebSocketHandle(hub, chatService, botService)
 
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"https://kitdev.vercel.app"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-API-Key"},
 		AllowCredentials: true,
 		AllowWebSockets:  true,
 	}))
 
 	authMiddleware := middleware.AuthMiddleware(*authService)
+	// Routes bots may call with an API key; handlers check the key's scopes
+	botAuthMiddleware := middleware.AuthOrAPIKeyMiddleware(*authService, botService)
 
 	authGroup := r.Group("/auth")
 	{
@@ -47,6 +131,20 @@ func SetupRouter(r *gin.Engine, JWTSecret string, client *mongo.Client) (*gin.En
 		authGroup.POST("/register", authHandle.Register)
 		authGroup.POST("/refresh", authHandle.RefreshToken)
 		authGroup.POST("/logout", authHandle.Logout)
+		authGroup.POST("/verify-email/request", authHandle.RequestEmailVerification)
+		authGroup.POST("/verify-email", authHandle.VerifyEmail)
+		authGroup.POST("/password-reset/request", authHandle.RequestPasswordReset)
+		authGroup.POST("/password-reset", authHandle.ResetPassword)
+		authGroup.POST("/change-password", authMiddleware, authHandle.ChangePassword)
+	}
+
+	if cfg.OIDC.Issuer != "" {
+		provider := oidc.NewProvider(cfg.OIDC.Issuer, cfg.OIDC.ClientID, cfg.OIDC.ClientSecret, cfg.OIDC.RedirectURL, cfg.OIDC.Scopes)
+		ssoService := auth.NewSSOService(authService, userRepo, loginStateRepo, provider)
+		ssoHandle := http.NewSSOHandle(ssoService)
+
+		authGroup.GET("/oidc/login", ssoHandle.Login)
+		authGroup.POST("/oidc/callback", ssoHandle.Callback)
 	}
 
 	userGroup := r.Group("/user")
@@ -54,18 +152,114 @@ func SetupRouter(r *gin.Engine, JWTSecret string, client *mongo.Client) (*gin.En
 	{
 		userGroup.POST("/find-by-phone", userHandle.FindUserByPhone)
 		userGroup.GET("/conversation", userHandle.GetConversationList)
-
+		userGroup.GET("/conversation/:id/settings", userHandle.GetConversationSettings)
+		userGroup.PATCH("/conversation/:id/settings", userHandle.UpdateConversationSettings)
+		userGroup.GET("/search", userHandle.SearchUsers)
+		userGroup.GET("/me", userHandle.GetMyProfile)
+		userGroup.PATCH("/me", userHandle.UpdateMyProfile)
+		userGroup.POST("/me/avatar", userHandle.UploadAvatar)
+		userGroup.DELETE("/me/avatar", userHandle.DeleteAvatar)
+		userGroup.GET("/me/privacy", userHandle.GetPrivacy)
+		userGroup.PATCH("/me/privacy", userHandle.UpdatePrivacy)
+		userGroup.GET("/:id", userHandle.GetProfile)
 	}
 
+	// Avatars are public so they can be used directly in <img> tags
+	r.GET("/user/:id/avatar", userHandle.GetAvatar)
+
 	chatGroup := r.Group("/chat")
-	chatGroup.Use(authMiddleware)
 	{
-		chatGroup.POST("/send", chatHandle.SendMessage)
-		chatGroup.POST("/conversation", chatHandle.CreateConversation)
-		chatGroup.GET("/conversation/:id", chatHandle.GetConversation)
+		chatGroup.POST("/send", botAuthMiddleware, chatHandle.SendMessage)
+		chatGroup.POST("/forward", botAuthMiddleware, chatHandle.ForwardMessages)
+		chatGroup.POST("/conversation", authMiddleware, chatHandle.CreateConversation)
+		chatGroup.GET("/mentions", authMiddleware, chatHandle.GetMentions)
+		chatGroup.POST("/scheduled", authMiddleware, scheduleHandle.ScheduleMessage)
+		chatGroup.GET("/scheduled", authMiddleware, scheduleHandle.ListScheduledMessages)
+		chatGroup.PATCH("/scheduled/:id", authMiddleware, scheduleHandle.UpdateScheduledMessage)
+		chatGroup.DELETE("/scheduled/:id", authMiddleware, scheduleHandle.CancelScheduledMessage)
+		chatGroup.GET("/conversation/:id", botAuthMiddleware, chatHandle.GetConversation)
+		chatGroup.PATCH("/conversation/:id", authMiddleware, chatHandle.RenameConversation)
+		chatGroup.DELETE("/conversation/:id", authMiddleware, chatHandle.DeleteConversation)
+		chatGroup.POST("/conversation/:id/leave", authMiddleware, chatHandle.LeaveConversation)
+		chatGroup.PUT("/conversation/:id/message-ttl", authMiddleware, chatHandle.SetMessageTTL)
+		chatGroup.POST("/conversation/:id/attachments", botAuthMiddleware, attachmentHandle.Upload)
+		chatGroup.GET("/conversation/:id/attachments/:attachmentId", botAuthMiddleware, attachmentHandle.Download)
+		chatGroup.GET("/conversation/:id/attachments/:attachmentId/thumbnail", botAuthMiddleware, attachmentHandle.DownloadThumbnail)
+		chatGroup.GET("/conversation/:id/pins", authMiddleware, chatHandle.ListPinnedMessages)
+		chatGroup.POST("/conversation/:id/pins/:messageId", authMiddleware, chatHandle.PinMessage)
+		chatGroup.DELETE("/conversation/:id/pins/:messageId", authMiddleware, chatHandle.UnpinMessage)
+		chatGroup.GET("/conversation/:id/polls/:messageId", authMiddleware, chatHandle.GetPoll)
+		chatGroup.POST("/conversation/:id/polls/:messageId/votes", authMiddleware, chatHandle.VotePoll)
+		chatGroup.DELETE("/conversation/:id/polls/:messageId/votes", authMiddleware, chatHandle.RetractPollVote)
+		chatGroup.POST("/conversation/:id/polls/:messageId/close", authMiddleware, chatHandle.ClosePoll)
+		chatGroup.POST("/conversation/:id/invites", authMiddleware, chatHandle.CreateInvite)
+		chatGroup.GET("/conversation/:id/invites", authMiddleware, chatHandle.ListInvites)
+		chatGroup.DELETE("/conversation/:id/invites/:inviteId", authMiddleware, chatHandle.RevokeInvite)
+		chatGroup.GET("/invite/:token", authMiddleware, chatHandle.PreviewInvite)
+		chatGroup.POST("/invite/:token/join", authMiddleware, chatHandle.JoinByInvite)
+	}
+
+	contactGroup := r.Group("/contacts")
+	contactGroup.Use(authMiddleware)
+	{
+		contactGroup.GET("", contactHandle.ListContacts)
+		contactGroup.DELETE("/:id", contactHandle.RemoveContact)
+		contactGroup.POST("/requests", contactHandle.SendRequest)
+		contactGroup.GET("/requests", contactHandle.ListRequests)
+		contactGroup.POST("/requests/:id/accept", contactHandle.AcceptRequest)
+		contactGroup.POST("/requests/:id/decline", contactHandle.DeclineRequest)
+		contactGroup.DELETE("/requests/:id", contactHandle.CancelRequest)
+		contactGroup.GET("/blocked", contactHandle.ListBlocked)
+		contactGroup.POST("/blocked", contactHandle.BlockUser)
+		contactGroup.DELETE("/blocked/:id", contactHandle.UnblockUser)
+	}
+
+	accountGroup := r.Group("/account")
+	accountGroup.Use(authMiddleware)
+	{
+		accountGroup.POST("/export", accountHandle.RequestExport)
+		accountGroup.GET("/export", accountHandle.ListExports)
+		accountGroup.GET("/export/:id/download", accountHandle.DownloadExport)
+		accountGroup.GET("/deletion", accountHandle.GetDeletion)
+		accountGroup.POST("/deletion", accountHandle.RequestDeletion)
+		accountGroup.DELETE("/deletion", accountHandle.CancelDeletion)
+	}
+
+	botGroup := r.Group("/bot")
+	botGroup.Use(authMiddleware)
+	{
+		botGroup.POST("", botHandle.CreateBot)
+		botGroup.GET("", botHandle.ListBots)
+		botGroup.POST("/:id/keys", botHandle.CreateAPIKey)
+		botGroup.GET("/:id/keys", botHandle.ListAPIKeys)
+		botGroup.DELETE("/:id/keys/:keyId", botHandle.RevokeAPIKey)
 	}
 
 	// WebSocket endpoint - separate to avoid CORS preflight issues
-	r.GET("/ws", authMiddleware, wsHandle.HandleWebSocket)
-	return r, hub
+	r.GET("/ws", botAuthMiddleware, wsHandle.HandleWebSocket)
+	return r, hub, &Workers{account: accountService, attachment: attachmentService, linkPreview: linkPreviewService, schedule: scheduleService, expiry: expiryService, pollCloser: pollCloser}
+}
+
+func newMailer(cfg MailConfig) mail.Mailer {
+	if cfg.SMTPHost == "" {
+		log.Println("SMTP_HOST not set, emails go to the local outbox")
+		return mailer.NewOutboxMailer(cfg.OutboxDir, cfg.From)
+	}
+	return mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From)
+}
+
+func newBlobStore(cfg *Config, client *mongo.Client) media.BlobStore {
+	switch cfg.StorageBackend {
+	case "gridfs":
+		store, err := storage.NewGridFSStore(client, "chat-app")
+		if err != nil {
+			log.Fatal("Failed to set up GridFS storage: ", err)
+		}
+		return store
+	case "local", "":
+		return storage.NewLocalStore(cfg.StorageDir)
+	default:
+		log.Fatal("Unknown STORAGE_BACKEND: ", cfg.StorageBackend)
+		return nil
+	}
 }
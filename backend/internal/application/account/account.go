@@ -0,0 +1,205 @@
+package account
+
+import (
+	"backend-chat-app/internal/application"
+	attachmentapp "backend-chat-app/internal/application/attachment"
+	userapp "backend-chat-app/internal/application/user"
+	"backend-chat-app/internal/domain/account"
+	"backend-chat-app/internal/domain/apikey"
+	"backend-chat-app/internal/domain/contact"
+	"backend-chat-app/internal/domain/conversation"
+	"backend-chat-app/internal/domain/media"
+	"backend-chat-app/internal/domain/message"
+	"backend-chat-app/internal/domain/token"
+	"backend-chat-app/internal/domain/user"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// AccountService lets users take their data with them and delete their
+// account. Both run in the background, see RunWorker.
+type AccountService struct {
+	userRepo          user.UserRepository
+	conversationRepo  conversation.ConversationRepository
+	messageRepo       message.MessageRepository
+	contactRepo       contact.ContactRepository
+	requestRepo       contact.RequestRepository
+	blockRepo         contact.BlockRepository
+	tokenRepo         token.TokenRepository
+	apiKeyRepo        apikey.APIKeyRepository
+	exportRepo        account.ExportJobRepository
+	scheduledRepo     message.ScheduledMessageRepository
+	blobStore         media.BlobStore
+	userService       *userapp.UserService
+	attachmentService *attachmentapp.AttachmentService
+	passwordHasher    user.PasswordHasher
+	deletionGrace     time.Duration
+	// exportQueued wakes the worker when a new export is requested
+	exportQueued chan struct{}
+}
+
+func NewAccountService(userRepo user.UserRepository, conversationRepo conversation.ConversationRepository, messageRepo message.MessageRepository, contactRepo contact.ContactRepository, requestRepo contact.RequestRepository, blockRepo contact.BlockRepository, tokenRepo token.TokenRepository, apiKeyRepo apikey.APIKeyRepository, exportRepo account.ExportJobRepository, scheduledRepo message.ScheduledMessageRepository, blobStore media.BlobStore, userService *userapp.UserService, attachmentService *attachmentapp.AttachmentService, passwordHasher user.PasswordHasher, deletionGrace time.Duration) *AccountService {
+	return &AccountService{
+		userRepo:          userRepo,
+		conversationRepo:  conversationRepo,
+		messageRepo:       messageRepo,
+		contactRepo:       contactRepo,
+		requestRepo:       requestRepo,
+		blockRepo:         blockRepo,
+		tokenRepo:         tokenRepo,
+		apiKeyRepo:        apiKeyRepo,
+		exportRepo:        exportRepo,
+		scheduledRepo:     scheduledRepo,
+		blobStore:         blobStore,
+		userService:       userService,
+		attachmentService: attachmentService,
+		passwordHasher:    passwordHasher,
+		deletionGrace:     deletionGrace,
+		exportQueued:      make(chan struct{}, 1),
+	}
+}
+
+// Deletion
+
+// RequestDeletion schedules the account to be anonymized once the grace
+// period is over. Until then the user can still sign in and cancel.
+func (s *AccountService) RequestDeletion(userID string, req application.DeleteAccountRequest) (*application.AccountDeletionStatus, error) {
+	u, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.IsBot {
+		return nil, errors.New("bots are deleted by their owner")
+	}
+	if u.Password != "" {
+		match, err := s.passwordHasher.Verify(u.Password, req.Password)
+		if err != nil || !match {
+			return nil, errors.New("password is incorrect")
+		}
+	}
+	if !u.DeletionDueAt.IsZero() {
+		return toDeletionStatus(u.DeletionDueAt), nil
+	}
+
+	dueAt := time.Now().Add(s.deletionGrace)
+	if err := s.userRepo.ScheduleDeletion(u.ID, dueAt); err != nil {
+		return nil, errors.New("failed to schedule deletion: " + err.Error())
+	}
+	log.Printf("User %s scheduled account deletion for %s", u.ID, dueAt.Format(time.RFC3339))
+	return toDeletionStatus(dueAt), nil
+}
+
+func (s *AccountService) GetDeletion(userID string) (*application.AccountDeletionStatus, error) {
+	u, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	return toDeletionStatus(u.DeletionDueAt), nil
+}
+
+func (s *AccountService) CancelDeletion(userID string) error {
+	u, err := s.getUser(userID)
+	if err != nil {
+		return err
+	}
+	if u.DeletionDueAt.IsZero() {
+		return errors.New("account deletion is not scheduled")
+	}
+	if err := s.userRepo.ScheduleDeletion(u.ID, time.Time{}); err != nil {
+		return err
+	}
+	log.Printf("User %s cancelled account deletion", u.ID)
+	return nil
+}
+
+// deleteAccount removes everything personal about the user. The user
+// document itself is anonymized last, so a failed run is retried by the
+// next sweep.
+func (s *AccountService) deleteAccount(u *user.User) error {
+	bots, err := s.userRepo.GetBotsByOwner(u.ID)
+	if err != nil {
+		return err
+	}
+	for _, bot := range bots {
+		if err := s.deleteBot(bot); err != nil {
+			return fmt.Errorf("bot %s: %w", bot.ID, err)
+		}
+	}
+
+	if err := s.userService.DeleteAvatar(u.ID); err != nil {
+		return err
+	}
+	if err := s.deleteExports(u.ID); err != nil {
+		return err
+	}
+	if err := s.contactRepo.RemoveAll(u.ID); err != nil {
+		return err
+	}
+	if err := s.requestRepo.DeleteByUser(u.ID); err != nil {
+		return err
+	}
+	if err := s.blockRepo.DeleteByUser(u.ID); err != nil {
+		return err
+	}
+	for _, purpose := range []string{token.PurposeEmailVerification, token.PurposePasswordReset} {
+		if err := s.tokenRepo.DeleteByUser(u.ID, purpose); err != nil {
+			return err
+		}
+	}
+	if err := s.attachmentService.DeleteByUploader(u.ID); err != nil {
+		return err
+	}
+	if err := s.scheduledRepo.DeleteBySender(u.ID); err != nil {
+		return err
+	}
+	if err := s.messageRepo.RedactBySender(u.ID); err != nil {
+		return err
+	}
+	if err := s.conversationRepo.RedactLastMessages(u.ID); err != nil {
+		return err
+	}
+	if err := s.conversationRepo.UpdateParticipantName(u.ID, user.DeletedUserName); err != nil {
+		return err
+	}
+	return s.userRepo.Anonymize(u.ID)
+}
+
+func (s *AccountService) deleteBot(bot *user.User) error {
+	keys, err := s.apiKeyRepo.GetByBot(bot.ID)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if key.RevokedAt.IsZero() {
+			if err := s.apiKeyRepo.Revoke(key.ID, bot.ID); err != nil {
+				return err
+			}
+		}
+	}
+	if err := s.conversationRepo.UpdateParticipantName(bot.ID, user.DeletedUserName); err != nil {
+		return err
+	}
+	return s.userRepo.Anonymize(bot.ID)
+}
+
+// Helper functions
+
+func (s *AccountService) getUser(userID string) (*user.User, error) {
+	u, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil || u.IsDeleted() {
+		return nil, errors.New("user not found")
+	}
+	return u, nil
+}
+
+func toDeletionStatus(dueAt time.Time) *application.AccountDeletionStatus {
+	if dueAt.IsZero() {
+		return &application.AccountDeletionStatus{}
+	}
+	return &application.AccountDeletionStatus{Scheduled: true, DueAt: dueAt.Unix()}
+}
@@ -0,0 +1,308 @@
+package account
+
+import (
+	"archive/zip"
+	"backend-chat-app/internal/application"
+	userapp "backend-chat-app/internal/application/user"
+	"backend-chat-app/internal/domain/account"
+	"backend-chat-app/internal/domain/conversation"
+	"backend-chat-app/internal/domain/media"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// ExportTTL is how long a finished export can be downloaded.
+const ExportTTL = 7 * 24 * time.Hour
+
+// exportPageSize is how many conversations are read at once while exporting.
+const exportPageSize = 100
+
+type exportedConversation struct {
+	ID           string                        `json:"conversation_id"`
+	Participants []application.ParticipantInfo `json:"participants"`
+	CreatedAt    int64                         `json:"created_at"`
+}
+
+// RequestExport queues a new export. Only one export per user can be in
+// progress at a time.
+func (s *AccountService) RequestExport(userID string) (*application.ExportJobInfo, error) {
+	if _, err := s.getUser(userID); err != nil {
+		return nil, err
+	}
+
+	job, err := account.NewExportJob(userID)
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.exportRepo.Create(*job)
+	if errors.Is(err, account.ErrExportInProgress) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.New("failed to queue export: " + err.Error())
+	}
+
+	select {
+	case s.exportQueued <- struct{}{}:
+	default:
+	}
+	return toExportJobInfo(created), nil
+}
+
+func (s *AccountService) ListExports(userID string) ([]application.ExportJobInfo, error) {
+	jobs, err := s.exportRepo.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]application.ExportJobInfo, 0, len(jobs))
+	for _, job := range jobs {
+		infos = append(infos, *toExportJobInfo(job))
+	}
+	return infos, nil
+}
+
+// DownloadExport opens the archive of a finished export of the user.
+func (s *AccountService) DownloadExport(userID string, jobID string) (io.ReadCloser, *media.BlobInfo, error) {
+	job, err := s.exportRepo.GetByID(jobID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if job == nil || job.UserID != userID {
+		return nil, nil, errors.New("export not found")
+	}
+	if job.Status != account.ExportReady || time.Now().After(job.ExpiresAt) {
+		return nil, nil, errors.New("export is not available for download")
+	}
+
+	content, info, err := s.blobStore.Get(job.BlobKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if content == nil {
+		return nil, nil, errors.New("export not found")
+	}
+	return content, info, nil
+}
+
+// runExport builds the archive of a claimed job and stores it.
+func (s *AccountService) runExport(job *account.ExportJob) {
+	blobKey := fmt.Sprintf("exports/%s/%s.zip", job.UserID, job.ID)
+	size, err := s.buildExport(job.UserID, blobKey)
+	if err != nil {
+		log.Printf("Export %s of %s failed: %v", job.ID, job.UserID, err)
+		if err := s.exportRepo.Fail(job.ID, "export failed, please try again"); err != nil {
+			log.Printf("Failed to mark export %s as failed: %v", job.ID, err)
+		}
+		return
+	}
+	if err := s.exportRepo.Complete(job.ID, blobKey, size, time.Now().Add(ExportTTL)); err != nil {
+		log.Printf("Failed to complete export %s: %v", job.ID, err)
+		return
+	}
+	log.Printf("Export %s of %s is ready (%d bytes)", job.ID, job.UserID, size)
+}
+
+// buildExport writes the zip to a temporary file first so large histories
+// are not held in memory.
+func (s *AccountService) buildExport(userID string, blobKey string) (int64, error) {
+	tmp, err := os.CreateTemp("", "export-*.zip")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	archive := zip.NewWriter(tmp)
+	if err := s.writeExport(archive, userID); err != nil {
+		return 0, err
+	}
+	if err := archive.Close(); err != nil {
+		return 0, err
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	info, err := s.blobStore.Put(blobKey, "application/zip", tmp)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size, nil
+}
+
+func (s *AccountService) writeExport(archive *zip.Writer, userID string) error {
+	profile, err := s.userService.GetMyProfile(userID)
+	if err != nil {
+		return err
+	}
+	privacy, err := s.userService.GetPrivacy(userID)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(archive, "profile.json", map[string]interface{}{
+		"profile": profile,
+		"privacy": privacy,
+	}); err != nil {
+		return err
+	}
+
+	contacts, err := s.contactRepo.List(userID)
+	if err != nil {
+		return err
+	}
+	contactInfos := make([]application.ContactInfo, 0, len(contacts))
+	for _, c := range contacts {
+		info := application.ContactInfo{ID: c.ContactID, Since: c.CreatedAt.Unix()}
+		if u, err := s.userRepo.GetByID(c.ContactID); err == nil && u != nil {
+			info.Username = u.Username
+			info.Name = u.PublicName()
+		}
+		contactInfos = append(contactInfos, info)
+	}
+	if err := writeJSON(archive, "contacts.json", contactInfos); err != nil {
+		return err
+	}
+
+	// The user's conversation list can miss conversations they were added to,
+	// so the export follows the participants of the conversations instead
+	userConversations, err := s.participantConversations(userID)
+	if err != nil {
+		return err
+	}
+	conversations := make([]exportedConversation, 0, len(userConversations))
+	now := time.Now()
+	for _, c := range userConversations {
+		participants := make([]application.ParticipantInfo, 0, len(c.Participant))
+		for _, p := range c.Participant {
+			participants = append(participants, application.ParticipantInfo{ID: p.ID, Name: p.Name})
+		}
+		conversations = append(conversations, exportedConversation{
+			ID:           c.ID,
+			Participants: participants,
+			CreatedAt:    c.CreatedAt.Unix(),
+		})
+
+		messages, err := s.messageRepo.GetMessagesByConversationID(c.ID)
+		if err != nil {
+			return err
+		}
+		appMessages := make([]application.Message, 0, len(messages))
+		for _, m := range messages {
+			// Expired messages may not have been deleted yet
+			if m.IsExpired(now) {
+				continue
+			}
+			appMessages = append(appMessages, application.Message{
+				ID:        m.ID,
+				SenderID:  m.SenderID,
+				Type:      m.Type,
+				Message:   m.Message,
+				Redacted:  m.Redacted,
+				CreatedAt: m.CreatedAt.Unix(),
+			})
+		}
+		if err := writeJSON(archive, "messages/"+c.ID+".json", appMessages); err != nil {
+			return err
+		}
+	}
+	if err := writeJSON(archive, "conversations.json", conversations); err != nil {
+		return err
+	}
+
+	if profile.AvatarURLs != nil {
+		return s.writeAvatar(archive, userID)
+	}
+	return nil
+}
+
+// participantConversations pages through every conversation the user takes
+// part in. A conversation that becomes active meanwhile moves back to the
+// front of the list and is seen twice, so it is only kept once.
+func (s *AccountService) participantConversations(userID string) ([]*conversation.Conversation, error) {
+	var conversations []*conversation.Conversation
+	seen := make(map[string]bool)
+	query := conversation.ListQuery{UserID: userID, Limit: exportPageSize}
+	for {
+		page, err := s.conversationRepo.ListByParticipant(query)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range page {
+			if !seen[c.ID] {
+				seen[c.ID] = true
+				conversations = append(conversations, c)
+			}
+		}
+		if len(page) < exportPageSize {
+			return conversations, nil
+		}
+		last := page[len(page)-1]
+		query.After = &conversation.Cursor{UpdateAt: last.UpdateAt, ID: last.ID}
+	}
+}
+
+func (s *AccountService) writeAvatar(archive *zip.Writer, userID string) error {
+	content, _, err := s.userService.GetAvatar(userID, userapp.AvatarSizes[len(userapp.AvatarSizes)-1])
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	w, err := archive.Create("avatar.jpg")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, content)
+	return err
+}
+
+func writeJSON(archive *zip.Writer, name string, value interface{}) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// deleteExports removes every export of the user, including the archives.
+func (s *AccountService) deleteExports(userID string) error {
+	jobs, err := s.exportRepo.ListByUser(userID)
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if job.BlobKey != "" {
+			if err := s.blobStore.Delete(job.BlobKey); err != nil {
+				return err
+			}
+		}
+	}
+	return s.exportRepo.DeleteByUser(userID)
+}
+
+func toExportJobInfo(job *account.ExportJob) *application.ExportJobInfo {
+	info := &application.ExportJobInfo{
+		ID:        job.ID,
+		Status:    job.Status,
+		Size:      job.Size,
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt.Unix(),
+	}
+	if !job.CompletedAt.IsZero() {
+		info.CompletedAt = job.CompletedAt.Unix()
+	}
+	if !job.ExpiresAt.IsZero() {
+		info.ExpiresAt = job.ExpiresAt.Unix()
+	}
+	if job.Status == account.ExportReady {
+		info.DownloadURL = fmt.Sprintf("/account/export/%s/download", job.ID)
+	}
+	return info
+}
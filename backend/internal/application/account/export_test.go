@@ -0,0 +1,152 @@
+package account
+
+import (
+	"archive/zip"
+	userapp "backend-chat-app/internal/application/user"
+	"backend-chat-app/internal/domain/account"
+	"backend-chat-app/internal/domain/conversation"
+	"backend-chat-app/internal/domain/message"
+	"backend-chat-app/internal/domain/user"
+	"backend-chat-app/internal/infrastructure/storage"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRequestExportAllowsOneInProgress(t *testing.T) {
+	exports := &memoryExports{}
+	service := &AccountService{
+		userRepo:     newMemoryUsers(user.User{ID: "alice"}),
+		exportRepo:   exports,
+		exportQueued: make(chan struct{}, 1),
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	queued, refused := 0, 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.RequestExport("alice")
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				queued++
+			case errors.Is(err, account.ErrExportInProgress):
+				refused++
+			default:
+				t.Errorf("RequestExport: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if queued != 1 || refused != 9 {
+		t.Fatalf("queued %d and refused %d exports, want 1 and 9", queued, refused)
+	}
+
+	exports.jobs[0].Status = account.ExportReady
+	if _, err := service.RequestExport("alice"); err != nil {
+		t.Fatalf("RequestExport after the first finished: %v", err)
+	}
+}
+
+func TestExportListsConversationsTheUserTakesPartIn(t *testing.T) {
+	// More than a page, so the export has to follow the cursor
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	conversations := &memoryConversations{}
+	messages := &memoryMessages{messages: make(map[string][]*message.Message)}
+	want := make(map[string]bool)
+	for i := 0; i < exportPageSize+20; i++ {
+		id := fmt.Sprintf("c%03d", i)
+		conversations.conversations = append(conversations.conversations, &conversation.Conversation{
+			ID:          id,
+			Participant: []conversation.Participant{{ID: "alice"}, {ID: "bob"}},
+			// Pairs share a time to check ties are paged by ID
+			UpdateAt: base.Add(time.Duration(i/2) * time.Minute),
+		})
+		messages.messages[id] = []*message.Message{
+			{ID: "m-" + id, ConversationID: id, SenderID: "bob", Message: "hi"},
+			// Not deleted by the expiry worker yet
+			{ID: "expired-" + id, ConversationID: id, SenderID: "bob", Message: "poof", ExpiresAt: base},
+		}
+		want[id] = true
+	}
+	conversations.conversations = append(conversations.conversations, &conversation.Conversation{
+		ID:          "other",
+		Participant: []conversation.Participant{{ID: "bob"}, {ID: "carol"}},
+		UpdateAt:    base,
+	})
+
+	// GetConversationList is not faked, reading the user's own list panics
+	users := newMemoryUsers(user.User{ID: "alice", Username: "alice"})
+	store := storage.NewLocalStore(t.TempDir())
+	exports := &memoryExports{}
+	service := &AccountService{
+		userRepo:         users,
+		conversationRepo: conversations,
+		messageRepo:      messages,
+		contactRepo:      memoryContacts{},
+		exportRepo:       exports,
+		blobStore:        store,
+		userService:      userapp.NewUserService(users, conversations, store, nil, nil, nil, nil, nil),
+		exportQueued:     make(chan struct{}, 1),
+	}
+
+	job, err := service.RequestExport("alice")
+	if err != nil {
+		t.Fatalf("RequestExport: %v", err)
+	}
+	service.runExport(&account.ExportJob{ID: job.ID, UserID: "alice"})
+
+	content, _, err := service.DownloadExport("alice", job.ID)
+	if err != nil {
+		t.Fatalf("DownloadExport: %v", err)
+	}
+	defer content.Close()
+	data, err := io.ReadAll(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("export is not a zip: %v", err)
+	}
+
+	var exported []exportedConversation
+	readJSON(t, archive, "conversations.json", &exported)
+	if len(exported) != len(want) {
+		t.Fatalf("exported %d conversations, want %d", len(exported), len(want))
+	}
+	for _, c := range exported {
+		if !want[c.ID] {
+			t.Fatalf("exported conversation %q, alice is not a participant or it is listed twice", c.ID)
+		}
+		delete(want, c.ID)
+	}
+
+	var exportedMessages []map[string]interface{}
+	readJSON(t, archive, "messages/c000.json", &exportedMessages)
+	if len(exportedMessages) != 1 || exportedMessages[0]["message"] != "hi" {
+		t.Fatalf("messages of c000 = %v", exportedMessages)
+	}
+}
+
+func readJSON(t *testing.T, archive *zip.Reader, name string, value interface{}) {
+	t.Helper()
+	f, err := archive.Open(name)
+	if err != nil {
+		t.Fatalf("open %s: %v", name, err)
+	}
+	defer f.Close()
+	if err := json.NewDecoder(f).Decode(value); err != nil {
+		t.Fatalf("decode %s: %v", name, err)
+	}
+}
@@ -0,0 +1,143 @@
+package account
+
+import (
+	"backend-chat-app/internal/domain/account"
+	"backend-chat-app/internal/domain/contact"
+	"backend-chat-app/internal/domain/conversation"
+	"backend-chat-app/internal/domain/message"
+	"backend-chat-app/internal/domain/user"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The fakes keep their data in maps. Methods the tests don't reach fall
+// through to the nil embedded interface and panic.
+
+type memoryUsers struct {
+	user.UserRepository
+	mu    sync.Mutex
+	users map[string]*user.User
+}
+
+func newMemoryUsers(users ...user.User) *memoryUsers {
+	repo := &memoryUsers{users: make(map[string]*user.User)}
+	for _, u := range users {
+		u := u
+		repo.users[u.ID] = &u
+	}
+	return repo
+}
+
+func (r *memoryUsers) GetByID(userID string) (*user.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, found := r.users[userID]
+	if !found {
+		return nil, nil
+	}
+	copied := *u
+	return &copied, nil
+}
+
+type memoryConversations struct {
+	conversation.ConversationRepository
+	conversations []*conversation.Conversation
+}
+
+// ListByParticipant sorts like the Mongo repository: newest activity first,
+// then by ID.
+func (r *memoryConversations) ListByParticipant(query conversation.ListQuery) ([]*conversation.Conversation, error) {
+	var matched []*conversation.Conversation
+	for _, c := range r.conversations {
+		if c.HasParticipant(query.UserID) {
+			matched = append(matched, c)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].UpdateAt.Equal(matched[j].UpdateAt) {
+			return matched[i].UpdateAt.After(matched[j].UpdateAt)
+		}
+		return matched[i].ID > matched[j].ID
+	})
+
+	var page []*conversation.Conversation
+	for _, c := range matched {
+		if after := query.After; after != nil {
+			if c.UpdateAt.After(after.UpdateAt) || (c.UpdateAt.Equal(after.UpdateAt) && c.ID >= after.ID) {
+				continue
+			}
+		}
+		if len(page) == query.Limit {
+			break
+		}
+		page = append(page, c)
+	}
+	return page, nil
+}
+
+type memoryMessages struct {
+	message.MessageRepository
+	messages map[string][]*message.Message
+}
+
+func (r *memoryMessages) GetMessagesByConversationID(conversationID string) ([]*message.Message, error) {
+	return r.messages[conversationID], nil
+}
+
+type memoryContacts struct {
+	contact.ContactRepository
+}
+
+func (memoryContacts) List(userID string) ([]*contact.Contact, error) {
+	return nil, nil
+}
+
+// memoryExports enforces one pending or running job per user, like the
+// unique index of the Mongo repository.
+type memoryExports struct {
+	account.ExportJobRepository
+	mu   sync.Mutex
+	jobs []*account.ExportJob
+}
+
+func (r *memoryExports) Create(job account.ExportJob) (*account.ExportJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.jobs {
+		if existing.UserID == job.UserID && (existing.Status == account.ExportPending || existing.Status == account.ExportRunning) {
+			return nil, account.ErrExportInProgress
+		}
+	}
+	job.ID = fmt.Sprintf("job-%d", len(r.jobs)+1)
+	r.jobs = append(r.jobs, &job)
+	copied := job
+	return &copied, nil
+}
+
+func (r *memoryExports) Complete(jobID string, blobKey string, size int64, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, job := range r.jobs {
+		if job.ID == jobID {
+			job.Status = account.ExportReady
+			job.BlobKey = blobKey
+			job.Size = size
+			job.ExpiresAt = expiresAt
+		}
+	}
+	return nil
+}
+
+func (r *memoryExports) GetByID(jobID string) (*account.ExportJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, job := range r.jobs {
+		if job.ID == jobID {
+			copied := *job
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
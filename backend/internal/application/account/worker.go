@@ -0,0 +1,77 @@
+package account
+
+import (
+	"log"
+	"time"
+)
+
+// sweepInterval is how often due deletions and expired exports are handled.
+const sweepInterval = time.Hour
+
+// RunWorker builds queued exports as they come in and periodically deletes
+// accounts whose grace period is over. It blocks, run it in a goroutine.
+func (s *AccountService) RunWorker() {
+	// Jobs that were running when the server stopped start over
+	if err := s.exportRepo.ResetRunning(); err != nil {
+		log.Printf("Failed to requeue interrupted exports: %v", err)
+	}
+
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	s.processExports()
+	s.sweep()
+	for {
+		select {
+		case <-s.exportQueued:
+			s.processExports()
+		case <-ticker.C:
+			s.processExports()
+			s.sweep()
+		}
+	}
+}
+
+func (s *AccountService) processExports() {
+	for {
+		job, err := s.exportRepo.ClaimPending()
+		if err != nil {
+			log.Printf("Failed to claim export job: %v", err)
+			return
+		}
+		if job == nil {
+			return
+		}
+		s.runExport(job)
+	}
+}
+
+func (s *AccountService) sweep() {
+	now := time.Now()
+
+	due, err := s.userRepo.GetDueForDeletion(now)
+	if err != nil {
+		log.Printf("Failed to list accounts due for deletion: %v", err)
+	}
+	for _, u := range due {
+		if err := s.deleteAccount(u); err != nil {
+			log.Printf("Failed to delete account %s: %v", u.ID, err)
+			continue
+		}
+		log.Printf("Deleted account %s", u.ID)
+	}
+
+	expired, err := s.exportRepo.ListExpired(now)
+	if err != nil {
+		log.Printf("Failed to list expired exports: %v", err)
+	}
+	for _, job := range expired {
+		if err := s.blobStore.Delete(job.BlobKey); err != nil {
+			log.Printf("Failed to delete export %s: %v", job.ID, err)
+			continue
+		}
+		if err := s.exportRepo.MarkExpired(job.ID); err != nil {
+			log.Printf("Failed to expire export %s: %v", job.ID, err)
+		}
+	}
+}
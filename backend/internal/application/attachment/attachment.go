@@ -0,0 +1,423 @@
+package attachment
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/attachment"
+	"backend-chat-app/internal/domain/conversation"
+	"backend-chat-app/internal/domain/media"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	MaxAttachmentSize = 25 << 20
+	// ThumbnailSize is the longest side of image previews, in pixels
+	ThumbnailSize = 320
+	// WaveformSamples is how many levels describe a voice recording
+	WaveformSamples   = 64
+	maxFileNameLength = 255
+)
+
+// ErrAlreadySent is returned when an attachment went out with another message.
+var ErrAlreadySent = errors.New("attachment was already sent")
+
+// allowedTypes are checked against the sniffed content, not the type the
+// client claims. HTML and SVG are left out so files can't run scripts when
+// opened from our origin.
+var allowedTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+	"application/zip": true, // also Office documents
+	"text/plain":      true,
+	"video/mp4":       true,
+	"video/webm":      true,
+}
+
+// audioTypes are the voice formats we can measure, by sniffed type, and the
+// type they are stored and served as.
+var audioTypes = map[string]string{
+	"audio/wave":      "audio/wav",
+	"application/ogg": "audio/ogg",
+}
+
+var ErrNotFound = errors.New("attachment not found")
+
+// AttachmentService stores files sent in conversations. Only participants
+// of the conversation can upload to or download from it.
+type AttachmentService struct {
+	attachmentRepo   attachment.AttachmentRepository
+	conversationRepo conversation.ConversationRepository
+	blobStore        media.BlobStore
+	imageProcessor   media.ImageProcessor
+	audioAnalyzer    media.AudioAnalyzer
+}
+
+func NewAttachmentService(attachmentRepo attachment.AttachmentRepository, conversationRepo conversation.ConversationRepository, blobStore media.BlobStore, imageProcessor media.ImageProcessor, audioAnalyzer media.AudioAnalyzer) *AttachmentService {
+	return &AttachmentService{
+		attachmentRepo:   attachmentRepo,
+		conversationRepo: conversationRepo,
+		blobStore:        blobStore,
+		imageProcessor:   imageProcessor,
+		audioAnalyzer:    audioAnalyzer,
+	}
+}
+
+// Upload stores a file and, for images, a JPEG thumbnail. Audio is measured
+// up front so voice messages can be previewed. The attachment is sent by
+// passing its ID to SendMessage.
+func (s *AttachmentService) Upload(userID string, conversationID string, fileName string, content io.Reader) (*application.AttachmentInfo, error) {
+	if err := s.checkParticipant(userID, conversationID); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(content, MaxAttachmentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, errors.New("file is empty")
+	}
+	if len(data) > MaxAttachmentSize {
+		return nil, fmt.Errorf("file must be at most %d MB", MaxAttachmentSize>>20)
+	}
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	if audioType, ok := audioTypes[contentType]; ok {
+		contentType = audioType
+	} else if !allowedTypes[contentType] {
+		return nil, errors.New("this file type is not allowed")
+	}
+
+	a, err := attachment.NewAttachment(conversationID, userID, cleanFileName(fileName), contentType, int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	blobID, err := newBlobID()
+	if err != nil {
+		return nil, err
+	}
+	a.BlobKey = path.Join("attachments", conversationID, blobID)
+
+	if a.IsAudio() {
+		audio, err := s.audioAnalyzer.Analyze(data, a.ContentType, WaveformSamples)
+		if err != nil {
+			return nil, errors.New("can not read audio, use WAV or Ogg Opus: " + err.Error())
+		}
+		a.Duration = audio.Duration
+		a.Waveform = audio.Waveform
+	}
+
+	if a.IsImage() {
+		// Images we can't decode are still stored, just without a preview
+		thumbnail, err := s.imageProcessor.Thumbnail(data, ThumbnailSize, false)
+		if err != nil {
+			log.Printf("No thumbnail for upload of %s in %s: %v", userID, conversationID, err)
+		} else {
+			a.ThumbnailKey = a.BlobKey + "-thumb.jpg"
+			if _, err := s.blobStore.Put(a.ThumbnailKey, "image/jpeg", bytes.NewReader(thumbnail)); err != nil {
+				return nil, errors.New("failed to store thumbnail: " + err.Error())
+			}
+		}
+	}
+	if _, err := s.blobStore.Put(a.BlobKey, a.ContentType, bytes.NewReader(data)); err != nil {
+		s.deleteBlobs(a)
+		return nil, errors.New("failed to store file: " + err.Error())
+	}
+
+	created, err := s.attachmentRepo.Create(*a)
+	if err != nil {
+		s.deleteBlobs(a)
+		return nil, errors.New("failed to save attachment: " + err.Error())
+	}
+	return toAttachmentInfo(created), nil
+}
+
+// Open returns the file, or its thumbnail, for a participant of the
+// conversation it was sent in.
+func (s *AttachmentService) Open(userID string, conversationID string, attachmentID string, thumbnail bool) (io.ReadCloser, *application.AttachmentInfo, error) {
+	if err := s.checkParticipant(userID, conversationID); err != nil {
+		return nil, nil, err
+	}
+	a, err := s.attachmentRepo.GetByID(attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Unsent uploads are only visible to the uploader
+	if a == nil || a.ConversationID != conversationID || (a.MessageID == "" && a.UploaderID != userID) {
+		return nil, nil, ErrNotFound
+	}
+
+	key := a.BlobKey
+	info := toAttachmentInfo(a)
+	if thumbnail {
+		if a.ThumbnailKey == "" {
+			return nil, nil, errors.New("attachment has no thumbnail")
+		}
+		key = a.ThumbnailKey
+		info.ContentType = "image/jpeg"
+	}
+	content, blob, err := s.blobStore.Get(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if content == nil {
+		return nil, nil, ErrNotFound
+	}
+	info.Size = blob.Size
+	return content, info, nil
+}
+
+// CheckSendable verifies the sender uploaded every attachment to this
+// conversation and has not sent them yet, and returns them in order.
+func (s *AttachmentService) CheckSendable(senderID string, conversationID string, attachmentIDs []string) ([]application.AttachmentInfo, error) {
+	attachments, err := s.attachmentRepo.GetByIDs(attachmentIDs)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]*attachment.Attachment, len(attachments))
+	for _, a := range attachments {
+		if a.UploaderID != senderID || a.ConversationID != conversationID {
+			continue
+		}
+		if a.MessageID != "" {
+			return nil, errors.New("attachment was already sent: " + a.ID)
+		}
+		found[a.ID] = a
+	}
+	res := make([]application.AttachmentInfo, 0, len(attachmentIDs))
+	for _, id := range attachmentIDs {
+		a, ok := found[id]
+		if !ok {
+			return nil, errors.New("attachment not found: " + id)
+		}
+		res = append(res, *toAttachmentInfo(a))
+	}
+	return res, nil
+}
+
+// AttachToMessage links the attachments to a sent message. CheckSendable
+// can pass for two messages sent at once, so only one of them gets the
+// attachments: the other gets ErrAlreadySent and none of them.
+func (s *AttachmentService) AttachToMessage(attachmentIDs []string, messageID string) error {
+	linked, err := s.attachmentRepo.AttachToMessage(attachmentIDs, messageID)
+	distinct := slices.Compact(slices.Sorted(slices.Values(attachmentIDs)))
+	if err == nil && linked == len(distinct) {
+		return nil
+	}
+	if linked > 0 {
+		if err := s.attachmentRepo.DetachFromMessage(messageID); err != nil {
+			log.Printf("Failed to unlink attachments of message %s: %v", messageID, err)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return ErrAlreadySent
+}
+
+// Describe returns the attachments by ID, for rendering messages.
+func (s *AttachmentService) Describe(attachmentIDs []string) (map[string]application.AttachmentInfo, error) {
+	attachments, err := s.attachmentRepo.GetByIDs(attachmentIDs)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]application.AttachmentInfo, len(attachments))
+	for _, a := range attachments {
+		res[a.ID] = *toAttachmentInfo(a)
+	}
+	return res, nil
+}
+
+// CopyToConversation duplicates sent attachments, files included, into
+// another conversation so they can be forwarded. The copies belong to the
+// user forwarding them and are returned in order, ready to be sent.
+func (s *AttachmentService) CopyToConversation(userID string, conversationID string, attachmentIDs []string) ([]string, error) {
+	attachments, err := s.attachmentRepo.GetByIDs(attachmentIDs)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*attachment.Attachment, len(attachments))
+	for _, a := range attachments {
+		byID[a.ID] = a
+	}
+
+	copies := make([]string, 0, len(attachmentIDs))
+	for _, id := range attachmentIDs {
+		original, ok := byID[id]
+		if !ok || original.MessageID == "" {
+			return nil, errors.New("attachment not found: " + id)
+		}
+		a, err := attachment.NewAttachment(conversationID, userID, original.FileName, original.ContentType, original.Size)
+		if err != nil {
+			return nil, err
+		}
+		a.Duration = original.Duration
+		a.Waveform = original.Waveform
+		blobID, err := newBlobID()
+		if err != nil {
+			return nil, err
+		}
+		a.BlobKey = path.Join("attachments", conversationID, blobID)
+		if err := s.copyBlob(original.BlobKey, a.BlobKey); err != nil {
+			return nil, errors.New("failed to copy file: " + err.Error())
+		}
+		if original.ThumbnailKey != "" {
+			a.ThumbnailKey = a.BlobKey + "-thumb.jpg"
+			if err := s.copyBlob(original.ThumbnailKey, a.ThumbnailKey); err != nil {
+				s.deleteBlobs(a)
+				return nil, errors.New("failed to copy thumbnail: " + err.Error())
+			}
+		}
+
+		created, err := s.attachmentRepo.Create(*a)
+		if err != nil {
+			s.deleteBlobs(a)
+			return nil, errors.New("failed to save attachment: " + err.Error())
+		}
+		copies = append(copies, created.ID)
+	}
+	return copies, nil
+}
+
+func (s *AttachmentService) DeleteByConversation(conversationID string) error {
+	attachments, err := s.attachmentRepo.ListByConversation(conversationID)
+	if err != nil {
+		return err
+	}
+	return s.deleteAll(attachments)
+}
+
+func (s *AttachmentService) DeleteByUploader(userID string) error {
+	attachments, err := s.attachmentRepo.ListByUploader(userID)
+	if err != nil {
+		return err
+	}
+	return s.deleteAll(attachments)
+}
+
+// Delete removes attachments of messages that are gone.
+func (s *AttachmentService) Delete(attachmentIDs []string) error {
+	attachments, err := s.attachmentRepo.GetByIDs(attachmentIDs)
+	if err != nil {
+		return err
+	}
+	return s.deleteAll(attachments)
+}
+
+// Helper functions
+
+func (s *AttachmentService) checkParticipant(userID string, conversationID string) error {
+	conv, err := s.conversationRepo.GetByID(conversationID)
+	if err != nil {
+		return err
+	}
+	if conv == nil || !conv.HasParticipant(userID) {
+		return errors.New("conversation not found")
+	}
+	return nil
+}
+
+// deleteAll removes the files before the records, so a failure leaves
+// nothing behind that can't be found again.
+func (s *AttachmentService) deleteAll(attachments []*attachment.Attachment) error {
+	for _, a := range attachments {
+		if err := s.blobStore.Delete(a.BlobKey); err != nil {
+			return err
+		}
+		if a.ThumbnailKey != "" {
+			if err := s.blobStore.Delete(a.ThumbnailKey); err != nil {
+				return err
+			}
+		}
+		if err := s.attachmentRepo.Delete(a.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *AttachmentService) copyBlob(from string, to string) error {
+	content, blob, err := s.blobStore.Get(from)
+	if err != nil {
+		return err
+	}
+	if content == nil {
+		return ErrNotFound
+	}
+	defer content.Close()
+	_, err = s.blobStore.Put(to, blob.ContentType, content)
+	return err
+}
+
+func (s *AttachmentService) deleteBlobs(a *attachment.Attachment) {
+	for _, key := range []string{a.BlobKey, a.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		if err := s.blobStore.Delete(key); err != nil {
+			log.Printf("Failed to delete blob %s: %v", key, err)
+		}
+	}
+}
+
+func newBlobID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// cleanFileName keeps only the base name of what the client sent, without
+// control characters, for display and downloads.
+func cleanFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(path.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		return "file"
+	}
+	for utf8.RuneCountInString(name) > maxFileNameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+func toAttachmentInfo(a *attachment.Attachment) *application.AttachmentInfo {
+	url := "/chat/conversation/" + a.ConversationID + "/attachments/" + a.ID
+	info := &application.AttachmentInfo{
+		ID:          a.ID,
+		FileName:    a.FileName,
+		ContentType: a.ContentType,
+		Size:        a.Size,
+		URL:         url,
+		CreatedAt:   a.CreatedAt.Unix(),
+	}
+	if a.ThumbnailKey != "" {
+		info.ThumbnailURL = url + "/thumbnail"
+	}
+	if a.IsAudio() {
+		info.DurationMs = a.Duration.Milliseconds()
+		info.Waveform = a.Waveform
+	}
+	return info
+}
@@ -0,0 +1,102 @@
+package attachment
+
+import (
+	"backend-chat-app/internal/domain/attachment"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// memoryAttachments links attachments to messages like the Mongo repository:
+// only unsent attachments are linked. Other methods panic.
+type memoryAttachments struct {
+	attachment.AttachmentRepository
+	mu        sync.Mutex
+	messageOf map[string]string
+}
+
+func (r *memoryAttachments) AttachToMessage(attachmentIDs []string, messageID string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	linked := 0
+	for _, id := range attachmentIDs {
+		if current, found := r.messageOf[id]; found && current == "" {
+			r.messageOf[id] = messageID
+			linked++
+		}
+	}
+	return linked, nil
+}
+
+func (r *memoryAttachments) DetachFromMessage(messageID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, current := range r.messageOf {
+		if current == messageID {
+			r.messageOf[id] = ""
+		}
+	}
+	return nil
+}
+
+func TestAttachToMessageIsAllOrNothing(t *testing.T) {
+	repo := &memoryAttachments{messageOf: map[string]string{"a1": "", "a2": "m0", "a3": ""}}
+	s := NewAttachmentService(repo, nil, nil, nil, nil)
+
+	// a2 went out with another message, a1 must not stay linked to m1
+	if err := s.AttachToMessage([]string{"a1", "a2"}, "m1"); !errors.Is(err, ErrAlreadySent) {
+		t.Fatalf("AttachToMessage error = %v, want ErrAlreadySent", err)
+	}
+	if repo.messageOf["a1"] != "" || repo.messageOf["a2"] != "m0" {
+		t.Fatalf("links after a failed attach: %v", repo.messageOf)
+	}
+
+	// Listing an attachment twice does not make it count twice
+	if err := s.AttachToMessage([]string{"a1", "a3", "a1"}, "m2"); err != nil {
+		t.Fatalf("AttachToMessage: %v", err)
+	}
+	if repo.messageOf["a1"] != "m2" || repo.messageOf["a3"] != "m2" {
+		t.Fatalf("links %v, want a1 and a3 on m2", repo.messageOf)
+	}
+}
+
+func TestAttachToMessageConcurrentSends(t *testing.T) {
+	repo := &memoryAttachments{messageOf: map[string]string{"a1": "", "a2": ""}}
+	s := NewAttachmentService(repo, nil, nil, nil, nil)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Half of the sends list the attachments the other way around
+			ids := []string{"a1", "a2"}
+			if i%2 == 1 {
+				ids = []string{"a2", "a1"}
+			}
+			errs[i] = s.AttachToMessage(ids, string(rune('A'+i)))
+		}(i)
+	}
+	wg.Wait()
+
+	sent := 0
+	for _, err := range errs {
+		if err == nil {
+			sent++
+		} else if !errors.Is(err, ErrAlreadySent) {
+			t.Fatalf("AttachToMessage: %v", err)
+		}
+	}
+	// Crossed sends may all fail, but no two succeed and no message keeps
+	// only part of its attachments
+	if sent > 1 {
+		t.Fatalf("%d messages got the attachments", sent)
+	}
+	if repo.messageOf["a1"] != repo.messageOf["a2"] {
+		t.Fatalf("attachments split between messages: %v", repo.messageOf)
+	}
+	if sent == 0 && repo.messageOf["a1"] != "" {
+		t.Fatalf("every send failed and the attachments are still linked: %v", repo.messageOf)
+	}
+}
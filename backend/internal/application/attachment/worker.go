@@ -0,0 +1,35 @@
+package attachment
+
+import (
+	"log"
+	"time"
+)
+
+const (
+	sweepInterval = time.Hour
+	// unsentTTL is how long an upload can wait to be sent in a message
+	unsentTTL = 24 * time.Hour
+)
+
+// RunWorker periodically deletes uploads that were never sent. It blocks,
+// run it in a goroutine.
+func (s *AttachmentService) RunWorker() {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	s.sweep()
+	for range ticker.C {
+		s.sweep()
+	}
+}
+
+func (s *AttachmentService) sweep() {
+	unsent, err := s.attachmentRepo.ListUnsentBefore(time.Now().Add(-unsentTTL))
+	if err != nil {
+		log.Printf("Failed to list unsent attachments: %v", err)
+		return
+	}
+	if err := s.deleteAll(unsent); err != nil {
+		log.Printf("Failed to delete unsent attachments: %v", err)
+	}
+}
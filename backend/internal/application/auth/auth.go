@@ -2,11 +2,15 @@ package auth
 
 import (
 	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/mail"
+	"backend-chat-app/internal/domain/phone"
+	"backend-chat-app/internal/domain/token"
 	"backend-chat-app/internal/domain/user"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -14,32 +18,68 @@ import (
 )
 
 type Service struct {
-	userRepo  user.UserRepository
-	jwtSecret string
+	userRepo       user.UserRepository
+	tokenRepo      token.TokenRepository
+	mailer         mail.Mailer
+	loginGuard     *LoginGuard
+	passwordHasher user.PasswordHasher
+	passwordPolicy *PasswordPolicy
+	phones         *phone.Normalizer
+	jwtSecret      string
+	appBaseURL     string
+	// dummyHash is verified against when the username is unknown so that
+	// both failure paths take about as long as each other.
+	dummyHash string
 }
 
-func NewService(userRepo user.UserRepository, jwtKeySecret string) *Service {
+func NewService(userRepo user.UserRepository, tokenRepo token.TokenRepository, mailer mail.Mailer, loginGuard *LoginGuard, passwordHasher user.PasswordHasher, passwordPolicy *PasswordPolicy, phones *phone.Normalizer, jwtKeySecret string, appBaseURL string) *Service {
+	dummyHash, err := passwordHasher.Hash("dummy-password")
+	if err != nil {
+		log.Printf("Failed to prepare dummy password hash: %v", err)
+	}
 	return &Service{
-		userRepo:  userRepo,
-		jwtSecret: jwtKeySecret,
+		userRepo:       userRepo,
+		tokenRepo:      tokenRepo,
+		mailer:         mailer,
+		loginGuard:     loginGuard,
+		passwordHasher: passwordHasher,
+		passwordPolicy: passwordPolicy,
+		phones:         phones,
+		jwtSecret:      jwtKeySecret,
+		appBaseURL:     appBaseURL,
+		dummyHash:      dummyHash,
 	}
 }
 
 // Login
 
 func (s *Service) Login(request application.LoginRequest) (*application.AuthResponse, error) {
+	attempt, err := s.loginGuard.Begin(request.Username, request.ClientIP)
+	if err != nil {
+		return nil, err
+	}
+
 	user, err := s.userRepo.GetByUsername(request.Username)
 	if err != nil {
+		// Our failure, not the caller's, so it must not count toward a lockout
+		s.loginGuard.Abort(attempt)
 		return nil, err
 	}
-	if user == nil {
-		return nil, errors.New("username doesnt exists")
+	if user == nil || user.Password == "" {
+		// Unknown users and accounts without a local password (SSO, bots)
+		s.passwordHasher.Verify(s.dummyHash, request.Password)
+		s.loginGuard.Failure(attempt, "")
+		return nil, ErrInvalidCredentials
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(request.Password))
-	if err != nil {
-		return nil, errors.New("your password is wrong")
+	match, err := s.passwordHasher.Verify(user.Password, request.Password)
+	if err != nil || !match {
+		s.loginGuard.Failure(attempt, user.ID)
+		return nil, ErrInvalidCredentials
 	}
+	s.loginGuard.Success(attempt)
+	s.upgradePasswordHash(user, request.Password)
+
 	accessToken, refreshToken, err := s.generateAndSaveTokens(user)
 	if err != nil {
 		return nil, err
@@ -60,12 +100,31 @@ func (s *Service) Register(request application.RegisterRequest) (*application.Au
 		return nil, errors.New("username already exists")
 	}
 
-	hashPassword, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost)
+	if err := s.passwordPolicy.Validate(request.Password, request.Username, request.Email); err != nil {
+		return nil, err
+	}
+
+	if request.Phone == "" {
+		return nil, errors.New("phone can not empty")
+	}
+	phoneNumber, err := s.phones.Normalize(request.Phone)
+	if err != nil {
+		return nil, err
+	}
+	phoneOwner, err := s.userRepo.GetByPhone(phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	if phoneOwner != nil {
+		return nil, errors.New("phone already exists")
+	}
+
+	hashPassword, err := s.passwordHasher.Hash(request.Password)
 	if err != nil {
 		return nil, err
 	}
 
-	user, err := user.NewUser(request.Username, string(hashPassword), request.Email, request.Name, request.Phone)
+	user, err := user.NewUser(request.Username, hashPassword, request.Email, request.Name, phoneNumber)
 	if err != nil {
 		return nil, err
 	}
@@ -77,6 +136,10 @@ func (s *Service) Register(request application.RegisterRequest) (*application.Au
 
 	fmt.Print("Create user successfully: ", resUser)
 
+	if err := s.sendEmailVerification(resUser); err != nil {
+		log.Printf("Failed to send verification email to %s: %v", resUser.Email, err)
+	}
+
 	accessToken, refreshToken, err := s.generateAndSaveTokens(resUser)
 	if err != nil {
 		return nil, err
@@ -137,6 +200,7 @@ func (s *Service) createAuthResponse(user *user.User, accessToken, refreshToken
 		User: application.UserData{
 			ID:            user.ID,
 			Name:          user.Name,
+			EmailVerified: user.EmailVerified,
 			Conversations: user.Conversations,
 		},
 		Token: application.TokenData{
@@ -165,6 +229,7 @@ func (s *Service) generateAndSaveTokens(user *user.User) (string, string, error)
 func (s *Service) generateToken(user user.User) (string, string, error) {
 	claims := jwt.MapClaims{
 		"user_id": user.ID,
+		"ver":     user.TokenVersion,
 		"exp":     time.Now().Add(time.Hour * 24).Unix(),
 	}
 	access_token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -180,6 +245,22 @@ func (s *Service) generateToken(user user.User) (string, string, error) {
 	return tokenString, refresh_token, nil
 }
 
+func (s *Service) upgradePasswordHash(user *user.User, password string) {
+	if !s.passwordHasher.NeedsRehash(user.Password) {
+		return
+	}
+	newHash, err := s.passwordHasher.Hash(password)
+	if err != nil {
+		log.Printf("Failed to rehash password for %s: %v", user.ID, err)
+		return
+	}
+	if err := s.userRepo.UpdatePassword(user.ID, newHash); err != nil {
+		log.Printf("Failed to save rehashed password for %s: %v", user.ID, err)
+		return
+	}
+	user.Password = newHash
+}
+
 func (s *Service) ValidateToken(tokenString string) (string, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		// Ensure the signing method is HMAC
@@ -197,6 +278,15 @@ func (s *Service) ValidateToken(tokenString string) (string, error) {
 		if !ok {
 			return "", errors.New("invalid token claims")
 		}
+		// Tokens issued before a password change or reset carry an old version
+		version, _ := claims["ver"].(float64)
+		user, err := s.userRepo.GetByID(userID)
+		if err != nil {
+			return "", err
+		}
+		if user == nil || int(version) != user.TokenVersion {
+			return "", errors.New("session has been revoked")
+		}
 		return userID, nil
 	}
 	return "", errors.New("invalid token")
@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"backend-chat-app/internal/domain/security"
+	"backend-chat-app/internal/domain/token"
+	"backend-chat-app/internal/domain/user"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryUserRepository keeps users in a map. Methods the tests don't reach
+// fall through to the nil embedded interface and panic.
+type memoryUserRepository struct {
+	user.UserRepository
+	mu    sync.Mutex
+	users map[string]*user.User
+}
+
+func newMemoryUserRepository(users ...user.User) *memoryUserRepository {
+	repo := &memoryUserRepository{users: make(map[string]*user.User)}
+	for _, u := range users {
+		u := u
+		repo.users[u.ID] = &u
+	}
+	return repo
+}
+
+func (r *memoryUserRepository) find(match func(u *user.User) bool) *user.User {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.users {
+		if match(u) {
+			found := *u
+			return &found
+		}
+	}
+	return nil
+}
+
+func (r *memoryUserRepository) GetByID(userID string) (*user.User, error) {
+	return r.find(func(u *user.User) bool { return u.ID == userID }), nil
+}
+
+func (r *memoryUserRepository) GetByUsername(username string) (*user.User, error) {
+	return r.find(func(u *user.User) bool { return u.Username == username }), nil
+}
+
+func (r *memoryUserRepository) GetByEmail(email string) (*user.User, error) {
+	return r.find(func(u *user.User) bool { return strings.EqualFold(u.Email, email) }), nil
+}
+
+func (r *memoryUserRepository) GetByOIDCIdentity(issuer string, subject string) (*user.User, error) {
+	return r.find(func(u *user.User) bool { return u.OIDCIssuer == issuer && u.OIDCSubject == subject }), nil
+}
+
+func (r *memoryUserRepository) Create(newUser user.User) (*user.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	newUser.ID = "u" + strconv.Itoa(len(r.users)+1)
+	r.users[newUser.ID] = &newUser
+	created := newUser
+	return &created, nil
+}
+
+func (r *memoryUserRepository) SaveRefreshToken(refreshToken string, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, found := r.users[userID]; found {
+		u.RefreshToken = refreshToken
+	}
+	return nil
+}
+
+func (r *memoryUserRepository) MarkEmailVerified(userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, found := r.users[userID]; found {
+		u.EmailVerified = true
+	}
+	return nil
+}
+
+func (r *memoryUserRepository) UpdatePassword(userID string, hashPassword string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, found := r.users[userID]; found {
+		u.Password = hashPassword
+	}
+	return nil
+}
+
+func (r *memoryUserRepository) RevokeSessions(userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, found := r.users[userID]; found {
+		u.RefreshToken = ""
+		u.TokenVersion++
+	}
+	return nil
+}
+
+type memoryTokenRepository struct {
+	mu     sync.Mutex
+	nextID int
+	tokens map[string]token.Token
+}
+
+func newMemoryTokenRepository() *memoryTokenRepository {
+	return &memoryTokenRepository{tokens: make(map[string]token.Token)}
+}
+
+func (r *memoryTokenRepository) Create(t token.Token) (*token.Token, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	t.ID = strconv.Itoa(r.nextID)
+	r.tokens[t.ID] = t
+	return &t, nil
+}
+
+func (r *memoryTokenRepository) Consume(tokenID string, purpose string) (*token.Token, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, found := r.tokens[tokenID]
+	if !found || t.Purpose != purpose {
+		return nil, nil
+	}
+	delete(r.tokens, tokenID)
+	return &t, nil
+}
+
+func (r *memoryTokenRepository) DeleteByUser(userID string, purpose string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, t := range r.tokens {
+		if t.UserID == userID && t.Purpose == purpose {
+			delete(r.tokens, id)
+		}
+	}
+	return nil
+}
+
+// plainHasher stores passwords with a prefix so tests stay fast.
+type plainHasher struct{}
+
+func (plainHasher) Hash(password string) (string, error) {
+	return "plain:" + password, nil
+}
+
+func (plainHasher) Verify(hash string, password string) (bool, error) {
+	return hash == "plain:"+password, nil
+}
+
+func (plainHasher) NeedsRehash(hash string) bool {
+	return false
+}
+
+// memoryAttemptRepository mirrors the single-update semantics of the Mongo
+// repository under a mutex.
+type memoryAttemptRepository struct {
+	mu       sync.Mutex
+	attempts map[string]security.LoginAttempt
+}
+
+func newMemoryAttemptRepository() *memoryAttemptRepository {
+	return &memoryAttemptRepository{attempts: make(map[string]security.LoginAttempt)}
+}
+
+func (r *memoryAttemptRepository) RecordAttempt(key string, at time.Time, resetAfter time.Duration, waits []time.Duration) (*security.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	attempt := r.attempts[key]
+	attempt.Key = key
+	prior := attempt.Failures
+	if attempt.LastFailureAt.Before(at.Add(-resetAfter)) {
+		prior = 0
+	}
+	wait := waits[min(prior, len(waits)-1)]
+	attempt.Allowed = !at.Before(attempt.LastFailureAt.Add(wait))
+	if attempt.Allowed {
+		attempt.Failures = prior + 1
+		attempt.LastFailureAt = at
+	}
+	r.attempts[key] = attempt
+	return &attempt, nil
+}
+
+func (r *memoryAttemptRepository) Release(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if attempt, found := r.attempts[key]; found && attempt.Failures > 0 {
+		attempt.Failures--
+		r.attempts[key] = attempt
+	}
+	return nil
+}
+
+func (r *memoryAttemptRepository) Delete(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.attempts, key)
+	return nil
+}
+
+func (r *memoryAttemptRepository) set(key string, failures int, lastFailureAt time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts[key] = security.LoginAttempt{Key: key, Failures: failures, LastFailureAt: lastFailureAt}
+}
+
+func (r *memoryAttemptRepository) failures(key string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.attempts[key].Failures
+}
+
+type memoryEventRepository struct {
+	mu     sync.Mutex
+	events []security.Event
+}
+
+func (r *memoryEventRepository) Create(event security.Event) (*security.Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return &event, nil
+}
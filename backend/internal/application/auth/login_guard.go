@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"backend-chat-app/internal/domain/security"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrTooManyAttempts    = errors.New("too many failed login attempts, please try again later")
+)
+
+func DefaultAccountPolicy() security.LockoutPolicy {
+	return security.LockoutPolicy{
+		FreeAttempts:    3,
+		BaseDelay:       2 * time.Second,
+		MaxDelay:        5 * time.Minute,
+		Threshold:       10,
+		LockoutDuration: 30 * time.Minute,
+		ResetAfter:      time.Hour,
+	}
+}
+
+// DefaultIPPolicy is looser than the account policy because many users can
+// share one address behind a NAT.
+func DefaultIPPolicy() security.LockoutPolicy {
+	return security.LockoutPolicy{
+		FreeAttempts:    10,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		Threshold:       50,
+		LockoutDuration: 30 * time.Minute,
+		ResetAfter:      time.Hour,
+	}
+}
+
+// LoginGuard tracks failed logins per account and per client IP.
+type LoginGuard struct {
+	attemptRepo   security.LoginAttemptRepository
+	eventRepo     security.EventRepository
+	accountPolicy security.LockoutPolicy
+	ipPolicy      security.LockoutPolicy
+}
+
+func NewLoginGuard(attemptRepo security.LoginAttemptRepository, eventRepo security.EventRepository, accountPolicy security.LockoutPolicy, ipPolicy security.LockoutPolicy) *LoginGuard {
+	return &LoginGuard{
+		attemptRepo:   attemptRepo,
+		eventRepo:     eventRepo,
+		accountPolicy: accountPolicy,
+		ipPolicy:      ipPolicy,
+	}
+}
+
+func accountKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Attempt is a login that has been counted against its account and client IP
+// and is waiting for the password check.
+type Attempt struct {
+	username string
+	ip       string
+	account  *security.LoginAttempt
+	client   *security.LoginAttempt
+}
+
+// Begin counts a login before the password is checked, so parallel guesses
+// can't all get in ahead of the first recorded failure. It returns
+// ErrTooManyAttempts while either the account or the IP is waiting out a
+// backoff delay or a lockout.
+func (g *LoginGuard) Begin(username string, ip string) (*Attempt, error) {
+	now := time.Now()
+	attempt := &Attempt{username: username, ip: ip}
+
+	account, err := g.attemptRepo.RecordAttempt(accountKey(username), now, g.accountPolicy.ResetAfter, g.accountPolicy.Waits())
+	if err != nil {
+		return nil, err
+	}
+	if !account.Allowed {
+		return nil, ErrTooManyAttempts
+	}
+	attempt.account = account
+
+	if ip != "" {
+		client, err := g.attemptRepo.RecordAttempt(ipKey(ip), now, g.ipPolicy.ResetAfter, g.ipPolicy.Waits())
+		if err != nil || !client.Allowed {
+			g.release(accountKey(username))
+			if err != nil {
+				return nil, err
+			}
+			return nil, ErrTooManyAttempts
+		}
+		attempt.client = client
+	}
+	return attempt, nil
+}
+
+// Failure keeps the attempt counted. Unknown usernames are counted too, so the
+// response never reveals whether an account exists.
+func (g *LoginGuard) Failure(attempt *Attempt, userID string) {
+	g.reportLock(attempt.account, g.accountPolicy, security.EventAccountLocked, userID, attempt.username, attempt.ip)
+	if attempt.client != nil {
+		g.reportLock(attempt.client, g.ipPolicy, security.EventIPLocked, "", "", attempt.ip)
+	}
+}
+
+// Success clears the account counter. The IP only gets this attempt back, so
+// one valid account can't be used to reset an attacker's address.
+func (g *LoginGuard) Success(attempt *Attempt) {
+	if err := g.attemptRepo.Delete(accountKey(attempt.username)); err != nil {
+		log.Printf("Failed to reset login attempts for %s: %v", attempt.username, err)
+	}
+	if attempt.client != nil {
+		g.release(ipKey(attempt.ip))
+	}
+}
+
+// Abort takes the attempt back from both counters, for logins that could
+// not be checked at all.
+func (g *LoginGuard) Abort(attempt *Attempt) {
+	g.release(accountKey(attempt.username))
+	if attempt.client != nil {
+		g.release(ipKey(attempt.ip))
+	}
+}
+
+func (g *LoginGuard) release(key string) {
+	if err := g.attemptRepo.Release(key); err != nil {
+		log.Printf("Failed to release login attempt for %s: %v", key, err)
+	}
+}
+
+func (g *LoginGuard) reportLock(attempt *security.LoginAttempt, policy security.LockoutPolicy, eventType string, userID string, username string, ip string) {
+	wait, locked := policy.NextLock(attempt.Failures)
+	// Report only the failure that crosses the threshold, not every one after it
+	if locked && attempt.Failures == policy.Threshold {
+		g.emit(eventType, userID, username, ip, fmt.Sprintf("%d failed logins, locked for %s", attempt.Failures, wait))
+	}
+}
+
+func (g *LoginGuard) emit(eventType string, userID string, username string, ip string, detail string) {
+	log.Printf("Security event %s: user=%q ip=%q %s", eventType, username, ip, detail)
+	event, err := security.NewEvent(eventType, userID, username, ip, detail)
+	if err != nil {
+		log.Printf("Failed to build security event: %v", err)
+		return
+	}
+	if _, err := g.eventRepo.Create(*event); err != nil {
+		log.Printf("Failed to save security event: %v", err)
+	}
+}
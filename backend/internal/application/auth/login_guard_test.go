@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"backend-chat-app/internal/domain/security"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestLoginGuard() (*LoginGuard, *memoryAttemptRepository, *memoryEventRepository) {
+	attempts := newMemoryAttemptRepository()
+	events := &memoryEventRepository{}
+	return NewLoginGuard(attempts, events, DefaultAccountPolicy(), DefaultIPPolicy()), attempts, events
+}
+
+func TestLoginGuardParallelGuesses(t *testing.T) {
+	guard, attempts, _ := newTestLoginGuard()
+	free := DefaultAccountPolicy().FreeAttempts
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			attempt, err := guard.Begin("alice", "10.0.0.1")
+			if errors.Is(err, ErrTooManyAttempts) {
+				return
+			}
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			allowed++
+			mu.Unlock()
+			guard.Failure(attempt, "u1")
+		}()
+	}
+	wg.Wait()
+
+	// The free attempts plus the one that starts the first delay
+	if allowed != free+1 {
+		t.Fatalf("%d parallel guesses allowed, want %d", allowed, free+1)
+	}
+	if got := attempts.failures(accountKey("alice")); got != allowed {
+		t.Fatalf("account counted %d failures, want %d", got, allowed)
+	}
+}
+
+func TestLoginGuardSuccessReleasesIP(t *testing.T) {
+	guard, attempts, _ := newTestLoginGuard()
+
+	failed, err := guard.Begin("alice", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	guard.Failure(failed, "u1")
+
+	attempt, err := guard.Begin("alice", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	guard.Success(attempt)
+
+	if got := attempts.failures(accountKey("alice")); got != 0 {
+		t.Fatalf("account has %d failures after a success", got)
+	}
+	if got := attempts.failures(ipKey("10.0.0.1")); got != 1 {
+		t.Fatalf("ip has %d failures, want only the failed one", got)
+	}
+}
+
+func TestLoginGuardLockoutEvent(t *testing.T) {
+	guard, attempts, events := newTestLoginGuard()
+	policy := DefaultAccountPolicy()
+
+	// One failure short of the threshold, long enough ago that no delay applies
+	attempts.set(accountKey("alice"), policy.Threshold-1, time.Now().Add(-policy.MaxDelay))
+
+	attempt, err := guard.Begin("alice", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	guard.Failure(attempt, "u1")
+	if len(events.events) != 1 || events.events[0].Type != security.EventAccountLocked {
+		t.Fatalf("expected one account_locked event, got %+v", events.events)
+	}
+	if _, err := guard.Begin("alice", ""); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("locked account allowed, err %v", err)
+	}
+}
+
+func TestLoginGuardAbortReleasesBothCounters(t *testing.T) {
+	guard, attempts, _ := newTestLoginGuard()
+
+	attempt, err := guard.Begin("alice", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	guard.Abort(attempt)
+
+	if got := attempts.failures(accountKey("alice")); got != 0 {
+		t.Fatalf("account has %d failures after an abort", got)
+	}
+	if got := attempts.failures(ipKey("10.0.0.1")); got != 0 {
+		t.Fatalf("ip has %d failures after an abort", got)
+	}
+}
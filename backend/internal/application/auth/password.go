@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"backend-chat-app/internal/application"
+	"errors"
+)
+
+// ChangePassword sets a new password for a signed-in user and revokes every
+// other session. The caller gets fresh tokens so it stays signed in.
+func (s *Service) ChangePassword(req application.ChangePasswordRequest) (*application.AuthResponse, error) {
+	user, err := s.userRepo.GetByID(req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("invalid user exists")
+	}
+	if user.Password == "" {
+		return nil, errors.New("this account has no password, it signs in through single sign-on")
+	}
+
+	match, err := s.passwordHasher.Verify(user.Password, req.CurrentPassword)
+	if err != nil || !match {
+		return nil, errors.New("current password is wrong")
+	}
+	if req.NewPassword == req.CurrentPassword {
+		return nil, errors.New("new password must be different from the current one")
+	}
+	if err := s.passwordPolicy.Validate(req.NewPassword, user.Username, user.Email); err != nil {
+		return nil, err
+	}
+
+	hashPassword, err := s.passwordHasher.Hash(req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.UpdatePassword(user.ID, hashPassword); err != nil {
+		return nil, errors.New("failed to change password: " + err.Error())
+	}
+	if err := s.userRepo.RevokeSessions(user.ID); err != nil {
+		return nil, errors.New("failed to revoke sessions: " + err.Error())
+	}
+
+	// Reload to pick up the new token version
+	user, err = s.userRepo.GetByID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, refreshToken, err := s.generateAndSaveTokens(user)
+	if err != nil {
+		return nil, err
+	}
+	return s.createAuthResponse(user, accessToken, refreshToken), nil
+}
@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/sso"
+	"backend-chat-app/internal/domain/user"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// SSOLoginStateTTL is how long a started login can be completed.
+const SSOLoginStateTTL = 10 * time.Minute
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// SSOService logs users in through an external OpenID Connect provider and
+// then hands out the same tokens as a password login.
+type SSOService struct {
+	authService *Service
+	userRepo    user.UserRepository
+	stateRepo   sso.LoginStateRepository
+	provider    sso.IdentityProvider
+}
+
+func NewSSOService(authService *Service, userRepo user.UserRepository, stateRepo sso.LoginStateRepository, provider sso.IdentityProvider) *SSOService {
+	return &SSOService{
+		authService: authService,
+		userRepo:    userRepo,
+		stateRepo:   stateRepo,
+		provider:    provider,
+	}
+}
+
+// Start
+
+// Start returns the provider URL to send the user to and a binding value.
+// The binding must be kept in the browser that started the login, so a
+// callback carrying someone else's state is refused.
+func (s *SSOService) Start() (string, string, error) {
+	state, err := randomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	codeVerifier, err := randomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	loginState, err := sso.NewLoginState(state, nonce, codeVerifier, SSOLoginStateTTL)
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.stateRepo.Create(*loginState); err != nil {
+		return "", "", errors.New("failed to save login state: " + err.Error())
+	}
+
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	authURL, err := s.provider.AuthCodeURL(state, nonce, base64.RawURLEncoding.EncodeToString(challenge[:]))
+	if err != nil {
+		return "", "", err
+	}
+	return authURL, stateBinding(state), nil
+}
+
+// Callback
+
+func (s *SSOService) Callback(req application.SSOCallbackRequest) (*application.AuthResponse, error) {
+	if req.Code == "" || req.State == "" {
+		return nil, errors.New("code and state are required")
+	}
+	// Checked before Consume so a mismatched callback doesn't burn the real login
+	if subtle.ConstantTimeCompare([]byte(req.Binding), []byte(stateBinding(req.State))) != 1 {
+		return nil, errors.New("login was not started in this browser, please try again")
+	}
+
+	loginState, err := s.stateRepo.Consume(req.State)
+	if err != nil {
+		return nil, err
+	}
+	if loginState == nil || time.Now().After(loginState.ExpiresAt) {
+		return nil, errors.New("login session expired, please try again")
+	}
+
+	identity, err := s.provider.Exchange(req.Code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := s.resolveUser(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, refreshToken, err := s.authService.generateAndSaveTokens(account)
+	if err != nil {
+		return nil, err
+	}
+	return s.authService.createAuthResponse(account, accessToken, refreshToken), nil
+}
+
+// resolveUser finds the user already linked to the identity, links an
+// existing account with the same verified email, or provisions a new one.
+func (s *SSOService) resolveUser(identity *sso.Identity) (*user.User, error) {
+	linked, err := s.userRepo.GetByOIDCIdentity(identity.Issuer, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if linked != nil {
+		return linked, nil
+	}
+
+	if identity.Email == "" {
+		return nil, errors.New("identity provider did not share an email address")
+	}
+
+	existing, err := s.userRepo.GetByEmail(identity.Email)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		// Both sides must have verified the address, otherwise someone could
+		// pre-register a victim's email and later share their SSO account
+		if !identity.EmailVerified || !existing.EmailVerified {
+			return nil, errors.New("an account with this email already exists, verify the email and sign in with your password first")
+		}
+		if existing.OIDCSubject != "" {
+			return nil, errors.New("this account is already linked to another identity")
+		}
+		if err := s.userRepo.LinkOIDCIdentity(existing.ID, identity.Issuer, identity.Subject); err != nil {
+			return nil, errors.New("failed to link account: " + err.Error())
+		}
+		log.Printf("Linked user %s to SSO identity %s", existing.ID, identity.Subject)
+		existing.OIDCIssuer = identity.Issuer
+		existing.OIDCSubject = identity.Subject
+		return existing, nil
+	}
+
+	return s.provisionUser(identity)
+}
+
+func (s *SSOService) provisionUser(identity *sso.Identity) (*user.User, error) {
+	username, err := s.availableUsername(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	newUser, err := user.NewExternalUser(username, identity.Email, identity.Name, s.availablePhone(identity.Phone), identity.Issuer, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	newUser.EmailVerified = identity.EmailVerified
+
+	created, err := s.userRepo.Create(*newUser)
+	if err != nil {
+		return nil, errors.New("failed to create user: " + err.Error())
+	}
+	log.Printf("Provisioned user %s from SSO identity %s", created.ID, identity.Subject)
+	return created, nil
+}
+
+func (s *SSOService) availableUsername(identity *sso.Identity) (string, error) {
+	base := identity.PreferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(identity.Email, "@")
+	}
+	base = usernameInvalidChars.ReplaceAllString(strings.ToLower(base), "")
+	if base == "" {
+		base = "user"
+	}
+
+	candidate := base
+	for i := 0; i < 5; i++ {
+		existing, err := s.userRepo.GetByUsername(candidate)
+		if err != nil {
+			return "", err
+		}
+		if existing == nil {
+			return candidate, nil
+		}
+		suffix := make([]byte, 3)
+		if _, err := rand.Read(suffix); err != nil {
+			return "", err
+		}
+		candidate = base + "-" + hex.EncodeToString(suffix)
+	}
+	return "", errors.New("could not find a free username")
+}
+
+// availablePhone normalizes the phone number shared by the provider. It is
+// dropped when invalid or already used by another account, since the
+// provider's claim is not proof of ownership.
+func (s *SSOService) availablePhone(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	phoneNumber, err := s.authService.phones.Normalize(raw)
+	if err != nil {
+		return ""
+	}
+	owner, err := s.userRepo.GetByPhone(phoneNumber)
+	if err != nil || owner != nil {
+		return ""
+	}
+	return phoneNumber
+}
+
+// stateBinding is a hash of the state, so the cookie alone can't be used to
+// look up the stored login state.
+func stateBinding(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func randomToken(size int) (string, error) {
+	bytes := make([]byte, size)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
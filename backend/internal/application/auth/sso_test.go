@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/sso"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+type memoryLoginStateRepository struct {
+	mu     sync.Mutex
+	states map[string]sso.LoginState
+}
+
+func (r *memoryLoginStateRepository) Create(state sso.LoginState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.states[state.State] = state
+	return nil
+}
+
+func (r *memoryLoginStateRepository) Consume(state string) (*sso.LoginState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, found := r.states[state]
+	if !found {
+		return nil, nil
+	}
+	delete(r.states, state)
+	return &stored, nil
+}
+
+// stubIdentityProvider accepts any code and vouches for one identity. The
+// protocol itself is covered by the oidc package tests.
+type stubIdentityProvider struct{}
+
+func (stubIdentityProvider) AuthCodeURL(state string, nonce string, codeChallenge string) (string, error) {
+	return "https://idp.example.com/authorize?state=" + url.QueryEscape(state), nil
+}
+
+func (stubIdentityProvider) Exchange(code string, codeVerifier string, nonce string) (*sso.Identity, error) {
+	return &sso.Identity{
+		Issuer:        "https://idp.example.com",
+		Subject:       "subject-1",
+		Email:         "sso@example.com",
+		EmailVerified: true,
+		Name:          "SSO User",
+	}, nil
+}
+
+func newTestSSOService(t *testing.T) *SSOService {
+	t.Helper()
+	f := newVerificationFixture(t)
+	states := &memoryLoginStateRepository{states: make(map[string]sso.LoginState)}
+	return NewSSOService(f.service, f.users, states, stubIdentityProvider{})
+}
+
+// startLogin returns the state the provider would echo back and the binding
+// the browser keeps in its cookie.
+func startLogin(t *testing.T, s *SSOService) (string, string) {
+	t.Helper()
+	authURL, binding, err := s.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Query().Get("state"), binding
+}
+
+func TestSSOCallback(t *testing.T) {
+	s := newTestSSOService(t)
+	state, binding := startLogin(t, s)
+
+	res, err := s.Callback(application.SSOCallbackRequest{Code: "code", State: state, Binding: binding})
+	if err != nil {
+		t.Fatalf("Callback: %v", err)
+	}
+	if res.Token.AccessToken == "" {
+		t.Fatal("no access token")
+	}
+}
+
+func TestSSOCallbackReplayedState(t *testing.T) {
+	s := newTestSSOService(t)
+	state, binding := startLogin(t, s)
+
+	req := application.SSOCallbackRequest{Code: "code", State: state, Binding: binding}
+	if _, err := s.Callback(req); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Callback(req); err == nil {
+		t.Fatal("state accepted twice")
+	}
+}
+
+func TestSSOCallbackFromAnotherBrowser(t *testing.T) {
+	s := newTestSSOService(t)
+	// The attacker starts a login and gets the victim's browser to finish it
+	attackerState, attackerBinding := startLogin(t, s)
+	_, victimBinding := startLogin(t, s)
+
+	for _, binding := range []string{"", victimBinding} {
+		_, err := s.Callback(application.SSOCallbackRequest{Code: "code", State: attackerState, Binding: binding})
+		if err == nil {
+			t.Fatalf("callback accepted with binding %q", binding)
+		}
+	}
+
+	// Refused callbacks must not burn the state for the browser that owns it
+	if _, err := s.Callback(application.SSOCallbackRequest{Code: "code", State: attackerState, Binding: attackerBinding}); err != nil {
+		t.Fatalf("Callback with the right binding: %v", err)
+	}
+}
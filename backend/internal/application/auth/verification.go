@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/mail"
+	"backend-chat-app/internal/domain/token"
+	"backend-chat-app/internal/domain/user"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	emailVerificationTTL = 24 * time.Hour
+	passwordResetTTL     = time.Hour
+)
+
+var errInvalidAccountToken = errors.New("invalid or expired token")
+
+// Email verification
+
+func (s *Service) RequestEmailVerification(req application.EmailRequest) error {
+	user, err := s.userRepo.GetByEmail(req.Email)
+	if err != nil {
+		return err
+	}
+	// Same answer whether or not the address exists, so this can't be used to probe for accounts
+	if user == nil || user.EmailVerified {
+		return nil
+	}
+	return s.sendEmailVerification(user)
+}
+
+func (s *Service) VerifyEmail(req application.VerifyEmailRequest) error {
+	userID, err := s.consumeAccountToken(req.Token, token.PurposeEmailVerification)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.MarkEmailVerified(userID); err != nil {
+		return errors.New("failed to verify email: " + err.Error())
+	}
+	return s.tokenRepo.DeleteByUser(userID, token.PurposeEmailVerification)
+}
+
+// Password reset
+
+func (s *Service) RequestPasswordReset(req application.EmailRequest) error {
+	user, err := s.userRepo.GetByEmail(req.Email)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+
+	signed, err := s.issueAccountToken(user.ID, token.PurposePasswordReset, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+	body := fmt.Sprintf("Hi %s,\n\nWe received a request to reset your password. Open the link below to choose a new one:\n\n%s/reset-password?token=%s\n\nThe link expires in 1 hour. If you did not ask for this, you can ignore this email.\n",
+		user.Name, s.appBaseURL, signed)
+	return s.sendEmail(user.Email, "Reset your password", body)
+}
+
+func (s *Service) ResetPassword(req application.ResetPasswordRequest) error {
+	// Checked before the token is burned, so a rejected password can be retried with the same link
+	userID, tokenID, err := s.parseAccountToken(req.Token, token.PurposePasswordReset)
+	if err != nil {
+		return err
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errInvalidAccountToken
+	}
+	if err := s.passwordPolicy.Validate(req.NewPassword, user.Username, user.Email); err != nil {
+		return err
+	}
+	if err := s.burnAccountToken(tokenID, userID, token.PurposePasswordReset); err != nil {
+		return err
+	}
+
+	hashPassword, err := s.passwordHasher.Hash(req.NewPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdatePassword(user.ID, hashPassword); err != nil {
+		return errors.New("failed to reset password: " + err.Error())
+	}
+
+	// Old reset links and every existing session stop working once the password changes
+	if err := s.tokenRepo.DeleteByUser(userID, token.PurposePasswordReset); err != nil {
+		log.Printf("Failed to clear password reset tokens for %s: %v", userID, err)
+	}
+	return s.userRepo.RevokeSessions(userID)
+}
+
+// Helper functions
+
+func (s *Service) sendEmailVerification(user *user.User) error {
+	signed, err := s.issueAccountToken(user.ID, token.PurposeEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	body := fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s/verify-email?token=%s\n\nThe link expires in 24 hours.\n",
+		user.Name, s.appBaseURL, signed)
+	return s.sendEmail(user.Email, "Verify your email address", body)
+}
+
+func (s *Service) sendEmail(to string, subject string, body string) error {
+	email, err := mail.NewEmail(to, subject, body)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(*email)
+}
+
+// issueAccountToken stores a single-use token record and returns a signed
+// JWT that points at it.
+func (s *Service) issueAccountToken(userID string, purpose string, ttl time.Duration) (string, error) {
+	newToken, err := token.NewToken(userID, purpose, ttl)
+	if err != nil {
+		return "", err
+	}
+	saved, err := s.tokenRepo.Create(*newToken)
+	if err != nil {
+		return "", err
+	}
+
+	// "sub" rather than "user_id" so these can never pass ValidateToken as access tokens
+	claims := jwt.MapClaims{
+		"sub":     userID,
+		"jti":     saved.ID,
+		"purpose": purpose,
+		"exp":     saved.ExpiresAt.Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.jwtSecret))
+}
+
+// consumeAccountToken checks the signature and purpose of a token and burns
+// its stored record. It returns the user the token was issued for.
+func (s *Service) consumeAccountToken(tokenString string, purpose string) (string, error) {
+	userID, tokenID, err := s.parseAccountToken(tokenString, purpose)
+	if err != nil {
+		return "", err
+	}
+	if err := s.burnAccountToken(tokenID, userID, purpose); err != nil {
+		return "", err
+	}
+	return userID, nil
+}
+
+// parseAccountToken checks the signature and purpose of a token without
+// touching its stored record. It returns the user and the record id.
+func (s *Service) parseAccountToken(tokenString string, purpose string) (string, string, error) {
+	parsed, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return []byte(s.jwtSecret), nil
+	})
+	if err != nil || !parsed.Valid {
+		return "", "", errInvalidAccountToken
+	}
+
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", "", errInvalidAccountToken
+	}
+	tokenID, _ := claims["jti"].(string)
+	userID, _ := claims["sub"].(string)
+	if claimPurpose, _ := claims["purpose"].(string); claimPurpose != purpose || tokenID == "" {
+		return "", "", errInvalidAccountToken
+	}
+	return userID, tokenID, nil
+}
+
+// burnAccountToken deletes the stored record so the token can't be used again.
+func (s *Service) burnAccountToken(tokenID string, userID string, purpose string) error {
+	stored, err := s.tokenRepo.Consume(tokenID, purpose)
+	if err != nil {
+		return err
+	}
+	if stored == nil || stored.UserID != userID || time.Now().After(stored.ExpiresAt) {
+		return errInvalidAccountToken
+	}
+	return nil
+}
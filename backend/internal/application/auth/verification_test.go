@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/token"
+	"backend-chat-app/internal/domain/user"
+	"backend-chat-app/internal/infrastructure/mailer"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var tokenInBody = regexp.MustCompile(`token=(\S+)`)
+
+type verificationFixture struct {
+	service *Service
+	users   *memoryUserRepository
+	tokens  *memoryTokenRepository
+	outbox  *mailer.OutboxMailer
+}
+
+func newVerificationFixture(t *testing.T) *verificationFixture {
+	t.Helper()
+	policy, err := NewPasswordPolicy(8, 64, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &verificationFixture{
+		users: newMemoryUserRepository(user.User{
+			ID:           "u1",
+			Username:     "alice",
+			Password:     "plain:old-password",
+			Email:        "alice@example.com",
+			Name:         "Alice",
+			RefreshToken: "refresh",
+		}),
+		tokens: newMemoryTokenRepository(),
+		outbox: mailer.NewOutboxMailer("", "noreply@example.com"),
+	}
+	f.service = NewService(f.users, f.tokens, f.outbox, nil, plainHasher{}, policy, nil, "test-secret", "https://chat.example.com")
+	return f
+}
+
+// mailedToken returns the token in the latest email sent to the address.
+func (f *verificationFixture) mailedToken(t *testing.T, to string) string {
+	t.Helper()
+	email, found := f.outbox.Last(to)
+	if !found {
+		t.Fatalf("no email sent to %s", to)
+	}
+	match := tokenInBody.FindStringSubmatch(email.Body)
+	if match == nil {
+		t.Fatalf("no token in email %q", email.Body)
+	}
+	return match[1]
+}
+
+func TestVerifyEmail(t *testing.T) {
+	f := newVerificationFixture(t)
+	if err := f.service.RequestEmailVerification(application.EmailRequest{Email: "alice@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	signed := f.mailedToken(t, "alice@example.com")
+
+	if err := f.service.VerifyEmail(application.VerifyEmailRequest{Token: signed}); err != nil {
+		t.Fatalf("VerifyEmail: %v", err)
+	}
+	if u, _ := f.users.GetByID("u1"); !u.EmailVerified {
+		t.Fatal("email not marked verified")
+	}
+	if err := f.service.VerifyEmail(application.VerifyEmailRequest{Token: signed}); err == nil {
+		t.Fatal("token accepted twice")
+	}
+}
+
+func TestRequestEmailVerificationUnknownAddress(t *testing.T) {
+	f := newVerificationFixture(t)
+	if err := f.service.RequestEmailVerification(application.EmailRequest{Email: "nobody@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if sent := f.outbox.Messages(); len(sent) != 0 {
+		t.Fatalf("sent %d emails for an unknown address", len(sent))
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	f := newVerificationFixture(t)
+	if err := f.service.RequestPasswordReset(application.EmailRequest{Email: "alice@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	signed := f.mailedToken(t, "alice@example.com")
+
+	if err := f.service.ResetPassword(application.ResetPasswordRequest{Token: signed, NewPassword: "new-password-1"}); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	u, _ := f.users.GetByID("u1")
+	if u.Password != "plain:new-password-1" {
+		t.Fatalf("password not changed, got %q", u.Password)
+	}
+	if u.RefreshToken != "" || u.TokenVersion != 1 {
+		t.Fatal("sessions not revoked after reset")
+	}
+
+	err := f.service.ResetPassword(application.ResetPasswordRequest{Token: signed, NewPassword: "new-password-2"})
+	if err == nil {
+		t.Fatal("reset token accepted twice")
+	}
+}
+
+func TestAccountTokenWrongPurpose(t *testing.T) {
+	f := newVerificationFixture(t)
+	if err := f.service.RequestEmailVerification(application.EmailRequest{Email: "alice@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	verifyToken := f.mailedToken(t, "alice@example.com")
+
+	err := f.service.ResetPassword(application.ResetPasswordRequest{Token: verifyToken, NewPassword: "new-password-1"})
+	if err != errInvalidAccountToken {
+		t.Fatalf("verification token used for reset: %v", err)
+	}
+	if u, _ := f.users.GetByID("u1"); u.Password != "plain:old-password" {
+		t.Fatal("password changed with a verification token")
+	}
+
+	// The failed attempt must not have burned the token for its real purpose
+	if err := f.service.VerifyEmail(application.VerifyEmailRequest{Token: verifyToken}); err != nil {
+		t.Fatalf("VerifyEmail after misuse: %v", err)
+	}
+}
+
+func TestAccountTokenExpired(t *testing.T) {
+	f := newVerificationFixture(t)
+	signed, err := f.service.issueAccountToken("u1", token.PurposePasswordReset, -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.service.ResetPassword(application.ResetPasswordRequest{Token: signed, NewPassword: "new-password-1"})
+	if err != errInvalidAccountToken {
+		t.Fatalf("expired token: got %v", err)
+	}
+}
+
+func TestResetPasswordPolicyUsesAccount(t *testing.T) {
+	f := newVerificationFixture(t)
+	if err := f.service.RequestPasswordReset(application.EmailRequest{Email: "alice@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	signed := f.mailedToken(t, "alice@example.com")
+
+	for _, password := range []string{"alice@example.com", "ALICE@example.com"} {
+		if err := f.service.ResetPassword(application.ResetPasswordRequest{Token: signed, NewPassword: password}); err == nil {
+			t.Fatalf("accepted the email %q as password", password)
+		}
+	}
+	// The link still works after a rejected password
+	if err := f.service.ResetPassword(application.ResetPasswordRequest{Token: signed, NewPassword: "new-password-1"}); err != nil {
+		t.Fatalf("ResetPassword after rejection: %v", err)
+	}
+}
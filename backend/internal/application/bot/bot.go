@@ -0,0 +1,274 @@
+package bot
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/apikey"
+	"backend-chat-app/internal/domain/conversation"
+	"backend-chat-app/internal/domain/user"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	keyPrefix = "ck_"
+	// Avoid a database write on every request from a busy bot
+	lastUsedResolution = time.Minute
+)
+
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+type BotService struct {
+	userRepo         user.UserRepository
+	conversationRepo conversation.ConversationRepository
+	apiKeyRepo       apikey.APIKeyRepository
+}
+
+func NewBotService(userRepo user.UserRepository, conversationRepo conversation.ConversationRepository, apiKeyRepo apikey.APIKeyRepository) *BotService {
+	return &BotService{
+		userRepo:         userRepo,
+		conversationRepo: conversationRepo,
+		apiKeyRepo:       apiKeyRepo,
+	}
+}
+
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, keyPrefix)
+}
+
+func (s *BotService) CreateBot(req application.CreateBotRequest) (*application.BotInfo, error) {
+	existing, err := s.userRepo.GetByUsername(req.Username)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New("username already exists")
+	}
+
+	bot, err := user.NewBotUser(req.Username, req.Name, req.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.userRepo.Create(*bot)
+	if err != nil {
+		return nil, errors.New("failed to create bot: " + err.Error())
+	}
+	return toBotInfo(created), nil
+}
+
+func (s *BotService) ListBots(ownerID string) ([]application.BotInfo, error) {
+	bots, err := s.userRepo.GetBotsByOwner(ownerID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]application.BotInfo, 0, len(bots))
+	for _, b := range bots {
+		res = append(res, *toBotInfo(b))
+	}
+	return res, nil
+}
+
+// CreateAPIKey issues a key for a bot. The owner must belong to every
+// conversation named in the scopes; the bot is added to those conversations.
+func (s *BotService) CreateAPIKey(ownerID string, botID string, req application.CreateAPIKeyRequest) (*application.CreateAPIKeyResponse, error) {
+	bot, err := s.getOwnedBot(ownerID, botID)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := make([]apikey.Scope, 0, len(req.Scopes))
+	conversations := make(map[string]*conversation.Conversation)
+	for _, raw := range req.Scopes {
+		scope, err := apikey.ParseScope(raw)
+		if err != nil {
+			return nil, err
+		}
+		if _, seen := conversations[scope.ConversationID]; !seen {
+			conv, err := s.conversationRepo.GetByID(scope.ConversationID)
+			if err != nil {
+				return nil, errors.New("invalid conversation in scope: " + scope.ConversationID)
+			}
+			if conv == nil || !conv.HasParticipant(ownerID) {
+				return nil, errors.New("you are not a participant of conversation " + scope.ConversationID)
+			}
+			conversations[scope.ConversationID] = conv
+		}
+		scopes = append(scopes, *scope)
+	}
+
+	var expiresAt time.Time
+	if req.ExpiresInDays > 0 {
+		expiresAt = time.Now().AddDate(0, 0, req.ExpiresInDays)
+	}
+
+	prefix, secret, err := generateKey()
+	if err != nil {
+		return nil, err
+	}
+	rawKey := keyPrefix + prefix + "_" + secret
+
+	newKey, err := apikey.NewAPIKey(bot.ID, ownerID, req.Name, prefix, hashKey(rawKey), scopes, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.apiKeyRepo.Create(*newKey)
+	if err != nil {
+		return nil, errors.New("failed to create api key: " + err.Error())
+	}
+
+	for conversationID, conv := range conversations {
+		if conv.HasParticipant(bot.ID) {
+			continue
+		}
+		if _, err := s.conversationRepo.AddParticipant(conversationID, conversation.Participant{ID: bot.ID, Name: bot.PublicName()}); err != nil {
+			return nil, errors.New("failed to add bot to conversation: " + err.Error())
+		}
+		if err := s.userRepo.AddConversation(bot.ID, conversationID); err != nil {
+			return nil, errors.New("failed to add bot to conversation: " + err.Error())
+		}
+	}
+
+	return &application.CreateAPIKeyResponse{
+		Key:    rawKey,
+		APIKey: toAPIKeyInfo(created),
+	}, nil
+}
+
+func (s *BotService) ListAPIKeys(ownerID string, botID string) ([]application.APIKeyInfo, error) {
+	if _, err := s.getOwnedBot(ownerID, botID); err != nil {
+		return nil, err
+	}
+	keys, err := s.apiKeyRepo.GetByBot(botID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]application.APIKeyInfo, 0, len(keys))
+	for _, k := range keys {
+		res = append(res, toAPIKeyInfo(k))
+	}
+	return res, nil
+}
+
+func (s *BotService) RevokeAPIKey(ownerID string, botID string, keyID string) error {
+	if _, err := s.getOwnedBot(ownerID, botID); err != nil {
+		return err
+	}
+	return s.apiKeyRepo.Revoke(keyID, botID)
+}
+
+// Authenticate resolves a raw key from a request to its stored record.
+func (s *BotService) Authenticate(rawKey string) (*apikey.APIKey, error) {
+	body, ok := strings.CutPrefix(rawKey, keyPrefix)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+	prefix, _, ok := strings.Cut(body, "_")
+	if !ok || prefix == "" {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.apiKeyRepo.GetByPrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrInvalidAPIKey
+	}
+	if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hashKey(rawKey))) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if !key.IsActive(now) {
+		return nil, ErrInvalidAPIKey
+	}
+	if now.Sub(key.LastUsedAt) > lastUsedResolution {
+		if err := s.apiKeyRepo.TouchLastUsed(key.ID, now); err != nil {
+			log.Printf("Failed to update last use of api key %s: %v", key.ID, err)
+		}
+	}
+	return key, nil
+}
+
+// Recheck loads a key again, so a connection opened with it stops being
+// served once the key is revoked or expires.
+func (s *BotService) Recheck(key *apikey.APIKey) (*apikey.APIKey, error) {
+	current, err := s.apiKeyRepo.GetByPrefix(key.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil || current.ID != key.ID || !current.IsActive(time.Now()) {
+		return nil, ErrInvalidAPIKey
+	}
+	return current, nil
+}
+
+// Helper functions
+
+func (s *BotService) getOwnedBot(ownerID string, botID string) (*user.User, error) {
+	bot, err := s.userRepo.GetByID(botID)
+	if err != nil {
+		return nil, err
+	}
+	if bot == nil || !bot.IsBot || bot.OwnerID != ownerID {
+		return nil, errors.New("bot not found")
+	}
+	return bot, nil
+}
+
+func generateKey() (string, string, error) {
+	prefixBytes := make([]byte, 8)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", err
+	}
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(prefixBytes), base64.RawURLEncoding.EncodeToString(secretBytes), nil
+}
+
+// Keys carry 256 bits of randomness, so a fast hash is enough here
+func hashKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+func toBotInfo(u *user.User) *application.BotInfo {
+	return &application.BotInfo{
+		ID:        u.ID,
+		Username:  u.Username,
+		Name:      u.Name,
+		CreatedAt: u.CreatedAt.Unix(),
+	}
+}
+
+func toAPIKeyInfo(k *apikey.APIKey) application.APIKeyInfo {
+	scopes := make([]string, len(k.Scopes))
+	for i, scope := range k.Scopes {
+		scopes[i] = scope.String()
+	}
+	info := application.APIKeyInfo{
+		ID:        k.ID,
+		Name:      k.Name,
+		Prefix:    keyPrefix + k.Prefix,
+		Scopes:    scopes,
+		CreatedAt: k.CreatedAt.Unix(),
+	}
+	if !k.ExpiresAt.IsZero() {
+		info.ExpiresAt = k.ExpiresAt.Unix()
+	}
+	if !k.RevokedAt.IsZero() {
+		info.RevokedAt = k.RevokedAt.Unix()
+	}
+	if !k.LastUsedAt.IsZero() {
+		info.LastUsedAt = k.LastUsedAt.Unix()
+	}
+	return info
+}
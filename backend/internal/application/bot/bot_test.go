@@ -0,0 +1,184 @@
+package bot
+
+import (
+	"backend-chat-app/internal/domain/apikey"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryAPIKeyRepository keeps keys by prefix.
+type memoryAPIKeyRepository struct {
+	mu   sync.Mutex
+	keys map[string]*apikey.APIKey
+}
+
+func (r *memoryAPIKeyRepository) Create(key apikey.APIKey) (*apikey.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[key.Prefix] = &key
+	return &key, nil
+}
+
+func (r *memoryAPIKeyRepository) GetByPrefix(prefix string) (*apikey.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, found := r.keys[prefix]
+	if !found {
+		return nil, nil
+	}
+	copied := *key
+	return &copied, nil
+}
+
+func (r *memoryAPIKeyRepository) GetByBot(botID string) ([]*apikey.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var keys []*apikey.APIKey
+	for _, key := range r.keys {
+		if key.BotID == botID {
+			copied := *key
+			keys = append(keys, &copied)
+		}
+	}
+	return keys, nil
+}
+
+func (r *memoryAPIKeyRepository) Revoke(keyID string, botID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range r.keys {
+		if key.ID == keyID && key.BotID == botID {
+			key.RevokedAt = time.Now()
+		}
+	}
+	return nil
+}
+
+func (r *memoryAPIKeyRepository) TouchLastUsed(keyID string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range r.keys {
+		if key.ID == keyID {
+			key.LastUsedAt = at
+		}
+	}
+	return nil
+}
+
+// issueKey stores a key for bot b1 the way CreateAPIKey does and returns the
+// raw key handed to the owner.
+func issueKey(t *testing.T, repo *memoryAPIKeyRepository, id string, expiresAt time.Time) string {
+	t.Helper()
+	prefix, secret, err := generateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawKey := keyPrefix + prefix + "_" + secret
+	scopes := []apikey.Scope{{Action: apikey.ActionMessagesWrite, ConversationID: "c1"}}
+	key, err := apikey.NewAPIKey("b1", "owner", "ci", prefix, hashKey(rawKey), scopes, expiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key.ID = id
+	if _, err := repo.Create(*key); err != nil {
+		t.Fatal(err)
+	}
+	return rawKey
+}
+
+func newTestBotService() (*BotService, *memoryAPIKeyRepository) {
+	repo := &memoryAPIKeyRepository{keys: make(map[string]*apikey.APIKey)}
+	return NewBotService(nil, nil, repo), repo
+}
+
+func TestAuthenticate(t *testing.T) {
+	s, repo := newTestBotService()
+	rawKey := issueKey(t, repo, "k1", time.Time{})
+
+	key, err := s.Authenticate(rawKey)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if key.ID != "k1" || key.BotID != "b1" {
+		t.Fatalf("unexpected key %+v", key)
+	}
+	if !key.Allows(apikey.ActionMessagesWrite, "c1") || key.Allows(apikey.ActionMessagesRead, "c2") {
+		t.Fatal("key scopes not kept")
+	}
+	if stored, _ := repo.GetByPrefix(key.Prefix); stored.LastUsedAt.IsZero() {
+		t.Fatal("last use not recorded")
+	}
+}
+
+func TestAuthenticateRejectsBadKeys(t *testing.T) {
+	s, repo := newTestBotService()
+	rawKey := issueKey(t, repo, "k1", time.Time{})
+	prefix := rawKey[:len(keyPrefix)+16]
+
+	for _, candidate := range []string{
+		"",
+		"not-a-key",
+		keyPrefix,
+		keyPrefix + "_secret",
+		prefix + "_wrong-secret",
+		keyPrefix + "0000000000000000_" + "secret",
+		rawKey + "x",
+	} {
+		if _, err := s.Authenticate(candidate); !errors.Is(err, ErrInvalidAPIKey) {
+			t.Errorf("%q: got %v, want %v", candidate, err, ErrInvalidAPIKey)
+		}
+	}
+}
+
+func TestAuthenticateRejectsExpiredAndRevokedKeys(t *testing.T) {
+	s, repo := newTestBotService()
+	expired := issueKey(t, repo, "k1", time.Now().Add(-time.Minute))
+	revoked := issueKey(t, repo, "k2", time.Time{})
+	if err := repo.Revoke("k2", "b1"); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, rawKey := range map[string]string{"expired": expired, "revoked": revoked} {
+		if _, err := s.Authenticate(rawKey); !errors.Is(err, ErrInvalidAPIKey) {
+			t.Errorf("%s key: got %v, want %v", name, err, ErrInvalidAPIKey)
+		}
+	}
+}
+
+func TestRecheckNoticesRevocation(t *testing.T) {
+	s, repo := newTestBotService()
+	rawKey := issueKey(t, repo, "k1", time.Time{})
+	key, err := s.Authenticate(rawKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Recheck(key); err != nil {
+		t.Fatalf("Recheck of a valid key: %v", err)
+	}
+	if err := repo.Revoke("k1", "b1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Recheck(key); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Fatalf("Recheck after revoke: got %v, want %v", err, ErrInvalidAPIKey)
+	}
+}
+
+func TestRecheckNoticesExpiry(t *testing.T) {
+	s, repo := newTestBotService()
+	rawKey := issueKey(t, repo, "k1", time.Now().Add(time.Hour))
+	key, err := s.Authenticate(rawKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The connection outlives the key
+	repo.mu.Lock()
+	repo.keys[key.Prefix].ExpiresAt = time.Now().Add(-time.Second)
+	repo.mu.Unlock()
+	if _, err := s.Recheck(key); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Fatalf("Recheck after expiry: got %v, want %v", err, ErrInvalidAPIKey)
+	}
+}
@@ -2,24 +2,48 @@ package chat
 
 import (
 	"backend-chat-app/internal/application"
+	attachmentapp "backend-chat-app/internal/application/attachment"
+	linkpreviewapp "backend-chat-app/internal/application/linkpreview"
+	"backend-chat-app/internal/domain/contact"
 	"backend-chat-app/internal/domain/conversation"
 	"backend-chat-app/internal/domain/message"
+	"backend-chat-app/internal/domain/phone"
 	"backend-chat-app/internal/domain/user"
 	"errors"
 	"fmt"
+	"log"
+	"time"
 )
 
 type ChatService struct {
-	messageRepo      message.MessageRepository
-	conversationRepo conversation.ConversationRepository
-	userRepo         user.UserRepository
+	messageRepo       message.MessageRepository
+	conversationRepo  conversation.ConversationRepository
+	userRepo          user.UserRepository
+	contactRepo       contact.ContactRepository
+	blockRepo         contact.BlockRepository
+	phones            *phone.Normalizer
+	settingsRepo      conversation.SettingsRepository
+	inviteRepo        conversation.InviteRepository
+	pinRepo           message.PinRepository
+	scheduledRepo     message.ScheduledMessageRepository
+	attachmentService *attachmentapp.AttachmentService
+	linkPreviews      *linkpreviewapp.LinkPreviewService
 }
 
-func NewChatService(messageRepo message.MessageRepository, conversationRepo conversation.ConversationRepository, userRepo user.UserRepository) *ChatService {
+func NewChatService(messageRepo message.MessageRepository, conversationRepo conversation.ConversationRepository, userRepo user.UserRepository, contactRepo contact.ContactRepository, blockRepo contact.BlockRepository, phones *phone.Normalizer, settingsRepo conversation.SettingsRepository, inviteRepo conversation.InviteRepository, pinRepo message.PinRepository, scheduledRepo message.ScheduledMessageRepository, attachmentService *attachmentapp.AttachmentService, linkPreviews *linkpreviewapp.LinkPreviewService) *ChatService {
 	return &ChatService{
-		messageRepo:      messageRepo,
-		conversationRepo: conversationRepo,
-		userRepo:         userRepo,
+		messageRepo:       messageRepo,
+		conversationRepo:  conversationRepo,
+		userRepo:          userRepo,
+		contactRepo:       contactRepo,
+		blockRepo:         blockRepo,
+		phones:            phones,
+		settingsRepo:      settingsRepo,
+		inviteRepo:        inviteRepo,
+		pinRepo:           pinRepo,
+		scheduledRepo:     scheduledRepo,
+		attachmentService: attachmentService,
+		linkPreviews:      linkPreviews,
 	}
 }
 
@@ -32,6 +56,12 @@ func (s *ChatService) CreateConversation(req application.CreateConversationReque
 		return nil, errors.New("current user not found")
 	}
 
+	friendPhone, err := s.phones.Normalize(req.FriendPhone)
+	if err != nil {
+		return nil, err
+	}
+	req.FriendPhone = friendPhone
+
 	friendUser, err := s.userRepo.GetByPhone(req.FriendPhone)
 	if err != nil {
 		return nil, errors.New("failed to get friend user: " + err.Error())
@@ -40,6 +70,24 @@ func (s *ChatService) CreateConversation(req application.CreateConversationReque
 		return nil, errors.New("friend user not found")
 	}
 
+	blocked, err := s.blockRepo.IsBlockedEither(currentUser.ID, friendUser.ID)
+	if err != nil {
+		return nil, errors.New("failed to check blocks: " + err.Error())
+	}
+	if blocked {
+		return nil, errors.New("you can not start a conversation with this user")
+	}
+
+	if friendUser.ContactsOnly {
+		isContact, err := s.contactRepo.IsContact(friendUser.ID, currentUser.ID)
+		if err != nil {
+			return nil, errors.New("failed to check contacts: " + err.Error())
+		}
+		if !isContact {
+			return nil, errors.New("this user only accepts conversations from contacts")
+		}
+	}
+
 	check, err := s.conversationRepo.IsCommunicate(currentUser.ID, friendUser.ID)
 	if err != nil {
 		return nil, errors.New("failed to check communicate: " + err.Error())
@@ -52,11 +100,11 @@ func (s *ChatService) CreateConversation(req application.CreateConversationReque
 	participants := []conversation.Participant{
 		{
 			ID:   currentUser.ID,
-			Name: currentUser.Name,
+			Name: currentUser.PublicName(),
 		},
 		{
 			ID:   friendUser.ID,
-			Name: friendUser.Name,
+			Name: friendUser.PublicName(),
 		},
 	}
 
@@ -64,6 +112,8 @@ func (s *ChatService) CreateConversation(req application.CreateConversationReque
 	if err != nil {
 		return nil, err
 	}
+	newConversation.OwnerID = currentUser.ID
+	newConversation.Direct = true
 
 	res, err := s.conversationRepo.Create(*newConversation)
 	if err != nil {
@@ -84,38 +134,263 @@ func (s *ChatService) CreateConversation(req application.CreateConversationReque
 }
 
 func (s *ChatService) SendMessage(req application.SendMessageRequest) (*application.SendMessageResponse, error) {
-	message, err := message.NewMessage(req.ConversationID, req.SenderID, req.Message)
+	conv, err := s.conversationRepo.GetByID(req.ConversationID)
+	if err != nil {
+		return nil, errors.New("send message failed at GetConversation: " + err.Error())
+	}
+	if conv == nil || !conv.HasParticipant(req.SenderID) {
+		return nil, errors.New("conversation not found")
+	}
+	if err := s.checkNotBlocked(conv, req.SenderID); err != nil {
+		return nil, err
+	}
+
+	newMessage, err := s.newMessage(req)
 	if err != nil {
 		return nil, errors.New("send message failed at NewMessage: " + err.Error())
 	}
-	res, err := s.messageRepo.Create(*message)
+	newMessage.Mentions, newMessage.MentionedIDs = s.resolveMentions(conv, req.SenderID, newMessage.Message, newMessage.Entities)
+	return s.saveMessage(conv, newMessage)
+}
+
+// saveMessage stores a new message of the conversation, sends its
+// attachments and makes it the last message.
+func (s *ChatService) saveMessage(conv *conversation.Conversation, newMessage *message.Message) (*application.SendMessageResponse, error) {
+	if conv.MessageTTL > 0 {
+		newMessage.ExpiresAt = newMessage.CreatedAt.Add(conv.MessageTTL)
+	}
+	res, err := s.messageRepo.Create(*newMessage)
 	if err != nil {
 		return nil, errors.New("send message failed at CreateMessage: " + err.Error())
 	}
+	var attachments []application.AttachmentInfo
+	if len(res.AttachmentIDs) > 0 {
+		// Another message may have taken the attachments since they were
+		// checked, the message can't go out without them
+		if err := s.attachmentService.AttachToMessage(res.AttachmentIDs, res.ID); err != nil {
+			if err := s.messageRepo.DeleteByIDs([]string{res.ID}); err != nil {
+				log.Printf("Failed to delete message %s without its attachments: %v", res.ID, err)
+			}
+			return nil, errors.New("send message failed at AttachToMessage: " + err.Error())
+		}
+		attachments = s.describeAttachments([]*message.Message{res})[res.ID]
+	}
 
-	return &application.SendMessageResponse{
-		Message:   res.Message,
-		CreatedAt: res.CreatedAt.Unix(),
-	}, nil
+	last := conversation.LastMessage{ID: res.ID, SenderID: res.SenderID, Text: res.Message, CreatedAt: res.CreatedAt}
+	if err := s.conversationRepo.SetLastMessage(res.ConversationID, last); err != nil {
+		log.Printf("Failed to update last message of %s: %v", res.ConversationID, err)
+	}
+	// Previews arrive later as a message_preview event
+	if res.Type == "" && len(res.LinkPreviews) == 0 {
+		s.linkPreviews.Enqueue(res.ConversationID, res.ID, res.Message)
+	}
+
+	sent := &application.SendMessageResponse{
+		MessageID:      res.ID,
+		ConversationID: res.ConversationID,
+		Type:           res.Type,
+		Message:        res.Message,
+		Attachments:    attachments,
+		Mentions:       toMentionInfos(res.Mentions),
+		Entities:       toEntities(res),
+		ForwardedFrom:  toForwardInfo(res.ForwardedFrom),
+		Poll:           toPollInfo(res.Poll, time.Now()),
+		CreatedAt:      res.CreatedAt.Unix(),
+		Notification:   s.notificationFor(conv, res),
+	}
+	if !res.ExpiresAt.IsZero() {
+		sent.ExpiresAt = res.ExpiresAt.Unix()
+	}
+	return sent, nil
 }
 
-func (s *ChatService) GetConversation(conversationID string) (*application.GetConversationMessageResponse, error) {
+// newMessage builds the message to send: text, text with attachments, or a
+// voice note. Markup is stored as plain text with its formatting entities.
+func (s *ChatService) newMessage(req application.SendMessageRequest) (*message.Message, error) {
+	var text string
+	var entities []message.Entity
+	switch req.Format {
+	case "":
+		text = message.SanitizeText(req.Message)
+	case message.FormatMarkup:
+		text, entities = message.ParseMarkup(req.Message)
+	default:
+		return nil, errors.New("unknown message format: " + req.Format)
+	}
+
+	m, err := s.buildMessage(req, text)
+	if err != nil {
+		return nil, err
+	}
+	m.ID = req.MessageID
+	m.Entities = entities
+	return m, nil
+}
+
+func (s *ChatService) buildMessage(req application.SendMessageRequest, text string) (*message.Message, error) {
+	if req.Poll != nil && req.Type != message.TypePoll {
+		return nil, errors.New("poll is only sent with type poll")
+	}
+	switch req.Type {
+	case "":
+		if len(req.AttachmentIDs) == 0 {
+			return message.NewMessage(req.ConversationID, req.SenderID, text)
+		}
+		if _, err := s.attachmentService.CheckSendable(req.SenderID, req.ConversationID, req.AttachmentIDs); err != nil {
+			return nil, err
+		}
+		return message.NewAttachmentMessage(req.ConversationID, req.SenderID, text, req.AttachmentIDs)
+	case message.TypeVoice:
+		if len(req.AttachmentIDs) != 1 || text != "" {
+			return nil, errors.New("a voice message is a single audio attachment without text")
+		}
+		attachments, err := s.attachmentService.CheckSendable(req.SenderID, req.ConversationID, req.AttachmentIDs)
+		if err != nil {
+			return nil, err
+		}
+		if attachments[0].DurationMs == 0 {
+			return nil, errors.New("voice messages must be a WAV or Ogg Opus recording")
+		}
+		return message.NewVoiceMessage(req.ConversationID, req.SenderID, req.AttachmentIDs[0])
+	case message.TypePoll:
+		if req.Poll == nil || len(req.AttachmentIDs) > 0 {
+			return nil, errors.New("a poll is a question with options and no attachments")
+		}
+		var closesAt time.Time
+		if req.Poll.ClosesAt != 0 {
+			closesAt = time.Unix(req.Poll.ClosesAt, 0)
+		}
+		return message.NewPollMessage(req.ConversationID, req.SenderID, text, req.Poll.Options, req.Poll.Multiple, req.Poll.Anonymous, closesAt)
+	default:
+		return nil, errors.New("unknown message type: " + req.Type)
+	}
+}
+
+// notificationFor addresses a new message to every other participant who has
+// not muted the conversation. Muted users still receive the message itself,
+// and a mention event when they are mentioned.
+func (s *ChatService) notificationFor(conv *conversation.Conversation, m *message.Message) *application.MessageNotification {
+	muted := make(map[string]bool)
+	mutedUsers, err := s.settingsRepo.ListMutedUsers(conv.ID, time.Now())
+	if err != nil {
+		log.Printf("Failed to get muted users of %s: %v", conv.ID, err)
+	}
+	for _, userID := range mutedUsers {
+		muted[userID] = true
+	}
+
+	notification := &application.MessageNotification{
+		ConversationID: conv.ID,
+		MessageID:      m.ID,
+		SenderID:       m.SenderID,
+		Mentioned:      m.MentionedIDs,
+		Message:        m.Message,
+		CreatedAt:      m.CreatedAt.Unix(),
+	}
+	for _, p := range conv.Participant {
+		if p.ID == m.SenderID {
+			notification.SenderName = p.Name
+			continue
+		}
+		if !muted[p.ID] {
+			notification.Recipients = append(notification.Recipients, p.ID)
+		}
+	}
+	return notification
+}
+
+func (s *ChatService) GetConversation(userID string, conversationID string) (*application.GetConversationMessageResponse, error) {
+	if _, err := s.getParticipatingConversation(userID, conversationID); err != nil {
+		return nil, err
+	}
 
 	messages, err := s.messageRepo.GetMessagesByConversationID(conversationID)
 	if err != nil {
 		return nil, err
 	}
+	attachments := s.describeAttachments(messages)
 	// Convert *[]message.Message to []application.Message
 	var appMessages []application.Message
+	now := time.Now()
 	for _, m := range messages {
-		appMessages = append(appMessages, application.Message{
-			SenderID:  m.SenderID,
-			Message:   m.Message,
-			CreatedAt: m.CreatedAt.Unix(),
-		})
+		// Expired messages may not have been deleted yet
+		if m.IsExpired(now) {
+			continue
+		}
+		appMessages = append(appMessages, toMessage(m, attachments[m.ID]))
 	}
 	return &application.GetConversationMessageResponse{
 		ConversationID: conversationID,
 		Messages:       appMessages,
 	}, nil
 }
+
+func toMessage(m *message.Message, attachments []application.AttachmentInfo) application.Message {
+	res := application.Message{
+		ID:            m.ID,
+		SenderID:      m.SenderID,
+		Type:          m.Type,
+		Message:       m.Message,
+		Attachments:   attachments,
+		LinkPreviews:  linkpreviewapp.ToLinkPreviews(m.LinkPreviews),
+		Mentions:      toMentionInfos(m.Mentions),
+		Entities:      toEntities(m),
+		ForwardedFrom: toForwardInfo(m.ForwardedFrom),
+		Poll:          toPollInfo(m.Poll, time.Now()),
+		Redacted:      m.Redacted,
+		CreatedAt:     m.CreatedAt.Unix(),
+	}
+	if !m.ExpiresAt.IsZero() {
+		res.ExpiresAt = m.ExpiresAt.Unix()
+	}
+	return res
+}
+
+// describeAttachments looks up the attachments of all messages at once and
+// returns them by message ID, in the order they were sent.
+func (s *ChatService) describeAttachments(messages []*message.Message) map[string][]application.AttachmentInfo {
+	var ids []string
+	for _, m := range messages {
+		ids = append(ids, m.AttachmentIDs...)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	described, err := s.attachmentService.Describe(ids)
+	if err != nil {
+		log.Printf("Failed to get attachments: %v", err)
+		return nil
+	}
+
+	res := make(map[string][]application.AttachmentInfo)
+	for _, m := range messages {
+		for _, id := range m.AttachmentIDs {
+			if info, ok := described[id]; ok {
+				res[m.ID] = append(res[m.ID], info)
+			}
+		}
+	}
+	return res
+}
+
+// checkNotBlocked rejects messages in a one-to-one conversation where either
+// side blocked the other, also when bots were added to it. Group
+// conversations are not affected.
+func (s *ChatService) checkNotBlocked(conv *conversation.Conversation, senderID string) error {
+	if !conv.Direct {
+		return nil
+	}
+	for _, p := range conv.Participant {
+		if p.ID == senderID {
+			continue
+		}
+		blocked, err := s.blockRepo.IsBlockedEither(senderID, p.ID)
+		if err != nil {
+			return errors.New("failed to check blocks: " + err.Error())
+		}
+		if blocked {
+			return errors.New("you can not send messages to this user")
+		}
+	}
+	return nil
+}
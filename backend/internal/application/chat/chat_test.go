@@ -0,0 +1,137 @@
+package chat
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/conversation"
+	"backend-chat-app/internal/domain/message"
+	"backend-chat-app/internal/domain/phone"
+	"backend-chat-app/internal/domain/user"
+	"testing"
+)
+
+func testUsers() *memoryUsers {
+	return newMemoryUsers(
+		user.User{ID: "alice", Name: "Alice", Phone: "+84901000001"},
+		user.User{ID: "bob", Name: "Bob", Phone: "+84901000002"},
+		user.User{ID: "carol", Name: "Carol", Phone: "+84901000003", ContactsOnly: true},
+	)
+}
+
+func newCreateFixture(t *testing.T, blocks *memoryBlocks, contacts *memoryContacts) (*ChatService, *memoryConversations) {
+	t.Helper()
+	phones, err := phone.NewNormalizer("VN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conversations := newMemoryConversations()
+	s := &ChatService{
+		userRepo:         testUsers(),
+		conversationRepo: conversations,
+		blockRepo:        blocks,
+		contactRepo:      contacts,
+		phones:           phones,
+	}
+	return s, conversations
+}
+
+func TestCreateConversationIsDirect(t *testing.T) {
+	s, conversations := newCreateFixture(t, newMemoryBlocks(), newMemoryContacts())
+	res, err := s.CreateConversation(application.CreateConversationRequest{MineID: "alice", FriendPhone: "0901000002"})
+	if err != nil {
+		t.Fatalf("CreateConversation: %v", err)
+	}
+	conv, _ := conversations.GetByID(res.ID)
+	if !conv.Direct || conv.OwnerID != "alice" || !conv.HasParticipant("bob") {
+		t.Fatalf("unexpected conversation %+v", conv)
+	}
+
+	if _, err := s.CreateConversation(application.CreateConversationRequest{MineID: "bob", FriendPhone: "+84901000001"}); err == nil {
+		t.Fatal("second conversation between the same users")
+	}
+}
+
+func TestCreateConversationRefused(t *testing.T) {
+	tests := []struct {
+		name     string
+		blocks   *memoryBlocks
+		contacts *memoryContacts
+		friend   string
+	}{
+		{"blocked by friend", newMemoryBlocks([2]string{"bob", "alice"}), newMemoryContacts(), "+84901000002"},
+		{"friend blocked", newMemoryBlocks([2]string{"alice", "bob"}), newMemoryContacts(), "+84901000002"},
+		{"contacts only", newMemoryBlocks(), newMemoryContacts(), "+84901000003"},
+		{"unknown phone", newMemoryBlocks(), newMemoryContacts(), "+84901000009"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, conversations := newCreateFixture(t, tt.blocks, tt.contacts)
+			if _, err := s.CreateConversation(application.CreateConversationRequest{MineID: "alice", FriendPhone: tt.friend}); err == nil {
+				t.Fatal("conversation created")
+			}
+			if len(conversations.conversations) != 0 {
+				t.Fatal("conversation stored")
+			}
+		})
+	}
+}
+
+func TestCreateConversationWithContact(t *testing.T) {
+	s, _ := newCreateFixture(t, newMemoryBlocks(), newMemoryContacts([2]string{"alice", "carol"}))
+	if _, err := s.CreateConversation(application.CreateConversationRequest{MineID: "alice", FriendPhone: "+84901000003"}); err != nil {
+		t.Fatalf("contact refused: %v", err)
+	}
+}
+
+func TestCheckNotBlocked(t *testing.T) {
+	participants := func(ids ...string) []conversation.Participant {
+		var res []conversation.Participant
+		for _, id := range ids {
+			res = append(res, conversation.Participant{ID: id})
+		}
+		return res
+	}
+	tests := []struct {
+		name    string
+		conv    conversation.Conversation
+		blocked bool
+	}{
+		{"direct", conversation.Conversation{Direct: true, Participant: participants("alice", "bob")}, true},
+		// Adding a bot must not lift the block
+		{"direct with a bot", conversation.Conversation{Direct: true, Participant: participants("alice", "bob", "robot")}, true},
+		{"group", conversation.Conversation{Participant: participants("alice", "bob")}, false},
+		{"larger group", conversation.Conversation{Participant: participants("alice", "bob", "carol")}, false},
+	}
+	s := &ChatService{blockRepo: newMemoryBlocks([2]string{"bob", "alice"})}
+	for _, tt := range tests {
+		err := s.checkNotBlocked(&tt.conv, "alice")
+		if (err != nil) != tt.blocked {
+			t.Errorf("%s: got %v, blocked %v", tt.name, err, tt.blocked)
+		}
+	}
+
+	direct := conversation.Conversation{Direct: true, Participant: participants("alice", "carol")}
+	if err := s.checkNotBlocked(&direct, "alice"); err != nil {
+		t.Fatalf("unrelated users blocked: %v", err)
+	}
+}
+
+func TestNotificationForSkipsMutedUsers(t *testing.T) {
+	s := &ChatService{settingsRepo: mutedSettings{muted: []string{"carol"}}}
+	conv := &conversation.Conversation{
+		ID:          "c1",
+		Participant: []conversation.Participant{{ID: "alice", Name: "Alice"}, {ID: "bob", Name: "Bob"}, {ID: "carol", Name: "Carol"}},
+	}
+	m := &message.Message{ID: "m1", ConversationID: "c1", SenderID: "alice", Message: "hi @carol", MentionedIDs: []string{"carol"}}
+
+	notification := s.notificationFor(conv, m)
+	if notification.SenderName != "Alice" {
+		t.Fatalf("sender name %q, want Alice", notification.SenderName)
+	}
+	if len(notification.Recipients) != 1 || notification.Recipients[0] != "bob" {
+		t.Fatalf("recipients %v, want only bob", notification.Recipients)
+	}
+	// Mentions still reach muted users
+	if len(notification.Mentioned) != 1 || notification.Mentioned[0] != "carol" {
+		t.Fatalf("mentioned %v, want carol", notification.Mentioned)
+	}
+}
@@ -0,0 +1,274 @@
+package chat
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/conversation"
+	"backend-chat-app/internal/domain/message"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// LeaveConversation removes the user from a conversation and records it in
+// the history. The last participant to leave deletes the conversation.
+func (s *ChatService) LeaveConversation(userID string, conversationID string) (*application.ConversationChange, error) {
+	conv, err := s.getParticipatingConversation(userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The conversation returned by the removal is the one to go by: of users
+	// leaving at the same time, only the last one sees it empty
+	left, err := s.conversationRepo.RemoveParticipant(conv.ID, userID)
+	if err != nil {
+		return nil, errors.New("failed to leave conversation: " + err.Error())
+	}
+	if left == nil {
+		return nil, errors.New("conversation not found")
+	}
+	s.forgetConversation(userID, conv.ID)
+
+	if len(left.Participant) == 0 {
+		deleted, err := s.conversationRepo.DeleteIfEmpty(conv.ID)
+		if err != nil {
+			return nil, errors.New("failed to delete conversation: " + err.Error())
+		}
+		if deleted {
+			s.deleteHistory(conv.ID)
+			return &application.ConversationChange{ConversationID: conv.ID, Participants: []string{userID}}, nil
+		}
+		// Someone joined in the meantime, the conversation stays
+		if left, err = s.conversationRepo.GetByID(conv.ID); err != nil || left == nil {
+			return nil, errors.New("failed to leave conversation")
+		}
+	}
+
+	remaining := participantIDs(left)
+	// Someone has to be able to delete the conversation
+	if conv.OwnerID == userID {
+		if nextOwner := s.nextOwner(remaining); nextOwner != "" {
+			if err := s.conversationRepo.SetOwner(conv.ID, nextOwner); err != nil {
+				log.Printf("Failed to hand conversation %s over to %s: %v", conv.ID, nextOwner, err)
+			}
+		}
+	}
+
+	systemMessage := s.addSystemMessage(conv.ID, userID, conv.ParticipantName(userID)+" left")
+	return &application.ConversationChange{
+		ConversationID: conv.ID,
+		UserID:         userID,
+		Message:        systemMessage,
+		Participants:   remaining,
+	}, nil
+}
+
+// RenameConversation lets any participant change the name. An empty name
+// removes it.
+func (s *ChatService) RenameConversation(userID string, conversationID string, req application.RenameConversationRequest) (*application.ConversationChange, error) {
+	name := strings.TrimSpace(req.Name)
+	if utf8.RuneCountInString(name) > conversation.MaxNameLength {
+		return nil, fmt.Errorf("name must be at most %d characters", conversation.MaxNameLength)
+	}
+	conv, err := s.getParticipatingConversation(userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if name == conv.Name {
+		return nil, errors.New("conversation already has this name")
+	}
+
+	if err := s.conversationRepo.Rename(conv.ID, name); err != nil {
+		return nil, errors.New("failed to rename conversation: " + err.Error())
+	}
+
+	text := conv.ParticipantName(userID) + " named the conversation " + name
+	if name == "" {
+		text = conv.ParticipantName(userID) + " removed the conversation name"
+	}
+	systemMessage := s.addSystemMessage(conv.ID, userID, text)
+	return &application.ConversationChange{
+		ConversationID: conv.ID,
+		Name:           name,
+		Message:        systemMessage,
+		Participants:   participantIDs(conv),
+	}, nil
+}
+
+// SetMessageTTL makes messages sent from now on disappear after the timer,
+// or keeps them when it is zero. Any participant can change it.
+func (s *ChatService) SetMessageTTL(userID string, conversationID string, req application.SetMessageTTLRequest) (*application.ConversationChange, error) {
+	ttl := time.Duration(req.MessageTTL) * time.Second
+	if err := conversation.CheckMessageTTL(ttl); err != nil {
+		return nil, err
+	}
+	conv, err := s.getParticipatingConversation(userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if ttl == conv.MessageTTL {
+		return nil, errors.New("conversation already has this timer")
+	}
+
+	if err := s.conversationRepo.SetMessageTTL(conv.ID, ttl); err != nil {
+		return nil, errors.New("failed to set message timer: " + err.Error())
+	}
+
+	text := conv.ParticipantName(userID) + " set messages to disappear after " + describeTTL(ttl)
+	if ttl == 0 {
+		text = conv.ParticipantName(userID) + " turned off disappearing messages"
+	}
+	systemMessage := s.addSystemMessage(conv.ID, userID, text)
+	return &application.ConversationChange{
+		ConversationID: conv.ID,
+		MessageTTL:     &req.MessageTTL,
+		Message:        systemMessage,
+		Participants:   participantIDs(conv),
+	}, nil
+}
+
+// describeTTL writes a message timer the way the system message reads it.
+func describeTTL(ttl time.Duration) string {
+	unit, count := "hour", int(ttl/time.Hour)
+	if ttl%(24*time.Hour) == 0 {
+		unit, count = "day", int(ttl/(24*time.Hour))
+	}
+	if count == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", count, unit)
+}
+
+// DeleteConversation deletes the conversation and its history for everyone.
+func (s *ChatService) DeleteConversation(userID string, conversationID string) (*application.ConversationChange, error) {
+	conv, err := s.getParticipatingConversation(userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if !conv.CanDelete(userID) {
+		return nil, errors.New("only the owner can delete this conversation")
+	}
+	return s.deleteConversation(conv)
+}
+
+func (s *ChatService) deleteConversation(conv *conversation.Conversation) (*application.ConversationChange, error) {
+	if err := s.conversationRepo.Delete(conv.ID); err != nil {
+		return nil, errors.New("failed to delete conversation: " + err.Error())
+	}
+	for _, p := range conv.Participant {
+		s.forgetConversation(p.ID, conv.ID)
+	}
+	s.deleteHistory(conv.ID)
+	return &application.ConversationChange{
+		ConversationID: conv.ID,
+		Participants:   participantIDs(conv),
+	}, nil
+}
+
+// deleteHistory removes everything stored for a conversation that was
+// deleted.
+func (s *ChatService) deleteHistory(conversationID string) {
+	if err := s.messageRepo.DeleteByConversation(conversationID); err != nil {
+		log.Printf("Failed to delete messages of conversation %s: %v", conversationID, err)
+	}
+	if err := s.settingsRepo.DeleteByConversation(conversationID); err != nil {
+		log.Printf("Failed to delete settings of conversation %s: %v", conversationID, err)
+	}
+	if err := s.attachmentService.DeleteByConversation(conversationID); err != nil {
+		log.Printf("Failed to delete attachments of conversation %s: %v", conversationID, err)
+	}
+	if err := s.inviteRepo.DeleteByConversation(conversationID); err != nil {
+		log.Printf("Failed to delete invites of conversation %s: %v", conversationID, err)
+	}
+	if err := s.pinRepo.DeleteByConversation(conversationID); err != nil {
+		log.Printf("Failed to delete pins of conversation %s: %v", conversationID, err)
+	}
+	if err := s.scheduledRepo.DeleteByConversation(conversationID); err != nil {
+		log.Printf("Failed to delete scheduled messages of conversation %s: %v", conversationID, err)
+	}
+	log.Printf("Deleted conversation %s", conversationID)
+}
+
+// Helper functions
+
+// CheckParticipant returns an error unless the user is currently in the
+// conversation.
+func (s *ChatService) CheckParticipant(userID string, conversationID string) error {
+	_, err := s.getParticipatingConversation(userID, conversationID)
+	return err
+}
+
+// nextOwner returns the first human among the participants, or "" when only
+// bots are left. Bots can't own conversations, they act for their owners.
+func (s *ChatService) nextOwner(participantIDs []string) string {
+	for _, participantID := range participantIDs {
+		participant, err := s.userRepo.GetByID(participantID)
+		if err != nil {
+			log.Printf("Failed to load participant %s: %v", participantID, err)
+			continue
+		}
+		if participant != nil && !participant.IsBot {
+			return participant.ID
+		}
+	}
+	return ""
+}
+
+func (s *ChatService) getParticipatingConversation(userID string, conversationID string) (*conversation.Conversation, error) {
+	conv, err := s.conversationRepo.GetByID(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if conv == nil || !conv.HasParticipant(userID) {
+		return nil, errors.New("conversation not found")
+	}
+	return conv, nil
+}
+
+// forgetConversation drops the conversation from the user's own list and
+// settings.
+func (s *ChatService) forgetConversation(userID string, conversationID string) {
+	if err := s.userRepo.RemoveConversation(userID, conversationID); err != nil {
+		log.Printf("Failed to remove conversation %s from user %s: %v", conversationID, userID, err)
+	}
+	if err := s.settingsRepo.Delete(userID, conversationID); err != nil {
+		log.Printf("Failed to delete settings of %s in conversation %s: %v", userID, conversationID, err)
+	}
+}
+
+// addSystemMessage writes an event into the history. Failing to do so does
+// not undo the change it describes.
+func (s *ChatService) addSystemMessage(conversationID string, actorID string, text string) *application.Message {
+	m, err := message.NewSystemMessage(conversationID, actorID, text)
+	if err != nil {
+		log.Printf("Failed to create system message in %s: %v", conversationID, err)
+		return nil
+	}
+	res, err := s.messageRepo.Create(*m)
+	if err != nil {
+		log.Printf("Failed to save system message in %s: %v", conversationID, err)
+		return nil
+	}
+
+	last := conversation.LastMessage{ID: res.ID, SenderID: res.SenderID, Text: res.Message, CreatedAt: res.CreatedAt}
+	if err := s.conversationRepo.SetLastMessage(conversationID, last); err != nil {
+		log.Printf("Failed to update last message of %s: %v", conversationID, err)
+	}
+	return &application.Message{
+		ID:        res.ID,
+		SenderID:  res.SenderID,
+		Type:      res.Type,
+		Message:   res.Message,
+		CreatedAt: res.CreatedAt.Unix(),
+	}
+}
+
+func participantIDs(conv *conversation.Conversation) []string {
+	ids := make([]string, 0, len(conv.Participant))
+	for _, p := range conv.Participant {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
@@ -0,0 +1,148 @@
+package chat
+
+import (
+	attachmentapp "backend-chat-app/internal/application/attachment"
+	"backend-chat-app/internal/domain/conversation"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newLeaveFixture(conversations *memoryConversations) (*ChatService, *memoryMessages) {
+	messages := &memoryMessages{}
+	return &ChatService{
+		userRepo:          testUsers(),
+		conversationRepo:  conversations,
+		messageRepo:       messages,
+		settingsRepo:      mutedSettings{},
+		inviteRepo:        noInvites{},
+		pinRepo:           noPins{},
+		scheduledRepo:     noScheduled{},
+		attachmentService: attachmentapp.NewAttachmentService(noAttachments{}, nil, nil, nil, nil),
+	}, messages
+}
+
+func TestLeaveConversationHandsOverOwnership(t *testing.T) {
+	conversations := newMemoryConversations(conversation.Conversation{
+		ID:          "c1",
+		OwnerID:     "alice",
+		Participant: []conversation.Participant{{ID: "alice", Name: "Alice"}, {ID: "bob", Name: "Bob"}},
+	})
+	s, messages := newLeaveFixture(conversations)
+
+	change, err := s.LeaveConversation("alice", "c1")
+	if err != nil {
+		t.Fatalf("LeaveConversation: %v", err)
+	}
+	if len(change.Participants) != 1 || change.Participants[0] != "bob" {
+		t.Fatalf("remaining %v, want bob", change.Participants)
+	}
+	if change.Message == nil || change.Message.Message != "Alice left" {
+		t.Fatalf("system message %+v", change.Message)
+	}
+	conv, _ := conversations.GetByID("c1")
+	if conv.OwnerID != "bob" {
+		t.Fatalf("owner %q, want bob", conv.OwnerID)
+	}
+
+	if _, err := s.LeaveConversation("alice", "c1"); err == nil {
+		t.Fatal("left a conversation twice")
+	}
+
+	if _, err := s.LeaveConversation("bob", "c1"); err != nil {
+		t.Fatalf("last participant leaving: %v", err)
+	}
+	if conv, _ := conversations.GetByID("c1"); conv != nil {
+		t.Fatal("the conversation outlived its last participant")
+	}
+	if len(messages.messages) != 0 {
+		t.Fatalf("%d messages left after the conversation was deleted", len(messages.messages))
+	}
+}
+
+func TestConcurrentLeavesDeleteTheConversationOnce(t *testing.T) {
+	for round := 0; round < 50; round++ {
+		var participants []conversation.Participant
+		for _, id := range []string{"alice", "bob", "carol"} {
+			participants = append(participants, conversation.Participant{ID: id})
+		}
+		conversations := newMemoryConversations(conversation.Conversation{ID: "c1", Participant: participants})
+		s, _ := newLeaveFixture(conversations)
+
+		var wg sync.WaitGroup
+		for _, p := range participants {
+			wg.Add(1)
+			go func(userID string) {
+				defer wg.Done()
+				if _, err := s.LeaveConversation(userID, "c1"); err != nil {
+					t.Errorf("%s leaving: %v", userID, err)
+				}
+			}(p.ID)
+		}
+		wg.Wait()
+
+		if conv, _ := conversations.GetByID("c1"); conv != nil {
+			t.Fatalf("round %d: everyone left and the conversation is still there with %v", round, conv.Participant)
+		}
+		if conversations.deleted != 1 {
+			t.Fatalf("round %d: deleted %d times, want once", round, conversations.deleted)
+		}
+	}
+}
+
+// joinOnRemove adds a participant right after the last one is removed, as a
+// join through an invite could.
+type joinOnRemove struct {
+	*memoryConversations
+	joiner conversation.Participant
+}
+
+func (r *joinOnRemove) RemoveParticipant(conversationID string, userID string) (*conversation.Conversation, error) {
+	left, err := r.memoryConversations.RemoveParticipant(conversationID, userID)
+	if _, joinErr := r.AddParticipant(conversationID, r.joiner); joinErr != nil {
+		return nil, joinErr
+	}
+	return left, err
+}
+
+func TestLeaveConversationKeepsItWhenSomeoneJoins(t *testing.T) {
+	conversations := newMemoryConversations(conversation.Conversation{
+		ID:          "c1",
+		OwnerID:     "alice",
+		Participant: []conversation.Participant{{ID: "alice", Name: "Alice"}},
+	})
+	s, _ := newLeaveFixture(conversations)
+	s.conversationRepo = &joinOnRemove{memoryConversations: conversations, joiner: conversation.Participant{ID: "bob", Name: "Bob"}}
+
+	change, err := s.LeaveConversation("alice", "c1")
+	if err != nil {
+		t.Fatalf("LeaveConversation: %v", err)
+	}
+	conv, _ := conversations.GetByID("c1")
+	if conv == nil {
+		t.Fatal("the conversation was deleted while bob joined")
+	}
+	if fmt.Sprint(change.Participants) != "[bob]" || conv.OwnerID != "bob" {
+		t.Fatalf("remaining %v with owner %q, want bob for both", change.Participants, conv.OwnerID)
+	}
+}
+
+func TestDescribeTTL(t *testing.T) {
+	tests := []struct {
+		ttl  time.Duration
+		want string
+	}{
+		{time.Hour, "1 hour"},
+		{2 * time.Hour, "2 hours"},
+		{24 * time.Hour, "1 day"},
+		{7 * 24 * time.Hour, "7 days"},
+		{30 * 24 * time.Hour, "30 days"},
+		{36 * time.Hour, "36 hours"},
+	}
+	for _, tt := range tests {
+		if got := describeTTL(tt.ttl); got != tt.want {
+			t.Errorf("describeTTL(%v) = %q, want %q", tt.ttl, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package chat
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/conversation"
+	"backend-chat-app/internal/domain/message"
+	"log"
+	"slices"
+	"time"
+)
+
+const (
+	expirySweepInterval = time.Minute
+	expiryBatchSize     = 500
+)
+
+// ExpiryService deletes the messages of conversations with a message timer
+// once they expire. Until then readers skip expired messages themselves.
+type ExpiryService struct {
+	chatService *ChatService
+	// expired tells the participants which messages disappeared
+	expired func(*application.MessagesExpired)
+}
+
+func NewExpiryService(chatService *ChatService, expired func(*application.MessagesExpired)) *ExpiryService {
+	return &ExpiryService{
+		chatService: chatService,
+		expired:     expired,
+	}
+}
+
+// RunWorker periodically deletes expired messages. It blocks, run it in a
+// goroutine.
+func (s *ExpiryService) RunWorker() {
+	ticker := time.NewTicker(expirySweepInterval)
+	defer ticker.Stop()
+
+	s.sweep()
+	for range ticker.C {
+		s.sweep()
+	}
+}
+
+func (s *ExpiryService) sweep() {
+	for {
+		expired, err := s.chatService.messageRepo.ListExpired(time.Now(), expiryBatchSize)
+		if err != nil {
+			log.Printf("Failed to list expired messages: %v", err)
+			return
+		}
+
+		byConversation := make(map[string][]*message.Message)
+		for _, m := range expired {
+			byConversation[m.ConversationID] = append(byConversation[m.ConversationID], m)
+		}
+		for conversationID, messages := range byConversation {
+			if err := s.deleteExpired(conversationID, messages); err != nil {
+				log.Printf("Failed to delete expired messages of %s: %v", conversationID, err)
+				return
+			}
+		}
+		if len(expired) < expiryBatchSize {
+			return
+		}
+	}
+}
+
+func (s *ExpiryService) deleteExpired(conversationID string, messages []*message.Message) error {
+	messageIDs := make([]string, 0, len(messages))
+	var attachmentIDs []string
+	for _, m := range messages {
+		messageIDs = append(messageIDs, m.ID)
+		attachmentIDs = append(attachmentIDs, m.AttachmentIDs...)
+	}
+
+	if err := s.chatService.messageRepo.DeleteByIDs(messageIDs); err != nil {
+		return err
+	}
+	if len(attachmentIDs) > 0 {
+		if err := s.chatService.attachmentService.Delete(attachmentIDs); err != nil {
+			log.Printf("Failed to delete attachments of expired messages in %s: %v", conversationID, err)
+		}
+	}
+	unpinned, err := s.chatService.pinRepo.DeleteByMessages(conversationID, messageIDs)
+	if err != nil {
+		log.Printf("Failed to unpin expired messages in %s: %v", conversationID, err)
+	}
+	if unpinned > 0 {
+		s.chatService.releasePins(conversationID, unpinned)
+	}
+	s.replaceLastMessage(conversationID, messageIDs)
+
+	s.expired(&application.MessagesExpired{ConversationID: conversationID, MessageIDs: messageIDs})
+	return nil
+}
+
+// replaceLastMessage puts the newest remaining message in conversation
+// lists when the last one expired.
+func (s *ExpiryService) replaceLastMessage(conversationID string, expiredIDs []string) {
+	conv, err := s.chatService.conversationRepo.GetByID(conversationID)
+	if err != nil {
+		log.Printf("Failed to get conversation %s: %v", conversationID, err)
+		return
+	}
+	if conv == nil || conv.LastMessage == nil || !slices.Contains(expiredIDs, conv.LastMessage.ID) {
+		return
+	}
+
+	latest, err := s.chatService.messageRepo.GetLatest(conversationID)
+	if err != nil {
+		log.Printf("Failed to get latest message of %s: %v", conversationID, err)
+		return
+	}
+	var last *conversation.LastMessage
+	if latest != nil {
+		last = &conversation.LastMessage{ID: latest.ID, SenderID: latest.SenderID, Text: latest.Message, CreatedAt: latest.CreatedAt}
+	}
+	if err := s.chatService.conversationRepo.ReplaceLastMessage(conversationID, conv.LastMessage.ID, last); err != nil {
+		log.Printf("Failed to replace last message of %s: %v", conversationID, err)
+	}
+}
@@ -0,0 +1,53 @@
+package chat
+
+import (
+	"backend-chat-app/internal/domain/conversation"
+	"backend-chat-app/internal/domain/message"
+	"testing"
+	"time"
+)
+
+func TestReplaceLastMessage(t *testing.T) {
+	sent := time.Unix(1767225600, 0)
+	older := &message.Message{ID: "older", ConversationID: "c1", SenderID: "bob", Message: "still here", CreatedAt: sent}
+	last := &conversation.LastMessage{ID: "newest", SenderID: "alice", Text: "poof", CreatedAt: sent.Add(time.Minute)}
+
+	tests := []struct {
+		name      string
+		expiredID string
+		remaining []*message.Message
+		want      *conversation.LastMessage
+	}{
+		{
+			name:      "the newest remaining message takes over",
+			expiredID: "newest",
+			remaining: []*message.Message{older},
+			want:      &conversation.LastMessage{ID: "older", SenderID: "bob", Text: "still here", CreatedAt: sent},
+		},
+		{
+			name:      "no message is left",
+			expiredID: "newest",
+			want:      nil,
+		},
+		{
+			name:      "an older message expired",
+			expiredID: "older",
+			want:      last,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversations := newMemoryConversations(conversation.Conversation{ID: "c1", LastMessage: last})
+			s, messages := newLeaveFixture(conversations)
+			messages.messages = tt.remaining
+			expiry := NewExpiryService(s, nil)
+
+			expiry.replaceLastMessage("c1", []string{tt.expiredID})
+
+			conv, _ := conversations.GetByID("c1")
+			if (conv.LastMessage == nil) != (tt.want == nil) || conv.LastMessage != nil && *conv.LastMessage != *tt.want {
+				t.Errorf("last message = %+v, want %+v", conv.LastMessage, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,431 @@
+package chat
+
+import (
+	"backend-chat-app/internal/domain/attachment"
+	"backend-chat-app/internal/domain/contact"
+	"backend-chat-app/internal/domain/conversation"
+	"backend-chat-app/internal/domain/message"
+	"backend-chat-app/internal/domain/user"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The fakes below keep data in maps. Methods the tests don't reach fall
+// through to the nil embedded interface and panic.
+
+type memoryUsers struct {
+	user.UserRepository
+	mu    sync.Mutex
+	users map[string]*user.User
+}
+
+func newMemoryUsers(users ...user.User) *memoryUsers {
+	repo := &memoryUsers{users: make(map[string]*user.User)}
+	for _, u := range users {
+		u := u
+		repo.users[u.ID] = &u
+	}
+	return repo
+}
+
+func (r *memoryUsers) GetByID(userID string) (*user.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, found := r.users[userID]
+	if !found {
+		return nil, nil
+	}
+	copied := *u
+	return &copied, nil
+}
+
+func (r *memoryUsers) GetByPhone(phone string) (*user.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.users {
+		if u.Phone == phone {
+			copied := *u
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryUsers) AddConversationtoParticipants(userID string, phone string, conversationID string) error {
+	return nil
+}
+
+func (r *memoryUsers) AddConversation(userID string, conversationID string) error {
+	return nil
+}
+
+func (r *memoryUsers) RemoveConversation(userID string, conversationID string) error {
+	return nil
+}
+
+type memoryConversations struct {
+	conversation.ConversationRepository
+	mu            sync.Mutex
+	conversations map[string]*conversation.Conversation
+	deleted       int
+}
+
+func newMemoryConversations(conversations ...conversation.Conversation) *memoryConversations {
+	repo := &memoryConversations{conversations: make(map[string]*conversation.Conversation)}
+	for _, c := range conversations {
+		c := c
+		c.Participant = slices.Clone(c.Participant)
+		repo.conversations[c.ID] = &c
+	}
+	return repo
+}
+
+func (r *memoryConversations) Create(c conversation.Conversation) (*conversation.Conversation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c.ID = "c" + strconv.Itoa(len(r.conversations)+1)
+	r.conversations[c.ID] = &c
+	copied := c
+	return &copied, nil
+}
+
+func (r *memoryConversations) GetByID(conversationID string) (*conversation.Conversation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, found := r.conversations[conversationID]
+	if !found {
+		return nil, nil
+	}
+	copied := *c
+	copied.Participant = slices.Clone(c.Participant)
+	return &copied, nil
+}
+
+func (r *memoryConversations) IsCommunicate(participant1ID string, participant2ID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.conversations {
+		if c.Direct && c.HasParticipant(participant1ID) && c.HasParticipant(participant2ID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryConversations) AddParticipant(conversationID string, participant conversation.Participant) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, found := r.conversations[conversationID]
+	if !found || c.HasParticipant(participant.ID) {
+		return false, nil
+	}
+	c.Participant = append(c.Participant, participant)
+	return true, nil
+}
+
+func (r *memoryConversations) RemoveParticipant(conversationID string, userID string) (*conversation.Conversation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, found := r.conversations[conversationID]
+	if !found || !c.HasParticipant(userID) {
+		return nil, nil
+	}
+	c.Participant = slices.DeleteFunc(c.Participant, func(p conversation.Participant) bool { return p.ID == userID })
+	copied := *c
+	copied.Participant = slices.Clone(c.Participant)
+	return &copied, nil
+}
+
+func (r *memoryConversations) DeleteIfEmpty(conversationID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, found := r.conversations[conversationID]
+	if !found || len(c.Participant) > 0 {
+		return false, nil
+	}
+	delete(r.conversations, conversationID)
+	r.deleted++
+	return true, nil
+}
+
+func (r *memoryConversations) SetOwner(conversationID string, ownerID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, found := r.conversations[conversationID]; found {
+		c.OwnerID = ownerID
+	}
+	return nil
+}
+
+func (r *memoryConversations) SetLastMessage(conversationID string, last conversation.LastMessage) error {
+	return nil
+}
+
+func (r *memoryConversations) ReplaceLastMessage(conversationID string, messageID string, last *conversation.LastMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, found := r.conversations[conversationID]
+	if !found || c.LastMessage == nil || c.LastMessage.ID != messageID {
+		return nil
+	}
+	c.LastMessage = last
+	return nil
+}
+
+// memoryBlocks holds blocks as blocker/blocked pairs.
+type memoryBlocks struct {
+	contact.BlockRepository
+	blocked map[[2]string]bool
+}
+
+func newMemoryBlocks(pairs ...[2]string) *memoryBlocks {
+	repo := &memoryBlocks{blocked: make(map[[2]string]bool)}
+	for _, pair := range pairs {
+		repo.blocked[pair] = true
+	}
+	return repo
+}
+
+func (r *memoryBlocks) IsBlocked(blockerID string, blockedID string) (bool, error) {
+	return r.blocked[[2]string{blockerID, blockedID}], nil
+}
+
+func (r *memoryBlocks) IsBlockedEither(userA string, userB string) (bool, error) {
+	return r.blocked[[2]string{userA, userB}] || r.blocked[[2]string{userB, userA}], nil
+}
+
+func (r *memoryBlocks) ListRelated(userID string) ([]string, error) {
+	var related []string
+	for pair := range r.blocked {
+		if pair[0] == userID {
+			related = append(related, pair[1])
+		} else if pair[1] == userID {
+			related = append(related, pair[0])
+		}
+	}
+	return related, nil
+}
+
+// memoryContacts holds contacts as user/contact pairs, one per direction.
+type memoryContacts struct {
+	contact.ContactRepository
+	contacts map[[2]string]bool
+}
+
+func newMemoryContacts(pairs ...[2]string) *memoryContacts {
+	repo := &memoryContacts{contacts: make(map[[2]string]bool)}
+	for _, pair := range pairs {
+		repo.contacts[pair] = true
+		repo.contacts[[2]string{pair[1], pair[0]}] = true
+	}
+	return repo
+}
+
+func (r *memoryContacts) IsContact(userID string, contactID string) (bool, error) {
+	return r.contacts[[2]string{userID, contactID}], nil
+}
+
+// mutedSettings reports a fixed set of users as having muted every
+// conversation.
+type mutedSettings struct {
+	conversation.SettingsRepository
+	muted []string
+}
+
+func (r mutedSettings) ListMutedUsers(conversationID string, now time.Time) ([]string, error) {
+	return r.muted, nil
+}
+
+func (r mutedSettings) Delete(userID string, conversationID string) error {
+	return nil
+}
+
+func (r mutedSettings) DeleteByConversation(conversationID string) error {
+	return nil
+}
+
+// memoryMessages stores messages in the order they were created.
+type memoryMessages struct {
+	message.MessageRepository
+	mu       sync.Mutex
+	messages []*message.Message
+}
+
+func (r *memoryMessages) Create(m message.Message) (*message.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Scheduled messages are created under the ID of their schedule
+	if m.ID == "" {
+		m.ID = "m" + strconv.Itoa(len(r.messages)+1)
+	}
+	r.messages = append(r.messages, &m)
+	copied := m
+	return &copied, nil
+}
+
+func (r *memoryMessages) GetByID(messageID string) (*message.Message, error) {
+	found, err := r.GetByIDs([]string{messageID})
+	if err != nil || len(found) == 0 {
+		return nil, err
+	}
+	return found[0], nil
+}
+
+func (r *memoryMessages) GetByIDs(messageIDs []string) ([]*message.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []*message.Message
+	for _, m := range r.messages {
+		if slices.Contains(messageIDs, m.ID) {
+			copied := *m
+			found = append(found, &copied)
+		}
+	}
+	return found, nil
+}
+
+func (r *memoryMessages) GetLatest(conversationID string) (*message.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var latest *message.Message
+	for _, m := range r.messages {
+		if m.ConversationID == conversationID && (latest == nil || m.CreatedAt.After(latest.CreatedAt)) {
+			latest = m
+		}
+	}
+	if latest == nil {
+		return nil, nil
+	}
+	copied := *latest
+	return &copied, nil
+}
+
+func (r *memoryMessages) DeleteByConversation(conversationID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = slices.DeleteFunc(r.messages, func(m *message.Message) bool { return m.ConversationID == conversationID })
+	return nil
+}
+
+// The repositories below only take part in deleting a conversation, which
+// they have nothing to delete for.
+
+type noInvites struct{ conversation.InviteRepository }
+
+func (noInvites) DeleteByConversation(conversationID string) error { return nil }
+
+type noPins struct{ message.PinRepository }
+
+func (noPins) DeleteByConversation(conversationID string) error { return nil }
+
+type noScheduled struct {
+	message.ScheduledMessageRepository
+}
+
+func (noScheduled) DeleteByConversation(conversationID string) error { return nil }
+
+// memoryScheduled keeps scheduled messages and records what the worker did
+// with them.
+type memoryScheduled struct {
+	message.ScheduledMessageRepository
+	mu        sync.Mutex
+	scheduled map[string]*message.ScheduledMessage
+	deleted   []string
+	requeued  []string
+}
+
+func newMemoryScheduled(scheduled ...message.ScheduledMessage) *memoryScheduled {
+	r := &memoryScheduled{scheduled: make(map[string]*message.ScheduledMessage)}
+	for _, m := range scheduled {
+		r.scheduled[m.ID] = &m
+	}
+	return r
+}
+
+func (r *memoryScheduled) ClaimDue(now time.Time) (*message.ScheduledMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var due *message.ScheduledMessage
+	for _, m := range r.scheduled {
+		if m.Status != message.SchedulePending || m.SendAt.After(now) {
+			continue
+		}
+		if due == nil || m.SendAt.Before(due.SendAt) {
+			due = m
+		}
+	}
+	if due == nil {
+		return nil, nil
+	}
+	due.Status = message.ScheduleSending
+	copied := *due
+	return &copied, nil
+}
+
+func (r *memoryScheduled) ListSending() ([]*message.ScheduledMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var sending []*message.ScheduledMessage
+	for _, m := range r.scheduled {
+		if m.Status == message.ScheduleSending {
+			copied := *m
+			sending = append(sending, &copied)
+		}
+	}
+	return sending, nil
+}
+
+func (r *memoryScheduled) Fail(id string, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if m, ok := r.scheduled[id]; ok {
+		m.Status, m.Error = message.ScheduleFailed, reason
+	}
+	return nil
+}
+
+func (r *memoryScheduled) Requeue(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if m, ok := r.scheduled[id]; ok {
+		m.Status = message.SchedulePending
+	}
+	r.requeued = append(r.requeued, id)
+	return nil
+}
+
+func (r *memoryScheduled) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.scheduled, id)
+	r.deleted = append(r.deleted, id)
+	return nil
+}
+
+type noAttachments struct {
+	attachment.AttachmentRepository
+}
+
+func (noAttachments) ListByConversation(conversationID string) ([]*attachment.Attachment, error) {
+	return nil, nil
+}
+
+// singleInvite serves one invite with unlimited uses.
+type singleInvite struct {
+	conversation.InviteRepository
+	invite conversation.Invite
+}
+
+func (r singleInvite) GetByToken(token string) (*conversation.Invite, error) {
+	if token != r.invite.Token {
+		return nil, nil
+	}
+	copied := r.invite
+	return &copied, nil
+}
+
+func (r singleInvite) Use(inviteID string, now time.Time) (bool, error) {
+	return true, nil
+}
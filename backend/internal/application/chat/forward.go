@@ -0,0 +1,149 @@
+package chat
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/conversation"
+	"backend-chat-app/internal/domain/message"
+	"backend-chat-app/internal/domain/user"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// ForwardMessages copies messages, attachments included, from a
+// conversation into others the user belongs to. Each copy is a new message
+// of the user that credits the original author.
+func (s *ChatService) ForwardMessages(req application.ForwardMessagesRequest) (*application.ForwardMessagesResponse, error) {
+	messageIDs := uniqueIDs(req.MessageIDs)
+	targetIDs := uniqueIDs(req.TargetConversationIDs)
+	if len(messageIDs) == 0 || len(targetIDs) == 0 {
+		return nil, errors.New("message_ids and target_conversation_ids can not be empty")
+	}
+	if len(messageIDs) > message.MaxForwardMessages {
+		return nil, fmt.Errorf("at most %d messages can be forwarded at once", message.MaxForwardMessages)
+	}
+	if len(targetIDs) > message.MaxForwardTargets {
+		return nil, fmt.Errorf("messages can be forwarded to at most %d conversations at once", message.MaxForwardTargets)
+	}
+
+	source, err := s.getParticipatingConversation(req.UserID, req.ConversationID)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := s.forwardableMessages(source.ID, messageIDs)
+	if err != nil {
+		return nil, err
+	}
+	// Every target is checked before anything is sent
+	targets := make([]*conversation.Conversation, 0, len(targetIDs))
+	for _, id := range targetIDs {
+		target, err := s.getParticipatingConversation(req.UserID, id)
+		if err != nil {
+			return nil, errors.New("target " + id + ": " + err.Error())
+		}
+		if err := s.checkNotBlocked(target, req.UserID); err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+
+	senderNames := make(map[string]string)
+	for _, m := range messages {
+		if _, ok := senderNames[m.SenderID]; !ok {
+			senderNames[m.SenderID] = s.senderName(source, m.SenderID)
+		}
+	}
+
+	res := &application.ForwardMessagesResponse{Messages: make([]application.SendMessageResponse, 0, len(messages)*len(targets))}
+	for _, target := range targets {
+		for _, m := range messages {
+			forwarded, err := message.NewForwardedMessage(m, target.ID, req.UserID, senderNames[m.SenderID])
+			if err != nil {
+				return nil, err
+			}
+			if len(m.AttachmentIDs) > 0 {
+				forwarded.AttachmentIDs, err = s.attachmentService.CopyToConversation(req.UserID, target.ID, m.AttachmentIDs)
+				if err != nil {
+					return nil, err
+				}
+			}
+			sent, err := s.saveMessage(target, forwarded)
+			if err != nil {
+				return nil, err
+			}
+			res.Messages = append(res.Messages, *sent)
+		}
+	}
+	return res, nil
+}
+
+// forwardableMessages loads the messages of the conversation, oldest first,
+// and rejects those that can't be forwarded.
+func (s *ChatService) forwardableMessages(conversationID string, messageIDs []string) ([]*message.Message, error) {
+	found, err := s.messageRepo.GetByIDs(messageIDs)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*message.Message, len(found))
+	for _, m := range found {
+		byID[m.ID] = m
+	}
+
+	now := time.Now()
+	messages := make([]*message.Message, 0, len(messageIDs))
+	for _, id := range messageIDs {
+		m, ok := byID[id]
+		if !ok || m.ConversationID != conversationID || m.IsExpired(now) {
+			return nil, errors.New("message not found: " + id)
+		}
+		if m.Type == message.TypeSystem || m.Redacted {
+			return nil, errors.New("message can not be forwarded: " + id)
+		}
+		messages = append(messages, m)
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+	})
+	return messages, nil
+}
+
+// senderName is the name of the author as the source conversation shows it,
+// or their profile name if they left it.
+func (s *ChatService) senderName(conv *conversation.Conversation, userID string) string {
+	if name := conv.ParticipantName(userID); name != "" {
+		return name
+	}
+	u, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		log.Printf("Failed to get user %s: %v", userID, err)
+	}
+	if u == nil || u.IsDeleted() {
+		return user.DeletedUserName
+	}
+	return u.PublicName()
+}
+
+func toForwardInfo(forward *message.Forward) *application.ForwardInfo {
+	if forward == nil {
+		return nil
+	}
+	return &application.ForwardInfo{
+		SenderID:   forward.SenderID,
+		SenderName: forward.SenderName,
+		CreatedAt:  forward.CreatedAt.Unix(),
+	}
+}
+
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	res := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			res = append(res, id)
+		}
+	}
+	return res
+}
@@ -0,0 +1,124 @@
+package chat
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/conversation"
+	"backend-chat-app/internal/domain/message"
+	"backend-chat-app/internal/domain/user"
+	"testing"
+	"time"
+)
+
+func newForwardFixture() (*ChatService, *memoryMessages) {
+	conversations := newMemoryConversations(
+		conversation.Conversation{
+			ID: "source",
+			// bob is named differently here than in his profile, dave left
+			Participant: []conversation.Participant{{ID: "alice", Name: "Alice"}, {ID: "bob", Name: "Bobby"}},
+		},
+		conversation.Conversation{
+			ID:          "target",
+			Participant: []conversation.Participant{{ID: "alice", Name: "Alice"}, {ID: "carol", Name: "Carol"}},
+		},
+	)
+	s, messages := newLeaveFixture(conversations)
+	s.userRepo = newMemoryUsers(
+		user.User{ID: "alice", Name: "Alice"},
+		user.User{ID: "bob", Name: "Bob"},
+		user.User{ID: "dave", Name: "Dave"},
+		user.User{ID: "erin", Name: "Erin", DeletedAt: time.Unix(1767225600, 0)},
+	)
+
+	sent := time.Unix(1767225600, 0)
+	messages.messages = []*message.Message{
+		{ID: "from-bob", ConversationID: "source", SenderID: "bob", Message: "second", CreatedAt: sent.Add(time.Minute)},
+		{ID: "from-dave", ConversationID: "source", SenderID: "dave", Message: "first", CreatedAt: sent},
+		{ID: "from-erin", ConversationID: "source", SenderID: "erin", Message: "gone", CreatedAt: sent},
+		{ID: "forwarded", ConversationID: "source", SenderID: "alice", Message: "old news", CreatedAt: sent,
+			ForwardedFrom: &message.Forward{SenderID: "zoe", SenderName: "Zoe", CreatedAt: sent.Add(-time.Hour)}},
+		{ID: "system", ConversationID: "source", SenderID: "alice", Type: message.TypeSystem, Message: "Alice left", CreatedAt: sent},
+		{ID: "redacted", ConversationID: "source", SenderID: "bob", Redacted: true, CreatedAt: sent},
+		{ID: "expired", ConversationID: "source", SenderID: "bob", Message: "poof", CreatedAt: sent, ExpiresAt: sent.Add(time.Second)},
+		{ID: "elsewhere", ConversationID: "target", SenderID: "carol", Message: "hi", CreatedAt: sent},
+	}
+	return s, messages
+}
+
+func TestForwardMessagesCreditsTheAuthor(t *testing.T) {
+	s, _ := newForwardFixture()
+
+	res, err := s.ForwardMessages(application.ForwardMessagesRequest{
+		UserID:                "alice",
+		ConversationID:        "source",
+		MessageIDs:            []string{"from-bob", "from-dave", "from-erin", "forwarded"},
+		TargetConversationIDs: []string{"target"},
+	})
+	if err != nil {
+		t.Fatalf("ForwardMessages: %v", err)
+	}
+
+	// Oldest first, then in the order asked for
+	want := []struct {
+		text       string
+		senderID   string
+		senderName string
+	}{
+		{text: "first", senderID: "dave", senderName: "Dave"},
+		{text: "gone", senderID: "erin", senderName: user.DeletedUserName},
+		{text: "old news", senderID: "zoe", senderName: "Zoe"},
+		{text: "second", senderID: "bob", senderName: "Bobby"},
+	}
+	if len(res.Messages) != len(want) {
+		t.Fatalf("forwarded %d messages, want %d", len(res.Messages), len(want))
+	}
+	for i, w := range want {
+		got := res.Messages[i]
+		if got.ConversationID != "target" || got.Message != w.text {
+			t.Fatalf("message %d is %q in %s, want %q in target", i, got.Message, got.ConversationID, w.text)
+		}
+		if got.ForwardedFrom == nil || got.ForwardedFrom.SenderID != w.senderID || got.ForwardedFrom.SenderName != w.senderName {
+			t.Fatalf("message %q credits %+v, want %s (%s)", w.text, got.ForwardedFrom, w.senderName, w.senderID)
+		}
+		if got.Notification == nil || got.Notification.SenderID != "alice" {
+			t.Fatalf("message %q is not sent by alice: %+v", w.text, got.Notification)
+		}
+	}
+	// A re-forward keeps the time of the original message
+	if res.Messages[2].ForwardedFrom.CreatedAt != time.Unix(1767225600, 0).Add(-time.Hour).Unix() {
+		t.Fatalf("re-forward credited at %d", res.Messages[2].ForwardedFrom.CreatedAt)
+	}
+}
+
+func TestForwardMessagesRejects(t *testing.T) {
+	tests := []struct {
+		name       string
+		messageIDs []string
+		targets    []string
+	}{
+		{name: "system message", messageIDs: []string{"system"}, targets: []string{"target"}},
+		{name: "redacted message", messageIDs: []string{"redacted"}, targets: []string{"target"}},
+		{name: "expired message", messageIDs: []string{"expired"}, targets: []string{"target"}},
+		{name: "message of another conversation", messageIDs: []string{"elsewhere"}, targets: []string{"target"}},
+		{name: "unknown message", messageIDs: []string{"nope"}, targets: []string{"target"}},
+		{name: "target the user is not in", messageIDs: []string{"from-bob"}, targets: []string{"missing"}},
+		{name: "nothing to forward", messageIDs: nil, targets: []string{"target"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, messages := newForwardFixture()
+			before := len(messages.messages)
+			_, err := s.ForwardMessages(application.ForwardMessagesRequest{
+				UserID:                "alice",
+				ConversationID:        "source",
+				MessageIDs:            tt.messageIDs,
+				TargetConversationIDs: tt.targets,
+			})
+			if err == nil {
+				t.Fatal("forwarded")
+			}
+			if len(messages.messages) != before {
+				t.Fatalf("%d messages were sent before the request failed", len(messages.messages)-before)
+			}
+		})
+	}
+}
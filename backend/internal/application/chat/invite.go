@@ -0,0 +1,246 @@
+package chat
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/conversation"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log"
+	"slices"
+	"time"
+)
+
+const (
+	// Invites are meant to be shared, so the token is stored as is and
+	// only has to be hard to guess
+	inviteTokenBytes = 16
+	maxInviteHours   = 24 * 365
+)
+
+var ErrInvalidInvite = errors.New("invite link is invalid or has expired")
+
+// CreateInvite makes a link any participant can share to let others join.
+func (s *ChatService) CreateInvite(userID string, conversationID string, req application.CreateInviteRequest) (*application.InviteInfo, error) {
+	if req.MaxUses < 0 {
+		return nil, errors.New("max_uses can not be negative")
+	}
+	if req.ExpiresInHours < 0 || req.ExpiresInHours > maxInviteHours {
+		return nil, errors.New("expires_in_hours must be between 0 and 8760")
+	}
+	conv, err := s.getParticipatingConversation(userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var expiresAt time.Time
+	if req.ExpiresInHours > 0 {
+		expiresAt = time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour)
+	}
+	token, err := generateInviteToken()
+	if err != nil {
+		return nil, err
+	}
+	invite, err := conversation.NewInvite(conv.ID, userID, token, req.MaxUses, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.inviteRepo.Create(*invite)
+	if err != nil {
+		return nil, errors.New("failed to create invite: " + err.Error())
+	}
+	return toInviteInfo(created), nil
+}
+
+func (s *ChatService) ListInvites(userID string, conversationID string) ([]application.InviteInfo, error) {
+	conv, err := s.getParticipatingConversation(userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	invites, err := s.inviteRepo.ListByConversation(conv.ID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]application.InviteInfo, 0, len(invites))
+	for _, invite := range invites {
+		res = append(res, *toInviteInfo(invite))
+	}
+	return res, nil
+}
+
+// RevokeInvite is allowed to whoever created the invite and to the owner of
+// the conversation.
+func (s *ChatService) RevokeInvite(userID string, conversationID string, inviteID string) error {
+	conv, err := s.getParticipatingConversation(userID, conversationID)
+	if err != nil {
+		return err
+	}
+	invites, err := s.inviteRepo.ListByConversation(conv.ID)
+	if err != nil {
+		return err
+	}
+	for _, invite := range invites {
+		if invite.ID != inviteID {
+			continue
+		}
+		if invite.CreatorID != userID && !conv.CanDelete(userID) {
+			return errors.New("only the creator of the invite or the owner can revoke it")
+		}
+		return s.inviteRepo.Revoke(invite.ID, conv.ID)
+	}
+	return errors.New("invite not found")
+}
+
+func (s *ChatService) PreviewInvite(userID string, token string) (*application.InvitePreview, error) {
+	invite, conv, err := s.getActiveInvite(token)
+	if err != nil {
+		return nil, err
+	}
+	res := &application.InvitePreview{
+		ConversationID: conv.ID,
+		Name:           conv.Name,
+		Participant:    make([]application.ParticipantInfo, 0, len(conv.Participant)),
+		IsParticipant:  conv.HasParticipant(userID),
+	}
+	for _, p := range conv.Participant {
+		res.Participant = append(res.Participant, application.ParticipantInfo{ID: p.ID, Name: p.Name})
+	}
+	if !invite.ExpiresAt.IsZero() {
+		res.ExpiresAt = invite.ExpiresAt.Unix()
+	}
+	return res, nil
+}
+
+// JoinByInvite adds the user to the conversation the invite belongs to.
+func (s *ChatService) JoinByInvite(userID string, token string) (*application.ConversationChange, error) {
+	invite, conv, err := s.getActiveInvite(token)
+	if err != nil {
+		return nil, err
+	}
+	if conv.HasParticipant(userID) {
+		return nil, errors.New("you are already a participant of this conversation")
+	}
+	u, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil || u.IsDeleted() {
+		return nil, errors.New("user not found")
+	}
+	if err := s.checkCanJoin(u.ID, conv); err != nil {
+		return nil, err
+	}
+
+	// The conditional add decides between concurrent joins of the same user,
+	// so only the one that actually added them takes a use of the invite
+	participant := conversation.Participant{ID: u.ID, Name: u.PublicName()}
+	added, err := s.conversationRepo.AddParticipant(conv.ID, participant)
+	if err != nil {
+		return nil, errors.New("failed to join conversation: " + err.Error())
+	}
+	if !added {
+		return nil, errors.New("you are already a participant of this conversation")
+	}
+	ok, err := s.inviteRepo.Use(invite.ID, time.Now())
+	if err != nil || !ok {
+		// The invite ran out or was revoked in the meantime, take the join back
+		if _, removeErr := s.conversationRepo.RemoveParticipant(conv.ID, u.ID); removeErr != nil {
+			log.Printf("Failed to undo join of %s to conversation %s: %v", u.ID, conv.ID, removeErr)
+		}
+		if err != nil {
+			return nil, errors.New("failed to use invite: " + err.Error())
+		}
+		return nil, ErrInvalidInvite
+	}
+	if err := s.userRepo.AddConversation(u.ID, conv.ID); err != nil {
+		return nil, errors.New("failed to join conversation: " + err.Error())
+	}
+
+	systemMessage := s.addSystemMessage(conv.ID, u.ID, participant.Name+" joined using an invite link")
+	return &application.ConversationChange{
+		ConversationID: conv.ID,
+		Name:           conv.Name,
+		UserID:         u.ID,
+		Message:        systemMessage,
+		Participants:   append(participantIDs(conv), u.ID),
+	}, nil
+}
+
+// Helper functions
+
+// checkCanJoin holds a join to the rules for starting a conversation with
+// each participant: no block on either side, and participants who only
+// accept contacts must have the user among theirs.
+func (s *ChatService) checkCanJoin(userID string, conv *conversation.Conversation) error {
+	related, err := s.blockRepo.ListRelated(userID)
+	if err != nil {
+		return errors.New("failed to check blocks: " + err.Error())
+	}
+	for _, p := range conv.Participant {
+		if slices.Contains(related, p.ID) {
+			return errors.New("you can not join this conversation")
+		}
+		participant, err := s.userRepo.GetByID(p.ID)
+		if err != nil {
+			return err
+		}
+		if participant == nil || !participant.ContactsOnly {
+			continue
+		}
+		isContact, err := s.contactRepo.IsContact(participant.ID, userID)
+		if err != nil {
+			return errors.New("failed to check contacts: " + err.Error())
+		}
+		if !isContact {
+			return errors.New("a participant only accepts conversations from contacts")
+		}
+	}
+	return nil
+}
+
+func (s *ChatService) getActiveInvite(token string) (*conversation.Invite, *conversation.Conversation, error) {
+	invite, err := s.inviteRepo.GetByToken(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if invite == nil || !invite.IsActive(time.Now()) {
+		return nil, nil, ErrInvalidInvite
+	}
+	conv, err := s.conversationRepo.GetByID(invite.ConversationID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if conv == nil {
+		log.Printf("Invite %s points to missing conversation %s", invite.ID, invite.ConversationID)
+		return nil, nil, ErrInvalidInvite
+	}
+	return invite, conv, nil
+}
+
+func generateInviteToken() (string, error) {
+	b := make([]byte, inviteTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func toInviteInfo(invite *conversation.Invite) *application.InviteInfo {
+	info := &application.InviteInfo{
+		ID:             invite.ID,
+		ConversationID: invite.ConversationID,
+		CreatorID:      invite.CreatorID,
+		Token:          invite.Token,
+		MaxUses:        invite.MaxUses,
+		Uses:           invite.Uses,
+		Active:         invite.IsActive(time.Now()),
+		CreatedAt:      invite.CreatedAt.Unix(),
+	}
+	if !invite.ExpiresAt.IsZero() {
+		info.ExpiresAt = invite.ExpiresAt.Unix()
+	}
+	if !invite.RevokedAt.IsZero() {
+		info.RevokedAt = invite.RevokedAt.Unix()
+	}
+	return info
+}
@@ -0,0 +1,48 @@
+package chat
+
+import (
+	"backend-chat-app/internal/domain/conversation"
+	"backend-chat-app/internal/domain/user"
+	"testing"
+)
+
+func TestJoinByInviteChecksBlocksAndContactsOnly(t *testing.T) {
+	tests := []struct {
+		name     string
+		blocks   [][2]string
+		contacts [][2]string
+		wantErr  bool
+	}{
+		{name: "nobody minds", contacts: [][2]string{{"carol", "dave"}}},
+		{name: "a participant blocked the joiner", blocks: [][2]string{{"bob", "dave"}}, contacts: [][2]string{{"carol", "dave"}}, wantErr: true},
+		{name: "the joiner blocked a participant", blocks: [][2]string{{"dave", "alice"}}, contacts: [][2]string{{"carol", "dave"}}, wantErr: true},
+		{name: "a participant only accepts contacts", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversations := newMemoryConversations(conversation.Conversation{
+				ID:          "c1",
+				Participant: []conversation.Participant{{ID: "alice", Name: "Alice"}, {ID: "bob", Name: "Bob"}, {ID: "carol", Name: "Carol"}},
+			})
+			s, _ := newLeaveFixture(conversations)
+			s.userRepo = newMemoryUsers(
+				user.User{ID: "alice", Name: "Alice"},
+				user.User{ID: "bob", Name: "Bob"},
+				user.User{ID: "carol", Name: "Carol", ContactsOnly: true},
+				user.User{ID: "dave", Name: "Dave"},
+			)
+			s.blockRepo = newMemoryBlocks(tt.blocks...)
+			s.contactRepo = newMemoryContacts(tt.contacts...)
+			s.inviteRepo = singleInvite{invite: conversation.Invite{ID: "i1", ConversationID: "c1", CreatorID: "alice", Token: "token"}}
+
+			_, err := s.JoinByInvite("dave", "token")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("JoinByInvite error = %v, want error %v", err, tt.wantErr)
+			}
+			conv, _ := conversations.GetByID("c1")
+			if conv.HasParticipant("dave") == tt.wantErr {
+				t.Fatalf("dave is a participant: %v, want %v", conv.HasParticipant("dave"), !tt.wantErr)
+			}
+		})
+	}
+}
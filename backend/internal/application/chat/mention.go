@@ -0,0 +1,246 @@
+package chat
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/conversation"
+	"backend-chat-app/internal/domain/message"
+	"encoding/base64"
+	"errors"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMentionLimit = 20
+	maxMentionLimit     = 100
+)
+
+// GetMentions returns the messages that mention the user, newest first, in
+// the conversations the user still takes part in.
+func (s *ChatService) GetMentions(req application.GetMentionsRequest) (*application.GetMentionsResponse, error) {
+	if req.Limit < 1 {
+		req.Limit = defaultMentionLimit
+	}
+	if req.Limit > maxMentionLimit {
+		req.Limit = maxMentionLimit
+	}
+	query := message.MentionQuery{UserID: req.UserID, Limit: req.Limit + 1}
+	if req.Cursor != "" {
+		after, err := decodeMentionCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		query.After = after
+	}
+
+	conversationIDs, err := s.userRepo.GetConversationList(req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range conversationIDs {
+		if id != nil {
+			query.ConversationIDs = append(query.ConversationIDs, *id)
+		}
+	}
+	response := &application.GetMentionsResponse{Mentions: []application.MentionFeedItem{}}
+	if len(query.ConversationIDs) == 0 {
+		return response, nil
+	}
+
+	messages, err := s.messageRepo.ListMentions(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) > req.Limit {
+		messages = messages[:req.Limit]
+		last := messages[len(messages)-1]
+		response.NextCursor = encodeMentionCursor(message.MentionCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	conversations, err := s.conversationsOf(req.UserID, messages)
+	if err != nil {
+		return nil, err
+	}
+	attachments := s.describeAttachments(messages)
+	now := time.Now()
+	for _, m := range messages {
+		if m.IsExpired(now) {
+			continue
+		}
+		item := application.MentionFeedItem{
+			ConversationID: m.ConversationID,
+			Message:        toMessage(m, attachments[m.ID]),
+		}
+		if conv, ok := conversations[m.ConversationID]; ok {
+			item.ConversationName = conv.Name
+			item.SenderName = conv.ParticipantName(m.SenderID)
+		}
+		response.Mentions = append(response.Mentions, item)
+	}
+	return response, nil
+}
+
+// resolveMentions matches the @names of a message to participants of the
+// conversation and returns them with everyone they notify. Names of anyone
+// else, and names inside code, are left as plain text.
+func (s *ChatService) resolveMentions(conv *conversation.Conversation, senderID string, text string, entities []message.Entity) ([]message.Mention, []string) {
+	tokens := message.ParseMentions(text)
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	var mentions []message.Mention
+	mentionAll := false
+	// By name, empty when the name is not a participant
+	userIDs := make(map[string]string)
+	for _, token := range tokens {
+		if inCode(token, entities) {
+			continue
+		}
+		if token.Name == message.MentionAll {
+			mentionAll = true
+			mentions = append(mentions, message.Mention{Offset: token.Offset, Length: token.Length})
+			continue
+		}
+		userID, seen := userIDs[token.Name]
+		if !seen {
+			if len(userIDs) == message.MaxMentions {
+				continue
+			}
+			userID = s.participantByUsername(conv, token.Name)
+			userIDs[token.Name] = userID
+		}
+		if userID != "" {
+			mentions = append(mentions, message.Mention{UserID: userID, Offset: token.Offset, Length: token.Length})
+		}
+	}
+
+	mentioned := make(map[string]bool)
+	for _, m := range mentions {
+		mentioned[m.UserID] = true
+	}
+	var mentionedIDs []string
+	for _, p := range conv.Participant {
+		if p.ID != senderID && (mentionAll || mentioned[p.ID]) {
+			mentionedIDs = append(mentionedIDs, p.ID)
+		}
+	}
+	return mentions, mentionedIDs
+}
+
+func (s *ChatService) participantByUsername(conv *conversation.Conversation, username string) string {
+	u, err := s.userRepo.GetByUsername(username)
+	if err != nil {
+		log.Printf("Failed to look up mentioned user %s: %v", username, err)
+		return ""
+	}
+	if u == nil || u.IsDeleted() || !conv.HasParticipant(u.ID) {
+		return ""
+	}
+	return u.ID
+}
+
+// conversationsOf loads the conversations the messages were sent in, by ID.
+func (s *ChatService) conversationsOf(userID string, messages []*message.Message) (map[string]*conversation.Conversation, error) {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, m := range messages {
+		if !seen[m.ConversationID] {
+			seen[m.ConversationID] = true
+			ids = append(ids, m.ConversationID)
+		}
+	}
+	res := make(map[string]*conversation.Conversation, len(ids))
+	if len(ids) == 0 {
+		return res, nil
+	}
+	conversations, err := s.conversationRepo.ListByParticipant(conversation.ListQuery{UserID: userID, IDs: ids, Limit: len(ids)})
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range conversations {
+		res[c.ID] = c
+	}
+	return res, nil
+}
+
+func inCode(token message.MentionToken, entities []message.Entity) bool {
+	for _, e := range entities {
+		if e.IsCode() && token.Offset < e.Offset+e.Length && e.Offset < token.Offset+token.Length {
+			return true
+		}
+	}
+	return false
+}
+
+// toEntities lists the formatting and the mentions of a message together,
+// in reading order.
+func toEntities(m *message.Message) []application.MessageEntity {
+	if len(m.Entities) == 0 && len(m.Mentions) == 0 {
+		return nil
+	}
+	res := make([]application.MessageEntity, 0, len(m.Entities)+len(m.Mentions))
+	for _, e := range m.Entities {
+		res = append(res, application.MessageEntity{
+			Type:     e.Type,
+			Offset:   e.Offset,
+			Length:   e.Length,
+			URL:      e.URL,
+			Language: e.Language,
+		})
+	}
+	for _, mention := range m.Mentions {
+		res = append(res, application.MessageEntity{
+			Type:   "mention",
+			Offset: mention.Offset,
+			Length: mention.Length,
+			UserID: mention.UserID,
+			All:    mention.IsAll(),
+		})
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].Offset != res[j].Offset {
+			return res[i].Offset < res[j].Offset
+		}
+		return res[i].Length > res[j].Length
+	})
+	return res
+}
+
+func toMentionInfos(mentions []message.Mention) []application.MentionInfo {
+	if len(mentions) == 0 {
+		return nil
+	}
+	res := make([]application.MentionInfo, 0, len(mentions))
+	for _, m := range mentions {
+		res = append(res, application.MentionInfo{
+			UserID: m.UserID,
+			All:    m.IsAll(),
+			Offset: m.Offset,
+			Length: m.Length,
+		})
+	}
+	return res
+}
+
+// Cursors are opaque to clients: "<created_at>.<message_id>" in base64.
+func encodeMentionCursor(cursor message.MentionCursor) string {
+	raw := strconv.FormatInt(cursor.CreatedAt.Unix(), 10) + "." + cursor.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeMentionCursor(value string) (*message.MentionCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	createdAt, id, found := strings.Cut(string(raw), ".")
+	seconds, err := strconv.ParseInt(createdAt, 10, 64)
+	if !found || err != nil || id == "" {
+		return nil, errors.New("invalid cursor")
+	}
+	return &message.MentionCursor{CreatedAt: time.Unix(seconds, 0), ID: id}, nil
+}
@@ -0,0 +1,86 @@
+package chat
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/message"
+	"encoding/base64"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestInCode(t *testing.T) {
+	entities := []message.Entity{
+		{Type: message.EntityBold, Offset: 0, Length: 5},
+		{Type: message.EntityCode, Offset: 10, Length: 5},
+		{Type: message.EntityPre, Offset: 20, Length: 10},
+	}
+	tests := []struct {
+		name  string
+		token message.MentionToken
+		want  bool
+	}{
+		{name: "in bold", token: message.MentionToken{Offset: 0, Length: 4}, want: false},
+		{name: "before code", token: message.MentionToken{Offset: 6, Length: 4}, want: false},
+		{name: "overlaps the start of code", token: message.MentionToken{Offset: 8, Length: 4}, want: true},
+		{name: "inside code", token: message.MentionToken{Offset: 11, Length: 3}, want: true},
+		{name: "overlaps the end of code", token: message.MentionToken{Offset: 14, Length: 4}, want: true},
+		{name: "right after code", token: message.MentionToken{Offset: 15, Length: 4}, want: false},
+		{name: "inside pre", token: message.MentionToken{Offset: 25, Length: 4}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inCode(tt.token, entities); got != tt.want {
+				t.Fatalf("inCode(%+v) = %v, want %v", tt.token, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToEntitiesReadingOrder(t *testing.T) {
+	m := &message.Message{
+		Entities: []message.Entity{
+			// Inner spans come first from the parser
+			{Type: message.EntityItalic, Offset: 8, Length: 4},
+			{Type: message.EntityBold, Offset: 0, Length: 12},
+			{Type: message.EntityLink, Offset: 20, Length: 3, URL: "https://example.com"},
+		},
+		Mentions: []message.Mention{
+			{UserID: "bob", Offset: 14, Length: 4},
+			{Offset: 0, Length: 4},
+		},
+	}
+	want := []application.MessageEntity{
+		{Type: message.EntityBold, Offset: 0, Length: 12},
+		{Type: "mention", Offset: 0, Length: 4, All: true},
+		{Type: message.EntityItalic, Offset: 8, Length: 4},
+		{Type: "mention", Offset: 14, Length: 4, UserID: "bob"},
+		{Type: message.EntityLink, Offset: 20, Length: 3, URL: "https://example.com"},
+	}
+	if got := toEntities(m); !reflect.DeepEqual(got, want) {
+		t.Fatalf("toEntities = %+v\nwant %+v", got, want)
+	}
+	if toEntities(&message.Message{}) != nil {
+		t.Fatal("a message without entities has some")
+	}
+}
+
+func TestMentionCursorRoundTrip(t *testing.T) {
+	cursor := message.MentionCursor{CreatedAt: time.Unix(1767225600, 0), ID: "65a1b2c3d4e5f60718293a4b"}
+	decoded, err := decodeMentionCursor(encodeMentionCursor(cursor))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !decoded.CreatedAt.Equal(cursor.CreatedAt) || decoded.ID != cursor.ID {
+		t.Fatalf("decoded %+v, want %+v", decoded, cursor)
+	}
+
+	for _, raw := range []string{"1767225600", "1767225600.", "yesterday.m1"} {
+		if _, err := decodeMentionCursor(base64.RawURLEncoding.EncodeToString([]byte(raw))); err == nil {
+			t.Errorf("decodeMentionCursor accepted %q", raw)
+		}
+	}
+	if _, err := decodeMentionCursor("not base64!"); err == nil {
+		t.Error("decodeMentionCursor accepted invalid base64")
+	}
+}
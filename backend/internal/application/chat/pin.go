@@ -0,0 +1,146 @@
+package chat
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/message"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// PinMessage pins a message of the conversation for every participant. Any
+// participant can pin.
+func (s *ChatService) PinMessage(userID string, conversationID string, messageID string) (*application.PinnedMessage, error) {
+	conv, err := s.getParticipatingConversation(userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.getConversationMessage(conv.ID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if m.Type == message.TypeSystem {
+		return nil, errors.New("system messages can not be pinned")
+	}
+
+	pin, err := message.NewPin(conv.ID, m.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	reserved, err := s.conversationRepo.ReservePin(conv.ID, message.MaxPinnedMessages)
+	if err != nil {
+		return nil, err
+	}
+	if !reserved {
+		return nil, fmt.Errorf("a conversation can have at most %d pinned messages", message.MaxPinnedMessages)
+	}
+	if err := s.pinRepo.Create(*pin); err != nil {
+		s.releasePins(conv.ID, 1)
+		return nil, err
+	}
+
+	attachments := s.describeAttachments([]*message.Message{m})
+	res := toPinnedMessage(pin)
+	appMessage := toMessage(m, attachments[m.ID])
+	res.Message = &appMessage
+	return res, nil
+}
+
+// UnpinMessage is allowed to whoever pinned the message and to the owner of
+// the conversation.
+func (s *ChatService) UnpinMessage(userID string, conversationID string, messageID string) (*application.PinnedMessage, error) {
+	conv, err := s.getParticipatingConversation(userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	pin, err := s.pinRepo.Get(conv.ID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if pin == nil {
+		return nil, errors.New("message is not pinned")
+	}
+	if pin.PinnedBy != userID && !conv.CanDelete(userID) {
+		return nil, errors.New("only who pinned the message or the owner can unpin it")
+	}
+	deleted, err := s.pinRepo.Delete(conv.ID, messageID)
+	if err != nil {
+		return nil, errors.New("failed to unpin message: " + err.Error())
+	}
+	// Someone else may have unpinned it at the same time
+	if deleted {
+		s.releasePins(conv.ID, 1)
+	}
+	return toPinnedMessage(pin), nil
+}
+
+func (s *ChatService) releasePins(conversationID string, n int) {
+	if err := s.conversationRepo.ReleasePins(conversationID, n); err != nil {
+		log.Printf("Failed to release %d pins of %s: %v", n, conversationID, err)
+	}
+}
+
+// ListPinnedMessages returns the pinned messages, most recently pinned first.
+func (s *ChatService) ListPinnedMessages(userID string, conversationID string) ([]application.PinnedMessage, error) {
+	conv, err := s.getParticipatingConversation(userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	pins, err := s.pinRepo.ListByConversation(conv.ID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]application.PinnedMessage, 0, len(pins))
+	if len(pins) == 0 {
+		return res, nil
+	}
+
+	messageIDs := make([]string, 0, len(pins))
+	for _, pin := range pins {
+		messageIDs = append(messageIDs, pin.MessageID)
+	}
+	messages, err := s.messageRepo.GetByIDs(messageIDs)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*message.Message, len(messages))
+	for _, m := range messages {
+		byID[m.ID] = m
+	}
+	attachments := s.describeAttachments(messages)
+
+	now := time.Now()
+	for _, pin := range pins {
+		m, ok := byID[pin.MessageID]
+		if !ok || m.IsExpired(now) {
+			log.Printf("Pinned message %s of %s no longer exists", pin.MessageID, conv.ID)
+			continue
+		}
+		item := toPinnedMessage(pin)
+		appMessage := toMessage(m, attachments[m.ID])
+		item.Message = &appMessage
+		res = append(res, *item)
+	}
+	return res, nil
+}
+
+func (s *ChatService) getConversationMessage(conversationID string, messageID string) (*message.Message, error) {
+	m, err := s.messageRepo.GetByID(messageID)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil || m.ConversationID != conversationID || m.IsExpired(time.Now()) {
+		return nil, errors.New("message not found")
+	}
+	return m, nil
+}
+
+func toPinnedMessage(pin *message.Pin) *application.PinnedMessage {
+	return &application.PinnedMessage{
+		ConversationID: pin.ConversationID,
+		MessageID:      pin.MessageID,
+		PinnedBy:       pin.PinnedBy,
+		PinnedAt:       pin.PinnedAt.Unix(),
+	}
+}
@@ -0,0 +1,156 @@
+package chat
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/message"
+	"errors"
+	"time"
+)
+
+// GetPoll returns the current tally of a poll with the user's own vote.
+func (s *ChatService) GetPoll(userID string, conversationID string, messageID string) (*application.PollUpdate, error) {
+	m, err := s.getPoll(userID, conversationID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	return toPollUpdate(m, userID), nil
+}
+
+// VotePoll records the user's choice, replacing any earlier one.
+func (s *ChatService) VotePoll(req application.PollVoteRequest) (*application.PollUpdate, error) {
+	m, err := s.getPoll(req.UserID, req.ConversationID, req.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if m.Poll.IsClosed(now) {
+		return nil, errors.New("the poll is closed")
+	}
+	if err := m.Poll.CheckVote(req.OptionIDs); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.messageRepo.VotePoll(m.ID, message.PollVote{UserID: req.UserID, OptionIDs: req.OptionIDs, VotedAt: now})
+	if err != nil {
+		return nil, errors.New("failed to vote: " + err.Error())
+	}
+	if updated == nil {
+		return nil, errors.New("the poll is closed")
+	}
+	return toPollUpdate(updated, req.UserID), nil
+}
+
+func (s *ChatService) RetractPollVote(userID string, conversationID string, messageID string) (*application.PollUpdate, error) {
+	m, err := s.getPoll(userID, conversationID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if m.Poll.IsClosed(now) {
+		return nil, errors.New("the poll is closed")
+	}
+	if m.Poll.VoteOf(userID) == nil {
+		return nil, errors.New("you have not voted in this poll")
+	}
+
+	updated, err := s.messageRepo.RetractPollVote(m.ID, userID, now)
+	if err != nil {
+		return nil, errors.New("failed to retract vote: " + err.Error())
+	}
+	if updated == nil {
+		return nil, errors.New("the poll is closed")
+	}
+	return toPollUpdate(updated, userID), nil
+}
+
+// ClosePoll ends the voting early. Allowed to whoever created the poll and
+// to the conversation owner.
+func (s *ChatService) ClosePoll(userID string, conversationID string, messageID string) (*application.PollUpdate, error) {
+	conv, err := s.getParticipatingConversation(userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.getConversationMessage(conv.ID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if m.Type != message.TypePoll || m.Poll == nil {
+		return nil, errors.New("message is not a poll")
+	}
+	if m.SenderID != userID && !conv.CanDelete(userID) {
+		return nil, errors.New("only the author of the poll or the conversation owner can close it")
+	}
+	if m.Poll.IsClosed(time.Now()) {
+		return nil, errors.New("the poll is already closed")
+	}
+
+	closed, err := s.messageRepo.ClosePoll(m.ID, time.Now())
+	if err != nil {
+		return nil, errors.New("failed to close poll: " + err.Error())
+	}
+	if closed == nil {
+		return nil, errors.New("the poll is already closed")
+	}
+	return toPollUpdate(closed, userID), nil
+}
+
+func (s *ChatService) getPoll(userID string, conversationID string, messageID string) (*message.Message, error) {
+	conv, err := s.getParticipatingConversation(userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.getConversationMessage(conv.ID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if m.Type != message.TypePoll || m.Poll == nil {
+		return nil, errors.New("message is not a poll")
+	}
+	return m, nil
+}
+
+// toPollUpdate describes the poll for everyone, or with the vote of viewerID
+// when it is not empty.
+func toPollUpdate(m *message.Message, viewerID string) *application.PollUpdate {
+	update := &application.PollUpdate{
+		ConversationID: m.ConversationID,
+		MessageID:      m.ID,
+		Question:       m.Message,
+		Poll:           *toPollInfo(m.Poll, time.Now()),
+	}
+	if viewerID != "" {
+		update.Poll.VotedOptionIDs = m.Poll.VoteOf(viewerID)
+	}
+	return update
+}
+
+func toPollInfo(p *message.Poll, now time.Time) *application.PollInfo {
+	if p == nil {
+		return nil
+	}
+	info := &application.PollInfo{
+		Options:     make([]application.PollOptionInfo, len(p.Options)),
+		Multiple:    p.Multiple,
+		Anonymous:   p.Anonymous,
+		Closed:      p.IsClosed(now),
+		TotalVoters: len(p.Votes),
+	}
+	if !p.ClosesAt.IsZero() {
+		info.ClosesAt = p.ClosesAt.Unix()
+	}
+	for i, text := range p.Options {
+		info.Options[i] = application.PollOptionInfo{ID: i, Text: text}
+	}
+	for _, v := range p.Votes {
+		for _, id := range v.OptionIDs {
+			if id < 0 || id >= len(info.Options) {
+				continue
+			}
+			info.Options[id].Votes++
+			if !p.Anonymous {
+				info.Options[id].Voters = append(info.Options[id].Voters, v.UserID)
+			}
+		}
+	}
+	return info
+}
@@ -0,0 +1,57 @@
+package chat
+
+import (
+	"backend-chat-app/internal/domain/message"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testPoll(anonymous bool) *message.Poll {
+	return &message.Poll{
+		Options:   []string{"Pho", "Bun cha", "Com tam"},
+		Multiple:  true,
+		Anonymous: anonymous,
+		Votes: []message.PollVote{
+			{UserID: "alice", OptionIDs: []int{0, 2}},
+			{UserID: "bob", OptionIDs: []int{0}},
+			// Stored before the options were checked, it is not counted
+			{UserID: "carol", OptionIDs: []int{5}},
+		},
+	}
+}
+
+func TestToPollInfoCountsVotes(t *testing.T) {
+	info := toPollInfo(testPoll(false), time.Now())
+
+	if info.TotalVoters != 3 {
+		t.Errorf("total voters = %d, want 3", info.TotalVoters)
+	}
+	want := [][]string{{"alice", "bob"}, nil, {"alice"}}
+	for i, option := range info.Options {
+		if option.ID != i || option.Votes != len(want[i]) || !reflect.DeepEqual(option.Voters, want[i]) {
+			t.Errorf("option %d = %+v, want %d votes by %v", i, option, len(want[i]), want[i])
+		}
+	}
+}
+
+func TestToPollInfoHidesAnonymousVoters(t *testing.T) {
+	m := &message.Message{ID: "m1", ConversationID: "c1", Type: message.TypePoll, Message: "Lunch?", Poll: testPoll(true)}
+
+	// The voter's own choice is still returned to them
+	update := toPollUpdate(m, "alice")
+	if !reflect.DeepEqual(update.Poll.VotedOptionIDs, []int{0, 2}) {
+		t.Errorf("alice's vote = %v, want [0 2]", update.Poll.VotedOptionIDs)
+	}
+	for _, viewer := range []string{"alice", ""} {
+		poll := toPollUpdate(m, viewer).Poll
+		for _, option := range poll.Options {
+			if option.Voters != nil {
+				t.Errorf("viewer %q sees voters %v of option %d in an anonymous poll", viewer, option.Voters, option.ID)
+			}
+		}
+		if got := []int{poll.Options[0].Votes, poll.Options[1].Votes, poll.Options[2].Votes}; !reflect.DeepEqual(got, []int{2, 0, 1}) {
+			t.Errorf("viewer %q sees counts %v, want [2 0 1]", viewer, got)
+		}
+	}
+}
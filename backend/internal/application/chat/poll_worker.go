@@ -0,0 +1,64 @@
+package chat
+
+import (
+	"backend-chat-app/internal/application"
+	"log"
+	"time"
+)
+
+const (
+	pollSweepInterval = time.Minute
+	pollBatchSize     = 100
+)
+
+// PollCloser marks polls closed once their closing time is past and
+// announces the final tally. Votes are refused from the closing time on,
+// whether or not it ran yet.
+type PollCloser struct {
+	chatService *ChatService
+	// updated pushes the final state of a poll to its conversation
+	updated func(*application.PollUpdate)
+}
+
+func NewPollCloser(chatService *ChatService, updated func(*application.PollUpdate)) *PollCloser {
+	return &PollCloser{
+		chatService: chatService,
+		updated:     updated,
+	}
+}
+
+// RunWorker periodically closes due polls. It blocks, run it in a goroutine.
+func (c *PollCloser) RunWorker() {
+	ticker := time.NewTicker(pollSweepInterval)
+	defer ticker.Stop()
+
+	c.sweep()
+	for range ticker.C {
+		c.sweep()
+	}
+}
+
+func (c *PollCloser) sweep() {
+	for {
+		due, err := c.chatService.messageRepo.ListPollsToClose(time.Now(), pollBatchSize)
+		if err != nil {
+			log.Printf("Failed to list polls to close: %v", err)
+			return
+		}
+		for _, m := range due {
+			closed, err := c.chatService.messageRepo.ClosePoll(m.ID, m.Poll.ClosesAt)
+			if err != nil {
+				log.Printf("Failed to close poll %s: %v", m.ID, err)
+				return
+			}
+			// Closed by hand in the meantime
+			if closed == nil {
+				continue
+			}
+			c.updated(toPollUpdate(closed, ""))
+		}
+		if len(due) < pollBatchSize {
+			return
+		}
+	}
+}
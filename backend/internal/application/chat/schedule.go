@@ -0,0 +1,176 @@
+package chat
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/message"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ScheduleService holds messages to send later and delivers them through the
+// ChatService once they are due.
+type ScheduleService struct {
+	chatService   *ChatService
+	scheduledRepo message.ScheduledMessageRepository
+	// sent broadcasts a delivered message like one sent over HTTP
+	sent func(senderID string, res *application.SendMessageResponse)
+	// failed tells the sender a message could not be delivered
+	failed func(*application.ScheduledMessage)
+	// changed wakes the worker when the next due message may have changed
+	changed chan struct{}
+}
+
+func NewScheduleService(chatService *ChatService, scheduledRepo message.ScheduledMessageRepository, sent func(string, *application.SendMessageResponse), failed func(*application.ScheduledMessage)) *ScheduleService {
+	return &ScheduleService{
+		chatService:   chatService,
+		scheduledRepo: scheduledRepo,
+		sent:          sent,
+		failed:        failed,
+		changed:       make(chan struct{}, 1),
+	}
+}
+
+func (s *ScheduleService) ScheduleMessage(req application.ScheduleMessageRequest) (*application.ScheduledMessage, error) {
+	if err := s.checkSendable(req.SenderID, req.ConversationID, req.Message, req.Format); err != nil {
+		return nil, err
+	}
+	count, err := s.scheduledRepo.CountBySender(req.SenderID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= message.MaxScheduledMessages {
+		return nil, fmt.Errorf("at most %d messages can be scheduled", message.MaxScheduledMessages)
+	}
+
+	m, err := message.NewScheduledMessage(req.ConversationID, req.SenderID, req.Message, req.Format, time.Unix(req.SendAt, 0))
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.scheduledRepo.Create(*m)
+	if err != nil {
+		return nil, errors.New("failed to schedule message: " + err.Error())
+	}
+	s.wake()
+	return toScheduledMessage(created), nil
+}
+
+// ListScheduledMessages returns the user's messages waiting to be sent,
+// soonest first, only those of conversationID unless it is empty.
+func (s *ScheduleService) ListScheduledMessages(userID string, conversationID string) ([]application.ScheduledMessage, error) {
+	scheduled, err := s.scheduledRepo.ListBySender(userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]application.ScheduledMessage, 0, len(scheduled))
+	for _, m := range scheduled {
+		res = append(res, *toScheduledMessage(m))
+	}
+	return res, nil
+}
+
+// UpdateScheduledMessage changes the text or time of a message that was not
+// sent yet. A failed message is queued again, so it needs a new time.
+func (s *ScheduleService) UpdateScheduledMessage(req application.UpdateScheduledMessageRequest) (*application.ScheduledMessage, error) {
+	m, err := s.getOwned(req.SenderID, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !m.CanEdit() {
+		return nil, errors.New("the message is already being sent")
+	}
+	if req.Message != nil {
+		m.Message = *req.Message
+	}
+	if req.Format != nil {
+		m.Format = *req.Format
+	}
+	if req.SendAt != nil {
+		m.SendAt = time.Unix(*req.SendAt, 0)
+	}
+	now := time.Now()
+	if err := message.CheckSendAt(m.SendAt, now); err != nil {
+		return nil, err
+	}
+	if err := s.checkSendable(m.SenderID, m.ConversationID, m.Message, m.Format); err != nil {
+		return nil, err
+	}
+
+	m.UpdatedAt = now
+	updated, err := s.scheduledRepo.Update(*m)
+	if err != nil {
+		return nil, errors.New("failed to update scheduled message: " + err.Error())
+	}
+	if !updated {
+		return nil, errors.New("the message is already being sent")
+	}
+	m.Status, m.Error = message.SchedulePending, ""
+	s.wake()
+	return toScheduledMessage(m), nil
+}
+
+func (s *ScheduleService) CancelScheduledMessage(userID string, id string) error {
+	if _, err := s.getOwned(userID, id); err != nil {
+		return err
+	}
+	canceled, err := s.scheduledRepo.Cancel(id)
+	if err != nil {
+		return errors.New("failed to cancel scheduled message: " + err.Error())
+	}
+	if !canceled {
+		return errors.New("the message is already being sent")
+	}
+	return nil
+}
+
+func (s *ScheduleService) getOwned(userID string, id string) (*message.ScheduledMessage, error) {
+	m, err := s.scheduledRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil || m.SenderID != userID {
+		return nil, errors.New("scheduled message not found")
+	}
+	return m, nil
+}
+
+// checkSendable makes sure the message could be sent now, so mistakes are
+// reported when scheduling rather than when it is due.
+func (s *ScheduleService) checkSendable(senderID string, conversationID string, text string, format string) error {
+	conv, err := s.chatService.getParticipatingConversation(senderID, conversationID)
+	if err != nil {
+		return err
+	}
+	if err := s.chatService.checkNotBlocked(conv, senderID); err != nil {
+		return err
+	}
+	_, err = s.chatService.newMessage(application.SendMessageRequest{
+		ConversationID: conversationID,
+		SenderID:       senderID,
+		Message:        text,
+		Format:         format,
+	})
+	return err
+}
+
+func (s *ScheduleService) wake() {
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+func toScheduledMessage(m *message.ScheduledMessage) *application.ScheduledMessage {
+	return &application.ScheduledMessage{
+		ID:             m.ID,
+		ConversationID: m.ConversationID,
+		SenderID:       m.SenderID,
+		Message:        m.Message,
+		Format:         m.Format,
+		Status:         m.Status,
+		Error:          m.Error,
+		SendAt:         m.SendAt.Unix(),
+		CreatedAt:      m.CreatedAt.Unix(),
+		UpdatedAt:      m.UpdatedAt.Unix(),
+	}
+}
@@ -0,0 +1,103 @@
+package chat
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/message"
+	"log"
+	"time"
+)
+
+// maxScheduleWait is the longest the worker sleeps without checking for due
+// messages.
+const maxScheduleWait = time.Minute
+
+// RunWorker sends scheduled messages when they are due. It blocks, run it in
+// a goroutine.
+func (s *ScheduleService) RunWorker() {
+	s.recoverSending()
+
+	for {
+		s.deliverDue()
+		select {
+		case <-time.After(s.untilNext()):
+		case <-s.changed:
+		}
+	}
+}
+
+// recoverSending settles deliveries interrupted by a restart. Messages are
+// stored under the ID of their schedule, so one that exists was sent and
+// only its cleanup is missing; the others are sent again.
+func (s *ScheduleService) recoverSending() {
+	sending, err := s.scheduledRepo.ListSending()
+	if err != nil {
+		log.Printf("Failed to list interrupted scheduled messages: %v", err)
+		return
+	}
+	for _, m := range sending {
+		sent, err := s.chatService.messageRepo.GetByID(m.ID)
+		if err != nil {
+			log.Printf("Failed to check scheduled message %s: %v", m.ID, err)
+			continue
+		}
+		if sent != nil {
+			err = s.scheduledRepo.Delete(m.ID)
+		} else {
+			err = s.scheduledRepo.Requeue(m.ID)
+		}
+		if err != nil {
+			log.Printf("Failed to recover scheduled message %s: %v", m.ID, err)
+		}
+	}
+}
+
+func (s *ScheduleService) deliverDue() {
+	for {
+		m, err := s.scheduledRepo.ClaimDue(time.Now())
+		if err != nil {
+			log.Printf("Failed to claim scheduled message: %v", err)
+			return
+		}
+		if m == nil {
+			return
+		}
+		s.deliver(m)
+	}
+}
+
+func (s *ScheduleService) deliver(m *message.ScheduledMessage) {
+	res, err := s.chatService.SendMessage(application.SendMessageRequest{
+		MessageID:      m.ID,
+		ConversationID: m.ConversationID,
+		SenderID:       m.SenderID,
+		Message:        m.Message,
+		Format:         m.Format,
+	})
+	if err != nil {
+		log.Printf("Failed to send scheduled message %s: %v", m.ID, err)
+		if err := s.scheduledRepo.Fail(m.ID, err.Error()); err != nil {
+			log.Printf("Failed to mark scheduled message %s as failed: %v", m.ID, err)
+		}
+		m.Status, m.Error = message.ScheduleFailed, err.Error()
+		s.failed(toScheduledMessage(m))
+		return
+	}
+
+	if err := s.scheduledRepo.Delete(m.ID); err != nil {
+		log.Printf("Failed to delete sent scheduled message %s: %v", m.ID, err)
+	}
+	s.sent(m.SenderID, res)
+}
+
+// untilNext is how long to wait for the next due message.
+func (s *ScheduleService) untilNext() time.Duration {
+	next, err := s.scheduledRepo.NextSendAt()
+	if err != nil {
+		log.Printf("Failed to get next scheduled message: %v", err)
+		return maxScheduleWait
+	}
+	if next.IsZero() {
+		return maxScheduleWait
+	}
+	return min(max(time.Until(next), 0), maxScheduleWait)
+}
@@ -0,0 +1,117 @@
+package chat
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/conversation"
+	"backend-chat-app/internal/domain/message"
+	"slices"
+	"testing"
+	"time"
+)
+
+type scheduleFixture struct {
+	service   *ScheduleService
+	messages  *memoryMessages
+	scheduled *memoryScheduled
+	sent      []*application.SendMessageResponse
+	failed    []*application.ScheduledMessage
+}
+
+func newScheduleFixture(scheduled ...message.ScheduledMessage) *scheduleFixture {
+	conversations := newMemoryConversations(conversation.Conversation{
+		ID:          "c1",
+		Participant: []conversation.Participant{{ID: "alice", Name: "Alice"}, {ID: "bob", Name: "Bob"}},
+	})
+	chatService, messages := newLeaveFixture(conversations)
+	f := &scheduleFixture{messages: messages, scheduled: newMemoryScheduled(scheduled...)}
+	f.service = NewScheduleService(chatService, f.scheduled,
+		func(senderID string, res *application.SendMessageResponse) { f.sent = append(f.sent, res) },
+		func(m *application.ScheduledMessage) { f.failed = append(f.failed, m) })
+	return f
+}
+
+func TestRecoverSendingSettlesInterruptedDeliveries(t *testing.T) {
+	f := newScheduleFixture(
+		message.ScheduledMessage{ID: "delivered", ConversationID: "c1", SenderID: "alice", Message: "hi", Status: message.ScheduleSending},
+		message.ScheduledMessage{ID: "interrupted", ConversationID: "c1", SenderID: "alice", Message: "hello", Status: message.ScheduleSending},
+		message.ScheduledMessage{ID: "waiting", ConversationID: "c1", SenderID: "alice", Message: "later", Status: message.SchedulePending},
+	)
+	// The restart happened after "delivered" was stored but before its
+	// schedule was deleted
+	f.messages.messages = []*message.Message{{ID: "delivered", ConversationID: "c1", SenderID: "alice", Message: "hi"}}
+
+	f.service.recoverSending()
+
+	if !slices.Equal(f.scheduled.deleted, []string{"delivered"}) {
+		t.Errorf("deleted = %v, want [delivered]", f.scheduled.deleted)
+	}
+	if !slices.Equal(f.scheduled.requeued, []string{"interrupted"}) {
+		t.Errorf("requeued = %v, want [interrupted]", f.scheduled.requeued)
+	}
+	if got := f.scheduled.scheduled["interrupted"].Status; got != message.SchedulePending {
+		t.Errorf("interrupted status = %q, want %q", got, message.SchedulePending)
+	}
+	if len(f.messages.messages) != 1 {
+		t.Errorf("recovery sent %d messages, want none", len(f.messages.messages)-1)
+	}
+}
+
+func TestDeliverDueSendsDueMessagesInOrder(t *testing.T) {
+	now := time.Now()
+	f := newScheduleFixture(
+		message.ScheduledMessage{ID: "second", ConversationID: "c1", SenderID: "alice", Message: "second", Status: message.SchedulePending, SendAt: now.Add(-time.Minute)},
+		message.ScheduledMessage{ID: "first", ConversationID: "c1", SenderID: "bob", Message: "first", Status: message.SchedulePending, SendAt: now.Add(-time.Hour)},
+		message.ScheduledMessage{ID: "future", ConversationID: "c1", SenderID: "alice", Message: "future", Status: message.SchedulePending, SendAt: now.Add(time.Hour)},
+		message.ScheduledMessage{ID: "broken", ConversationID: "c1", SenderID: "alice", Message: "broken", Status: message.ScheduleFailed, SendAt: now.Add(-time.Hour)},
+	)
+
+	f.service.deliverDue()
+
+	var sent []string
+	for _, res := range f.sent {
+		sent = append(sent, res.MessageID)
+	}
+	if !slices.Equal(sent, []string{"first", "second"}) {
+		t.Fatalf("sent %v, want [first second]", sent)
+	}
+	// Messages keep the ID of their schedule, so recovery can tell them apart
+	if stored := f.messages.messages[0]; stored.ID != "first" || stored.SenderID != "bob" || stored.Message != "first" {
+		t.Errorf("stored %+v, want the first schedule sent by bob", stored)
+	}
+	if !slices.Equal(f.scheduled.deleted, []string{"first", "second"}) {
+		t.Errorf("deleted = %v, want [first second]", f.scheduled.deleted)
+	}
+	if _, ok := f.scheduled.scheduled["future"]; !ok {
+		t.Error("a message that is not due yet was delivered")
+	}
+	if got := f.scheduled.scheduled["broken"].Status; got != message.ScheduleFailed {
+		t.Errorf("failed message status = %q, want it left failed", got)
+	}
+}
+
+func TestDeliverMarksFailedMessages(t *testing.T) {
+	f := newScheduleFixture(message.ScheduledMessage{
+		// carol is not in the conversation, so sending is refused
+		ID: "s1", ConversationID: "c1", SenderID: "carol", Message: "hi",
+		Status: message.SchedulePending, SendAt: time.Now().Add(-time.Minute),
+	})
+
+	f.service.deliverDue()
+
+	if len(f.sent) != 0 || len(f.messages.messages) != 0 {
+		t.Fatalf("sent %d messages, want none", len(f.sent))
+	}
+	stored := f.scheduled.scheduled["s1"]
+	if stored == nil {
+		t.Fatal("the failed message was deleted")
+	}
+	if stored.Status != message.ScheduleFailed || stored.Error != "conversation not found" {
+		t.Errorf("stored status %q error %q, want failed with the send error", stored.Status, stored.Error)
+	}
+	if len(f.failed) != 1 {
+		t.Fatalf("sender was told about %d failures, want 1", len(f.failed))
+	}
+	if got := f.failed[0]; got.ID != "s1" || got.Status != message.ScheduleFailed || got.Error != "conversation not found" {
+		t.Errorf("failure notice = %+v, want s1 failed with the send error", got)
+	}
+}
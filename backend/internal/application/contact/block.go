@@ -0,0 +1,84 @@
+package contact
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/contact"
+	"errors"
+	"log"
+)
+
+// BlockUser stops the other user from reaching the blocker. Any contact
+// relationship and pending request between them is dropped.
+func (s *ContactService) BlockUser(blockerID string, blockedID string) (*application.BlockedUserInfo, error) {
+	if blockerID == blockedID {
+		return nil, errors.New("you can not block yourself")
+	}
+	target, err := s.userRepo.GetByID(blockedID)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, errors.New("user not found")
+	}
+
+	if err := s.blockRepo.Block(blockerID, blockedID); err != nil {
+		return nil, errors.New("failed to block user: " + err.Error())
+	}
+	if err := s.contactRepo.Remove(blockerID, blockedID); err != nil {
+		log.Printf("Failed to remove contact %s of blocker %s: %v", blockedID, blockerID, err)
+	}
+	s.closePendingRequest(blockerID, blockedID, contact.StatusCancelled)
+	s.closePendingRequest(blockedID, blockerID, contact.StatusDeclined)
+
+	log.Printf("User %s blocked %s", blockerID, blockedID)
+	return &application.BlockedUserInfo{
+		ID:       target.ID,
+		Username: target.Username,
+		Name:     target.PublicName(),
+	}, nil
+}
+
+func (s *ContactService) UnblockUser(blockerID string, blockedID string) error {
+	removed, err := s.blockRepo.Unblock(blockerID, blockedID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return errors.New("user is not blocked")
+	}
+	return nil
+}
+
+func (s *ContactService) ListBlocked(userID string) ([]application.BlockedUserInfo, error) {
+	blocks, err := s.blockRepo.List(userID)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]application.BlockedUserInfo, 0, len(blocks))
+	for _, b := range blocks {
+		u, err := s.userRepo.GetByID(b.BlockedID)
+		if err != nil {
+			return nil, err
+		}
+		if u == nil {
+			continue
+		}
+		infos = append(infos, application.BlockedUserInfo{
+			ID:        u.ID,
+			Username:  u.Username,
+			Name:      u.PublicName(),
+			BlockedAt: b.CreatedAt.Unix(),
+		})
+	}
+	return infos, nil
+}
+
+func (s *ContactService) closePendingRequest(fromID string, toID string, status string) {
+	request, err := s.requestRepo.GetPending(fromID, toID)
+	if err != nil || request == nil {
+		return
+	}
+	if _, err := s.requestRepo.UpdateStatus(request.ID, status); err != nil {
+		log.Printf("Failed to close contact request %s: %v", request.ID, err)
+	}
+}
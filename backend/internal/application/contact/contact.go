@@ -0,0 +1,233 @@
+package contact
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/contact"
+	"backend-chat-app/internal/domain/phone"
+	"backend-chat-app/internal/domain/user"
+	"errors"
+	"log"
+)
+
+type ContactService struct {
+	contactRepo contact.ContactRepository
+	requestRepo contact.RequestRepository
+	blockRepo   contact.BlockRepository
+	userRepo    user.UserRepository
+	phones      *phone.Normalizer
+}
+
+func NewContactService(contactRepo contact.ContactRepository, requestRepo contact.RequestRepository, blockRepo contact.BlockRepository, userRepo user.UserRepository, phones *phone.Normalizer) *ContactService {
+	return &ContactService{
+		contactRepo: contactRepo,
+		requestRepo: requestRepo,
+		blockRepo:   blockRepo,
+		userRepo:    userRepo,
+		phones:      phones,
+	}
+}
+
+// SendRequest asks another user to become a contact. If they already asked
+// us, their request is accepted instead of opening a second one.
+func (s *ContactService) SendRequest(req application.SendContactRequest) (*application.ContactRequestInfo, error) {
+	target, err := s.findTarget(req)
+	if err != nil {
+		return nil, err
+	}
+	if target.IsBot {
+		return nil, errors.New("bots can not be added as contacts")
+	}
+
+	blockedBySender, err := s.blockRepo.IsBlocked(req.FromID, target.ID)
+	if err != nil {
+		return nil, err
+	}
+	if blockedBySender {
+		return nil, errors.New("unblock this user first")
+	}
+	blockedByTarget, err := s.blockRepo.IsBlocked(target.ID, req.FromID)
+	if err != nil {
+		return nil, err
+	}
+	if blockedByTarget {
+		return nil, errors.New("user not found")
+	}
+
+	isContact, err := s.contactRepo.IsContact(req.FromID, target.ID)
+	if err != nil {
+		return nil, err
+	}
+	if isContact {
+		return nil, errors.New("you are already contacts")
+	}
+
+	reverse, err := s.requestRepo.GetPending(target.ID, req.FromID)
+	if err != nil {
+		return nil, err
+	}
+	if reverse != nil {
+		return s.answer(req.FromID, reverse.ID, contact.StatusAccepted)
+	}
+
+	request, err := contact.NewRequest(req.FromID, target.ID)
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.requestRepo.Create(*request)
+	if err != nil {
+		return nil, err
+	}
+	return s.toRequestInfo(created)
+}
+
+func (s *ContactService) AcceptRequest(userID string, requestID string) (*application.ContactRequestInfo, error) {
+	return s.answer(userID, requestID, contact.StatusAccepted)
+}
+
+func (s *ContactService) DeclineRequest(userID string, requestID string) (*application.ContactRequestInfo, error) {
+	return s.answer(userID, requestID, contact.StatusDeclined)
+}
+
+func (s *ContactService) CancelRequest(userID string, requestID string) (*application.ContactRequestInfo, error) {
+	return s.answer(userID, requestID, contact.StatusCancelled)
+}
+
+// ListRequests returns pending requests sent to the user, or sent by the
+// user when incoming is false.
+func (s *ContactService) ListRequests(userID string, incoming bool) ([]application.ContactRequestInfo, error) {
+	requests, err := s.requestRepo.ListPending(userID, incoming)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]application.ContactRequestInfo, 0, len(requests))
+	for _, request := range requests {
+		info, err := s.toRequestInfo(request)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, *info)
+	}
+	return infos, nil
+}
+
+func (s *ContactService) ListContacts(userID string) ([]application.ContactInfo, error) {
+	contacts, err := s.contactRepo.List(userID)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]application.ContactInfo, 0, len(contacts))
+	for _, c := range contacts {
+		u, err := s.userRepo.GetByID(c.ContactID)
+		if err != nil {
+			return nil, err
+		}
+		if u == nil {
+			continue
+		}
+		infos = append(infos, application.ContactInfo{
+			ID:       u.ID,
+			Username: u.Username,
+			Name:     u.PublicName(),
+			Since:    c.CreatedAt.Unix(),
+		})
+	}
+	return infos, nil
+}
+
+func (s *ContactService) RemoveContact(userID string, contactID string) error {
+	isContact, err := s.contactRepo.IsContact(userID, contactID)
+	if err != nil {
+		return err
+	}
+	if !isContact {
+		return errors.New("contact not found")
+	}
+	return s.contactRepo.Remove(userID, contactID)
+}
+
+// answer moves a pending request to status. Only the recipient may accept or
+// decline and only the sender may cancel.
+func (s *ContactService) answer(userID string, requestID string, status string) (*application.ContactRequestInfo, error) {
+	request, err := s.requestRepo.GetByID(requestID)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil {
+		return nil, errors.New("contact request not found")
+	}
+
+	allowedUser := request.ToID
+	if status == contact.StatusCancelled {
+		allowedUser = request.FromID
+	}
+	if userID != allowedUser {
+		return nil, errors.New("contact request not found")
+	}
+
+	updated, err := s.requestRepo.UpdateStatus(request.ID, status)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, errors.New("contact request is no longer pending")
+	}
+	request.Status = status
+
+	if status == contact.StatusAccepted {
+		if err := s.contactRepo.Add(request.FromID, request.ToID); err != nil {
+			return nil, errors.New("failed to save contact: " + err.Error())
+		}
+		log.Printf("Users %s and %s are now contacts", request.FromID, request.ToID)
+	}
+	return s.toRequestInfo(request)
+}
+
+func (s *ContactService) findTarget(req application.SendContactRequest) (*user.User, error) {
+	var target *user.User
+	var err error
+	switch {
+	case req.UserID != "":
+		target, err = s.userRepo.GetByID(req.UserID)
+	case req.Phone != "":
+		phoneNumber, normalizeErr := s.phones.Normalize(req.Phone)
+		if normalizeErr != nil {
+			return nil, normalizeErr
+		}
+		target, err = s.userRepo.GetByPhone(phoneNumber)
+	default:
+		return nil, errors.New("user_id or phone is required")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, errors.New("user not found")
+	}
+	return target, nil
+}
+
+func (s *ContactService) toRequestInfo(request *contact.Request) (*application.ContactRequestInfo, error) {
+	from, err := s.userRepo.GetByID(request.FromID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.userRepo.GetByID(request.ToID)
+	if err != nil {
+		return nil, err
+	}
+	info := &application.ContactRequestInfo{
+		ID:        request.ID,
+		FromID:    request.FromID,
+		ToID:      request.ToID,
+		Status:    request.Status,
+		CreatedAt: request.CreatedAt.Unix(),
+		UpdateAt:  request.UpdateAt.Unix(),
+	}
+	if from != nil {
+		info.FromName = from.PublicName()
+	}
+	if to != nil {
+		info.ToName = to.PublicName()
+	}
+	return info, nil
+}
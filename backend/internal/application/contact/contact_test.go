@@ -0,0 +1,202 @@
+package contact
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/contact"
+	"backend-chat-app/internal/domain/user"
+	"testing"
+)
+
+type contactFixture struct {
+	service  *ContactService
+	requests *memoryRequests
+	contacts *memoryContacts
+	blocks   *memoryBlocks
+}
+
+func newContactFixture() *contactFixture {
+	f := &contactFixture{
+		requests: &memoryRequests{},
+		contacts: newMemoryContacts(),
+		blocks:   &memoryBlocks{blocked: make(map[[2]string]bool)},
+	}
+	users := newMemoryUsers(
+		user.User{ID: "alice", Name: "Alice"},
+		user.User{ID: "bob", Name: "Bob"},
+		user.User{ID: "carol", Name: "Carol"},
+		user.User{ID: "robot", Name: "Robot", IsBot: true},
+	)
+	f.service = NewContactService(f.contacts, f.requests, f.blocks, users, nil)
+	return f
+}
+
+func (f *contactFixture) send(t *testing.T, from string, to string) *application.ContactRequestInfo {
+	t.Helper()
+	info, err := f.service.SendRequest(application.SendContactRequest{FromID: from, UserID: to})
+	if err != nil {
+		t.Fatalf("SendRequest %s -> %s: %v", from, to, err)
+	}
+	return info
+}
+
+func (f *contactFixture) areContacts(a string, b string) bool {
+	ab, _ := f.contacts.IsContact(a, b)
+	ba, _ := f.contacts.IsContact(b, a)
+	return ab && ba
+}
+
+func TestContactRequestAccept(t *testing.T) {
+	f := newContactFixture()
+	request := f.send(t, "alice", "bob")
+	if request.Status != contact.StatusPending || request.FromName != "Alice" || request.ToName != "Bob" {
+		t.Fatalf("unexpected request %+v", request)
+	}
+	if f.areContacts("alice", "bob") {
+		t.Fatal("contacts before the request was accepted")
+	}
+
+	accepted, err := f.service.AcceptRequest("bob", request.ID)
+	if err != nil {
+		t.Fatalf("AcceptRequest: %v", err)
+	}
+	if accepted.Status != contact.StatusAccepted || !f.areContacts("alice", "bob") {
+		t.Fatalf("request %+v did not make contacts", accepted)
+	}
+
+	if _, err := f.service.SendRequest(application.SendContactRequest{FromID: "bob", UserID: "alice"}); err == nil {
+		t.Fatal("request sent to an existing contact")
+	}
+}
+
+func TestContactRequestTransitions(t *testing.T) {
+	tests := []struct {
+		name    string
+		answer  func(s *ContactService, userID string, requestID string) (*application.ContactRequestInfo, error)
+		by      string
+		allowed bool
+		status  string
+	}{
+		{"recipient accepts", (*ContactService).AcceptRequest, "bob", true, contact.StatusAccepted},
+		{"recipient declines", (*ContactService).DeclineRequest, "bob", true, contact.StatusDeclined},
+		{"sender cancels", (*ContactService).CancelRequest, "alice", true, contact.StatusCancelled},
+		{"sender can not accept", (*ContactService).AcceptRequest, "alice", false, ""},
+		{"sender can not decline", (*ContactService).DeclineRequest, "alice", false, ""},
+		{"recipient can not cancel", (*ContactService).CancelRequest, "bob", false, ""},
+		{"stranger can not accept", (*ContactService).AcceptRequest, "carol", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newContactFixture()
+			request := f.send(t, "alice", "bob")
+
+			info, err := tt.answer(f.service, tt.by, request.ID)
+			if !tt.allowed {
+				if err == nil {
+					t.Fatalf("answered as %s", tt.by)
+				}
+				if stored, _ := f.requests.GetByID(request.ID); stored.Status != contact.StatusPending {
+					t.Fatalf("status changed to %s", stored.Status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Status != tt.status {
+				t.Fatalf("status = %s, want %s", info.Status, tt.status)
+			}
+			if got := f.areContacts("alice", "bob"); got != (tt.status == contact.StatusAccepted) {
+				t.Fatalf("contacts = %v after %s", got, tt.status)
+			}
+		})
+	}
+}
+
+func TestContactRequestAnsweredOnce(t *testing.T) {
+	f := newContactFixture()
+	request := f.send(t, "alice", "bob")
+	if _, err := f.service.DeclineRequest("bob", request.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// A declined request can not be accepted or cancelled afterwards
+	if _, err := f.service.AcceptRequest("bob", request.ID); err == nil {
+		t.Fatal("accepted a declined request")
+	}
+	if _, err := f.service.CancelRequest("alice", request.ID); err == nil {
+		t.Fatal("cancelled a declined request")
+	}
+	if f.areContacts("alice", "bob") {
+		t.Fatal("declined request made contacts")
+	}
+
+	// The sender may ask again
+	again := f.send(t, "alice", "bob")
+	if again.ID == request.ID || again.Status != contact.StatusPending {
+		t.Fatalf("unexpected new request %+v", again)
+	}
+}
+
+func TestContactRequestCrossingAccepts(t *testing.T) {
+	f := newContactFixture()
+	first := f.send(t, "alice", "bob")
+
+	// Bob asking Alice back accepts her request instead of opening another
+	answer := f.send(t, "bob", "alice")
+	if answer.ID != first.ID || answer.Status != contact.StatusAccepted {
+		t.Fatalf("unexpected answer %+v", answer)
+	}
+	if !f.areContacts("alice", "bob") {
+		t.Fatal("crossing requests did not make contacts")
+	}
+	if pending, _ := f.service.ListRequests("alice", true); len(pending) != 0 {
+		t.Fatalf("%d requests still pending", len(pending))
+	}
+}
+
+func TestContactRequestRefused(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		blocked [2]string
+	}{
+		{name: "to yourself", from: "alice", to: "alice"},
+		{name: "to a bot", from: "alice", to: "robot"},
+		{name: "to an unknown user", from: "alice", to: "nobody"},
+		{name: "to a user you blocked", from: "alice", to: "bob", blocked: [2]string{"alice", "bob"}},
+		{name: "to a user who blocked you", from: "alice", to: "bob", blocked: [2]string{"bob", "alice"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newContactFixture()
+			if tt.blocked[0] != "" {
+				f.blocks.blocked[tt.blocked] = true
+			}
+			if _, err := f.service.SendRequest(application.SendContactRequest{FromID: tt.from, UserID: tt.to}); err == nil {
+				t.Fatal("request sent")
+			}
+			if len(f.requests.requests) != 0 {
+				t.Fatal("request stored")
+			}
+		})
+	}
+}
+
+func TestRemoveContact(t *testing.T) {
+	f := newContactFixture()
+	request := f.send(t, "alice", "bob")
+	if _, err := f.service.AcceptRequest("bob", request.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.service.RemoveContact("bob", "alice"); err != nil {
+		t.Fatalf("RemoveContact: %v", err)
+	}
+	if f.areContacts("alice", "bob") {
+		t.Fatal("still contacts after removal")
+	}
+	if err := f.service.RemoveContact("bob", "alice"); err == nil {
+		t.Fatal("removed a contact twice")
+	}
+}
@@ -0,0 +1,163 @@
+package contact
+
+import (
+	"backend-chat-app/internal/domain/contact"
+	"backend-chat-app/internal/domain/user"
+	"strconv"
+	"sync"
+)
+
+// memoryUsers only answers lookups by ID. Methods the tests don't reach fall
+// through to the nil embedded interface and panic.
+type memoryUsers struct {
+	user.UserRepository
+	users map[string]*user.User
+}
+
+func newMemoryUsers(users ...user.User) *memoryUsers {
+	repo := &memoryUsers{users: make(map[string]*user.User)}
+	for _, u := range users {
+		u := u
+		repo.users[u.ID] = &u
+	}
+	return repo
+}
+
+func (r *memoryUsers) GetByID(userID string) (*user.User, error) {
+	u, found := r.users[userID]
+	if !found {
+		return nil, nil
+	}
+	copied := *u
+	return &copied, nil
+}
+
+type memoryRequests struct {
+	mu       sync.Mutex
+	requests []*contact.Request
+}
+
+func (r *memoryRequests) Create(request contact.Request) (*contact.Request, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	request.ID = "r" + strconv.Itoa(len(r.requests)+1)
+	r.requests = append(r.requests, &request)
+	copied := request
+	return &copied, nil
+}
+
+func (r *memoryRequests) GetByID(requestID string) (*contact.Request, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, request := range r.requests {
+		if request.ID == requestID {
+			copied := *request
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryRequests) GetPending(fromID string, toID string) (*contact.Request, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, request := range r.requests {
+		if request.FromID == fromID && request.ToID == toID && request.Status == contact.StatusPending {
+			copied := *request
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryRequests) UpdateStatus(requestID string, status string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, request := range r.requests {
+		if request.ID == requestID && request.Status == contact.StatusPending {
+			request.Status = status
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryRequests) ListPending(userID string, incoming bool) ([]*contact.Request, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []*contact.Request
+	for _, request := range r.requests {
+		mine := request.FromID == userID
+		if incoming {
+			mine = request.ToID == userID
+		}
+		if mine && request.Status == contact.StatusPending {
+			copied := *request
+			found = append(found, &copied)
+		}
+	}
+	return found, nil
+}
+
+func (r *memoryRequests) DeleteByUser(userID string) error {
+	return nil
+}
+
+// memoryContacts stores both directions of every contact like the Mongo
+// repository does.
+type memoryContacts struct {
+	mu       sync.Mutex
+	contacts map[[2]string]bool
+}
+
+func newMemoryContacts() *memoryContacts {
+	return &memoryContacts{contacts: make(map[[2]string]bool)}
+}
+
+func (r *memoryContacts) Add(userID string, contactID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.contacts[[2]string{userID, contactID}] = true
+	r.contacts[[2]string{contactID, userID}] = true
+	return nil
+}
+
+func (r *memoryContacts) Remove(userID string, contactID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.contacts, [2]string{userID, contactID})
+	delete(r.contacts, [2]string{contactID, userID})
+	return nil
+}
+
+func (r *memoryContacts) List(userID string) ([]*contact.Contact, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []*contact.Contact
+	for pair := range r.contacts {
+		if pair[0] == userID {
+			found = append(found, &contact.Contact{UserID: pair[0], ContactID: pair[1]})
+		}
+	}
+	return found, nil
+}
+
+func (r *memoryContacts) IsContact(userID string, contactID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.contacts[[2]string{userID, contactID}], nil
+}
+
+func (r *memoryContacts) RemoveAll(userID string) error {
+	return nil
+}
+
+// memoryBlocks only answers IsBlocked; the rest panics.
+type memoryBlocks struct {
+	contact.BlockRepository
+	blocked map[[2]string]bool
+}
+
+func (r *memoryBlocks) IsBlocked(blockerID string, blockedID string) (bool, error) {
+	return r.blocked[[2]string{blockerID, blockedID}], nil
+}
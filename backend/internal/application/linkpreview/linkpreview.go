@@ -0,0 +1,144 @@
+package linkpreview
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/linkpreview"
+	"backend-chat-app/internal/domain/message"
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	queueSize    = 256
+	fetchWorkers = 4
+	fetchTimeout = 10 * time.Second
+	// cacheTTL is how long a fetched page is reused, failures are retried
+	// sooner in case the site was only down
+	cacheTTL  = 24 * time.Hour
+	failedTTL = time.Hour
+)
+
+type job struct {
+	conversationID string
+	messageID      string
+	urls           []string
+}
+
+// LinkPreviewService fetches previews for the links in sent messages in the
+// background, stores them on the message and hands them to notify so they
+// can be pushed to the conversation.
+type LinkPreviewService struct {
+	messageRepo message.MessageRepository
+	previewRepo linkpreview.PreviewRepository
+	fetcher     linkpreview.Fetcher
+	notify      func(*application.MessagePreviews)
+	jobs        chan job
+}
+
+func NewLinkPreviewService(messageRepo message.MessageRepository, previewRepo linkpreview.PreviewRepository, fetcher linkpreview.Fetcher, notify func(*application.MessagePreviews)) *LinkPreviewService {
+	return &LinkPreviewService{
+		messageRepo: messageRepo,
+		previewRepo: previewRepo,
+		fetcher:     fetcher,
+		notify:      notify,
+		jobs:        make(chan job, queueSize),
+	}
+}
+
+// Enqueue schedules the links of a message to be previewed. It never blocks
+// sending: when the queue is full the message goes without previews.
+func (s *LinkPreviewService) Enqueue(conversationID string, messageID string, text string) {
+	urls := linkpreview.ExtractURLs(text)
+	if len(urls) == 0 {
+		return
+	}
+	select {
+	case s.jobs <- job{conversationID: conversationID, messageID: messageID, urls: urls}:
+	default:
+		log.Printf("Link preview queue is full, skipping message %s", messageID)
+	}
+}
+
+func (s *LinkPreviewService) process(j job) {
+	var previews []message.LinkPreview
+	for _, url := range j.urls {
+		p := s.preview(url)
+		if p == nil || p.Failed || p.IsEmpty() {
+			continue
+		}
+		previews = append(previews, message.LinkPreview{
+			URL:         p.URL,
+			Title:       p.Title,
+			Description: p.Description,
+			ImageURL:    p.ImageURL,
+			SiteName:    p.SiteName,
+		})
+	}
+	if len(previews) == 0 {
+		return
+	}
+
+	if err := s.messageRepo.SetLinkPreviews(j.messageID, previews); err != nil {
+		log.Printf("Failed to save link previews of message %s: %v", j.messageID, err)
+		return
+	}
+	if s.notify != nil {
+		s.notify(&application.MessagePreviews{
+			ConversationID: j.conversationID,
+			MessageID:      j.messageID,
+			LinkPreviews:   ToLinkPreviews(previews),
+		})
+	}
+}
+
+// preview returns the cached preview of the URL while it is fresh, and
+// fetches it otherwise. Failures are cached as well.
+func (s *LinkPreviewService) preview(url string) *linkpreview.Preview {
+	cached, err := s.previewRepo.Get(url)
+	if err != nil {
+		log.Printf("Failed to read cached preview of %s: %v", url, err)
+	}
+	if cached != nil && isFresh(cached, time.Now()) {
+		return cached
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+	fetched, err := s.fetcher.Fetch(ctx, url)
+	if err != nil {
+		log.Printf("Failed to fetch preview of %s: %v", url, err)
+		fetched = &linkpreview.Preview{URL: url, Failed: true}
+	}
+	fetched.URL = url
+	fetched.FetchedAt = time.Now()
+	if err := s.previewRepo.Save(*fetched); err != nil {
+		log.Printf("Failed to cache preview of %s: %v", url, err)
+	}
+	return fetched
+}
+
+func isFresh(p *linkpreview.Preview, now time.Time) bool {
+	ttl := cacheTTL
+	if p.Failed {
+		ttl = failedTTL
+	}
+	return now.Sub(p.FetchedAt) < ttl
+}
+
+func ToLinkPreviews(previews []message.LinkPreview) []application.LinkPreview {
+	if len(previews) == 0 {
+		return nil
+	}
+	res := make([]application.LinkPreview, 0, len(previews))
+	for _, p := range previews {
+		res = append(res, application.LinkPreview{
+			URL:         p.URL,
+			Title:       p.Title,
+			Description: p.Description,
+			ImageURL:    p.ImageURL,
+			SiteName:    p.SiteName,
+		})
+	}
+	return res
+}
@@ -0,0 +1,34 @@
+package linkpreview
+
+import (
+	"log"
+	"time"
+)
+
+const sweepInterval = time.Hour
+
+// RunWorker fetches queued links and drops expired cache entries. It blocks,
+// run it in a goroutine.
+func (s *LinkPreviewService) RunWorker() {
+	for range fetchWorkers {
+		go func() {
+			for j := range s.jobs {
+				s.process(j)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	s.sweep()
+	for range ticker.C {
+		s.sweep()
+	}
+}
+
+func (s *LinkPreviewService) sweep() {
+	if err := s.previewRepo.DeleteFetchedBefore(time.Now().Add(-cacheTTL)); err != nil {
+		log.Printf("Failed to delete expired link previews: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package application
 type UserData struct {
 	ID            string   `json:"user_id"`
 	Name          string   `json:"name"`
+	EmailVerified bool     `json:"email_verified"`
 	Conversations []string `json:"conversations"`
 }
 type TokenData struct {
@@ -18,6 +19,7 @@ type AuthResponse struct {
 type LoginRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	ClientIP string `json:"-"`
 }
 type RegisterRequest struct {
 	Username string `json:"username"`
@@ -26,13 +28,128 @@ type RegisterRequest struct {
 	Name     string `json:"name"`
 	Phone    string `json:"phone"`
 }
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+	UserID          string `json:"-"`
+}
+
+type SSOCallbackRequest struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+	// Binding is the value the login set in a cookie of the browser that started it
+	Binding string `json:"-"`
+}
+
+type SSOLoginResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
 type RefreshTokenRequest struct {
 	UserId       string `json:"userID"`
 	RefreshToken string `json:"refresh_token"`
 }
 
+type EmailRequest struct {
+	Email string `json:"email"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+type CreateBotRequest struct {
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	OwnerID  string `json:"-"`
+}
+
+type BotInfo struct {
+	ID        string `json:"bot_id"`
+	Username  string `json:"username"`
+	Name      string `json:"name"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+type APIKeyInfo struct {
+	ID         string   `json:"key_id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  int64    `json:"expires_at,omitempty"`
+	RevokedAt  int64    `json:"revoked_at,omitempty"`
+	LastUsedAt int64    `json:"last_used_at,omitempty"`
+	CreatedAt  int64    `json:"created_at"`
+}
+
+type CreateAPIKeyResponse struct {
+	// Key is shown only once, when it is created
+	Key    string     `json:"key"`
+	APIKey APIKeyInfo `json:"api_key"`
+}
+
+type UserProfile struct {
+	ID            string            `json:"user_id"`
+	Username      string            `json:"username"`
+	Name          string            `json:"name"`
+	DisplayName   string            `json:"display_name"`
+	Bio           string            `json:"bio"`
+	Timezone      string            `json:"timezone"`
+	AvatarURLs    map[string]string `json:"avatar_urls,omitempty"`
+	IsBot         bool              `json:"is_bot,omitempty"`
+	Email         string            `json:"email,omitempty"`
+	Phone         string            `json:"phone,omitempty"`
+	EmailVerified *bool             `json:"email_verified,omitempty"`
+	CreatedAt     int64             `json:"created_at"`
+}
+
+// UpdateProfileRequest only changes the fields that are present
+type UpdateProfileRequest struct {
+	Name        *string `json:"name"`
+	DisplayName *string `json:"display_name"`
+	Bio         *string `json:"bio"`
+	Timezone    *string `json:"timezone"`
+}
+
+type PrivacySettings struct {
+	ContactsOnly    bool   `json:"contacts_only"`
+	Discoverability string `json:"discoverability"`
+}
+
+// UpdatePrivacyRequest only changes the fields that are present
+type UpdatePrivacyRequest struct {
+	ContactsOnly    *bool   `json:"contacts_only"`
+	Discoverability *string `json:"discoverability"`
+}
+
+type SearchUsersRequest struct {
+	ViewerID string `json:"-"`
+	Query    string `form:"q"`
+	Page     int    `form:"page"`
+	Limit    int    `form:"limit"`
+}
+
+type SearchUsersResponse struct {
+	Users   []UserProfile `json:"users"`
+	Page    int           `json:"page"`
+	Limit   int           `json:"limit"`
+	HasMore bool          `json:"has_more"`
+}
+
 type FindUserByPhoneRequest struct {
-	Phone string `json:"phone"`
+	UserID string `json:"-"`
+	Phone  string `json:"phone"`
 }
 
 type FindUserByPhoneResponse struct {
@@ -55,17 +172,239 @@ type SendMessageRequest struct {
 	Message        string `json:"message"`
 	ConversationID string `json:"conversation_id"`
 	SenderID       string `json:"sender_id"`
+	// AttachmentIDs are uploads to send with the message, which may then have no text
+	AttachmentIDs []string `json:"attachment_ids"`
+	// Type is "voice" to send a single audio attachment as a voice message
+	Type string `json:"type"`
+	// Format is "markup" to parse formatting out of Message, see MessageEntity
+	Format string `json:"format"`
+	// Poll is required with Type "poll", whose question is Message
+	Poll *PollRequest `json:"poll"`
+	// MessageID, set by the server, is the ID to store the message under
+	MessageID string `json:"-"`
 }
 
 type SendMessageResponse struct {
-	Message   string `json:"message"`
-	CreatedAt int64  `json:"created_at"`
+	MessageID      string           `json:"message_id"`
+	ConversationID string           `json:"conversation_id"`
+	Type           string           `json:"type,omitempty"`
+	Message        string           `json:"message"`
+	Attachments    []AttachmentInfo `json:"attachments,omitempty"`
+	Mentions       []MentionInfo    `json:"mentions,omitempty"`
+	Entities       []MessageEntity  `json:"entities,omitempty"`
+	ForwardedFrom  *ForwardInfo     `json:"forwarded_from,omitempty"`
+	Poll           *PollInfo        `json:"poll,omitempty"`
+	CreatedAt      int64            `json:"created_at"`
+	ExpiresAt      int64            `json:"expires_at,omitempty"` // when the message disappears
+	// Notification is pushed by the caller to the users who should be alerted
+	Notification *MessageNotification `json:"-"`
+}
+
+type MessageNotification struct {
+	ConversationID string   `json:"conversation_id"`
+	MessageID      string   `json:"message_id"`
+	SenderID       string   `json:"sender_id"`
+	SenderName     string   `json:"sender_name"`
+	Message        string   `json:"message"`
+	CreatedAt      int64    `json:"created_at"`
+	Recipients     []string `json:"-"`
+	// Mentioned users also get a mention event, even when muted
+	Mentioned []string `json:"-"`
 }
 
 type Message struct {
-	SenderID  string `json:"sender_id"`
-	Message   string `json:"message"`
-	CreatedAt int64  `json:"created_at"`
+	ID            string           `json:"message_id"`
+	SenderID      string           `json:"sender_id"`
+	Type          string           `json:"type,omitempty"` // "system" for events such as "Alice left", or "voice"
+	Message       string           `json:"message"`
+	Attachments   []AttachmentInfo `json:"attachments,omitempty"`
+	LinkPreviews  []LinkPreview    `json:"link_previews,omitempty"`
+	Mentions      []MentionInfo    `json:"mentions,omitempty"`
+	Entities      []MessageEntity  `json:"entities,omitempty"`
+	ForwardedFrom *ForwardInfo     `json:"forwarded_from,omitempty"`
+	Poll          *PollInfo        `json:"poll,omitempty"`
+	Redacted      bool             `json:"redacted,omitempty"`
+	CreatedAt     int64            `json:"created_at"`
+	ExpiresAt     int64            `json:"expires_at,omitempty"` // when the message disappears
+}
+
+type PollRequest struct {
+	Options   []string `json:"options"`
+	Multiple  bool     `json:"multiple"`
+	Anonymous bool     `json:"anonymous"`
+	// ClosesAt is when voting ends, in unix seconds; 0 keeps the poll open
+	ClosesAt int64 `json:"closes_at"`
+}
+
+// PollInfo is the state of a poll. Voters are left out of anonymous polls.
+type PollInfo struct {
+	Options     []PollOptionInfo `json:"options"`
+	Multiple    bool             `json:"multiple"`
+	Anonymous   bool             `json:"anonymous"`
+	ClosesAt    int64            `json:"closes_at,omitempty"`
+	Closed      bool             `json:"closed"`
+	TotalVoters int              `json:"total_voters"`
+	// VotedOptionIDs is the caller's own vote, only in responses to them
+	VotedOptionIDs []int `json:"voted_option_ids,omitempty"`
+}
+
+type PollOptionInfo struct {
+	ID     int      `json:"option_id"`
+	Text   string   `json:"text"`
+	Votes  int      `json:"votes"`
+	Voters []string `json:"voters,omitempty"`
+}
+
+type PollVoteRequest struct {
+	UserID         string `json:"-"`
+	ConversationID string `json:"conversation_id"`
+	MessageID      string `json:"message_id"`
+	OptionIDs      []int  `json:"option_ids"`
+}
+
+// PollUpdate is pushed to the conversation as poll_updated whenever the
+// votes change or the poll closes.
+type PollUpdate struct {
+	ConversationID string   `json:"conversation_id"`
+	MessageID      string   `json:"message_id"`
+	Question       string   `json:"question"`
+	Poll           PollInfo `json:"poll"`
+}
+
+// ForwardInfo credits the author of a forwarded message. CreatedAt is when
+// it was first sent.
+type ForwardInfo struct {
+	SenderID   string `json:"sender_id"`
+	SenderName string `json:"sender_name"`
+	CreatedAt  int64  `json:"created_at"`
+}
+
+// ForwardMessagesRequest copies messages of one conversation into others.
+type ForwardMessagesRequest struct {
+	UserID                string   `json:"-"`
+	ConversationID        string   `json:"conversation_id"`
+	MessageIDs            []string `json:"message_ids"`
+	TargetConversationIDs []string `json:"target_conversation_ids"`
+}
+
+type ForwardMessagesResponse struct {
+	Messages []SendMessageResponse `json:"messages"`
+}
+
+type ScheduleMessageRequest struct {
+	SenderID       string `json:"-"`
+	ConversationID string `json:"conversation_id"`
+	Message        string `json:"message"`
+	Format         string `json:"format"`
+	// SendAt is when to send the message, in unix seconds
+	SendAt int64 `json:"send_at"`
+}
+
+// UpdateScheduledMessageRequest changes only the fields that are set.
+type UpdateScheduledMessageRequest struct {
+	SenderID string  `json:"-"`
+	ID       string  `json:"-"`
+	Message  *string `json:"message"`
+	Format   *string `json:"format"`
+	SendAt   *int64  `json:"send_at"`
+}
+
+// ScheduledMessage is a message waiting to be sent. Once sent, the message
+// has the same ID.
+type ScheduledMessage struct {
+	ID             string `json:"scheduled_message_id"`
+	ConversationID string `json:"conversation_id"`
+	SenderID       string `json:"sender_id"`
+	Message        string `json:"message"`
+	Format         string `json:"format,omitempty"`
+	Status         string `json:"status"`
+	Error          string `json:"error,omitempty"`
+	SendAt         int64  `json:"send_at"`
+	CreatedAt      int64  `json:"created_at"`
+	UpdatedAt      int64  `json:"updated_at"`
+}
+
+// PinnedMessage is a message pinned to the top of a conversation. Message is
+// left out when it was unpinned.
+type PinnedMessage struct {
+	ConversationID string   `json:"conversation_id"`
+	MessageID      string   `json:"message_id"`
+	PinnedBy       string   `json:"pinned_by"`
+	PinnedAt       int64    `json:"pinned_at"`
+	Message        *Message `json:"message,omitempty"`
+}
+
+// MentionInfo marks an @name in the message text. Offset and Length count
+// characters (Unicode code points), including the @.
+type MentionInfo struct {
+	UserID string `json:"user_id,omitempty"`
+	All    bool   `json:"all,omitempty"` // @all
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+}
+
+// MessageEntity formats part of the text: bold, italic, code, pre (a code
+// block with an optional language), link (with a URL) or mention (of
+// user_id, or all). Offset and Length count characters like mentions.
+type MessageEntity struct {
+	Type     string `json:"type"`
+	Offset   int    `json:"offset"`
+	Length   int    `json:"length"`
+	URL      string `json:"url,omitempty"`
+	Language string `json:"language,omitempty"`
+	UserID   string `json:"user_id,omitempty"`
+	All      bool   `json:"all,omitempty"`
+}
+
+type GetMentionsRequest struct {
+	UserID string `json:"-"`
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit"`
+}
+
+type MentionFeedItem struct {
+	ConversationID   string  `json:"conversation_id"`
+	ConversationName string  `json:"conversation_name,omitempty"`
+	SenderName       string  `json:"sender_name"`
+	Message          Message `json:"message"`
+}
+
+type GetMentionsResponse struct {
+	Mentions []MentionFeedItem `json:"mentions"`
+	// NextCursor is passed as cursor to get the next page, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// LinkPreview is the card shown under a link. ImageURL points to the linked
+// site, not to this server.
+type LinkPreview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+}
+
+// MessagePreviews is pushed once the links of a sent message were fetched.
+type MessagePreviews struct {
+	ConversationID string        `json:"conversation_id"`
+	MessageID      string        `json:"message_id"`
+	LinkPreviews   []LinkPreview `json:"link_previews"`
+}
+
+// AttachmentInfo describes an uploaded file. URL and ThumbnailURL need the
+// same authorization as the conversation.
+type AttachmentInfo struct {
+	ID           string `json:"attachment_id"`
+	FileName     string `json:"file_name"`
+	ContentType  string `json:"content_type"`
+	Size         int64  `json:"size"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+	// DurationMs and Waveform (levels from 0 to 100) are set for audio
+	DurationMs int64 `json:"duration_ms,omitempty"`
+	Waveform   []int `json:"waveform,omitempty"`
+	CreatedAt  int64 `json:"created_at"`
 }
 type GetConversationMessageResponse struct {
 	ConversationID string    `json:"conversation_id"`
@@ -77,11 +416,167 @@ type ParticipantInfo struct {
 	Name string `json:"name"`
 }
 
+type LastMessageInfo struct {
+	ID         string `json:"message_id"`
+	SenderID   string `json:"sender_id"`
+	SenderName string `json:"sender_name"`
+	Message    string `json:"message"`
+	CreatedAt  int64  `json:"created_at"`
+}
+
 type Conversation struct {
-	ID          string            `json:"conversation_id"`
-	Participant []ParticipantInfo `json:"participant"`
+	ID          string               `json:"conversation_id"`
+	Name        string               `json:"name,omitempty"`
+	OwnerID     string               `json:"owner_id,omitempty"`
+	Direct      bool                 `json:"direct"` // one-to-one, false for groups
+	Participant []ParticipantInfo    `json:"participant"`
+	LastMessage *LastMessageInfo     `json:"last_message,omitempty"`
+	MessageTTL  int64                `json:"message_ttl,omitempty"` // seconds until messages disappear
+	Settings    ConversationSettings `json:"settings"`
+	UpdateAt    int64                `json:"update_at"`
+}
+
+type RenameConversationRequest struct {
+	Name string `json:"name"`
+}
+
+type SetMessageTTLRequest struct {
+	// MessageTTL is in seconds, 0 to keep messages
+	MessageTTL int64 `json:"message_ttl"`
+}
+
+// MessagesExpired lists messages that disappeared, for clients to remove.
+type MessagesExpired struct {
+	ConversationID string   `json:"conversation_id"`
+	MessageIDs     []string `json:"message_ids"`
+}
+
+// ConversationChange describes a join, leave, rename or delete so it can be
+// pushed to the participants.
+type ConversationChange struct {
+	ConversationID string   `json:"conversation_id"`
+	Name           string   `json:"name,omitempty"`
+	UserID         string   `json:"user_id,omitempty"` // the participant who left or joined
+	Message        *Message `json:"message,omitempty"` // the system message added to the history
+	MessageTTL     *int64   `json:"message_ttl,omitempty"`
+	// Participants are the users to notify
+	Participants []string `json:"-"`
+}
+
+type CreateInviteRequest struct {
+	MaxUses        int `json:"max_uses"`         // 0 for unlimited
+	ExpiresInHours int `json:"expires_in_hours"` // 0 for never
+}
+
+type InviteInfo struct {
+	ID             string `json:"invite_id"`
+	ConversationID string `json:"conversation_id"`
+	CreatorID      string `json:"creator_id"`
+	Token          string `json:"token"`
+	MaxUses        int    `json:"max_uses,omitempty"`
+	Uses           int    `json:"uses"`
+	Active         bool   `json:"active"`
+	ExpiresAt      int64  `json:"expires_at,omitempty"`
+	RevokedAt      int64  `json:"revoked_at,omitempty"`
+	CreatedAt      int64  `json:"created_at"`
+}
+
+// InvitePreview is what someone holding an invite link sees before joining.
+type InvitePreview struct {
+	ConversationID string            `json:"conversation_id"`
+	Name           string            `json:"name,omitempty"`
+	Participant    []ParticipantInfo `json:"participant"`
+	IsParticipant  bool              `json:"is_participant"`
+	ExpiresAt      int64             `json:"expires_at,omitempty"`
+}
+
+type GetConversationListRequest struct {
+	UserID string `json:"-"`
+	Filter string `form:"filter"`
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit"`
+}
+
+// ConversationSettings are the caller's own preferences for a conversation
+type ConversationSettings struct {
+	ConversationID string `json:"conversation_id"`
+	Muted          bool   `json:"muted"`
+	MutedUntil     int64  `json:"muted_until,omitempty"`
+	Pinned         bool   `json:"pinned"`
+	Archived       bool   `json:"archived"`
+}
+
+// UpdateConversationSettingsRequest only changes the fields that are present.
+// MutedUntil is a unix time, 0 to unmute or -1 to mute until unmuted.
+type UpdateConversationSettingsRequest struct {
+	MutedUntil *int64 `json:"muted_until"`
+	Pinned     *bool  `json:"pinned"`
+	Archived   *bool  `json:"archived"`
 }
 
 type GetConversationListResponse struct {
 	ConversationLists []Conversation `json:"conversation_list"`
+	// NextCursor is passed as cursor to get the next page, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Contacts
+
+// SendContactRequest targets a user either by ID or by phone number
+type SendContactRequest struct {
+	FromID string `json:"-"`
+	UserID string `json:"user_id"`
+	Phone  string `json:"phone"`
+}
+
+type ContactRequestInfo struct {
+	ID        string `json:"request_id"`
+	FromID    string `json:"from_id"`
+	FromName  string `json:"from_name"`
+	ToID      string `json:"to_id"`
+	ToName    string `json:"to_name"`
+	Status    string `json:"status"`
+	CreatedAt int64  `json:"created_at"`
+	UpdateAt  int64  `json:"update_at"`
+}
+
+type ContactInfo struct {
+	ID       string `json:"user_id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	Since    int64  `json:"since"`
+}
+
+type BlockUserRequest struct {
+	UserID string `json:"user_id"`
+}
+
+type BlockedUserInfo struct {
+	ID        string `json:"user_id"`
+	Username  string `json:"username"`
+	Name      string `json:"name"`
+	BlockedAt int64  `json:"blocked_at"`
+}
+
+// Account
+
+type ExportJobInfo struct {
+	ID          string `json:"export_id"`
+	Status      string `json:"status"`
+	Size        int64  `json:"size,omitempty"`
+	Error       string `json:"error,omitempty"`
+	DownloadURL string `json:"download_url,omitempty"`
+	CreatedAt   int64  `json:"created_at"`
+	CompletedAt int64  `json:"completed_at,omitempty"`
+	ExpiresAt   int64  `json:"expires_at,omitempty"`
+}
+
+// DeleteAccountRequest must carry the current password of accounts that have one
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+type AccountDeletionStatus struct {
+	Scheduled bool  `json:"scheduled"`
+	DueAt     int64 `json:"due_at,omitempty"`
 }
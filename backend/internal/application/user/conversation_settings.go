@@ -0,0 +1,133 @@
+package user
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/conversation"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// mutedForever is stored for conversations muted until the user unmutes them.
+var mutedForever = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+func (us *UserService) GetConversationSettings(userID string, conversationID string) (*application.ConversationSettings, error) {
+	settings, err := us.getConversationSettings(userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	return toConversationSettings(conversationID, settings), nil
+}
+
+// UpdateConversationSettings mutes, pins or archives a conversation for the
+// user only. Archiving unpins and pinning unarchives.
+func (us *UserService) UpdateConversationSettings(userID string, conversationID string, req application.UpdateConversationSettingsRequest) (*application.ConversationSettings, error) {
+	settings, err := us.getConversationSettings(userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
+	if req.MutedUntil != nil {
+		switch mutedUntil := *req.MutedUntil; {
+		case mutedUntil == -1:
+			settings.MutedUntil = mutedForever
+		case mutedUntil == 0:
+			settings.MutedUntil = time.Time{}
+		case mutedUntil > now.Unix():
+			settings.MutedUntil = time.Unix(mutedUntil, 0)
+		default:
+			return nil, errors.New("muted_until must be in the future, 0 or -1")
+		}
+	}
+	if req.Archived != nil {
+		settings.Archived = *req.Archived
+		if settings.Archived {
+			settings.Pinned = false
+		}
+	}
+	if req.Pinned != nil && *req.Pinned != settings.Pinned {
+		if *req.Pinned {
+			if err := us.checkPinLimit(userID); err != nil {
+				return nil, err
+			}
+			settings.PinnedAt = now
+			settings.Archived = false
+		} else {
+			settings.PinnedAt = time.Time{}
+		}
+		settings.Pinned = *req.Pinned
+	}
+	settings.UpdateAt = now
+
+	if err := us.settingsRepo.Save(*settings); err != nil {
+		return nil, errors.New("failed to save conversation settings: " + err.Error())
+	}
+	return toConversationSettings(conversationID, settings), nil
+}
+
+// getConversationSettings returns the stored settings, or defaults, of a
+// conversation the user takes part in.
+func (us *UserService) getConversationSettings(userID string, conversationID string) (*conversation.Settings, error) {
+	conv, err := us.conversationRepo.GetByID(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if conv == nil || !conv.HasParticipant(userID) {
+		return nil, errors.New("conversation not found")
+	}
+
+	settings, err := us.settingsRepo.Get(userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		settings = &conversation.Settings{UserID: userID, ConversationID: conversationID}
+	}
+	return settings, nil
+}
+
+func (us *UserService) checkPinLimit(userID string) error {
+	settings, err := us.settingsRepo.ListByUser(userID)
+	if err != nil {
+		return err
+	}
+	pinned := 0
+	for _, s := range settings {
+		if s.Pinned {
+			pinned++
+		}
+	}
+	if pinned >= conversation.MaxPinned {
+		return fmt.Errorf("you can pin at most %d conversations", conversation.MaxPinned)
+	}
+	return nil
+}
+
+func (us *UserService) settingsByConversation(userID string) (map[string]*conversation.Settings, error) {
+	settings, err := us.settingsRepo.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	byConversation := make(map[string]*conversation.Settings, len(settings))
+	for _, s := range settings {
+		byConversation[s.ConversationID] = s
+	}
+	return byConversation, nil
+}
+
+func toConversationSettings(conversationID string, settings *conversation.Settings) *application.ConversationSettings {
+	res := &application.ConversationSettings{
+		ConversationID: conversationID,
+		Pinned:         settings.Pinned,
+		Archived:       settings.Archived,
+	}
+	if settings.IsMuted(time.Now()) {
+		res.Muted = true
+		res.MutedUntil = settings.MutedUntil.Unix()
+		if settings.MutedUntil.Equal(mutedForever) {
+			res.MutedUntil = -1
+		}
+	}
+	return res
+}
@@ -0,0 +1,133 @@
+package user
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/conversation"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func newSettingsService(count int, settings ...conversation.Settings) (*UserService, *memorySettings) {
+	conversations := &memoryConversations{}
+	base := time.Unix(1767225600, 0)
+	for i := 0; i < count; i++ {
+		conversations.conversations = append(conversations.conversations, &conversation.Conversation{
+			ID:          fmt.Sprintf("c%d", i),
+			Participant: []conversation.Participant{{ID: "alice"}, {ID: "bob"}},
+			UpdateAt:    base.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	repo := newMemorySettings(settings...)
+	return NewUserService(nil, conversations, nil, nil, nil, nil, nil, repo), repo
+}
+
+func TestUpdateConversationSettingsMute(t *testing.T) {
+	service, _ := newSettingsService(1)
+	future := time.Now().Add(time.Hour).Unix()
+
+	tests := []struct {
+		name       string
+		mutedUntil int64
+		muted      bool
+		wantUntil  int64
+		wantErr    bool
+	}{
+		{name: "until a time", mutedUntil: future, muted: true, wantUntil: future},
+		{name: "forever", mutedUntil: -1, muted: true, wantUntil: -1},
+		{name: "unmute", mutedUntil: 0},
+		{name: "in the past", mutedUntil: time.Now().Add(-time.Hour).Unix(), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := service.UpdateConversationSettings("alice", "c0", application.UpdateConversationSettingsRequest{MutedUntil: ptr(tt.mutedUntil)})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("accepted")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateConversationSettings: %v", err)
+			}
+			if res.Muted != tt.muted || res.MutedUntil != tt.wantUntil {
+				t.Fatalf("muted %v until %d, want %v until %d", res.Muted, res.MutedUntil, tt.muted, tt.wantUntil)
+			}
+		})
+	}
+}
+
+func TestUpdateConversationSettingsPinAndArchive(t *testing.T) {
+	service, _ := newSettingsService(1)
+
+	res, err := service.UpdateConversationSettings("alice", "c0", application.UpdateConversationSettingsRequest{Pinned: ptr(true)})
+	if err != nil || !res.Pinned {
+		t.Fatalf("pin: %+v, %v", res, err)
+	}
+	res, err = service.UpdateConversationSettings("alice", "c0", application.UpdateConversationSettingsRequest{Archived: ptr(true)})
+	if err != nil || !res.Archived || res.Pinned {
+		t.Fatalf("archiving must unpin: %+v, %v", res, err)
+	}
+	res, err = service.UpdateConversationSettings("alice", "c0", application.UpdateConversationSettingsRequest{Pinned: ptr(true)})
+	if err != nil || res.Archived || !res.Pinned {
+		t.Fatalf("pinning must unarchive: %+v, %v", res, err)
+	}
+
+	if _, err := service.UpdateConversationSettings("carol", "c0", application.UpdateConversationSettingsRequest{Pinned: ptr(true)}); err == nil {
+		t.Fatal("a user who is not a participant changed the settings")
+	}
+}
+
+func TestUpdateConversationSettingsPinLimit(t *testing.T) {
+	var pinned []conversation.Settings
+	for i := 0; i < conversation.MaxPinned; i++ {
+		pinned = append(pinned, conversation.Settings{UserID: "alice", ConversationID: fmt.Sprintf("c%d", i), Pinned: true})
+	}
+	service, _ := newSettingsService(conversation.MaxPinned+1, pinned...)
+
+	next := fmt.Sprintf("c%d", conversation.MaxPinned)
+	if _, err := service.UpdateConversationSettings("alice", next, application.UpdateConversationSettingsRequest{Pinned: ptr(true)}); err == nil {
+		t.Fatal("pinned more than MaxPinned conversations")
+	}
+	// Pinning one that is already pinned does not count twice
+	if _, err := service.UpdateConversationSettings("alice", "c0", application.UpdateConversationSettingsRequest{Pinned: ptr(true)}); err != nil {
+		t.Fatalf("re-pinning a pinned conversation: %v", err)
+	}
+}
+
+func TestGetConversationListFilters(t *testing.T) {
+	// c3 is the most recent, c0 the oldest
+	service, _ := newSettingsService(4,
+		conversation.Settings{UserID: "alice", ConversationID: "c0", Pinned: true},
+		conversation.Settings{UserID: "alice", ConversationID: "c2", Archived: true},
+	)
+
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{filter: "", want: []string{"c0", "c3", "c1"}},
+		{filter: ConversationFilterInbox, want: []string{"c0", "c3", "c1"}},
+		{filter: ConversationFilterArchived, want: []string{"c2"}},
+		{filter: ConversationFilterAll, want: []string{"c3", "c2", "c1", "c0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			res, err := service.GetConversationList(application.GetConversationListRequest{UserID: "alice", Filter: tt.filter})
+			if err != nil {
+				t.Fatalf("GetConversationList: %v", err)
+			}
+			var got []string
+			for _, c := range res.ConversationLists {
+				got = append(got, c.ID)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("conversations %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := service.GetConversationList(application.GetConversationListRequest{UserID: "alice", Filter: "starred"}); err == nil {
+		t.Fatal("unknown filter accepted")
+	}
+}
@@ -0,0 +1,189 @@
+package user
+
+import (
+	"backend-chat-app/internal/domain/contact"
+	"backend-chat-app/internal/domain/conversation"
+	"backend-chat-app/internal/domain/user"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// memoryUserRepository keeps users in a map. Methods the tests don't reach
+// fall through to the nil embedded interface and panic.
+type memoryUserRepository struct {
+	user.UserRepository
+	mu    sync.Mutex
+	users map[string]*user.User
+}
+
+func newMemoryUserRepository(users ...user.User) *memoryUserRepository {
+	repo := &memoryUserRepository{users: make(map[string]*user.User)}
+	for _, u := range users {
+		u := u
+		repo.users[u.ID] = &u
+	}
+	return repo
+}
+
+func (r *memoryUserRepository) GetByID(userID string) (*user.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, found := r.users[userID]
+	if !found {
+		return nil, nil
+	}
+	copied := *u
+	return &copied, nil
+}
+
+func (r *memoryUserRepository) UpdateProfile(updated user.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[updated.ID] = &updated
+	return nil
+}
+
+func (r *memoryUserRepository) SetAvatar(userID string, avatarID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, found := r.users[userID]; found {
+		u.AvatarID = avatarID
+	}
+	return nil
+}
+
+// renameRecorder remembers the participant names pushed to conversations.
+type renameRecorder struct {
+	conversation.ConversationRepository
+	names map[string]string
+}
+
+func (r *renameRecorder) UpdateParticipantName(userID string, name string) error {
+	r.names[userID] = name
+	return nil
+}
+
+// searchRecorder returns found users and remembers the last query.
+type searchRecorder struct {
+	*memoryUserRepository
+	found []*user.User
+	query user.SearchQuery
+}
+
+func (r *searchRecorder) Search(query user.SearchQuery) ([]*user.User, error) {
+	r.query = query
+	if len(r.found) > query.Limit {
+		return r.found[:query.Limit], nil
+	}
+	return r.found, nil
+}
+
+type staticContacts struct {
+	contact.ContactRepository
+	contacts []*contact.Contact
+}
+
+func (r staticContacts) List(userID string) ([]*contact.Contact, error) {
+	return r.contacts, nil
+}
+
+type staticBlocks struct {
+	contact.BlockRepository
+	related []string
+}
+
+func (r staticBlocks) ListRelated(userID string) ([]string, error) {
+	return r.related, nil
+}
+
+// memoryConversations lists conversations like the Mongo repository: newest
+// activity first, ties broken by ID.
+type memoryConversations struct {
+	conversation.ConversationRepository
+	conversations []*conversation.Conversation
+}
+
+func (r *memoryConversations) GetByID(conversationID string) (*conversation.Conversation, error) {
+	for _, c := range r.conversations {
+		if c.ID == conversationID {
+			return c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryConversations) ListByParticipant(query conversation.ListQuery) ([]*conversation.Conversation, error) {
+	var matched []*conversation.Conversation
+	for _, c := range r.conversations {
+		if !c.HasParticipant(query.UserID) || slices.Contains(query.ExcludeIDs, c.ID) {
+			continue
+		}
+		if query.IDs != nil && !slices.Contains(query.IDs, c.ID) {
+			continue
+		}
+		if after := query.After; after != nil {
+			if c.UpdateAt.After(after.UpdateAt) || (c.UpdateAt.Equal(after.UpdateAt) && c.ID >= after.ID) {
+				continue
+			}
+		}
+		matched = append(matched, c)
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].UpdateAt.Equal(matched[j].UpdateAt) {
+			return matched[i].UpdateAt.After(matched[j].UpdateAt)
+		}
+		return matched[i].ID > matched[j].ID
+	})
+	if len(matched) > query.Limit {
+		matched = matched[:query.Limit]
+	}
+	return matched, nil
+}
+
+// memorySettings keeps conversation settings by user and conversation.
+type memorySettings struct {
+	conversation.SettingsRepository
+	mu       sync.Mutex
+	settings map[string]*conversation.Settings
+}
+
+func newMemorySettings(settings ...conversation.Settings) *memorySettings {
+	repo := &memorySettings{settings: make(map[string]*conversation.Settings)}
+	for _, s := range settings {
+		s := s
+		repo.settings[s.UserID+"/"+s.ConversationID] = &s
+	}
+	return repo
+}
+
+func (r *memorySettings) ListByUser(userID string) ([]*conversation.Settings, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var settings []*conversation.Settings
+	for _, s := range r.settings {
+		if s.UserID == userID {
+			copied := *s
+			settings = append(settings, &copied)
+		}
+	}
+	return settings, nil
+}
+
+func (r *memorySettings) Get(userID string, conversationID string) (*conversation.Settings, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, found := r.settings[userID+"/"+conversationID]
+	if !found {
+		return nil, nil
+	}
+	copied := *s
+	return &copied, nil
+}
+
+func (r *memorySettings) Save(settings conversation.Settings) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.settings[settings.UserID+"/"+settings.ConversationID] = &settings
+	return nil
+}
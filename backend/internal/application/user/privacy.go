@@ -0,0 +1,46 @@
+package user
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/user"
+	"errors"
+)
+
+func (us *UserService) GetPrivacy(userID string) (*application.PrivacySettings, error) {
+	u, err := us.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	return toPrivacySettings(u), nil
+}
+
+func (us *UserService) UpdatePrivacy(userID string, req application.UpdatePrivacyRequest) (*application.PrivacySettings, error) {
+	u, err := us.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if req.ContactsOnly != nil {
+		u.ContactsOnly = *req.ContactsOnly
+	}
+	if req.Discoverability != nil {
+		if !user.IsValidDiscoverability(*req.Discoverability) {
+			return nil, errors.New("discoverability must be everyone, contacts or nobody")
+		}
+		u.Discoverability = *req.Discoverability
+	}
+	if err := us.userRepo.UpdatePrivacy(*u); err != nil {
+		return nil, errors.New("failed to update privacy settings: " + err.Error())
+	}
+	return toPrivacySettings(u), nil
+}
+
+func toPrivacySettings(u *user.User) *application.PrivacySettings {
+	discoverability := u.Discoverability
+	if discoverability == "" {
+		discoverability = user.DiscoverableEveryone
+	}
+	return &application.PrivacySettings{
+		ContactsOnly:    u.ContactsOnly,
+		Discoverability: discoverability,
+	}
+}
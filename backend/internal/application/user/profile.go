@@ -0,0 +1,235 @@
+package user
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/media"
+	"backend-chat-app/internal/domain/user"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const MaxAvatarSize = 5 << 20
+
+// AvatarSizes are the square thumbnails generated for every avatar, in pixels.
+var AvatarSizes = []int{64, 128, 256}
+
+var allowedAvatarTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+func (us *UserService) GetMyProfile(userID string) (*application.UserProfile, error) {
+	u, err := us.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	profile := toUserProfile(u)
+	profile.Email = u.Email
+	profile.Phone = u.Phone
+	profile.EmailVerified = &u.EmailVerified
+	return profile, nil
+}
+
+func (us *UserService) GetProfile(userID string) (*application.UserProfile, error) {
+	u, err := us.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	return toUserProfile(u), nil
+}
+
+func (us *UserService) UpdateProfile(userID string, req application.UpdateProfileRequest) (*application.UserProfile, error) {
+	u, err := us.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	oldPublicName := u.PublicName()
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, errors.New("name can not empty")
+		}
+		u.Name = name
+	}
+	if req.DisplayName != nil {
+		displayName := strings.TrimSpace(*req.DisplayName)
+		if utf8.RuneCountInString(displayName) > user.MaxDisplayNameLength {
+			return nil, fmt.Errorf("display name must be at most %d characters", user.MaxDisplayNameLength)
+		}
+		u.DisplayName = displayName
+	}
+	if req.Bio != nil {
+		if utf8.RuneCountInString(*req.Bio) > user.MaxBioLength {
+			return nil, fmt.Errorf("bio must be at most %d characters", user.MaxBioLength)
+		}
+		u.Bio = *req.Bio
+	}
+	if req.Timezone != nil {
+		if *req.Timezone != "" {
+			if _, err := time.LoadLocation(*req.Timezone); err != nil {
+				return nil, errors.New("unknown timezone: " + *req.Timezone)
+			}
+		}
+		u.Timezone = *req.Timezone
+	}
+
+	if err := us.userRepo.UpdateProfile(*u); err != nil {
+		return nil, errors.New("failed to update profile: " + err.Error())
+	}
+
+	// Conversations keep their own copy of each participant's name
+	if u.PublicName() != oldPublicName {
+		if err := us.conversationRepo.UpdateParticipantName(u.ID, u.PublicName()); err != nil {
+			log.Printf("Failed to update participant name of %s: %v", u.ID, err)
+		}
+	}
+	return us.GetMyProfile(u.ID)
+}
+
+// UploadAvatar validates an uploaded image and stores a square thumbnail
+// for every size in AvatarSizes. The original upload is not kept.
+func (us *UserService) UploadAvatar(userID string, content io.Reader) (*application.UserProfile, error) {
+	u, err := us.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(content, MaxAvatarSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxAvatarSize {
+		return nil, fmt.Errorf("avatar must be at most %d MB", MaxAvatarSize>>20)
+	}
+	if contentType := http.DetectContentType(data); !allowedAvatarTypes[contentType] {
+		return nil, errors.New("avatar must be a JPEG, PNG, GIF or WebP image")
+	}
+
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, err
+	}
+	avatarID := hex.EncodeToString(idBytes)
+
+	for _, size := range AvatarSizes {
+		thumbnail, err := us.imageProcessor.Thumbnail(data, size, true)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := us.blobStore.Put(avatarKey(u.ID, avatarID, size), "image/jpeg", bytes.NewReader(thumbnail)); err != nil {
+			return nil, errors.New("failed to store avatar: " + err.Error())
+		}
+	}
+
+	if err := us.userRepo.SetAvatar(u.ID, avatarID); err != nil {
+		return nil, errors.New("failed to save avatar: " + err.Error())
+	}
+	us.deleteAvatarFiles(u.ID, u.AvatarID)
+	return us.GetMyProfile(u.ID)
+}
+
+func (us *UserService) DeleteAvatar(userID string) error {
+	u, err := us.getUser(userID)
+	if err != nil {
+		return err
+	}
+	if err := us.userRepo.SetAvatar(u.ID, ""); err != nil {
+		return err
+	}
+	us.deleteAvatarFiles(u.ID, u.AvatarID)
+	return nil
+}
+
+// GetAvatar returns the smallest stored thumbnail at least size pixels wide.
+func (us *UserService) GetAvatar(userID string, size int) (io.ReadCloser, *media.BlobInfo, error) {
+	u, err := us.getUser(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.AvatarID == "" {
+		return nil, nil, errors.New("user has no avatar")
+	}
+
+	chosen := AvatarSizes[len(AvatarSizes)-1]
+	for _, s := range AvatarSizes {
+		if s >= size {
+			chosen = s
+			break
+		}
+	}
+	content, info, err := us.blobStore.Get(avatarKey(u.ID, u.AvatarID, chosen))
+	if err != nil {
+		return nil, nil, err
+	}
+	if content == nil {
+		return nil, nil, errors.New("avatar not found")
+	}
+	return content, info, nil
+}
+
+// Helper functions
+
+func (us *UserService) getUser(userID string) (*user.User, error) {
+	u, err := us.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, errors.New("user not found")
+	}
+	return u, nil
+}
+
+func (us *UserService) deleteAvatarFiles(userID string, avatarID string) {
+	if avatarID == "" {
+		return
+	}
+	for _, size := range AvatarSizes {
+		if err := us.blobStore.Delete(avatarKey(userID, avatarID, size)); err != nil {
+			log.Printf("Failed to delete avatar %s of %s: %v", avatarID, userID, err)
+		}
+	}
+}
+
+func avatarKey(userID string, avatarID string, size int) string {
+	return fmt.Sprintf("avatars/%s/%s_%d.jpg", userID, avatarID, size)
+}
+
+func avatarURLs(u *user.User) map[string]string {
+	if u.AvatarID == "" {
+		return nil
+	}
+	urls := make(map[string]string, len(AvatarSizes))
+	for _, size := range AvatarSizes {
+		// v changes with every upload so clients and proxies can cache forever
+		urls[strconv.Itoa(size)] = fmt.Sprintf("/user/%s/avatar?size=%d&v=%s", u.ID, size, u.AvatarID)
+	}
+	return urls
+}
+
+func toUserProfile(u *user.User) *application.UserProfile {
+	return &application.UserProfile{
+		ID:          u.ID,
+		Username:    u.Username,
+		Name:        u.Name,
+		DisplayName: u.DisplayName,
+		Bio:         u.Bio,
+		Timezone:    u.Timezone,
+		AvatarURLs:  avatarURLs(u),
+		IsBot:       u.IsBot,
+		CreatedAt:   u.CreatedAt.Unix(),
+	}
+}
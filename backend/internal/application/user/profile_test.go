@@ -0,0 +1,181 @@
+package user
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/user"
+	"backend-chat-app/internal/infrastructure/imaging"
+	"backend-chat-app/internal/infrastructure/storage"
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+)
+
+type profileFixture struct {
+	service *UserService
+	users   *memoryUserRepository
+	renames *renameRecorder
+	store   *storage.LocalStore
+}
+
+func newProfileFixture(t *testing.T) *profileFixture {
+	t.Helper()
+	f := &profileFixture{
+		users:   newMemoryUserRepository(user.User{ID: "u1", Username: "alice", Name: "Alice"}),
+		renames: &renameRecorder{names: make(map[string]string)},
+		store:   storage.NewLocalStore(t.TempDir()),
+	}
+	f.service = NewUserService(f.users, f.renames, f.store, imaging.NewProcessor(4096*4096), nil, nil, nil, nil)
+	return f
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+func TestUpdateProfile(t *testing.T) {
+	f := newProfileFixture(t)
+	profile, err := f.service.UpdateProfile("u1", application.UpdateProfileRequest{
+		DisplayName: ptr("  Ally "),
+		Bio:         ptr("Hi there"),
+		Timezone:    ptr("Asia/Ho_Chi_Minh"),
+	})
+	if err != nil {
+		t.Fatalf("UpdateProfile: %v", err)
+	}
+	if profile.DisplayName != "Ally" || profile.Bio != "Hi there" || profile.Timezone != "Asia/Ho_Chi_Minh" {
+		t.Fatalf("unexpected profile %+v", profile)
+	}
+	// Conversations show the display name from now on
+	if f.renames.names["u1"] != "Ally" {
+		t.Fatalf("participant name = %q", f.renames.names["u1"])
+	}
+}
+
+func TestUpdateProfileKeepsNameWhenUnchanged(t *testing.T) {
+	f := newProfileFixture(t)
+	if _, err := f.service.UpdateProfile("u1", application.UpdateProfileRequest{Bio: ptr("Hi there")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, renamed := f.renames.names["u1"]; renamed {
+		t.Fatal("conversations updated although the public name did not change")
+	}
+}
+
+func TestUpdateProfileValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		req  application.UpdateProfileRequest
+	}{
+		{"empty name", application.UpdateProfileRequest{Name: ptr("   ")}},
+		{"long display name", application.UpdateProfileRequest{DisplayName: ptr(strings.Repeat("é", user.MaxDisplayNameLength+1))}},
+		{"long bio", application.UpdateProfileRequest{Bio: ptr(strings.Repeat("a", user.MaxBioLength+1))}},
+		{"unknown timezone", application.UpdateProfileRequest{Timezone: ptr("Mars/Olympus_Mons")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newProfileFixture(t)
+			if _, err := f.service.UpdateProfile("u1", tt.req); err == nil {
+				t.Fatal("accepted")
+			}
+			if u, _ := f.users.GetByID("u1"); u.Name != "Alice" || u.DisplayName != "" || u.Bio != "" || u.Timezone != "" {
+				t.Fatalf("profile changed: %+v", u)
+			}
+		})
+	}
+}
+
+func TestUpdateProfileAllowsLimits(t *testing.T) {
+	f := newProfileFixture(t)
+	// Lengths are counted in characters, not bytes
+	_, err := f.service.UpdateProfile("u1", application.UpdateProfileRequest{
+		DisplayName: ptr(strings.Repeat("é", user.MaxDisplayNameLength)),
+		Bio:         ptr(strings.Repeat("é", user.MaxBioLength)),
+		Timezone:    ptr(""),
+	})
+	if err != nil {
+		t.Fatalf("UpdateProfile: %v", err)
+	}
+}
+
+func testPNG(t *testing.T, width int, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUploadAvatar(t *testing.T) {
+	f := newProfileFixture(t)
+	profile, err := f.service.UploadAvatar("u1", bytes.NewReader(testPNG(t, 600, 400)))
+	if err != nil {
+		t.Fatalf("UploadAvatar: %v", err)
+	}
+	if len(profile.AvatarURLs) != len(AvatarSizes) {
+		t.Fatalf("avatar urls %v", profile.AvatarURLs)
+	}
+
+	for _, tt := range []struct{ requested, stored int }{{1, 64}, {64, 64}, {100, 128}, {256, 256}, {1000, 256}} {
+		content, _, err := f.service.GetAvatar("u1", tt.requested)
+		if err != nil {
+			t.Fatalf("GetAvatar(%d): %v", tt.requested, err)
+		}
+		data, _ := io.ReadAll(content)
+		content.Close()
+		config, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("GetAvatar(%d): %v", tt.requested, err)
+		}
+		if config.Width != tt.stored || config.Height != tt.stored {
+			t.Fatalf("GetAvatar(%d) is %dx%d, want %dx%d", tt.requested, config.Width, config.Height, tt.stored, tt.stored)
+		}
+	}
+}
+
+func TestUploadAvatarReplacesFiles(t *testing.T) {
+	f := newProfileFixture(t)
+	if _, err := f.service.UploadAvatar("u1", bytes.NewReader(testPNG(t, 64, 64))); err != nil {
+		t.Fatal(err)
+	}
+	first, _ := f.users.GetByID("u1")
+	if _, err := f.service.UploadAvatar("u1", bytes.NewReader(testPNG(t, 64, 64))); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, size := range AvatarSizes {
+		content, _, err := f.store.Get(avatarKey("u1", first.AvatarID, size))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if content != nil {
+			content.Close()
+			t.Fatalf("old avatar of size %d still stored", size)
+		}
+	}
+}
+
+func TestUploadAvatarRejectsNonImages(t *testing.T) {
+	f := newProfileFixture(t)
+	for name, data := range map[string][]byte{
+		"text":      []byte("definitely not an image"),
+		"too large": append(testPNG(t, 8, 8), make([]byte, MaxAvatarSize)...),
+	} {
+		if _, err := f.service.UploadAvatar("u1", bytes.NewReader(data)); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+	if u, _ := f.users.GetByID("u1"); u.AvatarID != "" {
+		t.Fatal("avatar set from a rejected upload")
+	}
+}
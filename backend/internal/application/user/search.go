@@ -0,0 +1,77 @@
+package user
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/user"
+	"errors"
+	"unicode/utf8"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+	minSearchLength    = 2
+	maxSearchLength    = 100
+)
+
+// SearchUsers looks users up by name, username or email. Users who chose
+// not to be discoverable, bots, the viewer and users on either side of a
+// block with the viewer are left out.
+func (us *UserService) SearchUsers(req application.SearchUsersRequest) (*application.SearchUsersResponse, error) {
+	length := utf8.RuneCountInString(req.Query)
+	if length < minSearchLength {
+		return nil, errors.New("search query must be at least 2 characters")
+	}
+	if length > maxSearchLength {
+		return nil, errors.New("search query is too long")
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 {
+		req.Limit = defaultSearchLimit
+	}
+	if req.Limit > maxSearchLimit {
+		req.Limit = maxSearchLimit
+	}
+
+	contacts, err := us.contactRepo.List(req.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	contactIDs := make([]string, 0, len(contacts))
+	for _, c := range contacts {
+		contactIDs = append(contactIDs, c.ContactID)
+	}
+	related, err := us.blockRepo.ListRelated(req.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// One extra row tells whether there is a next page
+	found, err := us.userRepo.Search(user.SearchQuery{
+		Term:       req.Query,
+		ContactIDs: contactIDs,
+		ExcludeIDs: append(related, req.ViewerID),
+		Offset:     (req.Page - 1) * req.Limit,
+		Limit:      req.Limit + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(found) > req.Limit
+	if hasMore {
+		found = found[:req.Limit]
+	}
+	users := make([]application.UserProfile, 0, len(found))
+	for _, u := range found {
+		users = append(users, *toUserProfile(u))
+	}
+	return &application.SearchUsersResponse{
+		Users:   users,
+		Page:    req.Page,
+		Limit:   req.Limit,
+		HasMore: hasMore,
+	}, nil
+}
@@ -0,0 +1,79 @@
+package user
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/contact"
+	"backend-chat-app/internal/domain/user"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func newSearchFixture(found int) (*UserService, *searchRecorder) {
+	users := &searchRecorder{memoryUserRepository: newMemoryUserRepository()}
+	for i := 0; i < found; i++ {
+		users.found = append(users.found, &user.User{ID: "u" + strconv.Itoa(i), Username: "user" + strconv.Itoa(i)})
+	}
+	contacts := staticContacts{contacts: []*contact.Contact{{UserID: "viewer", ContactID: "friend"}}}
+	blocks := staticBlocks{related: []string{"blocked"}}
+	return NewUserService(users, nil, nil, nil, contacts, blocks, nil, nil), users
+}
+
+func TestSearchUsersQuery(t *testing.T) {
+	s, users := newSearchFixture(0)
+	if _, err := s.SearchUsers(application.SearchUsersRequest{ViewerID: "viewer", Query: "ng", Page: 3, Limit: 10}); err != nil {
+		t.Fatal(err)
+	}
+	q := users.query
+	if q.Term != "ng" || q.Offset != 20 || q.Limit != 11 {
+		t.Fatalf("unexpected query %+v", q)
+	}
+	if !slices.Equal(q.ContactIDs, []string{"friend"}) {
+		t.Fatalf("contacts = %v", q.ContactIDs)
+	}
+	// Neither the viewer nor users on either side of a block are returned
+	if !slices.Contains(q.ExcludeIDs, "viewer") || !slices.Contains(q.ExcludeIDs, "blocked") {
+		t.Fatalf("excluded = %v", q.ExcludeIDs)
+	}
+}
+
+func TestSearchUsersPaging(t *testing.T) {
+	tests := []struct {
+		found       int
+		limit       int
+		wantLimit   int
+		wantUsers   int
+		wantHasMore bool
+	}{
+		{found: 5, limit: 10, wantLimit: 10, wantUsers: 5},
+		{found: 10, limit: 10, wantLimit: 10, wantUsers: 10},
+		{found: 11, limit: 10, wantLimit: 10, wantUsers: 10, wantHasMore: true},
+		{found: 30, limit: 0, wantLimit: defaultSearchLimit, wantUsers: defaultSearchLimit, wantHasMore: true},
+		{found: 60, limit: 500, wantLimit: maxSearchLimit, wantUsers: maxSearchLimit, wantHasMore: true},
+	}
+	for _, tt := range tests {
+		s, _ := newSearchFixture(tt.found)
+		res, err := s.SearchUsers(application.SearchUsersRequest{ViewerID: "viewer", Query: "user", Limit: tt.limit})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Limit != tt.wantLimit || len(res.Users) != tt.wantUsers || res.HasMore != tt.wantHasMore || res.Page != 1 {
+			t.Errorf("found %d, limit %d: got limit %d, %d users, has_more %v, page %d",
+				tt.found, tt.limit, res.Limit, len(res.Users), res.HasMore, res.Page)
+		}
+	}
+}
+
+func TestSearchUsersQueryLength(t *testing.T) {
+	s, _ := newSearchFixture(0)
+	for _, query := range []string{"", "a", "đ", strings.Repeat("a", maxSearchLength+1)} {
+		if _, err := s.SearchUsers(application.SearchUsersRequest{ViewerID: "viewer", Query: query}); err == nil {
+			t.Errorf("query %q accepted", query)
+		}
+	}
+	// Length is counted in characters
+	if _, err := s.SearchUsers(application.SearchUsersRequest{ViewerID: "viewer", Query: "đă"}); err != nil {
+		t.Errorf("two letter query refused: %v", err)
+	}
+}
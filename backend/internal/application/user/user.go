@@ -2,31 +2,64 @@ package user
 
 import (
 	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/contact"
 	"backend-chat-app/internal/domain/conversation"
+	"backend-chat-app/internal/domain/media"
+	"backend-chat-app/internal/domain/phone"
 	"backend-chat-app/internal/domain/user"
+	"encoding/base64"
 	"errors"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type UserService struct {
 	userRepo         user.UserRepository
 	conversationRepo conversation.ConversationRepository
+	blobStore        media.BlobStore
+	imageProcessor   media.ImageProcessor
+	contactRepo      contact.ContactRepository
+	blockRepo        contact.BlockRepository
+	phones           *phone.Normalizer
+	settingsRepo     conversation.SettingsRepository
 }
 
-func NewUserService(userRepository user.UserRepository, conversationRepo conversation.ConversationRepository) *UserService {
+func NewUserService(userRepository user.UserRepository, conversationRepo conversation.ConversationRepository, blobStore media.BlobStore, imageProcessor media.ImageProcessor, contactRepo contact.ContactRepository, blockRepo contact.BlockRepository, phones *phone.Normalizer, settingsRepo conversation.SettingsRepository) *UserService {
 	return &UserService{
 		userRepo:         userRepository,
 		conversationRepo: conversationRepo,
+		blobStore:        blobStore,
+		imageProcessor:   imageProcessor,
+		contactRepo:      contactRepo,
+		blockRepo:        blockRepo,
+		phones:           phones,
+		settingsRepo:     settingsRepo,
 	}
 }
 
 func (us *UserService) FindUserByPhone(request application.FindUserByPhoneRequest) (*application.FindUserByPhoneResponse, error) {
-	user, err := us.userRepo.GetByPhone(request.Phone)
+	phoneNumber, err := us.phones.Normalize(request.Phone)
+	if err != nil {
+		return nil, err
+	}
+	user, err := us.userRepo.GetByPhone(phoneNumber)
 	if err != nil {
 		return nil, err
 	}
 	if user == nil {
 		return nil, errors.New("phone doesnt exists")
 	}
+	// Users who blocked the requester look like they don't exist
+	if request.UserID != "" {
+		blocked, err := us.blockRepo.IsBlocked(user.ID, request.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if blocked {
+			return nil, errors.New("phone doesnt exists")
+		}
+	}
 	return &application.FindUserByPhoneResponse{
 		Email: user.Email,
 		Name:  user.Name,
@@ -34,38 +67,138 @@ func (us *UserService) FindUserByPhone(request application.FindUserByPhoneReques
 	}, nil
 }
 
-func (us *UserService) GetConversationList(userID string) (*application.GetConversationListResponse, error) {
-	conversations, err := us.userRepo.GetConversationList(userID)
-	if err != nil {
-		return nil, err
+const (
+	defaultConversationLimit = 20
+	maxConversationLimit     = 100
+)
+
+const (
+	ConversationFilterInbox    = "inbox"
+	ConversationFilterArchived = "archived"
+	ConversationFilterAll      = "all"
+)
+
+// GetConversationList returns a page of the user's conversations, most
+// recently active first. The inbox leaves out archived conversations and
+// starts with the pinned ones on its first page.
+func (us *UserService) GetConversationList(req application.GetConversationListRequest) (*application.GetConversationListResponse, error) {
+	if req.Limit < 1 {
+		req.Limit = defaultConversationLimit
 	}
-	if conversations == nil {
-		return nil, errors.New("doesnt have any conversation")
+	if req.Limit > maxConversationLimit {
+		req.Limit = maxConversationLimit
 	}
-	var response application.GetConversationListResponse
-	for _, conversationID := range conversations {
-		if conversationID == nil {
-			continue
-		}
-		res, err := us.conversationRepo.GetByID(*conversationID)
+	query := conversation.ListQuery{UserID: req.UserID, Limit: req.Limit + 1}
+	if req.Cursor != "" {
+		after, err := decodeConversationCursor(req.Cursor)
 		if err != nil {
 			return nil, err
 		}
-		if res == nil {
-			continue
-		}
-		participants := make([]application.ParticipantInfo, 0, len(res.Participant))
-		for _, p := range res.Participant {
-			participants = append(participants, application.ParticipantInfo{
-				ID:   p.ID,
-				Name: p.Name,
-			})
+		query.After = after
+	}
+
+	settings, err := us.settingsByConversation(req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	var pinnedIDs, archivedIDs []string
+	for conversationID, s := range settings {
+		if s.Archived {
+			archivedIDs = append(archivedIDs, conversationID)
+		} else if s.Pinned {
+			pinnedIDs = append(pinnedIDs, conversationID)
 		}
-		conversationModel := application.Conversation{
-			ID:          res.ID,
-			Participant: participants,
+	}
+
+	var pinned []*conversation.Conversation
+	switch req.Filter {
+	case "", ConversationFilterInbox:
+		query.ExcludeIDs = append(pinnedIDs, archivedIDs...)
+		if req.Cursor == "" && len(pinnedIDs) > 0 {
+			pinned, err = us.conversationRepo.ListByParticipant(conversation.ListQuery{UserID: req.UserID, IDs: pinnedIDs, Limit: len(pinnedIDs)})
+			if err != nil {
+				return nil, err
+			}
 		}
-		response.ConversationLists = append(response.ConversationLists, conversationModel)
+	case ConversationFilterArchived:
+		query.IDs = append([]string{}, archivedIDs...)
+	case ConversationFilterAll:
+	default:
+		return nil, errors.New("filter must be inbox, archived or all")
+	}
+
+	conversations, err := us.conversationRepo.ListByParticipant(query)
+	if err != nil {
+		return nil, err
+	}
+
+	response := application.GetConversationListResponse{
+		ConversationLists: make([]application.Conversation, 0, len(pinned)+len(conversations)),
+	}
+	if len(conversations) > req.Limit {
+		conversations = conversations[:req.Limit]
+		last := conversations[len(conversations)-1]
+		response.NextCursor = encodeConversationCursor(conversation.Cursor{UpdateAt: last.UpdateAt, ID: last.ID})
+	}
+	for _, c := range append(pinned, conversations...) {
+		response.ConversationLists = append(response.ConversationLists, toConversation(c, settings[c.ID]))
 	}
 	return &response, nil
 }
+
+func toConversation(c *conversation.Conversation, settings *conversation.Settings) application.Conversation {
+	participants := make([]application.ParticipantInfo, 0, len(c.Participant))
+	for _, p := range c.Participant {
+		participants = append(participants, application.ParticipantInfo{
+			ID:   p.ID,
+			Name: p.Name,
+		})
+	}
+	res := application.Conversation{
+		ID:          c.ID,
+		Name:        c.Name,
+		OwnerID:     c.OwnerID,
+		Direct:      c.Direct,
+		Participant: participants,
+		MessageTTL:  int64(c.MessageTTL / time.Second),
+		UpdateAt:    c.UpdateAt.Unix(),
+	}
+	if c.LastMessage != nil {
+		res.LastMessage = &application.LastMessageInfo{
+			ID:        c.LastMessage.ID,
+			SenderID:  c.LastMessage.SenderID,
+			Message:   c.LastMessage.Text,
+			CreatedAt: c.LastMessage.CreatedAt.Unix(),
+		}
+		for _, p := range c.Participant {
+			if p.ID == c.LastMessage.SenderID {
+				res.LastMessage.SenderName = p.Name
+			}
+		}
+	}
+	if settings != nil {
+		res.Settings = *toConversationSettings(c.ID, settings)
+	} else {
+		res.Settings = application.ConversationSettings{ConversationID: c.ID}
+	}
+	return res
+}
+
+// Cursors are opaque to clients: "<update_at>.<conversation_id>" in base64.
+func encodeConversationCursor(cursor conversation.Cursor) string {
+	raw := strconv.FormatInt(cursor.UpdateAt.Unix(), 10) + "." + cursor.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeConversationCursor(value string) (*conversation.Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	updateAt, id, found := strings.Cut(string(raw), ".")
+	seconds, err := strconv.ParseInt(updateAt, 10, 64)
+	if !found || err != nil || id == "" {
+		return nil, errors.New("invalid cursor")
+	}
+	return &conversation.Cursor{UpdateAt: time.Unix(seconds, 0), ID: id}, nil
+}
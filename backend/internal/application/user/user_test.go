@@ -0,0 +1,103 @@
+package user
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/domain/conversation"
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestConversationCursorRoundTrip(t *testing.T) {
+	cursor := conversation.Cursor{UpdateAt: time.Unix(1767225600, 0), ID: "65a1b2c3d4e5f60718293a4b"}
+	decoded, err := decodeConversationCursor(encodeConversationCursor(cursor))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !decoded.UpdateAt.Equal(cursor.UpdateAt) || decoded.ID != cursor.ID {
+		t.Fatalf("decoded %+v, want %+v", decoded, cursor)
+	}
+
+	for _, value := range []string{"not base64!", encode("1767225600"), encode("1767225600."), encode("soon.c1")} {
+		if _, err := decodeConversationCursor(value); err == nil {
+			t.Errorf("decodeConversationCursor(%q) accepted", value)
+		}
+	}
+}
+
+func encode(raw string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func TestGetConversationListPagesByActivity(t *testing.T) {
+	base := time.Unix(1767225600, 0)
+	conversations := &memoryConversations{}
+	// c1 and c2 share a time, the cursor has to break the tie by ID
+	for i, minutes := range []int{0, 1, 1, 2, 3} {
+		conversations.conversations = append(conversations.conversations, &conversation.Conversation{
+			ID:          fmt.Sprintf("c%d", i),
+			Participant: []conversation.Participant{{ID: "alice", Name: "Alice"}, {ID: "bob", Name: "Bob"}},
+			UpdateAt:    base.Add(time.Duration(minutes) * time.Minute),
+		})
+	}
+	conversations.conversations = append(conversations.conversations, &conversation.Conversation{
+		ID:          "not-alice",
+		Participant: []conversation.Participant{{ID: "bob"}, {ID: "carol"}},
+		UpdateAt:    base.Add(time.Hour),
+	})
+	service := NewUserService(nil, conversations, nil, nil, nil, nil, nil, newMemorySettings())
+
+	var got []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("paging does not end")
+		}
+		res, err := service.GetConversationList(application.GetConversationListRequest{UserID: "alice", Cursor: cursor, Limit: 2})
+		if err != nil {
+			t.Fatalf("GetConversationList: %v", err)
+		}
+		if len(res.ConversationLists) > 2 {
+			t.Fatalf("page has %d conversations, limit is 2", len(res.ConversationLists))
+		}
+		for _, c := range res.ConversationLists {
+			got = append(got, c.ID)
+		}
+		if res.NextCursor == "" {
+			break
+		}
+		cursor = res.NextCursor
+	}
+
+	want := []string{"c4", "c3", "c2", "c1", "c0"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("conversations %v, want %v", got, want)
+	}
+}
+
+func TestToConversationNamesLastSender(t *testing.T) {
+	sent := time.Unix(1767225600, 0)
+	c := &conversation.Conversation{
+		ID:          "c1",
+		Participant: []conversation.Participant{{ID: "alice", Name: "Alice"}, {ID: "bob", Name: "Bob"}},
+		LastMessage: &conversation.LastMessage{ID: "m1", SenderID: "bob", Text: "see you", CreatedAt: sent},
+		UpdateAt:    sent,
+	}
+
+	res := toConversation(c, nil)
+	last := res.LastMessage
+	if last == nil {
+		t.Fatal("no last message")
+	}
+	if last.ID != "m1" || last.SenderID != "bob" || last.SenderName != "Bob" || last.Message != "see you" || last.CreatedAt != sent.Unix() {
+		t.Fatalf("last message %+v", last)
+	}
+	if res.Settings.ConversationID != "c1" {
+		t.Fatalf("default settings for %q, want c1", res.Settings.ConversationID)
+	}
+
+	if toConversation(&conversation.Conversation{ID: "c2"}, nil).LastMessage != nil {
+		t.Fatal("conversation without messages has a last message")
+	}
+}
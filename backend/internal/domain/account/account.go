@@ -0,0 +1,21 @@
+package account
+
+import "time"
+
+type ExportJobRepository interface {
+	// Create returns ErrExportInProgress when the user has a pending or
+	// running job, checked atomically with the insert
+	Create(job ExportJob) (*ExportJob, error)
+	GetByID(jobID string) (*ExportJob, error)
+	ListByUser(userID string) ([]*ExportJob, error)
+	// ClaimPending atomically moves the oldest pending job to running,
+	// returning nil when there is none
+	ClaimPending() (*ExportJob, error)
+	Complete(jobID string, blobKey string, size int64, expiresAt time.Time) error
+	Fail(jobID string, reason string) error
+	// ResetRunning puts jobs interrupted by a restart back in the queue
+	ResetRunning() error
+	ListExpired(now time.Time) ([]*ExportJob, error)
+	MarkExpired(jobID string) error
+	DeleteByUser(userID string) error
+}
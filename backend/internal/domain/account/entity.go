@@ -0,0 +1,43 @@
+package account
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	ExportPending = "pending"
+	ExportRunning = "running"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+	ExportExpired = "expired"
+)
+
+// ErrExportInProgress is returned when the user already has a pending or
+// running export.
+var ErrExportInProgress = errors.New("an export is already in progress")
+
+// ExportJob builds a zip of everything we store about a user. The archive
+// is kept in blob storage until ExpiresAt.
+type ExportJob struct {
+	ID          string
+	UserID      string
+	Status      string
+	BlobKey     string
+	Size        int64
+	Error       string
+	CreatedAt   time.Time
+	CompletedAt time.Time
+	ExpiresAt   time.Time
+}
+
+func NewExportJob(userID string) (*ExportJob, error) {
+	if userID == "" {
+		return nil, errors.New("user can not empty")
+	}
+	return &ExportJob{
+		UserID:    userID,
+		Status:    ExportPending,
+		CreatedAt: time.Now(),
+	}, nil
+}
@@ -0,0 +1,11 @@
+package apikey
+
+import "time"
+
+type APIKeyRepository interface {
+	Create(key APIKey) (*APIKey, error)
+	GetByPrefix(prefix string) (*APIKey, error)
+	GetByBot(botID string) ([]*APIKey, error)
+	Revoke(keyID string, botID string) error
+	TouchLastUsed(keyID string, at time.Time) error
+}
@@ -0,0 +1,95 @@
+package apikey
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	ActionMessagesWrite = "messages:write"
+	ActionMessagesRead  = "messages:read"
+)
+
+// Scope grants one action inside one conversation, written as
+// "<action>:<conversation_id>", e.g. "messages:write:65f1a2b3c4d5e6f7a8b9c0d1".
+type Scope struct {
+	Action         string
+	ConversationID string
+}
+
+func ParseScope(raw string) (*Scope, error) {
+	idx := strings.LastIndex(raw, ":")
+	if idx <= 0 || idx == len(raw)-1 {
+		return nil, errors.New("scope must look like <action>:<conversation_id>")
+	}
+	scope := &Scope{Action: raw[:idx], ConversationID: raw[idx+1:]}
+	if scope.Action != ActionMessagesWrite && scope.Action != ActionMessagesRead {
+		return nil, errors.New("unknown scope action: " + scope.Action)
+	}
+	return scope, nil
+}
+
+func (s Scope) String() string {
+	return s.Action + ":" + s.ConversationID
+}
+
+type APIKey struct {
+	ID         string
+	BotID      string
+	OwnerID    string
+	Name       string
+	Prefix     string
+	Hash       string
+	Scopes     []Scope
+	ExpiresAt  time.Time
+	RevokedAt  time.Time
+	LastUsedAt time.Time
+	CreatedAt  time.Time
+}
+
+func NewAPIKey(botID string, ownerID string, name string, prefix string, hash string, scopes []Scope, expiresAt time.Time) (*APIKey, error) {
+	if botID == "" {
+		return nil, errors.New("bot_id can not empty")
+	}
+	if name == "" {
+		return nil, errors.New("name can not empty")
+	}
+	if prefix == "" || hash == "" {
+		return nil, errors.New("key material can not empty")
+	}
+	if len(scopes) == 0 {
+		return nil, errors.New("api key needs at least one scope")
+	}
+	return &APIKey{
+		BotID:     botID,
+		OwnerID:   ownerID,
+		Name:      name,
+		Prefix:    prefix,
+		Hash:      hash,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+func (k *APIKey) IsActive(now time.Time) bool {
+	if !k.RevokedAt.IsZero() {
+		return false
+	}
+	return k.ExpiresAt.IsZero() || now.Before(k.ExpiresAt)
+}
+
+// Allows reports whether the key may perform action in the conversation.
+// Write access implies read access.
+func (k *APIKey) Allows(action string, conversationID string) bool {
+	for _, scope := range k.Scopes {
+		if scope.ConversationID != conversationID {
+			continue
+		}
+		if scope.Action == action || (action == ActionMessagesRead && scope.Action == ActionMessagesWrite) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package apikey
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScope(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    Scope
+		wantErr bool
+	}{
+		{raw: "messages:write:c1", want: Scope{Action: ActionMessagesWrite, ConversationID: "c1"}},
+		{raw: "messages:read:c2", want: Scope{Action: ActionMessagesRead, ConversationID: "c2"}},
+		{raw: "messages:delete:c1", wantErr: true},
+		{raw: "messages:write:", wantErr: true},
+		{raw: ":c1", wantErr: true},
+		{raw: "c1", wantErr: true},
+		{raw: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		scope, err := ParseScope(tt.raw)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: parsed as %+v", tt.raw, scope)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.raw, err)
+			continue
+		}
+		if *scope != tt.want {
+			t.Errorf("%q: got %+v, want %+v", tt.raw, *scope, tt.want)
+		}
+		if scope.String() != tt.raw {
+			t.Errorf("%q: String() = %q", tt.raw, scope.String())
+		}
+	}
+}
+
+func TestAPIKeyAllows(t *testing.T) {
+	key := &APIKey{Scopes: []Scope{
+		{Action: ActionMessagesWrite, ConversationID: "c1"},
+		{Action: ActionMessagesRead, ConversationID: "c2"},
+	}}
+	tests := []struct {
+		action         string
+		conversationID string
+		want           bool
+	}{
+		{ActionMessagesWrite, "c1", true},
+		{ActionMessagesRead, "c1", true},
+		{ActionMessagesRead, "c2", true},
+		{ActionMessagesWrite, "c2", false},
+		{ActionMessagesRead, "c3", false},
+		{ActionMessagesWrite, "c3", false},
+	}
+	for _, tt := range tests {
+		if got := key.Allows(tt.action, tt.conversationID); got != tt.want {
+			t.Errorf("Allows(%s, %s) = %v, want %v", tt.action, tt.conversationID, got, tt.want)
+		}
+	}
+}
+
+func TestAPIKeyIsActive(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name string
+		key  APIKey
+		want bool
+	}{
+		{"no expiry", APIKey{}, true},
+		{"expires later", APIKey{ExpiresAt: now.Add(time.Hour)}, true},
+		{"expired", APIKey{ExpiresAt: now.Add(-time.Hour)}, false},
+		{"expires now", APIKey{ExpiresAt: now}, false},
+		{"revoked", APIKey{RevokedAt: now.Add(-time.Minute)}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.key.IsActive(now); got != tt.want {
+			t.Errorf("%s: IsActive = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package attachment
+
+import "time"
+
+type AttachmentRepository interface {
+	Create(attachment Attachment) (*Attachment, error)
+	// GetByID returns nil when the attachment does not exist
+	GetByID(attachmentID string) (*Attachment, error)
+	GetByIDs(attachmentIDs []string) ([]*Attachment, error)
+	// AttachToMessage links unsent attachments to a message and returns how
+	// many were linked; attachments already sent are left alone.
+	AttachToMessage(attachmentIDs []string, messageID string) (int, error)
+	// DetachFromMessage makes the attachments of a message unsent again
+	DetachFromMessage(messageID string) error
+	// ListUnsentBefore returns uploads never sent in a message
+	ListUnsentBefore(t time.Time) ([]*Attachment, error)
+	ListByConversation(conversationID string) ([]*Attachment, error)
+	ListByUploader(uploaderID string) ([]*Attachment, error)
+	Delete(attachmentID string) error
+}
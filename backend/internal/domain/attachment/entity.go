@@ -0,0 +1,53 @@
+package attachment
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Attachment is a file uploaded to a conversation. It belongs to no message
+// until it is sent, and unsent uploads are cleaned up after a while.
+type Attachment struct {
+	ID             string
+	ConversationID string
+	UploaderID     string
+	MessageID      string // empty until sent
+	FileName       string
+	ContentType    string
+	Size           int64
+	BlobKey        string
+	ThumbnailKey   string // empty for files that are not images
+	// Duration and Waveform are only set for audio, see media.AudioInfo
+	Duration  time.Duration
+	Waveform  []int
+	CreatedAt time.Time
+}
+
+func NewAttachment(conversationID string, uploaderID string, fileName string, contentType string, size int64) (*Attachment, error) {
+	if conversationID == "" {
+		return nil, errors.New("conversation_id can not empty")
+	}
+	if uploaderID == "" {
+		return nil, errors.New("uploader can not empty")
+	}
+	if contentType == "" {
+		return nil, errors.New("content type can not empty")
+	}
+	return &Attachment{
+		ConversationID: conversationID,
+		UploaderID:     uploaderID,
+		FileName:       fileName,
+		ContentType:    contentType,
+		Size:           size,
+		CreatedAt:      time.Now(),
+	}, nil
+}
+
+func (a *Attachment) IsImage() bool {
+	return strings.HasPrefix(a.ContentType, "image/")
+}
+
+func (a *Attachment) IsAudio() bool {
+	return strings.HasPrefix(a.ContentType, "audio/")
+}
@@ -0,0 +1,35 @@
+package contact
+
+type RequestRepository interface {
+	Create(request Request) (*Request, error)
+	GetByID(requestID string) (*Request, error)
+	GetPending(fromID string, toID string) (*Request, error)
+	// UpdateStatus only moves a request that is still pending and reports
+	// whether it did, so two concurrent answers can't both win.
+	UpdateStatus(requestID string, status string) (bool, error)
+	ListPending(userID string, incoming bool) ([]*Request, error)
+	DeleteByUser(userID string) error
+}
+
+type ContactRepository interface {
+	Add(userID string, contactID string) error
+	Remove(userID string, contactID string) error
+	List(userID string) ([]*Contact, error)
+	IsContact(userID string, contactID string) (bool, error)
+	// RemoveAll deletes every contact relationship of the user
+	RemoveAll(userID string) error
+}
+
+type BlockRepository interface {
+	Block(blockerID string, blockedID string) error
+	// Unblock reports whether a block was removed
+	Unblock(blockerID string, blockedID string) (bool, error)
+	List(blockerID string) ([]*Block, error)
+	IsBlocked(blockerID string, blockedID string) (bool, error)
+	// IsBlockedEither is true when one of the users blocked the other
+	IsBlockedEither(userA string, userB string) (bool, error)
+	// ListRelated returns the users the user blocked or was blocked by
+	ListRelated(userID string) ([]string, error)
+	// DeleteByUser removes blocks the user made or received
+	DeleteByUser(userID string) error
+}
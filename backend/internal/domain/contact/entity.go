@@ -0,0 +1,52 @@
+package contact
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusAccepted  = "accepted"
+	StatusDeclined  = "declined"
+	StatusCancelled = "cancelled"
+)
+
+type Request struct {
+	ID        string
+	FromID    string
+	ToID      string
+	Status    string
+	CreatedAt time.Time
+	UpdateAt  time.Time
+}
+
+func NewRequest(fromID string, toID string) (*Request, error) {
+	if fromID == "" || toID == "" {
+		return nil, errors.New("both users are required")
+	}
+	if fromID == toID {
+		return nil, errors.New("you can not add yourself as a contact")
+	}
+	return &Request{
+		FromID:    fromID,
+		ToID:      toID,
+		Status:    StatusPending,
+		CreatedAt: time.Now(),
+		UpdateAt:  time.Now(),
+	}, nil
+}
+
+// Contact is one direction of a mutual contact relationship; accepting a
+// request stores one for each user.
+type Contact struct {
+	UserID    string
+	ContactID string
+	CreatedAt time.Time
+}
+
+type Block struct {
+	BlockerID string
+	BlockedID string
+	CreatedAt time.Time
+}
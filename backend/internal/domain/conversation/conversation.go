@@ -1,8 +1,61 @@
 package conversation
 
+import "time"
+
 type ConversationRepository interface {
 	Create(conversation Conversation) (*Conversation, error)
 	GetByID(conversationID string) (*Conversation, error)
+	// ListByParticipant returns the user's conversations, most recently active first
+	ListByParticipant(query ListQuery) ([]*Conversation, error)
+	// AddParticipant reports false when the user was already a participant
+	AddParticipant(conversationID string, participant Participant) (bool, error)
+	// RemoveParticipant returns the conversation without the user, or nil
+	// when they were not a participant
+	RemoveParticipant(conversationID string, userID string) (*Conversation, error)
+	Rename(conversationID string, name string) error
+	SetOwner(conversationID string, ownerID string) error
+	Delete(conversationID string) error
+	// DeleteIfEmpty deletes the conversation only if it has no participants,
+	// and reports whether it did
+	DeleteIfEmpty(conversationID string) (bool, error)
+	// UpdateParticipantName refreshes the copy of a user's name kept in every conversation
+	UpdateParticipantName(userID string, name string) error
+	// SetLastMessage records a new message unless a newer one is already recorded
+	SetLastMessage(conversationID string, last LastMessage) error
+	// RedactLastMessages erases the text of last messages sent by the user
+	RedactLastMessages(senderID string) error
+	// ReplaceLastMessage sets the last message to last, or removes it when
+	// last is nil, unless it is no longer messageID
+	ReplaceLastMessage(conversationID string, messageID string, last *LastMessage) error
+	SetMessageTTL(conversationID string, ttl time.Duration) error
+	// ReservePin counts one more pinned message and reports false, counting
+	// nothing, when the conversation already has limit of them
+	ReservePin(conversationID string, limit int) (bool, error)
+	// ReleasePins takes back n pinned messages counted by ReservePin
+	ReleasePins(conversationID string, n int) error
 
 	IsCommunicate(participant1ID string, participant2ID string) (bool, error)
 }
+
+type SettingsRepository interface {
+	// Get returns nil when the user has no settings for the conversation
+	Get(userID string, conversationID string) (*Settings, error)
+	ListByUser(userID string) ([]*Settings, error)
+	Save(settings Settings) error
+	Delete(userID string, conversationID string) error
+	DeleteByConversation(conversationID string) error
+	// ListMutedUsers returns the users who muted the conversation at now
+	ListMutedUsers(conversationID string, now time.Time) ([]string, error)
+}
+
+type InviteRepository interface {
+	Create(invite Invite) (*Invite, error)
+	// GetByToken returns nil when no invite has the token
+	GetByToken(token string) (*Invite, error)
+	ListByConversation(conversationID string) ([]*Invite, error)
+	Revoke(inviteID string, conversationID string) error
+	// Use counts one join and reports false when the invite was revoked,
+	// expired or used up in the meantime.
+	Use(inviteID string, now time.Time) (bool, error)
+	DeleteByConversation(conversationID string) error
+}
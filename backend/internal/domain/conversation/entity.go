@@ -1,6 +1,8 @@
 package conversation
 
 import (
+	"errors"
+	"slices"
 	"time"
 )
 
@@ -9,11 +11,122 @@ type Participant struct {
 	Name string
 }
 
+// LastMessage is a copy of the newest message, kept on the conversation so
+// conversation lists don't have to look up messages.
+type LastMessage struct {
+	ID        string
+	SenderID  string
+	Text      string
+	CreatedAt time.Time
+}
+
+const MaxNameLength = 100
+
 type Conversation struct {
-	ID          string
+	ID      string
+	Name    string // empty for conversations that were never named
+	OwnerID string // may delete the conversation; empty for conversations created before owners
+	// Direct is set on one-to-one conversations started with CreateConversation.
+	// They stay direct when bots are added, other conversations are groups.
+	Direct      bool
 	Participant []Participant
+	LastMessage *LastMessage  // nil until the first message is sent
+	MessageTTL  time.Duration // messages disappear this long after being sent; zero keeps them
 	CreatedAt   time.Time
-	UpdateAt    time.Time
+	UpdateAt    time.Time // time of the last activity
+}
+
+// MessageTTLs are the timers a conversation can set for its messages to
+// disappear after.
+var MessageTTLs = []time.Duration{time.Hour, 24 * time.Hour, 7 * 24 * time.Hour, 30 * 24 * time.Hour}
+
+// CheckMessageTTL accepts zero, which turns disappearing messages off, or
+// one of MessageTTLs.
+func CheckMessageTTL(ttl time.Duration) error {
+	if ttl == 0 || slices.Contains(MessageTTLs, ttl) {
+		return nil
+	}
+	return errors.New("message_ttl must be 0, 3600 (1 hour), 86400 (1 day), 604800 (7 days) or 2592000 (30 days)")
+}
+
+// Cursor is the position of a conversation in a list sorted by UpdateAt,
+// newest first.
+type Cursor struct {
+	UpdateAt time.Time
+	ID       string
+}
+
+// ListQuery pages through the conversations of a user. After is nil for the
+// first page. IDs, when not nil, restricts the result to those conversations.
+type ListQuery struct {
+	UserID     string
+	After      *Cursor
+	IDs        []string
+	ExcludeIDs []string
+	Limit      int
+}
+
+// MaxPinned is how many conversations a user can pin.
+const MaxPinned = 5
+
+// Settings are one user's preferences for a conversation. Users without
+// stored settings get the zero value.
+type Settings struct {
+	UserID         string
+	ConversationID string
+	MutedUntil     time.Time // zero when not muted
+	Pinned         bool
+	PinnedAt       time.Time
+	Archived       bool
+	UpdateAt       time.Time
+}
+
+func (s *Settings) IsMuted(now time.Time) bool {
+	return s.MutedUntil.After(now)
+}
+
+// Invite is a shareable link that lets anyone holding Token join the
+// conversation. MaxUses is 0 and ExpiresAt zero when unlimited.
+type Invite struct {
+	ID             string
+	ConversationID string
+	CreatorID      string
+	Token          string
+	MaxUses        int
+	Uses           int
+	ExpiresAt      time.Time
+	RevokedAt      time.Time
+	CreatedAt      time.Time
+}
+
+func NewInvite(conversationID string, creatorID string, token string, maxUses int, expiresAt time.Time) (*Invite, error) {
+	if conversationID == "" {
+		return nil, errors.New("conversation_id can not empty")
+	}
+	if token == "" {
+		return nil, errors.New("token can not empty")
+	}
+	if maxUses < 0 {
+		return nil, errors.New("max_uses can not be negative")
+	}
+	return &Invite{
+		ConversationID: conversationID,
+		CreatorID:      creatorID,
+		Token:          token,
+		MaxUses:        maxUses,
+		ExpiresAt:      expiresAt,
+		CreatedAt:      time.Now(),
+	}, nil
+}
+
+func (i *Invite) IsActive(now time.Time) bool {
+	if !i.RevokedAt.IsZero() {
+		return false
+	}
+	if i.MaxUses > 0 && i.Uses >= i.MaxUses {
+		return false
+	}
+	return i.ExpiresAt.IsZero() || now.Before(i.ExpiresAt)
 }
 
 func NewConversation(participants []Participant) (*Conversation, error) {
@@ -23,3 +136,30 @@ func NewConversation(participants []Participant) (*Conversation, error) {
 		UpdateAt:    time.Now(),
 	}, nil
 }
+
+// CanDelete reports whether the user may delete the conversation for
+// everyone: its owner, or either side of a one-to-one conversation.
+func (c *Conversation) CanDelete(userID string) bool {
+	if !c.HasParticipant(userID) {
+		return false
+	}
+	return c.OwnerID == "" || c.OwnerID == userID || c.Direct
+}
+
+func (c *Conversation) ParticipantName(userID string) string {
+	for _, p := range c.Participant {
+		if p.ID == userID {
+			return p.Name
+		}
+	}
+	return ""
+}
+
+func (c *Conversation) HasParticipant(userID string) bool {
+	for _, p := range c.Participant {
+		if p.ID == userID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package linkpreview
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MaxURLsPerMessage limits how many links of one message are unfurled.
+const MaxURLsPerMessage = 3
+
+// Preview is the OpenGraph or Twitter card metadata of a page. Failed
+// fetches are cached too, so a broken link isn't fetched for every message.
+type Preview struct {
+	URL         string
+	Title       string
+	Description string
+	ImageURL    string
+	SiteName    string
+	Failed      bool
+	FetchedAt   time.Time
+}
+
+func (p *Preview) IsEmpty() bool {
+	return p.Title == "" && p.Description == "" && p.ImageURL == ""
+}
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"'` + "`" + `]+`)
+
+// ExtractURLs returns the distinct http(s) links in a message, in order,
+// without punctuation that ends the sentence around them.
+func ExtractURLs(text string) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, match := range urlPattern.FindAllString(text, -1) {
+		match = trimTrailingPunctuation(match)
+		parsed, err := url.Parse(match)
+		if err != nil || parsed.Host == "" {
+			continue
+		}
+		if seen[match] {
+			continue
+		}
+		seen[match] = true
+		urls = append(urls, match)
+		if len(urls) == MaxURLsPerMessage {
+			break
+		}
+	}
+	return urls
+}
+
+func trimTrailingPunctuation(s string) string {
+	for len(s) > 0 {
+		last := s[len(s)-1]
+		switch {
+		case strings.IndexByte(".,;:!?'\"]}", last) >= 0:
+			s = s[:len(s)-1]
+		// Keep the parenthesis of links like .../Go_(programming_language)
+		case last == ')' && strings.Count(s, "(") < strings.Count(s, ")"):
+			s = s[:len(s)-1]
+		default:
+			return s
+		}
+	}
+	return s
+}
@@ -0,0 +1,19 @@
+package linkpreview
+
+import (
+	"context"
+	"time"
+)
+
+type PreviewRepository interface {
+	// Get returns nil when the URL was never fetched
+	Get(url string) (*Preview, error)
+	Save(preview Preview) error
+	DeleteFetchedBefore(t time.Time) error
+}
+
+type Fetcher interface {
+	// Fetch downloads the page and reads its metadata. It must refuse to
+	// connect to private networks.
+	Fetch(ctx context.Context, url string) (*Preview, error)
+}
@@ -0,0 +1,28 @@
+package mail
+
+import (
+	"errors"
+	"time"
+)
+
+type Email struct {
+	To        string
+	Subject   string
+	Body      string
+	CreatedAt time.Time
+}
+
+func NewEmail(to string, subject string, body string) (*Email, error) {
+	if to == "" {
+		return nil, errors.New("recipient can not empty")
+	}
+	if subject == "" {
+		return nil, errors.New("subject can not empty")
+	}
+	return &Email{
+		To:        to,
+		Subject:   subject,
+		Body:      body,
+		CreatedAt: time.Now(),
+	}, nil
+}
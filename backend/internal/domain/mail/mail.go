@@ -0,0 +1,5 @@
+package mail
+
+type Mailer interface {
+	Send(email Email) error
+}
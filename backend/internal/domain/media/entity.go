@@ -0,0 +1,17 @@
+package media
+
+import "time"
+
+type BlobInfo struct {
+	Key         string
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
+
+// AudioInfo describes a voice recording so clients can show it before
+// downloading it. Waveform levels go from 0 to 100.
+type AudioInfo struct {
+	Duration time.Duration
+	Waveform []int
+}
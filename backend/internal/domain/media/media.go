@@ -0,0 +1,22 @@
+package media
+
+import "io"
+
+type BlobStore interface {
+	Put(key string, contentType string, content io.Reader) (*BlobInfo, error)
+	// Get returns nil when no blob is stored under key.
+	Get(key string) (io.ReadCloser, *BlobInfo, error)
+	Delete(key string) error
+}
+
+type ImageProcessor interface {
+	// Thumbnail decodes an image and re-encodes it as a JPEG that fits in a
+	// maxSize x maxSize box. With square set the image is center-cropped first.
+	Thumbnail(data []byte, maxSize int, square bool) ([]byte, error)
+}
+
+type AudioAnalyzer interface {
+	// Analyze reads the duration of a recording and a waveform with the
+	// given number of levels. contentType is audio/wav or audio/ogg.
+	Analyze(data []byte, contentType string, samples int) (*AudioInfo, error)
+}
@@ -2,15 +2,65 @@ package message
 
 import (
 	"errors"
+	"fmt"
 	"time"
 )
 
+// TypeSystem marks messages the server writes into the history, such as
+// "Alice left". SenderID is the user who caused the event.
+const TypeSystem = "system"
+
+// TypeVoice marks a voice note, whose only attachment is the recording.
+const TypeVoice = "voice"
+
+// MaxAttachments is how many files a single message can carry.
+const MaxAttachments = 10
+
 type Message struct {
 	ID             string
 	ConversationID string
 	SenderID       string
+	Type           string // empty for messages written by users
 	Message        string
+	AttachmentIDs  []string
+	LinkPreviews   []LinkPreview // filled in after sending, once fetched
+	Mentions       []Mention
+	Entities       []Entity // formatting of messages sent as markup
+	ForwardedFrom  *Forward // nil unless the message was forwarded
+	Poll           *Poll    // set on polls only
+	MentionedIDs   []string // everyone mentioned, by name or @all, but the sender
+	Redacted       bool     // the sender deleted their account
 	CreatedAt      time.Time
+	ExpiresAt      time.Time // zero unless the message disappears
+}
+
+// IsExpired reports whether the message disappeared, even if it was not
+// deleted yet.
+func (m *Message) IsExpired(now time.Time) bool {
+	return !m.ExpiresAt.IsZero() && !now.Before(m.ExpiresAt)
+}
+
+// MaxForwardMessages and MaxForwardTargets limit a single forward.
+const (
+	MaxForwardMessages = 20
+	MaxForwardTargets  = 10
+)
+
+// Forward credits the author of a forwarded message. The conversation it
+// came from is not kept, since the new readers may not belong to it.
+type Forward struct {
+	SenderID   string
+	SenderName string
+	CreatedAt  time.Time
+}
+
+// LinkPreview is the card shown under a link in the message.
+type LinkPreview struct {
+	URL         string
+	Title       string
+	Description string
+	ImageURL    string
+	SiteName    string
 }
 
 func NewMessage(conversation_id string, sender_id string, message string) (*Message, error) {
@@ -27,3 +77,92 @@ func NewMessage(conversation_id string, sender_id string, message string) (*Mess
 		CreatedAt:      time.Now(),
 	}, nil
 }
+
+func NewSystemMessage(conversationID string, actorID string, text string) (*Message, error) {
+	m, err := NewMessage(conversationID, actorID, text)
+	if err != nil {
+		return nil, err
+	}
+	m.Type = TypeSystem
+	return m, nil
+}
+
+// NewAttachmentMessage sends files, with the text as an optional caption.
+func NewAttachmentMessage(conversationID string, senderID string, caption string, attachmentIDs []string) (*Message, error) {
+	if conversationID == "" {
+		return nil, errors.New("conversation_id can't empty")
+	}
+	if len(attachmentIDs) == 0 {
+		return nil, errors.New("attachments can't empty")
+	}
+	if len(attachmentIDs) > MaxAttachments {
+		return nil, fmt.Errorf("a message can have at most %d attachments", MaxAttachments)
+	}
+	return &Message{
+		ConversationID: conversationID,
+		SenderID:       senderID,
+		Message:        caption,
+		AttachmentIDs:  attachmentIDs,
+		CreatedAt:      time.Now(),
+	}, nil
+}
+
+func NewVoiceMessage(conversationID string, senderID string, attachmentID string) (*Message, error) {
+	m, err := NewAttachmentMessage(conversationID, senderID, "", []string{attachmentID})
+	if err != nil {
+		return nil, err
+	}
+	m.Type = TypeVoice
+	return m, nil
+}
+
+// NewForwardedMessage copies the content of a message into another
+// conversation. Forwarding a forwarded message credits the original author.
+// Mentions are left out, they name participants of the source conversation,
+// and attachments are copied separately.
+func NewForwardedMessage(original *Message, conversationID string, senderID string, originalSenderName string) (*Message, error) {
+	if conversationID == "" {
+		return nil, errors.New("conversation_id can't empty")
+	}
+	// Polls belong to the conversation that votes on them
+	if original.Type == TypeSystem || original.Type == TypePoll || original.Redacted {
+		return nil, errors.New("this message can not be forwarded")
+	}
+	forward := original.ForwardedFrom
+	if forward == nil {
+		forward = &Forward{SenderID: original.SenderID, SenderName: originalSenderName, CreatedAt: original.CreatedAt}
+	}
+	return &Message{
+		ConversationID: conversationID,
+		SenderID:       senderID,
+		Type:           original.Type,
+		Message:        original.Message,
+		LinkPreviews:   original.LinkPreviews,
+		Entities:       original.Entities,
+		ForwardedFrom:  forward,
+		CreatedAt:      time.Now(),
+	}, nil
+}
+
+// MaxPinnedMessages is how many messages a conversation can have pinned.
+const MaxPinnedMessages = 20
+
+// Pin marks a message that stays visible at the top of the conversation.
+type Pin struct {
+	ConversationID string
+	MessageID      string
+	PinnedBy       string
+	PinnedAt       time.Time
+}
+
+func NewPin(conversationID string, messageID string, pinnedBy string) (*Pin, error) {
+	if conversationID == "" || messageID == "" {
+		return nil, errors.New("conversation_id and message_id can't empty")
+	}
+	return &Pin{
+		ConversationID: conversationID,
+		MessageID:      messageID,
+		PinnedBy:       pinnedBy,
+		PinnedAt:       time.Now(),
+	}, nil
+}
@@ -0,0 +1,332 @@
+package message
+
+import (
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// FormatMarkup is the format of messages written in the message markup:
+//
+//	*bold*  _italic_  `code`  ```lang
+//	code block```  [text](https://example.com)  \* escapes *, _, `, [, ], ( and )
+//
+// Mentions are found in the resulting text as in plain messages.
+const FormatMarkup = "markup"
+
+const (
+	EntityBold   = "bold"
+	EntityItalic = "italic"
+	EntityCode   = "code"
+	EntityPre    = "pre"
+	EntityLink   = "link"
+)
+
+// MaxEntities limits the formatting of one message; the rest of the text
+// stays plain.
+const MaxEntities = 100
+
+const maxLanguageLength = 20
+
+// Entity formats part of the plain text. Offset and Length count characters
+// (Unicode code points), like mentions.
+type Entity struct {
+	Type     string
+	Offset   int
+	Length   int
+	URL      string // for links
+	Language string // for code blocks, may be empty
+}
+
+// IsCode reports whether the entity's text is shown verbatim.
+func (e Entity) IsCode() bool {
+	return e.Type == EntityCode || e.Type == EntityPre
+}
+
+// ParseMarkup turns markup into the plain text clients without formatting
+// show, and the entities that format it. Markup that is not closed or not
+// allowed, such as links to other schemes than http, https and mailto, is
+// kept as text. It never fails.
+func ParseMarkup(source string) (string, []Entity) {
+	p := &markupParser{}
+	p.parse([]rune(SanitizeText(source)), false)
+	// Inner spans are closed first, list them in reading order with the
+	// outer span before the spans it contains. Reversed first so an outer
+	// span also comes first when it covers exactly the same text.
+	slices.Reverse(p.entities)
+	sort.SliceStable(p.entities, func(i, j int) bool {
+		a, b := p.entities[i], p.entities[j]
+		if a.Offset != b.Offset {
+			return a.Offset < b.Offset
+		}
+		return a.Length > b.Length
+	})
+	return string(p.out), p.entities
+}
+
+// SanitizeText normalizes line breaks and drops control characters other
+// than tabs and line breaks, which can't be displayed and could hide text.
+func SanitizeText(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if r == '\r' {
+			return '\n'
+		}
+		if unicode.IsControl(r) || r == unicode.ReplacementChar {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+type markupParser struct {
+	out      []rune
+	entities []Entity
+}
+
+func (p *markupParser) parse(src []rune, inLink bool) {
+	for i := 0; i < len(src); i++ {
+		r := src[i]
+		switch {
+		case r == '\\' && i+1 < len(src) && strings.ContainsRune("\\*_`[]()", src[i+1]):
+			p.out = append(p.out, src[i+1])
+			i++
+		case r == '`' && hasPrefix(src[i:], "```"):
+			end := p.codeBlock(src, i)
+			if end < 0 {
+				p.out = append(p.out, src[i:i+3]...)
+				i += 2
+				continue
+			}
+			i = end
+		case r == '`':
+			end := indexOnLine(src, i+1, '`')
+			if end < 0 || isBlank(src[i+1:end]) {
+				p.out = append(p.out, r)
+				continue
+			}
+			p.verbatim(EntityCode, src[i+1:end], "")
+			i = end
+		case (r == '*' || r == '_') && opensSpan(src, i):
+			end := closingDelimiter(src, i)
+			if end < 0 {
+				p.out = append(p.out, r)
+				continue
+			}
+			entityType := EntityBold
+			if r == '_' {
+				entityType = EntityItalic
+			}
+			start := len(p.out)
+			p.parse(src[i+1:end], inLink)
+			p.addEntity(Entity{Type: entityType, Offset: start, Length: len(p.out) - start})
+			i = end
+		case r == '[' && !inLink:
+			textEnd, link, end := parseLink(src, i)
+			if end < 0 {
+				p.out = append(p.out, r)
+				continue
+			}
+			start := len(p.out)
+			p.parse(src[i+1:textEnd], true)
+			p.addEntity(Entity{Type: EntityLink, Offset: start, Length: len(p.out) - start, URL: link})
+			i = end
+		default:
+			p.out = append(p.out, r)
+		}
+	}
+}
+
+// codeBlock reads a ``` block starting at i and returns the index of its
+// last backtick, or -1 when it is not closed.
+func (p *markupParser) codeBlock(src []rune, i int) int {
+	contentStart := i + 3
+	closing := -1
+	for j := contentStart; j+3 <= len(src); j++ {
+		if hasPrefix(src[j:], "```") {
+			closing = j
+			break
+		}
+	}
+	if closing < 0 {
+		return -1
+	}
+
+	// A word right after the opening backticks, alone on its line, names
+	// the language
+	var language string
+	if newline := indexOf(src[contentStart:closing], '\n'); newline >= 0 {
+		candidate := string(src[contentStart : contentStart+newline])
+		if isLanguage(candidate) {
+			language = candidate
+			contentStart += newline + 1
+		} else if candidate == "" {
+			contentStart++
+		}
+	}
+	content := src[contentStart:closing]
+	if len(content) > 0 && content[len(content)-1] == '\n' {
+		content = content[:len(content)-1]
+	}
+	if isBlank(content) {
+		return -1
+	}
+	p.verbatim(EntityPre, content, language)
+	return closing + 2
+}
+
+func (p *markupParser) verbatim(entityType string, text []rune, language string) {
+	start := len(p.out)
+	p.out = append(p.out, text...)
+	p.addEntity(Entity{Type: entityType, Offset: start, Length: len(text), Language: language})
+}
+
+func (p *markupParser) addEntity(e Entity) {
+	if e.Length == 0 || len(p.entities) >= MaxEntities {
+		return
+	}
+	p.entities = append(p.entities, e)
+}
+
+// opensSpan allows * and _ to start formatting only at the start of a word,
+// so 2*3*4 and snake_case stay as they are.
+func opensSpan(src []rune, i int) bool {
+	if i+1 >= len(src) || unicode.IsSpace(src[i+1]) || src[i+1] == src[i] {
+		return false
+	}
+	return i == 0 || !isWordRune(src[i-1])
+}
+
+// closingDelimiter finds the delimiter that ends the span opened at i, on
+// the same line and at the end of a word.
+func closingDelimiter(src []rune, i int) int {
+	delimiter := src[i]
+	for j := i + 1; j < len(src); j++ {
+		switch {
+		case src[j] == '\n':
+			return -1
+		case src[j] == '\\':
+			j++
+		case src[j] == delimiter && !unicode.IsSpace(src[j-1]) && (j+1 == len(src) || !isWordRune(src[j+1])):
+			return j
+		}
+	}
+	return -1
+}
+
+// parseLink reads [text](url) starting at i. It returns the index of the ],
+// the cleaned URL and the index of the closing parenthesis, or -1 as end
+// when the link is invalid.
+func parseLink(src []rune, i int) (int, string, int) {
+	textEnd := closingBracket(src, i+1)
+	if textEnd <= i+1 || textEnd+1 >= len(src) || src[textEnd+1] != '(' {
+		return 0, "", -1
+	}
+	urlStart := textEnd + 2
+	urlEnd := -1
+	for j := urlStart; j < len(src); j++ {
+		if src[j] == ')' {
+			urlEnd = j
+			break
+		}
+		if unicode.IsSpace(src[j]) {
+			break
+		}
+	}
+	if urlEnd <= urlStart {
+		return 0, "", -1
+	}
+	link, ok := cleanLink(string(src[urlStart:urlEnd]))
+	if !ok {
+		return 0, "", -1
+	}
+	return textEnd, link, urlEnd
+}
+
+// closingBracket finds the ] that ends link text starting at start, on the
+// same line. Escaped brackets are part of the text.
+func closingBracket(src []rune, start int) int {
+	for j := start; j < len(src); j++ {
+		switch src[j] {
+		case '\n':
+			return -1
+		case '\\':
+			j++
+		case ']':
+			return j
+		}
+	}
+	return -1
+}
+
+func cleanLink(raw string) (string, bool) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		if u.Host == "" {
+			return "", false
+		}
+	case "mailto":
+		if u.Opaque == "" {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+	return u.String(), true
+}
+
+func isLanguage(s string) bool {
+	if s == "" || len(s) > maxLanguageLength {
+		return false
+	}
+	for _, r := range s {
+		if !(r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("+#-_.", r))) {
+			return false
+		}
+	}
+	return true
+}
+
+func isBlank(text []rune) bool {
+	return strings.TrimSpace(string(text)) == ""
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func hasPrefix(src []rune, prefix string) bool {
+	return strings.HasPrefix(string(src[:min(len(src), len(prefix))]), prefix)
+}
+
+func indexOf(src []rune, r rune) int {
+	for i, c := range src {
+		if c == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// indexOnLine returns the index of r from start on, or -1 when a line break
+// comes first.
+func indexOnLine(src []rune, start int, r rune) int {
+	for j := start; j < len(src); j++ {
+		if src[j] == '\n' {
+			return -1
+		}
+		if src[j] == r {
+			return j
+		}
+	}
+	return -1
+}
@@ -0,0 +1,85 @@
+package message
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMarkup(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		text     string
+		entities []Entity
+	}{
+		{name: "plain", source: "hello", text: "hello"},
+		{name: "bold", source: "a *b* c", text: "a b c", entities: []Entity{{Type: EntityBold, Offset: 2, Length: 1}}},
+		{name: "italic", source: "_hi_", text: "hi", entities: []Entity{{Type: EntityItalic, Offset: 0, Length: 2}}},
+		{name: "nested", source: "*bold _both_*", text: "bold both", entities: []Entity{
+			{Type: EntityBold, Offset: 0, Length: 9},
+			{Type: EntityItalic, Offset: 5, Length: 4},
+		}},
+		{name: "escapes", source: `\*not bold\* \_ \[x\] \\`, text: `*not bold* _ [x] \`},
+		{name: "unclosed bold", source: "*open", text: "*open"},
+		{name: "unclosed code", source: "`open", text: "`open"},
+		{name: "unclosed code block", source: "```go\nx := 1", text: "```go\nx := 1"},
+		{name: "span does not cross lines", source: "*a\nb*", text: "*a\nb*"},
+		{name: "snake_case", source: "snake_case_name", text: "snake_case_name"},
+		{name: "multiplication", source: "2*3*4", text: "2*3*4"},
+		{name: "space after opener", source: "* not bold*", text: "* not bold*"},
+		{name: "inline code keeps markup", source: "`*x*`", text: "*x*", entities: []Entity{{Type: EntityCode, Offset: 0, Length: 3}}},
+		{name: "blank code", source: "` `", text: "` `"},
+		{name: "code block with language", source: "```go\nfmt.Println()\n```", text: "fmt.Println()", entities: []Entity{
+			{Type: EntityPre, Offset: 0, Length: 13, Language: "go"},
+		}},
+		{name: "code block without language", source: "```\nx\n```", text: "x", entities: []Entity{{Type: EntityPre, Offset: 0, Length: 1}}},
+		{name: "code block first line is code", source: "```x = 1\ny```", text: "x = 1\ny", entities: []Entity{{Type: EntityPre, Offset: 0, Length: 7}}},
+		{name: "link", source: "see [docs](https://example.com/a)", text: "see docs", entities: []Entity{
+			{Type: EntityLink, Offset: 4, Length: 4, URL: "https://example.com/a"},
+		}},
+		{name: "mailto link", source: "[mail](mailto:a@example.com)", text: "mail", entities: []Entity{
+			{Type: EntityLink, Offset: 0, Length: 4, URL: "mailto:a@example.com"},
+		}},
+		{name: "javascript link", source: "[x](javascript:alert(1))", text: "[x](javascript:alert(1))"},
+		{name: "link without host", source: "[x](https:///path)", text: "[x](https:///path)"},
+		{name: "link with formatted text", source: "[*b*](http://example.com)", text: "b", entities: []Entity{
+			{Type: EntityLink, Offset: 0, Length: 1, URL: "http://example.com"},
+			{Type: EntityBold, Offset: 0, Length: 1},
+		}},
+		{name: "escaped bracket in link text", source: `[a\]b](https://example.com)`, text: "a]b", entities: []Entity{
+			{Type: EntityLink, Offset: 0, Length: 3, URL: "https://example.com"},
+		}},
+		{name: "escaped bracket is not the end", source: `[a\](https://example.com)`, text: "[a](https://example.com)"},
+		{name: "empty link text", source: "[](https://example.com)", text: "[](https://example.com)"},
+		// Offsets count code points
+		{name: "non-ASCII", source: "tiếng *Việt* 👋 _ơi_", text: "tiếng Việt 👋 ơi", entities: []Entity{
+			{Type: EntityBold, Offset: 6, Length: 4},
+			{Type: EntityItalic, Offset: 13, Length: 2},
+		}},
+		{name: "control characters", source: "a\x00b\r\nc", text: "ab\nc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, entities := ParseMarkup(tt.source)
+			if text != tt.text {
+				t.Fatalf("text %q, want %q", text, tt.text)
+			}
+			if !reflect.DeepEqual(entities, tt.entities) {
+				t.Fatalf("entities %+v, want %+v", entities, tt.entities)
+			}
+		})
+	}
+}
+
+func TestParseMarkupCapsEntities(t *testing.T) {
+	source := strings.Repeat("*b* ", MaxEntities+10)
+	text, entities := ParseMarkup(source)
+	if len(entities) != MaxEntities {
+		t.Fatalf("%d entities, want %d", len(entities), MaxEntities)
+	}
+	// The markup past the cap is dropped, not kept as text
+	if strings.Contains(text, "*") {
+		t.Fatalf("text still has markup: %q", text)
+	}
+}
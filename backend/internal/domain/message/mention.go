@@ -0,0 +1,83 @@
+package message
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// MaxMentions limits how many different names one message can mention.
+const MaxMentions = 20
+
+// MentionAll mentions every participant of the conversation.
+const MentionAll = "all"
+
+// Mention points at an @name in the text. Offset and Length count
+// characters (Unicode code points) and include the @.
+type Mention struct {
+	UserID string // empty for @all
+	Offset int
+	Length int
+}
+
+func (m Mention) IsAll() bool {
+	return m.UserID == ""
+}
+
+// MentionToken is an @name found in the text, before it is matched to a
+// participant.
+type MentionToken struct {
+	Name   string
+	Offset int
+	Length int
+}
+
+// ParseMentions finds the @names in a message. An @ inside a word, as in an
+// email address, does not start a mention.
+func ParseMentions(text string) []MentionToken {
+	runes := []rune(text)
+	var tokens []MentionToken
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '@' || (i > 0 && (isNameRune(runes[i-1]) || runes[i-1] == '@')) {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && isNameRune(runes[end]) {
+			end++
+		}
+		// Punctuation ending a sentence is not part of the name
+		for end > i+1 && strings.ContainsRune(".-", runes[end-1]) {
+			end--
+		}
+		if end == i+1 {
+			continue
+		}
+		tokens = append(tokens, MentionToken{
+			Name:   string(runes[i+1 : end]),
+			Offset: i,
+			Length: end - i,
+		})
+		i = end - 1
+	}
+	return tokens
+}
+
+func isNameRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-'
+}
+
+// MentionCursor is the position of a message in a mentions feed, newest
+// first.
+type MentionCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// MentionQuery pages through the messages mentioning a user, in the given
+// conversations only. After is nil for the first page.
+type MentionQuery struct {
+	UserID          string
+	ConversationIDs []string
+	After           *MentionCursor
+	Limit           int
+}
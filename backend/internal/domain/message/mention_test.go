@@ -0,0 +1,41 @@
+package message
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []MentionToken
+	}{
+		{name: "none", text: "hello there"},
+		{name: "one", text: "hi @bob", want: []MentionToken{{Name: "bob", Offset: 3, Length: 4}}},
+		{name: "start of text", text: "@alice hi", want: []MentionToken{{Name: "alice", Offset: 0, Length: 6}}},
+		{name: "several", text: "@a, @b_c and @d.e", want: []MentionToken{
+			{Name: "a", Offset: 0, Length: 2},
+			{Name: "b_c", Offset: 4, Length: 4},
+			{Name: "d.e", Offset: 13, Length: 4},
+		}},
+		{name: "sentence punctuation", text: "ask @bob. or @carol-", want: []MentionToken{
+			{Name: "bob", Offset: 4, Length: 4},
+			{Name: "carol", Offset: 13, Length: 6},
+		}},
+		{name: "email address", text: "mail bob@example.com"},
+		{name: "double at", text: "@@bob"},
+		{name: "lone at", text: "meet @ noon"},
+		{name: "only punctuation", text: "@..."},
+		// Offsets count code points, not bytes
+		{name: "after non-ASCII", text: "chào @bình ơi", want: []MentionToken{{Name: "bình", Offset: 5, Length: 5}}},
+		{name: "emoji before", text: "👋 @bob", want: []MentionToken{{Name: "bob", Offset: 2, Length: 4}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseMentions(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ParseMentions(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,77 @@
 package message
 
+import "time"
+
 type MessageRepository interface {
+	// Create stores the message under its ID if it has one, which fails
+	// when that ID is taken
 	Create(message Message) (*Message, error)
+	GetByID(messageID string) (*Message, error)
+	GetByIDs(messageIDs []string) ([]*Message, error)
 	GetMessagesByConversationID(conversation string) ([]*Message, error)
+	ListMentions(query MentionQuery) ([]*Message, error)
+	// GetLatest returns the newest message of the conversation, nil when
+	// it has none
+	GetLatest(conversationID string) (*Message, error)
+	// ListExpired returns up to limit messages that expired at now, the
+	// oldest first
+	ListExpired(now time.Time, limit int) ([]*Message, error)
+	// RedactBySender erases the text of every message the user sent
+	RedactBySender(senderID string) error
+	SetLinkPreviews(messageID string, previews []LinkPreview) error
+	// VotePoll replaces the user's vote, and RetractPollVote removes it.
+	// Both return the updated poll message, nil when the poll was closed at
+	// the time of the vote.
+	VotePoll(messageID string, vote PollVote) (*Message, error)
+	RetractPollVote(messageID string, userID string, now time.Time) (*Message, error)
+	// ClosePoll closes the poll at closedAt and returns it, nil when it was
+	// already closed
+	ClosePoll(messageID string, closedAt time.Time) (*Message, error)
+	// ListPollsToClose returns open polls whose closing time is past
+	ListPollsToClose(now time.Time, limit int) ([]*Message, error)
+	DeleteByIDs(messageIDs []string) error
+	DeleteByConversation(conversationID string) error
+}
+
+type PinRepository interface {
+	// Create fails when the message is already pinned
+	Create(pin Pin) error
+	// Get returns nil when the message is not pinned
+	Get(conversationID string, messageID string) (*Pin, error)
+	// ListByConversation returns the pins, most recent first
+	ListByConversation(conversationID string) ([]*Pin, error)
+	// Delete reports whether the message was pinned
+	Delete(conversationID string, messageID string) (bool, error)
+	// DeleteByMessages returns how many pins it removed
+	DeleteByMessages(conversationID string, messageIDs []string) (int, error)
+	DeleteByConversation(conversationID string) error
+}
+
+type ScheduledMessageRepository interface {
+	Create(m ScheduledMessage) (*ScheduledMessage, error)
+	// GetByID returns nil when there is no such scheduled message
+	GetByID(id string) (*ScheduledMessage, error)
+	// ListBySender returns the user's scheduled messages, soonest first,
+	// only those of conversationID unless it is empty
+	ListBySender(senderID string, conversationID string) ([]*ScheduledMessage, error)
+	CountBySender(senderID string) (int, error)
+	// Update changes the text and time and queues the message again. It
+	// returns false when its delivery has already started.
+	Update(m ScheduledMessage) (bool, error)
+	// Cancel deletes the message, returning false when its delivery has
+	// already started
+	Cancel(id string) (bool, error)
+	// ClaimDue atomically moves the first pending message due at now to
+	// sending, returning nil when there is none
+	ClaimDue(now time.Time) (*ScheduledMessage, error)
+	// NextSendAt is when the next pending message is due, zero when none is
+	NextSendAt() (time.Time, error)
+	// ListSending returns the messages whose delivery was started
+	ListSending() ([]*ScheduledMessage, error)
+	Fail(id string, reason string) error
+	Requeue(id string) error
+	// Delete removes a message once it was delivered
+	Delete(id string) error
+	DeleteByConversation(conversationID string) error
+	DeleteBySender(senderID string) error
 }
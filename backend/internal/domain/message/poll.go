@@ -0,0 +1,122 @@
+package message
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// TypePoll marks a poll, whose question is the message text.
+const TypePoll = "poll"
+
+const (
+	MinPollOptions        = 2
+	MaxPollOptions        = 10
+	MaxPollQuestionLength = 300
+	MaxPollOptionLength   = 100
+)
+
+// Poll asks the participants to choose between Options, which are
+// identified by their position. Each user has at most one vote, which may
+// pick several options when Multiple is set.
+type Poll struct {
+	Options   []string
+	Multiple  bool
+	Anonymous bool      // voters are never shown, only the counts
+	ClosesAt  time.Time // zero unless the poll closes on its own
+	ClosedAt  time.Time // zero while the poll is open
+	Votes     []PollVote
+}
+
+type PollVote struct {
+	UserID    string
+	OptionIDs []int
+	VotedAt   time.Time
+}
+
+func NewPollMessage(conversationID string, senderID string, question string, options []string, multiple bool, anonymous bool, closesAt time.Time) (*Message, error) {
+	if conversationID == "" {
+		return nil, errors.New("conversation_id can't empty")
+	}
+	// The question is not trimmed, formatting entities point into it
+	if strings.TrimSpace(question) == "" {
+		return nil, errors.New("a poll needs a question")
+	}
+	if utf8.RuneCountInString(question) > MaxPollQuestionLength {
+		return nil, fmt.Errorf("the question must be at most %d characters", MaxPollQuestionLength)
+	}
+	if len(options) < MinPollOptions || len(options) > MaxPollOptions {
+		return nil, fmt.Errorf("a poll has %d to %d options", MinPollOptions, MaxPollOptions)
+	}
+	cleaned := make([]string, 0, len(options))
+	for _, option := range options {
+		option = strings.TrimSpace(SanitizeText(option))
+		if option == "" {
+			return nil, errors.New("poll options can't empty")
+		}
+		if utf8.RuneCountInString(option) > MaxPollOptionLength {
+			return nil, fmt.Errorf("poll options must be at most %d characters", MaxPollOptionLength)
+		}
+		if slices.Contains(cleaned, option) {
+			return nil, errors.New("poll options must be different")
+		}
+		cleaned = append(cleaned, option)
+	}
+	now := time.Now()
+	if !closesAt.IsZero() && !closesAt.After(now) {
+		return nil, errors.New("closes_at must be in the future")
+	}
+
+	return &Message{
+		ConversationID: conversationID,
+		SenderID:       senderID,
+		Type:           TypePoll,
+		Message:        question,
+		Poll: &Poll{
+			Options:   cleaned,
+			Multiple:  multiple,
+			Anonymous: anonymous,
+			ClosesAt:  closesAt,
+		},
+		CreatedAt: now,
+	}, nil
+}
+
+func (p *Poll) IsClosed(now time.Time) bool {
+	return !p.ClosedAt.IsZero() || (!p.ClosesAt.IsZero() && !now.Before(p.ClosesAt))
+}
+
+// CheckVote makes sure a vote picks existing options, each once, and only
+// one unless the poll allows several.
+func (p *Poll) CheckVote(optionIDs []int) error {
+	if len(optionIDs) == 0 {
+		return errors.New("choose at least one option")
+	}
+	if len(optionIDs) > 1 && !p.Multiple {
+		return errors.New("this poll allows a single option")
+	}
+	seen := make(map[int]bool, len(optionIDs))
+	for _, id := range optionIDs {
+		if id < 0 || id >= len(p.Options) {
+			return fmt.Errorf("unknown option %d", id)
+		}
+		if seen[id] {
+			return fmt.Errorf("option %d is chosen twice", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// VoteOf returns the options the user chose, nil if they did not vote.
+func (p *Poll) VoteOf(userID string) []int {
+	for _, v := range p.Votes {
+		if v.UserID == userID {
+			return v.OptionIDs
+		}
+	}
+	return nil
+}
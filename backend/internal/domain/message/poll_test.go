@@ -0,0 +1,112 @@
+package message
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewPollMessage(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	tests := []struct {
+		name           string
+		conversationID string
+		question       string
+		options        []string
+		closesAt       time.Time
+		wantErr        string
+		wantOptions    []string
+	}{
+		{name: "valid", conversationID: "c1", question: "Lunch?", options: []string{"Pho", "Bun cha"}, closesAt: future, wantOptions: []string{"Pho", "Bun cha"}},
+		{name: "options are trimmed", conversationID: "c1", question: "Lunch?", options: []string{"  Pho ", "Bun cha\n"}, wantOptions: []string{"Pho", "Bun cha"}},
+		{name: "no conversation", question: "Lunch?", options: []string{"Pho", "Bun cha"}, wantErr: "conversation_id can't empty"},
+		{name: "blank question", conversationID: "c1", question: "  ", options: []string{"Pho", "Bun cha"}, wantErr: "a poll needs a question"},
+		{name: "long question", conversationID: "c1", question: strings.Repeat("ơ", MaxPollQuestionLength+1), options: []string{"Pho", "Bun cha"}, wantErr: "the question must be at most 300 characters"},
+		{name: "one option", conversationID: "c1", question: "Lunch?", options: []string{"Pho"}, wantErr: "a poll has 2 to 10 options"},
+		{name: "too many options", conversationID: "c1", question: "Lunch?", options: strings.Split("abcdefghijk", ""), wantErr: "a poll has 2 to 10 options"},
+		{name: "blank option", conversationID: "c1", question: "Lunch?", options: []string{"Pho", " "}, wantErr: "poll options can't empty"},
+		{name: "long option", conversationID: "c1", question: "Lunch?", options: []string{"Pho", strings.Repeat("a", MaxPollOptionLength+1)}, wantErr: "poll options must be at most 100 characters"},
+		{name: "duplicate options", conversationID: "c1", question: "Lunch?", options: []string{"Pho", "Bun cha", "Pho"}, wantErr: "poll options must be different"},
+		{name: "duplicate after trimming", conversationID: "c1", question: "Lunch?", options: []string{"Pho", " Pho"}, wantErr: "poll options must be different"},
+		{name: "closes in the past", conversationID: "c1", question: "Lunch?", options: []string{"Pho", "Bun cha"}, closesAt: time.Now().Add(-time.Minute), wantErr: "closes_at must be in the future"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewPollMessage(tt.conversationID, "alice", tt.question, tt.options, false, false, tt.closesAt)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("NewPollMessage error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewPollMessage: %v", err)
+			}
+			if m.Type != TypePoll || m.Message != tt.question {
+				t.Errorf("message type %q text %q, want a poll asking %q", m.Type, m.Message, tt.question)
+			}
+			if !reflect.DeepEqual(m.Poll.Options, tt.wantOptions) {
+				t.Errorf("options = %q, want %q", m.Poll.Options, tt.wantOptions)
+			}
+			if !m.Poll.ClosesAt.Equal(tt.closesAt) {
+				t.Errorf("closes at %v, want %v", m.Poll.ClosesAt, tt.closesAt)
+			}
+		})
+	}
+}
+
+func TestCheckVote(t *testing.T) {
+	options := []string{"Pho", "Bun cha", "Com tam"}
+	tests := []struct {
+		name      string
+		multiple  bool
+		optionIDs []int
+		wantErr   string
+	}{
+		{name: "single choice", optionIDs: []int{1}},
+		{name: "several on a single choice poll", optionIDs: []int{0, 1}, wantErr: "this poll allows a single option"},
+		{name: "several on a multiple choice poll", multiple: true, optionIDs: []int{0, 2}},
+		{name: "all on a multiple choice poll", multiple: true, optionIDs: []int{2, 0, 1}},
+		{name: "nothing", optionIDs: nil, wantErr: "choose at least one option"},
+		{name: "nothing on a multiple choice poll", multiple: true, optionIDs: []int{}, wantErr: "choose at least one option"},
+		{name: "same option twice", multiple: true, optionIDs: []int{1, 1}, wantErr: "option 1 is chosen twice"},
+		{name: "past the last option", optionIDs: []int{3}, wantErr: "unknown option 3"},
+		{name: "negative option", optionIDs: []int{-1}, wantErr: "unknown option -1"},
+		{name: "unknown among known", multiple: true, optionIDs: []int{0, 7}, wantErr: "unknown option 7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Poll{Options: options, Multiple: tt.multiple}
+			err := p.CheckVote(tt.optionIDs)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("CheckVote(%v): %v", tt.optionIDs, err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("CheckVote(%v) error = %v, want %q", tt.optionIDs, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPollIsClosed(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name string
+		poll Poll
+		want bool
+	}{
+		{name: "open", poll: Poll{}, want: false},
+		{name: "closes later", poll: Poll{ClosesAt: now.Add(time.Minute)}, want: false},
+		{name: "closes now", poll: Poll{ClosesAt: now}, want: true},
+		{name: "closed early", poll: Poll{ClosesAt: now.Add(time.Minute), ClosedAt: now.Add(-time.Minute)}, want: true},
+	}
+	for _, tt := range tests {
+		if got := tt.poll.IsClosed(now); got != tt.want {
+			t.Errorf("%s: IsClosed = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package message
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// MaxScheduledMessages is how many messages a user can have waiting
+	MaxScheduledMessages = 100
+	// MaxScheduleAhead is how far in the future a message can be scheduled
+	MaxScheduleAhead = 365 * 24 * time.Hour
+)
+
+const (
+	SchedulePending = "pending"
+	ScheduleSending = "sending"
+	ScheduleFailed  = "failed"
+)
+
+// ScheduledMessage is a message to send on behalf of the sender at SendAt.
+// Text is kept as written, with its Format, and only parsed when sent. Once
+// delivered the message is stored under the same ID and the schedule is
+// removed.
+type ScheduledMessage struct {
+	ID             string
+	ConversationID string
+	SenderID       string
+	Message        string
+	Format         string
+	Status         string
+	// Error is why the last delivery failed
+	Error     string
+	SendAt    time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func NewScheduledMessage(conversationID string, senderID string, text string, format string, sendAt time.Time) (*ScheduledMessage, error) {
+	if conversationID == "" {
+		return nil, errors.New("conversation_id can't empty")
+	}
+	if err := CheckSendAt(sendAt, time.Now()); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &ScheduledMessage{
+		ConversationID: conversationID,
+		SenderID:       senderID,
+		Message:        text,
+		Format:         format,
+		Status:         SchedulePending,
+		SendAt:         sendAt,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}, nil
+}
+
+// CheckSendAt makes sure a message is scheduled in the future, but not too
+// far in it.
+func CheckSendAt(sendAt time.Time, now time.Time) error {
+	if !sendAt.After(now) {
+		return errors.New("send_at must be in the future")
+	}
+	if sendAt.After(now.Add(MaxScheduleAhead)) {
+		return errors.New("messages can be scheduled at most a year ahead")
+	}
+	return nil
+}
+
+// CanEdit reports whether the message can still be changed or canceled,
+// which is until its delivery starts.
+func (m *ScheduledMessage) CanEdit() bool {
+	return m.Status == SchedulePending || m.Status == ScheduleFailed
+}
@@ -0,0 +1,94 @@
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// E.164 allows at most 15 digits after the +
+const (
+	minE164Digits = 7
+	maxE164Digits = 15
+)
+
+var ErrInvalidNumber = errors.New("invalid phone number")
+
+// Normalizer turns phone numbers as users type them into E.164
+// ("0912 345 678" -> "+84912345678"). Numbers without a + or 00 prefix
+// are read as national numbers of the default region.
+type Normalizer struct {
+	defaultRegion string
+	region        region
+}
+
+// NewNormalizer accepts an empty region, in which case only international
+// numbers are accepted.
+func NewNormalizer(defaultRegion string) (*Normalizer, error) {
+	defaultRegion = strings.ToUpper(strings.TrimSpace(defaultRegion))
+	if defaultRegion == "" {
+		return &Normalizer{}, nil
+	}
+	r, ok := regions[defaultRegion]
+	if !ok {
+		return nil, fmt.Errorf("unsupported phone region %q", defaultRegion)
+	}
+	return &Normalizer{defaultRegion: defaultRegion, region: r}, nil
+}
+
+func (n *Normalizer) Normalize(raw string) (string, error) {
+	trimmed := strings.TrimSpace(raw)
+	international := strings.HasPrefix(trimmed, "+")
+
+	digits := make([]byte, 0, len(trimmed))
+	for i, r := range trimmed {
+		switch {
+		case r >= '0' && r <= '9':
+			digits = append(digits, byte(r))
+		case r == '+' && i == 0:
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')' || r == '/':
+		default:
+			return "", ErrInvalidNumber
+		}
+	}
+	number := string(digits)
+
+	if !international && strings.HasPrefix(number, "00") {
+		international = true
+		number = number[2:]
+	}
+	if !international {
+		var err error
+		if number, err = n.fromNational(number); err != nil {
+			return "", err
+		}
+	}
+
+	if len(number) < minE164Digits || len(number) > maxE164Digits || number[0] == '0' {
+		return "", ErrInvalidNumber
+	}
+	return "+" + number, nil
+}
+
+// fromNational prefixes a national number with the default region's calling
+// code. A number that is not a valid national number but starts with the
+// calling code ("84912345678" in VN) is taken as international without the +.
+func (n *Normalizer) fromNational(number string) (string, error) {
+	if n.defaultRegion == "" {
+		return "", errors.New("phone number must start with + and the country code")
+	}
+	r := n.region
+
+	national := number
+	if r.trunkPrefix != "" && strings.HasPrefix(national, r.trunkPrefix) && len(national)-len(r.trunkPrefix) >= r.minLength {
+		national = national[len(r.trunkPrefix):]
+	}
+	if len(national) >= r.minLength && len(national) <= r.maxLength {
+		return r.callingCode + national, nil
+	}
+
+	if rest, ok := strings.CutPrefix(number, r.callingCode); ok && len(rest) >= r.minLength && len(rest) <= r.maxLength {
+		return number, nil
+	}
+	return "", ErrInvalidNumber
+}
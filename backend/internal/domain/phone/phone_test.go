@@ -0,0 +1,57 @@
+package phone
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	vn, err := NewNormalizer("vn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"0912 345 678", "+84912345678"},
+		{"0912-345-678", "+84912345678"},
+		{"(091) 234.5678", "+84912345678"},
+		{"+84 912 345 678", "+84912345678"},
+		{"0084912345678", "+84912345678"},
+		{"84912345678", "+84912345678"},
+		{"+1 415 555 2671", "+14155552671"},
+		{"912345678", "+84912345678"},
+		{"not a phone", ""},
+		{"12", ""},
+		{"+0912345678", ""},
+		{"+1234567890123456", ""},
+		{"0912+345678", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got, err := vn.Normalize(tt.raw)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("Normalize(%q) = %q, want an error", tt.raw, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Normalize(%q) = %q, %v, want %q", tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizeWithoutRegion(t *testing.T) {
+	n, err := NewNormalizer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := n.Normalize("+84 912 345 678"); err != nil || got != "+84912345678" {
+		t.Fatalf("international number: %q, %v", got, err)
+	}
+	if _, err := n.Normalize("0912345678"); err == nil {
+		t.Fatal("national number accepted without a default region")
+	}
+	if _, err := NewNormalizer("XX"); err == nil {
+		t.Fatal("unknown region accepted")
+	}
+}
@@ -0,0 +1,43 @@
+package phone
+
+// region describes how national numbers are written in a country.
+type region struct {
+	callingCode string
+	// trunkPrefix is dialled before national numbers and dropped in E.164
+	trunkPrefix string
+	// minLength and maxLength bound the national number without trunk prefix
+	minLength int
+	maxLength int
+}
+
+// regions maps ISO 3166-1 alpha-2 codes to their numbering rules. It only
+// needs entries for the default regions deployments use; numbers written
+// with a leading + work for every country.
+var regions = map[string]region{
+	"AU": {callingCode: "61", trunkPrefix: "0", minLength: 9, maxLength: 9},
+	"BR": {callingCode: "55", trunkPrefix: "0", minLength: 10, maxLength: 11},
+	"CA": {callingCode: "1", trunkPrefix: "1", minLength: 10, maxLength: 10},
+	"CN": {callingCode: "86", trunkPrefix: "0", minLength: 10, maxLength: 11},
+	"DE": {callingCode: "49", trunkPrefix: "0", minLength: 6, maxLength: 13},
+	"ES": {callingCode: "34", trunkPrefix: "", minLength: 9, maxLength: 9},
+	"FR": {callingCode: "33", trunkPrefix: "0", minLength: 9, maxLength: 9},
+	"GB": {callingCode: "44", trunkPrefix: "0", minLength: 9, maxLength: 10},
+	"HK": {callingCode: "852", trunkPrefix: "", minLength: 8, maxLength: 8},
+	"ID": {callingCode: "62", trunkPrefix: "0", minLength: 8, maxLength: 12},
+	"IN": {callingCode: "91", trunkPrefix: "0", minLength: 10, maxLength: 10},
+	"IT": {callingCode: "39", trunkPrefix: "", minLength: 6, maxLength: 11},
+	"JP": {callingCode: "81", trunkPrefix: "0", minLength: 9, maxLength: 10},
+	"KH": {callingCode: "855", trunkPrefix: "0", minLength: 8, maxLength: 9},
+	"KR": {callingCode: "82", trunkPrefix: "0", minLength: 8, maxLength: 10},
+	"LA": {callingCode: "856", trunkPrefix: "0", minLength: 8, maxLength: 10},
+	"MX": {callingCode: "52", trunkPrefix: "", minLength: 10, maxLength: 10},
+	"MY": {callingCode: "60", trunkPrefix: "0", minLength: 8, maxLength: 10},
+	"NL": {callingCode: "31", trunkPrefix: "0", minLength: 9, maxLength: 9},
+	"PH": {callingCode: "63", trunkPrefix: "0", minLength: 10, maxLength: 10},
+	"RU": {callingCode: "7", trunkPrefix: "8", minLength: 10, maxLength: 10},
+	"SG": {callingCode: "65", trunkPrefix: "", minLength: 8, maxLength: 8},
+	"TH": {callingCode: "66", trunkPrefix: "0", minLength: 8, maxLength: 9},
+	"TW": {callingCode: "886", trunkPrefix: "0", minLength: 8, maxLength: 9},
+	"US": {callingCode: "1", trunkPrefix: "1", minLength: 10, maxLength: 10},
+	"VN": {callingCode: "84", trunkPrefix: "0", minLength: 9, maxLength: 10},
+}
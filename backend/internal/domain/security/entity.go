@@ -0,0 +1,94 @@
+package security
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	EventAccountLocked = "account_locked"
+	EventIPLocked      = "ip_locked"
+)
+
+// LockoutPolicy describes how failed logins for one key (an account or an IP)
+// are throttled. The first FreeAttempts failures cost nothing, after that each
+// failure doubles the wait starting from BaseDelay up to MaxDelay, and once
+// Threshold failures are reached the key is locked for LockoutDuration.
+// Failures older than ResetAfter are forgotten.
+//
+// Attempts are counted as failures when they start and taken back when they
+// succeed, so logins running in parallel each see the ones before them.
+type LockoutPolicy struct {
+	FreeAttempts    int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	Threshold       int
+	LockoutDuration time.Duration
+	ResetAfter      time.Duration
+}
+
+type LoginAttempt struct {
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	// Allowed is false when the attempt came in while the key was still
+	// waiting out a delay. Such attempts are not counted.
+	Allowed bool
+}
+
+// NextLock returns how long the key must wait after its latest failure and
+// whether that wait is a full lockout rather than a backoff delay.
+func (p LockoutPolicy) NextLock(failures int) (time.Duration, bool) {
+	if p.Threshold > 0 && failures >= p.Threshold {
+		return p.LockoutDuration, true
+	}
+	if failures <= p.FreeAttempts {
+		return 0, false
+	}
+	delay := p.BaseDelay
+	for i := p.FreeAttempts + 1; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay, false
+}
+
+// Waits returns the wait after each number of failures, starting from zero.
+// The last entry also applies to every count beyond the end of the list.
+func (p LockoutPolicy) Waits() []time.Duration {
+	var waits []time.Duration
+	for failures := 0; failures < 1000; failures++ {
+		wait, locked := p.NextLock(failures)
+		waits = append(waits, wait)
+		if locked || (p.Threshold <= 0 && failures > p.FreeAttempts && wait >= p.MaxDelay) {
+			break
+		}
+	}
+	return waits
+}
+
+type Event struct {
+	ID        string
+	Type      string
+	UserID    string
+	Username  string
+	IP        string
+	Detail    string
+	CreatedAt time.Time
+}
+
+func NewEvent(eventType string, userID string, username string, ip string, detail string) (*Event, error) {
+	if eventType == "" {
+		return nil, errors.New("event type can not empty")
+	}
+	return &Event{
+		Type:      eventType,
+		UserID:    userID,
+		Username:  username,
+		IP:        ip,
+		Detail:    detail,
+		CreatedAt: time.Now(),
+	}, nil
+}
@@ -0,0 +1,18 @@
+package security
+
+import "time"
+
+type LoginAttemptRepository interface {
+	// RecordAttempt atomically counts one more failure for key, starting over
+	// when the previous failure is older than resetAfter. Nothing is counted
+	// and Allowed is false while the key is still within waits[n] of its
+	// latest failure, n being the failures so far.
+	RecordAttempt(key string, at time.Time, resetAfter time.Duration, waits []time.Duration) (*LoginAttempt, error)
+	// Release takes back one failure counted by RecordAttempt
+	Release(key string) error
+	Delete(key string) error
+}
+
+type EventRepository interface {
+	Create(event Event) (*Event, error)
+}
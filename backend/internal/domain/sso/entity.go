@@ -0,0 +1,40 @@
+package sso
+
+import (
+	"errors"
+	"time"
+)
+
+// Identity is what the identity provider vouches for after a successful login.
+type Identity struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+	Phone             string
+}
+
+// LoginState is kept between redirecting the user to the provider and the
+// provider redirecting back with a code.
+type LoginState struct {
+	State        string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+}
+
+func NewLoginState(state string, nonce string, codeVerifier string, ttl time.Duration) (*LoginState, error) {
+	if state == "" || nonce == "" || codeVerifier == "" {
+		return nil, errors.New("state, nonce and code verifier can not empty")
+	}
+	return &LoginState{
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(ttl),
+		CreatedAt:    time.Now(),
+	}, nil
+}
@@ -0,0 +1,15 @@
+package sso
+
+type IdentityProvider interface {
+	// AuthCodeURL builds the provider URL the user is sent to, using PKCE with S256.
+	AuthCodeURL(state string, nonce string, codeChallenge string) (string, error)
+	// Exchange trades an authorization code for tokens and returns the
+	// verified claims of the ID token.
+	Exchange(code string, codeVerifier string, nonce string) (*Identity, error)
+}
+
+type LoginStateRepository interface {
+	Create(state LoginState) error
+	// Consume removes the state so a callback can't be replayed.
+	Consume(state string) (*LoginState, error)
+}
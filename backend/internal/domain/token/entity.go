@@ -0,0 +1,36 @@
+package token
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	PurposeEmailVerification = "email_verification"
+	PurposePasswordReset     = "password_reset"
+)
+
+// Token is the server-side record of a signed single-use token.
+// The signed value itself is only ever handed to the user.
+type Token struct {
+	ID        string
+	UserID    string
+	Purpose   string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+func NewToken(userID string, purpose string, ttl time.Duration) (*Token, error) {
+	if userID == "" {
+		return nil, errors.New("user_id can not empty")
+	}
+	if purpose == "" {
+		return nil, errors.New("purpose can not empty")
+	}
+	return &Token{
+		UserID:    userID,
+		Purpose:   purpose,
+		ExpiresAt: time.Now().Add(ttl),
+		CreatedAt: time.Now(),
+	}, nil
+}
@@ -0,0 +1,9 @@
+package token
+
+type TokenRepository interface {
+	Create(token Token) (*Token, error)
+	// Consume deletes the token and reports whether it still existed,
+	// so a token can only be used once.
+	Consume(tokenID string, purpose string) (*Token, error)
+	DeleteByUser(userID string, purpose string) error
+}
@@ -13,13 +13,57 @@ type User struct {
 	Email              string
 	Phone              string
 	Name               string
+	DisplayName        string
+	Bio                string
+	Timezone           string
+	AvatarID           string
+	EmailVerified      bool
+	OIDCIssuer         string
+	OIDCSubject        string
+	IsBot              bool
+	OwnerID            string
+	ContactsOnly       bool      // only contacts may start a conversation with the user
+	Discoverability    string    // who can find the user in search, empty means everyone
+	DeletionDueAt      time.Time // zero unless the user asked to delete the account
+	DeletedAt          time.Time
 	RefreshToken       string
 	RefreshTokenExpiry int64
+	TokenVersion       int // embedded in access tokens, bumping it revokes them all
 	Conversations      []string
 	CreatedAt          time.Time
 	UpdateAt           time.Time
 }
 
+const (
+	MaxDisplayNameLength = 64
+	MaxBioLength         = 500
+)
+
+const (
+	DiscoverableEveryone = "everyone"
+	DiscoverableContacts = "contacts"
+	DiscoverableNobody   = "nobody"
+)
+
+func IsValidDiscoverability(value string) bool {
+	return value == DiscoverableEveryone || value == DiscoverableContacts || value == DiscoverableNobody
+}
+
+// DeletedUserName replaces the name of deleted users everywhere.
+const DeletedUserName = "Deleted user"
+
+func (u *User) IsDeleted() bool {
+	return !u.DeletedAt.IsZero()
+}
+
+// PublicName is the name shown to other users, e.g. in conversation participants.
+func (u *User) PublicName() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	return u.Name
+}
+
 func NewUser(username, password, email string, name string, phone string) (*User, error) {
 	if username == "" {
 		return nil, errors.New("username can not empty")
@@ -46,3 +90,52 @@ func NewUser(username, password, email string, name string, phone string) (*User
 		UpdateAt:  time.Now(),
 	}, nil
 }
+
+// NewExternalUser builds a user provisioned by a single sign-on provider.
+// Such users have no local password and may not have a phone number yet.
+func NewExternalUser(username, email, name, phone, issuer, subject string) (*User, error) {
+	if username == "" {
+		return nil, errors.New("username can not empty")
+	}
+	if email == "" {
+		return nil, errors.New("email can not empty")
+	}
+	if issuer == "" || subject == "" {
+		return nil, errors.New("external identity can not empty")
+	}
+	if name == "" {
+		name = username
+	}
+	return &User{
+		Username:    username,
+		Email:       email,
+		Name:        name,
+		Phone:       phone,
+		OIDCIssuer:  issuer,
+		OIDCSubject: subject,
+		CreatedAt:   time.Now(),
+		UpdateAt:    time.Now(),
+	}, nil
+}
+
+// NewBotUser builds a service account owned by a human user. Bots have no
+// password, email or phone and authenticate only with API keys.
+func NewBotUser(username string, name string, ownerID string) (*User, error) {
+	if username == "" {
+		return nil, errors.New("username can not empty")
+	}
+	if name == "" {
+		return nil, errors.New("name can not empty")
+	}
+	if ownerID == "" {
+		return nil, errors.New("owner can not empty")
+	}
+	return &User{
+		Username:  username,
+		Name:      name,
+		IsBot:     true,
+		OwnerID:   ownerID,
+		CreatedAt: time.Now(),
+		UpdateAt:  time.Now(),
+	}, nil
+}
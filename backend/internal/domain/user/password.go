@@ -0,0 +1,11 @@
+package user
+
+// PasswordHasher hashes passwords with the current preferred algorithm and
+// can still verify hashes written by older ones.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(hash string, password string) (bool, error)
+	// NeedsRehash reports whether hash was made with an algorithm or
+	// parameters other than the current preferred ones.
+	NeedsRehash(hash string) bool
+}
@@ -1,14 +1,45 @@
 package user
 
+import "time"
+
 // interface
 type UserRepository interface {
 	Create(user User) (*User, error)
 	GetByUsername(username string) (*User, error)
 	GetByID(userId string) (*User, error)
 	GetByPhone(phone string) (*User, error)
+	GetByEmail(email string) (*User, error)
+	GetByOIDCIdentity(issuer string, subject string) (*User, error)
+	GetBotsByOwner(ownerID string) ([]*User, error)
+	GetDueForDeletion(now time.Time) ([]*User, error)
 	GetConversationList(userID string) ([]*string, error)
+	Search(query SearchQuery) ([]*User, error)
 
 	SaveRefreshToken(token string, userID string) error
 	Logout(userID string) error
+	// RevokeSessions invalidates every access and refresh token of the user
+	RevokeSessions(userID string) error
+	UpdateProfile(user User) error
+	SetAvatar(userID string, avatarID string) error
+	UpdatePrivacy(user User) error
+	// ScheduleDeletion sets or, with a zero time, clears the deletion date
+	ScheduleDeletion(userID string, dueAt time.Time) error
+	// Anonymize erases the personal data of the user and revokes all sessions
+	Anonymize(userID string) error
+	MarkEmailVerified(userID string) error
+	UpdatePassword(userID string, hashPassword string) error
+	LinkOIDCIdentity(userID string, issuer string, subject string) error
 	AddConversationtoParticipants(part1 string, parrt2 string, conversationID string) error
+	AddConversation(userID string, conversationID string) error
+	RemoveConversation(userID string, conversationID string) error
+}
+
+// SearchQuery finds users by name, username or email on behalf of a viewer.
+// Users with DiscoverableContacts are only returned when listed in ContactIDs.
+type SearchQuery struct {
+	Term       string
+	ContactIDs []string
+	ExcludeIDs []string
+	Offset     int
+	Limit      int
 }
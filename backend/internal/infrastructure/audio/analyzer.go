@@ -0,0 +1,68 @@
+package audio
+
+import (
+	"backend-chat-app/internal/domain/media"
+	"errors"
+	"math"
+	"time"
+)
+
+// maxDuration is the longest recording accepted. A full size upload of the
+// lowest bitrate Opus lasts less than that, so only broken or crafted files
+// go over it.
+const maxDuration = 10 * time.Hour
+
+var errTooLong = errors.New("recording is too long")
+
+// Analyzer reads voice recordings without any external tools. WAV samples
+// are measured directly; Ogg Opus is not decoded, see analyzeOgg.
+type Analyzer struct{}
+
+func NewAnalyzer() *Analyzer {
+	return &Analyzer{}
+}
+
+func (a *Analyzer) Analyze(data []byte, contentType string, samples int) (*media.AudioInfo, error) {
+	if samples <= 0 {
+		return nil, errors.New("waveform needs at least one sample")
+	}
+	switch contentType {
+	case "audio/wav":
+		return analyzeWAV(data, samples)
+	case "audio/ogg":
+		return analyzeOgg(data, samples)
+	default:
+		return nil, errors.New("unsupported audio format")
+	}
+}
+
+// bucketLevels averages the levels that fall into each of the samples
+// equal parts of the recording, then scales them so the loudest part is 100.
+func bucketLevels(levels []float64, samples int) []int {
+	if len(levels) == 0 {
+		return make([]int, samples)
+	}
+	sums := make([]float64, samples)
+	counts := make([]int, samples)
+	for i, level := range levels {
+		bucket := i * samples / len(levels)
+		sums[bucket] += level
+		counts[bucket]++
+	}
+
+	peak := 0.0
+	for i := range sums {
+		if counts[i] > 0 {
+			sums[i] /= float64(counts[i])
+		}
+		peak = math.Max(peak, sums[i])
+	}
+	waveform := make([]int, samples)
+	if peak == 0 {
+		return waveform
+	}
+	for i, avg := range sums {
+		waveform[i] = int(math.Round(avg / peak * 100))
+	}
+	return waveform
+}
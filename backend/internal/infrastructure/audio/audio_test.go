@@ -0,0 +1,265 @@
+package audio
+
+import (
+	"encoding/binary"
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+// wavFile builds a mono WAV file; sample writes one sample of value -1..1.
+func wavFile(format uint16, bits int, rate int, values []float64) []byte {
+	bytesPerSample := bits / 8
+	pcm := make([]byte, 0, len(values)*bytesPerSample)
+	for _, v := range values {
+		b := make([]byte, 4)
+		switch {
+		case format == wavFormatFloat:
+			binary.LittleEndian.PutUint32(b, math.Float32bits(float32(v)))
+		case bits == 8:
+			b[0] = byte(int(v*127) + 128)
+		case bits == 16:
+			binary.LittleEndian.PutUint16(b, uint16(int16(v*math.MaxInt16)))
+		case bits == 24:
+			binary.LittleEndian.PutUint32(b, uint32(int32(v*(1<<23-1))))
+		}
+		pcm = append(pcm, b[:bytesPerSample]...)
+	}
+
+	fmtChunk := make([]byte, 16)
+	binary.LittleEndian.PutUint16(fmtChunk[0:2], format)
+	binary.LittleEndian.PutUint16(fmtChunk[2:4], 1)
+	binary.LittleEndian.PutUint32(fmtChunk[4:8], uint32(rate))
+	binary.LittleEndian.PutUint32(fmtChunk[8:12], uint32(rate*bytesPerSample))
+	binary.LittleEndian.PutUint16(fmtChunk[12:14], uint16(bytesPerSample))
+	binary.LittleEndian.PutUint16(fmtChunk[14:16], uint16(bits))
+
+	var data []byte
+	data = append(data, "RIFF\x00\x00\x00\x00WAVE"...)
+	data = appendChunk(data, "fmt ", fmtChunk)
+	data = appendChunk(data, "data", pcm)
+	binary.LittleEndian.PutUint32(data[4:8], uint32(len(data)-8))
+	return data
+}
+
+func appendChunk(data []byte, id string, body []byte) []byte {
+	data = append(data, id...)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(body)))
+	data = append(data, body...)
+	if len(body)%2 == 1 {
+		data = append(data, 0)
+	}
+	return data
+}
+
+// quietThenLoud is a second of silence followed by a second at half volume.
+func quietThenLoud(rate int) []float64 {
+	values := make([]float64, 2*rate)
+	for i := rate; i < len(values); i++ {
+		values[i] = 0.5
+		if i%2 == 1 {
+			values[i] = -0.5
+		}
+	}
+	return values
+}
+
+func TestAnalyzeWAV(t *testing.T) {
+	tests := []struct {
+		name   string
+		format uint16
+		bits   int
+	}{
+		{name: "8 bit", format: wavFormatPCM, bits: 8},
+		{name: "16 bit", format: wavFormatPCM, bits: 16},
+		{name: "24 bit", format: wavFormatPCM, bits: 24},
+		{name: "float", format: wavFormatFloat, bits: 32},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := analyzeWAV(wavFile(tt.format, tt.bits, 8000, quietThenLoud(8000)), 4)
+			if err != nil {
+				t.Fatalf("analyzeWAV: %v", err)
+			}
+			if info.Duration != 2*time.Second {
+				t.Fatalf("duration %v, want 2s", info.Duration)
+			}
+			if want := []int{0, 0, 100, 100}; !slices.Equal(info.Waveform, want) {
+				t.Fatalf("waveform %v, want %v", info.Waveform, want)
+			}
+		})
+	}
+}
+
+func TestAnalyzeWAVSkipsNaNAndInf(t *testing.T) {
+	values := make([]float64, 8000)
+	for i := range values {
+		values[i] = 0.5
+	}
+	// One NaN and one Inf in the first half, which must still read as loud
+	values[10] = math.NaN()
+	values[20] = math.Inf(1)
+
+	info, err := analyzeWAV(wavFile(wavFormatFloat, 32, 8000, values), 2)
+	if err != nil {
+		t.Fatalf("analyzeWAV: %v", err)
+	}
+	if want := []int{100, 100}; !slices.Equal(info.Waveform, want) {
+		t.Fatalf("waveform %v, want %v", info.Waveform, want)
+	}
+}
+
+func TestAnalyzeWAVRejects(t *testing.T) {
+	noData := []byte("RIFF\x00\x00\x00\x00WAVE")
+	fmtChunk := wavFile(wavFormatPCM, 16, 8000, nil)[12:36]
+	noData = append(noData, fmtChunk...)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "not RIFF", data: []byte("OggS and then some more bytes")},
+		{name: "no data chunk", data: noData},
+		{name: "12 bit", data: wavFile(wavFormatPCM, 12, 8000, make([]float64, 100))},
+		// One sample a second, for longer than a voice note can be
+		{name: "too long", data: wavFile(wavFormatPCM, 8, 1, make([]float64, int(maxDuration/time.Second)))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := analyzeWAV(tt.data, 4); err == nil {
+				t.Fatal("accepted")
+			}
+		})
+	}
+}
+
+// oggPage builds a page of one logical stream holding whole packets of less
+// than 255 bytes. The checksum is left empty, analyzeOgg does not read it.
+func oggPage(granule int64, packets ...[]byte) []byte {
+	page := []byte("OggS\x00\x00")
+	page = binary.LittleEndian.AppendUint64(page, uint64(granule))
+	page = binary.LittleEndian.AppendUint32(page, 1)
+	page = append(page, make([]byte, 8)...)
+	page = append(page, byte(len(packets)))
+	for _, p := range packets {
+		page = append(page, byte(len(p)))
+	}
+	for _, p := range packets {
+		page = append(page, p...)
+	}
+	return page
+}
+
+const testPreSkip = 312
+
+// opusStream is a second of 20 ms CELT packets, quiet ones then loud ones,
+// ending on the given granule position.
+func opusStream(granule int64) []byte {
+	head := []byte("OpusHead\x01\x01")
+	head = binary.LittleEndian.AppendUint16(head, testPreSkip)
+	head = append(head, make([]byte, 7)...)
+
+	// config 19 is CELT with 20 ms frames, one frame per packet
+	toc := byte(19 << 3)
+	var audio [][]byte
+	for i := 0; i < 50; i++ {
+		size := 3
+		if i >= 25 {
+			size = 120
+		}
+		packet := make([]byte, size)
+		packet[0] = toc
+		audio = append(audio, packet)
+	}
+
+	data := oggPage(0, head)
+	data = append(data, oggPage(0, []byte("OpusTags"))...)
+	data = append(data, oggPage(-1, audio[:25]...)...)
+	data = append(data, oggPage(granule, audio[25:]...)...)
+	return data
+}
+
+func TestAnalyzeOgg(t *testing.T) {
+	// 50 packets of 20 ms decode to 48000 samples, the end trimmed by 480
+	info, err := analyzeOgg(opusStream(48000-480), 2)
+	if err != nil {
+		t.Fatalf("analyzeOgg: %v", err)
+	}
+	if want := time.Duration(48000-480-testPreSkip) * time.Second / opusRate; info.Duration != want {
+		t.Fatalf("duration %v, want %v", info.Duration, want)
+	}
+	if want := []int{3, 100}; !slices.Equal(info.Waveform, want) {
+		t.Fatalf("waveform %v, want %v", info.Waveform, want)
+	}
+}
+
+func TestAnalyzeOggRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "not Ogg", data: []byte("RIFF....WAVE")},
+		{name: "not Opus", data: append(oggPage(0, []byte("\x01vorbis")), oggPage(0, []byte("tags"))...)},
+		{name: "granule past the audio", data: opusStream(48001)},
+		{name: "granule short of the audio", data: opusStream(48000 - maxPacketSamples - 1)},
+		// Would overflow time.Duration if it was converted
+		{name: "huge granule", data: opusStream(math.MaxInt64 / 2)},
+		{name: "negative granule", data: opusStream(-2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := analyzeOgg(tt.data, 4); err == nil {
+				t.Fatal("accepted")
+			}
+		})
+	}
+}
+
+func TestOpusPacketDuration(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet []byte
+		want   time.Duration
+	}{
+		{name: "empty", packet: nil, want: 0},
+		{name: "SILK 10 ms", packet: []byte{0 << 3}, want: 10 * time.Millisecond},
+		{name: "SILK 60 ms", packet: []byte{3 << 3}, want: 60 * time.Millisecond},
+		{name: "hybrid 20 ms", packet: []byte{13 << 3}, want: 20 * time.Millisecond},
+		{name: "CELT 2.5 ms", packet: []byte{16 << 3}, want: 2500 * time.Microsecond},
+		{name: "two frames", packet: []byte{19<<3 | 1}, want: 40 * time.Millisecond},
+		{name: "two frames of different sizes", packet: []byte{19<<3 | 2}, want: 40 * time.Millisecond},
+		{name: "six frames", packet: []byte{19<<3 | 3, 6}, want: 120 * time.Millisecond},
+		{name: "over 120 ms", packet: []byte{19<<3 | 3, 7}, want: 0},
+		{name: "no frames", packet: []byte{19<<3 | 3, 0}, want: 0},
+		{name: "frame count missing", packet: []byte{19<<3 | 3}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := opusPacketDuration(tt.packet); got != tt.want {
+				t.Fatalf("opusPacketDuration = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBucketLevels(t *testing.T) {
+	tests := []struct {
+		name    string
+		levels  []float64
+		samples int
+		want    []int
+	}{
+		{name: "no levels", levels: nil, samples: 3, want: []int{0, 0, 0}},
+		{name: "silence", levels: []float64{0, 0, 0, 0}, samples: 2, want: []int{0, 0}},
+		{name: "averaged and scaled", levels: []float64{1, 3, 4, 4}, samples: 2, want: []int{50, 100}},
+		{name: "fewer levels than samples", levels: []float64{2, 1}, samples: 4, want: []int{100, 0, 50, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bucketLevels(tt.levels, tt.samples); !slices.Equal(got, tt.want) {
+				t.Fatalf("bucketLevels = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
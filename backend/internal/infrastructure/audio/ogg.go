@@ -0,0 +1,165 @@
+package audio
+
+import (
+	"backend-chat-app/internal/domain/media"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// Opus timestamps always count 48 kHz samples, whatever the input rate was
+const opusRate = 48000
+
+// maxPacketSamples is the longest Opus packet, 120 ms, in 48 kHz samples.
+const maxPacketSamples = 120 * opusRate / 1000
+
+// analyzeOgg reads an Ogg Opus recording. The duration comes from the last
+// granule position. Opus is not decoded: the waveform uses the size of each
+// packet per millisecond, which follows loudness closely in the variable
+// bitrate streams recorders produce, since silence encodes to tiny packets.
+//
+// The granule position is only trusted when it agrees with the packets: it
+// counts every decoded sample, less what the encoder trimmed off the end of
+// the last packet.
+func analyzeOgg(data []byte, samples int) (*media.AudioInfo, error) {
+	packets, lastGranule, err := readOggPackets(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(packets) < 2 || !bytes.HasPrefix(packets[0], []byte("OpusHead")) || len(packets[0]) < 19 {
+		return nil, errors.New("not an Ogg Opus file")
+	}
+	preSkip := int64(binary.LittleEndian.Uint16(packets[0][10:12]))
+
+	// packets[1] is OpusTags, audio starts after it
+	var levels []float64
+	var decodedSamples int64
+	for _, packet := range packets[2:] {
+		duration := opusPacketDuration(packet)
+		if duration <= 0 {
+			continue
+		}
+		decodedSamples += int64(duration) * opusRate / int64(time.Second)
+		if decodedSamples > int64(maxDuration/time.Second)*opusRate {
+			return nil, errTooLong
+		}
+		level := float64(len(packet)) / duration.Seconds()
+		// Spread long packets over 10 ms steps so every part of the
+		// recording weighs the same
+		for range max(1, int(duration/(10*time.Millisecond))) {
+			levels = append(levels, level)
+		}
+	}
+
+	if lastGranule > decodedSamples || lastGranule < decodedSamples-maxPacketSamples {
+		return nil, errors.New("Ogg granule position does not match the audio")
+	}
+	durationSamples := max(0, lastGranule-preSkip)
+	// Checked before converting, as samples times time.Second overflows
+	// past about 9.2e9 samples
+	if durationSamples > int64(maxDuration/time.Second)*opusRate {
+		return nil, errTooLong
+	}
+	return &media.AudioInfo{
+		Duration: time.Duration(durationSamples) * time.Second / opusRate,
+		Waveform: bucketLevels(levels, samples),
+	}, nil
+}
+
+// readOggPackets joins the pages of the first logical stream back into
+// packets and returns the granule position of its last page.
+func readOggPackets(data []byte) ([][]byte, int64, error) {
+	var packets [][]byte
+	var current []byte
+	var serial uint32
+	var lastGranule int64
+	first := true
+
+	for offset := 0; offset < len(data); {
+		if len(data)-offset < 27 || string(data[offset:offset+4]) != "OggS" {
+			if first {
+				return nil, 0, errors.New("not an Ogg file")
+			}
+			// Trailing garbage after a valid stream is ignored
+			break
+		}
+		header := data[offset : offset+27]
+		granule := int64(binary.LittleEndian.Uint64(header[6:14]))
+		pageSerial := binary.LittleEndian.Uint32(header[14:18])
+		segmentCount := int(header[26])
+		if len(data)-offset < 27+segmentCount {
+			return nil, 0, errors.New("truncated Ogg page")
+		}
+		lacing := data[offset+27 : offset+27+segmentCount]
+		bodyLength := 0
+		for _, l := range lacing {
+			bodyLength += int(l)
+		}
+		bodyStart := offset + 27 + segmentCount
+		if len(data)-bodyStart < bodyLength {
+			return nil, 0, errors.New("truncated Ogg page")
+		}
+		body := data[bodyStart : bodyStart+bodyLength]
+		offset = bodyStart + bodyLength
+
+		if first {
+			serial = pageSerial
+			first = false
+		}
+		if pageSerial != serial {
+			continue
+		}
+		// -1 marks pages where no packet ends
+		if granule != -1 {
+			lastGranule = granule
+		}
+
+		// A packet ends at the first segment shorter than 255 bytes
+		for _, l := range lacing {
+			current = append(current, body[:l]...)
+			body = body[l:]
+			if l < 255 {
+				packets = append(packets, current)
+				current = nil
+			}
+		}
+	}
+	return packets, lastGranule, nil
+}
+
+// opusPacketDuration reads the length of audio in a packet from its TOC
+// byte (RFC 6716, section 3.1). It is zero for packets that are invalid,
+// including those over the 120 ms a packet can hold.
+func opusPacketDuration(packet []byte) time.Duration {
+	if len(packet) == 0 {
+		return 0
+	}
+	toc := packet[0]
+	config := toc >> 3
+
+	var frame time.Duration
+	switch {
+	case config < 12: // SILK: 10, 20, 40 or 60 ms
+		frame = []time.Duration{10, 20, 40, 60}[config%4] * time.Millisecond
+	case config < 16: // Hybrid: 10 or 20 ms
+		frame = []time.Duration{10, 20}[config%2] * time.Millisecond
+	default: // CELT: 2.5, 5, 10 or 20 ms
+		frame = []time.Duration{2500, 5000, 10000, 20000}[config%4] * time.Microsecond
+	}
+
+	frames := 1
+	switch toc & 0x3 {
+	case 1, 2:
+		frames = 2
+	case 3:
+		if len(packet) < 2 {
+			return 0
+		}
+		frames = int(packet[1] & 0x3F)
+	}
+	if duration := frame * time.Duration(frames); duration <= 120*time.Millisecond {
+		return duration
+	}
+	return 0
+}
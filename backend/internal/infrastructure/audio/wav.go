@@ -0,0 +1,143 @@
+package audio
+
+import (
+	"backend-chat-app/internal/domain/media"
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+)
+
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE
+)
+
+type wavFormat struct {
+	format        uint16
+	channels      int
+	sampleRate    int
+	blockAlign    int
+	bitsPerSample int
+}
+
+// analyzeWAV supports 8 to 32 bit integer PCM and 32 bit float, which covers
+// what browsers and phones record.
+func analyzeWAV(data []byte, samples int) (*media.AudioInfo, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, errors.New("not a WAV file")
+	}
+
+	var format *wavFormat
+	var pcm []byte
+	for offset := 12; offset+8 <= len(data); {
+		id := string(data[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		body := data[offset+8:]
+		// Recorders that stream write a placeholder size for the data chunk
+		if size > len(body) {
+			size = len(body)
+		}
+		body = body[:size]
+
+		switch id {
+		case "fmt ":
+			f, err := parseWAVFormat(body)
+			if err != nil {
+				return nil, err
+			}
+			format = f
+		case "data":
+			pcm = body
+		}
+		// Chunks are padded to an even size
+		offset += 8 + size + size%2
+	}
+	if format == nil || pcm == nil {
+		return nil, errors.New("WAV file has no audio")
+	}
+
+	frames := len(pcm) / format.blockAlign
+	if frames/format.sampleRate >= int(maxDuration/time.Second) {
+		return nil, errTooLong
+	}
+	duration := time.Duration(frames) * time.Second / time.Duration(format.sampleRate)
+
+	// One level per 10 ms is plenty for a preview and keeps long notes cheap
+	framesPerLevel := max(1, format.sampleRate/100)
+	bytesPerSample := format.bitsPerSample / 8
+	levels := make([]float64, 0, frames/framesPerLevel+1)
+	for start := 0; start < frames; start += framesPerLevel {
+		end := min(frames, start+framesPerLevel)
+		sum, count := 0.0, 0
+		for frame := start; frame < end; frame++ {
+			// The first channel is enough to show the shape of speech
+			at := frame * format.blockAlign
+			v := format.sample(pcm[at : at+bytesPerSample])
+			// Float samples can hold anything, NaN would spread to the
+			// whole waveform through the peak
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+			sum += v * v
+			count++
+		}
+		level := 0.0
+		if count > 0 {
+			level = math.Sqrt(sum / float64(count))
+		}
+		levels = append(levels, level)
+	}
+
+	return &media.AudioInfo{
+		Duration: duration,
+		Waveform: bucketLevels(levels, samples),
+	}, nil
+}
+
+func parseWAVFormat(body []byte) (*wavFormat, error) {
+	if len(body) < 16 {
+		return nil, errors.New("invalid WAV format chunk")
+	}
+	f := &wavFormat{
+		format:        binary.LittleEndian.Uint16(body[0:2]),
+		channels:      int(binary.LittleEndian.Uint16(body[2:4])),
+		sampleRate:    int(binary.LittleEndian.Uint32(body[4:8])),
+		blockAlign:    int(binary.LittleEndian.Uint16(body[12:14])),
+		bitsPerSample: int(binary.LittleEndian.Uint16(body[14:16])),
+	}
+	if f.format == wavFormatExtensible && len(body) >= 26 {
+		// The real format is the first two bytes of the sub-format GUID
+		f.format = binary.LittleEndian.Uint16(body[24:26])
+	}
+
+	switch {
+	case f.format == wavFormatPCM && (f.bitsPerSample == 8 || f.bitsPerSample == 16 || f.bitsPerSample == 24 || f.bitsPerSample == 32):
+	case f.format == wavFormatFloat && f.bitsPerSample == 32:
+	default:
+		return nil, errors.New("unsupported WAV encoding")
+	}
+	if f.channels <= 0 || f.sampleRate <= 0 || f.blockAlign < f.channels*f.bitsPerSample/8 {
+		return nil, errors.New("invalid WAV format chunk")
+	}
+	return f, nil
+}
+
+// sample returns one sample scaled to -1..1.
+func (f *wavFormat) sample(b []byte) float64 {
+	switch {
+	case f.format == wavFormatFloat:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	case f.bitsPerSample == 8:
+		// 8 bit WAV is unsigned
+		return (float64(b[0]) - 128) / 128
+	case f.bitsPerSample == 16:
+		return float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15)
+	case f.bitsPerSample == 24:
+		v := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+		return float64(v) / (1 << 23)
+	default:
+		return float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31)
+	}
+}
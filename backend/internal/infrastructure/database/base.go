@@ -12,3 +12,18 @@ func withContextTimeout() (context.Context, context.CancelFunc) {
 func timeFromUnix(i int64) time.Time {
 	return time.Unix(i, 0)
 }
+
+// optionalTimeFromUnix maps the 0 stored for unset dates to the zero time.
+func optionalTimeFromUnix(i int64) time.Time {
+	if i == 0 {
+		return time.Time{}
+	}
+	return time.Unix(i, 0)
+}
+
+func optionalUnix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
@@ -1,6 +1,8 @@
 package database
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -9,11 +11,27 @@ type MongoUser struct {
 	ID                 primitive.ObjectID   `bson:"_id,omitempty"`
 	Username           string               `bson:"username"`
 	Password           string               `bson:"password"`
-	Email              string               `bson:"email"`
-	Phone              string               `bson:"phone"`
+	Email              string               `bson:"email,omitempty"`
+	Phone              string               `bson:"phone,omitempty"`
 	Name               string               `bson:"name"`
+	DisplayName        string               `bson:"display_name,omitempty"`
+	Bio                string               `bson:"bio,omitempty"`
+	Timezone           string               `bson:"timezone,omitempty"`
+	AvatarID           string               `bson:"avatar_id,omitempty"`
+	EmailVerified      bool                 `bson:"email_verified"`
+	OIDCIssuer         string               `bson:"oidc_issuer,omitempty"`
+	OIDCSubject        string               `bson:"oidc_subject,omitempty"`
+	IsBot              bool                 `bson:"is_bot,omitempty"`
+	OwnerID            string               `bson:"owner_id,omitempty"`
+	ContactsOnly       bool                 `bson:"contacts_only,omitempty"`
+	Discoverability    string               `bson:"discoverability,omitempty"`
+	SearchTerms        []string             `bson:"search_terms,omitempty"`
+	SearchGrams        []string             `bson:"search_grams,omitempty"`
+	DeletionDueAt      int64                `bson:"deletion_due_at,omitempty"`
+	DeletedAt          int64                `bson:"deleted_at,omitempty"`
 	RefreshToken       string               `bson:"refresh_token,omitempty"`
 	RefreshTokenExpiry int64                `bson:"refresh_token_expiry"`
+	TokenVersion       int                  `bson:"token_version"`
 	Conversations      []primitive.ObjectID `bson:"conversations"`
 	CreatedAt          int64                `bson:"create_at"`
 	UpdateAt           int64                `bson:"update_at"`
@@ -21,11 +39,100 @@ type MongoUser struct {
 
 // Message Table
 type MongoMessage struct {
+	ID             primitive.ObjectID   `bson:"_id,omitempty"`
+	ConversationID primitive.ObjectID   `bson:"conversation_id"`
+	Sender         primitive.ObjectID   `bson:"sender_id"`
+	Type           string               `bson:"type,omitempty"`
+	Message        string               `bson:"message"`
+	AttachmentIDs  []primitive.ObjectID `bson:"attachment_ids,omitempty"`
+	LinkPreviews   []MongoLinkPreview   `bson:"link_previews,omitempty"`
+	Mentions       []MongoMention       `bson:"mentions,omitempty"`
+	MentionedIDs   []primitive.ObjectID `bson:"mentioned_ids,omitempty"`
+	Entities       []MongoEntity        `bson:"entities,omitempty"`
+	ForwardedFrom  *MongoForward        `bson:"forwarded_from,omitempty"`
+	Poll           *MongoPoll           `bson:"poll,omitempty"`
+	Redacted       bool                 `bson:"redacted,omitempty"`
+	CreatedAt      int64                `bson:"created_at"`
+	ExpiresAt      int64                `bson:"expires_at,omitempty"`
+}
+
+// Pinned message Table
+type MongoPinnedMessage struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	ConversationID primitive.ObjectID `bson:"conversation_id"`
+	MessageID      primitive.ObjectID `bson:"message_id"`
+	PinnedBy       primitive.ObjectID `bson:"pinned_by"`
+	PinnedAt       int64              `bson:"pinned_at"`
+}
+
+type MongoScheduledMessage struct {
 	ID             primitive.ObjectID `bson:"_id,omitempty"`
 	ConversationID primitive.ObjectID `bson:"conversation_id"`
-	Sender         primitive.ObjectID `bson:"sender_id"`
+	SenderID       primitive.ObjectID `bson:"sender_id"`
 	Message        string             `bson:"message"`
+	Format         string             `bson:"format,omitempty"`
+	Status         string             `bson:"status"`
+	Error          string             `bson:"error,omitempty"`
+	SendAt         int64              `bson:"send_at"`
 	CreatedAt      int64              `bson:"created_at"`
+	UpdatedAt      int64              `bson:"updated_at"`
+}
+
+type MongoPoll struct {
+	Options   []string `bson:"options"`
+	Multiple  bool     `bson:"multiple,omitempty"`
+	Anonymous bool     `bson:"anonymous,omitempty"`
+	ClosesAt  int64    `bson:"closes_at,omitempty"`
+	// CloseDueAt repeats ClosesAt while the poll is open, so the index the
+	// closing worker reads only holds open polls
+	CloseDueAt int64           `bson:"close_due_at,omitempty"`
+	ClosedAt   int64           `bson:"closed_at,omitempty"`
+	Votes      []MongoPollVote `bson:"votes"`
+}
+
+type MongoPollVote struct {
+	UserID    primitive.ObjectID `bson:"user_id"`
+	OptionIDs []int              `bson:"option_ids"`
+	VotedAt   int64              `bson:"voted_at"`
+}
+
+type MongoForward struct {
+	SenderID   primitive.ObjectID `bson:"sender_id"`
+	SenderName string             `bson:"sender_name"`
+	CreatedAt  int64              `bson:"created_at"`
+}
+
+type MongoEntity struct {
+	Type     string `bson:"type"`
+	Offset   int    `bson:"offset"`
+	Length   int    `bson:"length"`
+	URL      string `bson:"url,omitempty"`
+	Language string `bson:"language,omitempty"`
+}
+
+type MongoMention struct {
+	UserID primitive.ObjectID `bson:"user_id,omitempty"` // unset for @all
+	Offset int                `bson:"offset"`
+	Length int                `bson:"length"`
+}
+
+type MongoLinkPreview struct {
+	URL         string `bson:"url"`
+	Title       string `bson:"title,omitempty"`
+	Description string `bson:"description,omitempty"`
+	ImageURL    string `bson:"image_url,omitempty"`
+	SiteName    string `bson:"site_name,omitempty"`
+}
+
+// Link preview cache, one document per URL
+type MongoCachedLinkPreview struct {
+	URL         string `bson:"_id"`
+	Title       string `bson:"title,omitempty"`
+	Description string `bson:"description,omitempty"`
+	ImageURL    string `bson:"image_url,omitempty"`
+	SiteName    string `bson:"site_name,omitempty"`
+	Failed      bool   `bson:"failed,omitempty"`
+	FetchedAt   int64  `bson:"fetched_at"`
 }
 
 // Conversation Table
@@ -36,7 +143,154 @@ type Participant struct {
 
 type MongoConversation struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	Name        string             `bson:"name,omitempty"`
+	OwnerID     primitive.ObjectID `bson:"owner_id,omitempty"`
+	Direct      bool               `bson:"direct,omitempty"`
 	Participant []Participant      `bson:"participant"`
+	LastMessage *MongoLastMessage  `bson:"last_message,omitempty"`
+	MessageTTL  int64              `bson:"message_ttl,omitempty"` // seconds
+	PinCount    int                `bson:"pin_count,omitempty"`   // kept by ReservePin and ReleasePins
 	CreatedAt   int64              `bson:"created_at"`
 	UpdateAt    int64              `bson:"update_at"`
 }
+
+// Per-user conversation settings Table
+type MongoConversationSettings struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	UserID         primitive.ObjectID `bson:"user_id"`
+	ConversationID primitive.ObjectID `bson:"conversation_id"`
+	MutedUntil     int64              `bson:"muted_until,omitempty"`
+	Pinned         bool               `bson:"pinned"`
+	PinnedAt       int64              `bson:"pinned_at,omitempty"`
+	Archived       bool               `bson:"archived"`
+	UpdateAt       int64              `bson:"update_at"`
+}
+
+// Attachment Table
+type MongoAttachment struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	ConversationID primitive.ObjectID `bson:"conversation_id"`
+	UploaderID     primitive.ObjectID `bson:"uploader_id"`
+	MessageID      primitive.ObjectID `bson:"message_id,omitempty"`
+	FileName       string             `bson:"file_name"`
+	ContentType    string             `bson:"content_type"`
+	Size           int64              `bson:"size"`
+	BlobKey        string             `bson:"blob_key"`
+	ThumbnailKey   string             `bson:"thumbnail_key,omitempty"`
+	DurationMs     int64              `bson:"duration_ms,omitempty"`
+	Waveform       []int              `bson:"waveform,omitempty"`
+	CreatedAt      int64              `bson:"created_at"`
+}
+
+// Conversation invite link Table
+type MongoConversationInvite struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	ConversationID primitive.ObjectID `bson:"conversation_id"`
+	CreatorID      string             `bson:"creator_id"`
+	Token          string             `bson:"token"`
+	MaxUses        int                `bson:"max_uses"` // 0 for unlimited
+	Uses           int                `bson:"uses"`
+	ExpiresAt      int64              `bson:"expires_at,omitempty"`
+	RevokedAt      int64              `bson:"revoked_at,omitempty"`
+	CreatedAt      int64              `bson:"created_at"`
+}
+
+type MongoLastMessage struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	SenderID  primitive.ObjectID `bson:"sender_id"`
+	Message   string             `bson:"message"`
+	CreatedAt int64              `bson:"created_at"`
+}
+
+// Single-use token Table
+type MongoToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `bson:"user_id"`
+	Purpose   string             `bson:"purpose"`
+	ExpiresAt time.Time          `bson:"expires_at"` // BSON date so the TTL index can expire it
+	CreatedAt int64              `bson:"created_at"`
+}
+
+// Login attempt Table, keyed by "user:<username>" or "ip:<address>"
+type MongoLoginAttempt struct {
+	Key           string    `bson:"_id"`
+	Failures      int       `bson:"failures"`
+	LastFailureAt int64     `bson:"last_failure_at"`
+	Allowed       bool      `bson:"allowed"`
+	ExpiresAt     time.Time `bson:"expires_at"`
+}
+
+// Security event Table
+type MongoSecurityEvent struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Type      string             `bson:"type"`
+	UserID    string             `bson:"user_id,omitempty"`
+	Username  string             `bson:"username,omitempty"`
+	IP        string             `bson:"ip,omitempty"`
+	Detail    string             `bson:"detail,omitempty"`
+	CreatedAt int64              `bson:"created_at"`
+}
+
+// SSO login state Table
+type MongoLoginState struct {
+	State        string    `bson:"_id"`
+	Nonce        string    `bson:"nonce"`
+	CodeVerifier string    `bson:"code_verifier"`
+	ExpiresAt    time.Time `bson:"expires_at"`
+	CreatedAt    int64     `bson:"created_at"`
+}
+
+// API key Table
+type MongoAPIKey struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	BotID      primitive.ObjectID `bson:"bot_id"`
+	OwnerID    string             `bson:"owner_id"`
+	Name       string             `bson:"name"`
+	Prefix     string             `bson:"prefix"`
+	Hash       string             `bson:"hash"` // sha256 of the full key
+	Scopes     []string           `bson:"scopes"`
+	ExpiresAt  int64              `bson:"expires_at,omitempty"`
+	RevokedAt  int64              `bson:"revoked_at,omitempty"`
+	LastUsedAt int64              `bson:"last_used_at,omitempty"`
+	CreatedAt  int64              `bson:"created_at"`
+}
+
+// Contact request Table
+type MongoContactRequest struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	FromID    primitive.ObjectID `bson:"from_id"`
+	ToID      primitive.ObjectID `bson:"to_id"`
+	Status    string             `bson:"status"`
+	CreatedAt int64              `bson:"created_at"`
+	UpdateAt  int64              `bson:"update_at"`
+}
+
+// Contact Table, one document per direction
+type MongoContact struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `bson:"user_id"`
+	ContactID primitive.ObjectID `bson:"contact_id"`
+	CreatedAt int64              `bson:"created_at"`
+}
+
+// Block Table
+type MongoBlock struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	BlockerID primitive.ObjectID `bson:"blocker_id"`
+	BlockedID primitive.ObjectID `bson:"blocked_id"`
+	CreatedAt int64              `bson:"created_at"`
+}
+
+// Data export job Table
+type MongoExportJob struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	UserID      primitive.ObjectID `bson:"user_id"`
+	Status      string             `bson:"status"`
+	InProgress  bool               `bson:"in_progress,omitempty"` // while pending or running, for the unique index
+	BlobKey     string             `bson:"blob_key,omitempty"`
+	Size        int64              `bson:"size,omitempty"`
+	Error       string             `bson:"error,omitempty"`
+	CreatedAt   int64              `bson:"created_at"`
+	CompletedAt int64              `bson:"completed_at,omitempty"`
+	ExpiresAt   int64              `bson:"expires_at,omitempty"`
+}
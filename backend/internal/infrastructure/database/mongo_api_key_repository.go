@@ -0,0 +1,189 @@
+package database
+
+import (
+	"backend-chat-app/internal/domain/apikey"
+	"backend-chat-app/internal/infrastructure/database/registry"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	apiKeyIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "prefix", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "bot_id", Value: 1}},
+		},
+	}
+
+	registry.RegisterCollection("api_keys", apiKeyIndexes)
+}
+
+type MongoAPIKeyRepository struct {
+	client     *mongo.Client
+	database   string
+	collection *mongo.Collection
+}
+
+func NewMongoAPIKeyRepository(client *mongo.Client, database string) *MongoAPIKeyRepository {
+	collection := client.Database(database).Collection("api_keys")
+	return &MongoAPIKeyRepository{
+		client:     client,
+		database:   database,
+		collection: collection,
+	}
+}
+
+func (kr *MongoAPIKeyRepository) Create(key apikey.APIKey) (*apikey.APIKey, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	botObjID, err := primitive.ObjectIDFromHex(key.BotID)
+	if err != nil {
+		return nil, errors.New("invalid bot ID format")
+	}
+
+	scopes := make([]string, len(key.Scopes))
+	for i, scope := range key.Scopes {
+		scopes[i] = scope.String()
+	}
+
+	mongoKey := &MongoAPIKey{
+		BotID:     botObjID,
+		OwnerID:   key.OwnerID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Hash:      key.Hash,
+		Scopes:    scopes,
+		CreatedAt: key.CreatedAt.Unix(),
+	}
+	if !key.ExpiresAt.IsZero() {
+		mongoKey.ExpiresAt = key.ExpiresAt.Unix()
+	}
+
+	result, err := kr.collection.InsertOne(ctx, mongoKey)
+	if err != nil {
+		return nil, err
+	}
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		mongoKey.ID = oid
+	}
+	return kr.toDomainAPIKey(*mongoKey), nil
+}
+
+func (kr *MongoAPIKeyRepository) toDomainAPIKey(mongoKey MongoAPIKey) *apikey.APIKey {
+	scopes := make([]apikey.Scope, 0, len(mongoKey.Scopes))
+	for _, raw := range mongoKey.Scopes {
+		if scope, err := apikey.ParseScope(raw); err == nil {
+			scopes = append(scopes, *scope)
+		}
+	}
+
+	key := &apikey.APIKey{
+		ID:        mongoKey.ID.Hex(),
+		BotID:     mongoKey.BotID.Hex(),
+		OwnerID:   mongoKey.OwnerID,
+		Name:      mongoKey.Name,
+		Prefix:    mongoKey.Prefix,
+		Hash:      mongoKey.Hash,
+		Scopes:    scopes,
+		CreatedAt: timeFromUnix(mongoKey.CreatedAt),
+	}
+	if mongoKey.ExpiresAt != 0 {
+		key.ExpiresAt = timeFromUnix(mongoKey.ExpiresAt)
+	}
+	if mongoKey.RevokedAt != 0 {
+		key.RevokedAt = timeFromUnix(mongoKey.RevokedAt)
+	}
+	if mongoKey.LastUsedAt != 0 {
+		key.LastUsedAt = timeFromUnix(mongoKey.LastUsedAt)
+	}
+	return key
+}
+
+func (kr *MongoAPIKeyRepository) GetByPrefix(prefix string) (*apikey.APIKey, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	var mongoKey MongoAPIKey
+	err := kr.collection.FindOne(ctx, bson.M{"prefix": prefix}).Decode(&mongoKey)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return kr.toDomainAPIKey(mongoKey), nil
+}
+
+func (kr *MongoAPIKeyRepository) GetByBot(botID string) ([]*apikey.APIKey, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	botObjID, err := primitive.ObjectIDFromHex(botID)
+	if err != nil {
+		return nil, errors.New("invalid bot ID format")
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := kr.collection.Find(ctx, bson.M{"bot_id": botObjID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var mongoKeys []MongoAPIKey
+	if err = cursor.All(ctx, &mongoKeys); err != nil {
+		return nil, err
+	}
+
+	keys := make([]*apikey.APIKey, len(mongoKeys))
+	for i, mongoKey := range mongoKeys {
+		keys[i] = kr.toDomainAPIKey(mongoKey)
+	}
+	return keys, nil
+}
+
+func (kr *MongoAPIKeyRepository) Revoke(keyID string, botID string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	keyObjID, err := primitive.ObjectIDFromHex(keyID)
+	if err != nil {
+		return errors.New("invalid key ID format")
+	}
+	botObjID, err := primitive.ObjectIDFromHex(botID)
+	if err != nil {
+		return errors.New("invalid bot ID format")
+	}
+
+	filter := bson.M{"_id": keyObjID, "bot_id": botObjID}
+	update := bson.M{"$set": bson.M{"revoked_at": time.Now().Unix()}}
+	result, err := kr.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("api key not found")
+	}
+	return nil
+}
+
+func (kr *MongoAPIKeyRepository) TouchLastUsed(keyID string, at time.Time) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	keyObjID, err := primitive.ObjectIDFromHex(keyID)
+	if err != nil {
+		return errors.New("invalid key ID format")
+	}
+	_, err = kr.collection.UpdateOne(ctx, bson.M{"_id": keyObjID}, bson.M{"$set": bson.M{"last_used_at": at.Unix()}})
+	return err
+}
@@ -0,0 +1,216 @@
+package database
+
+import (
+	"backend-chat-app/internal/domain/attachment"
+	"backend-chat-app/internal/infrastructure/database/registry"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func init() {
+	attachmentIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "conversation_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "uploader_id", Value: 1}},
+		},
+		{
+			// Unsent uploads waiting to be cleaned up
+			Keys: bson.D{{Key: "message_id", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+	}
+
+	registry.RegisterCollection("attachments", attachmentIndexes)
+}
+
+type MongoAttachmentRepository struct {
+	client     *mongo.Client
+	database   string
+	collection *mongo.Collection
+}
+
+func NewMongoAttachmentRepository(client *mongo.Client, database string) *MongoAttachmentRepository {
+	collection := client.Database(database).Collection("attachments")
+	return &MongoAttachmentRepository{
+		client:     client,
+		database:   database,
+		collection: collection,
+	}
+}
+
+func (ar *MongoAttachmentRepository) toDomainAttachment(mongoAttachment MongoAttachment) *attachment.Attachment {
+	a := &attachment.Attachment{
+		ID:             mongoAttachment.ID.Hex(),
+		ConversationID: mongoAttachment.ConversationID.Hex(),
+		UploaderID:     mongoAttachment.UploaderID.Hex(),
+		FileName:       mongoAttachment.FileName,
+		ContentType:    mongoAttachment.ContentType,
+		Size:           mongoAttachment.Size,
+		BlobKey:        mongoAttachment.BlobKey,
+		ThumbnailKey:   mongoAttachment.ThumbnailKey,
+		Duration:       time.Duration(mongoAttachment.DurationMs) * time.Millisecond,
+		Waveform:       mongoAttachment.Waveform,
+		CreatedAt:      timeFromUnix(mongoAttachment.CreatedAt),
+	}
+	if !mongoAttachment.MessageID.IsZero() {
+		a.MessageID = mongoAttachment.MessageID.Hex()
+	}
+	return a
+}
+
+func (ar *MongoAttachmentRepository) find(filter bson.M) ([]*attachment.Attachment, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	cursor, err := ar.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var mongoAttachments []MongoAttachment
+	if err = cursor.All(ctx, &mongoAttachments); err != nil {
+		return nil, err
+	}
+
+	attachments := make([]*attachment.Attachment, len(mongoAttachments))
+	for i, a := range mongoAttachments {
+		attachments[i] = ar.toDomainAttachment(a)
+	}
+	return attachments, nil
+}
+
+func (ar *MongoAttachmentRepository) Create(a attachment.Attachment) (*attachment.Attachment, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	convObjID, err := primitive.ObjectIDFromHex(a.ConversationID)
+	if err != nil {
+		return nil, errors.New("invalid conversation ID format")
+	}
+	uploaderObjID, err := primitive.ObjectIDFromHex(a.UploaderID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	mongoAttachment := &MongoAttachment{
+		ConversationID: convObjID,
+		UploaderID:     uploaderObjID,
+		FileName:       a.FileName,
+		ContentType:    a.ContentType,
+		Size:           a.Size,
+		BlobKey:        a.BlobKey,
+		ThumbnailKey:   a.ThumbnailKey,
+		DurationMs:     a.Duration.Milliseconds(),
+		Waveform:       a.Waveform,
+		CreatedAt:      a.CreatedAt.Unix(),
+	}
+	result, err := ar.collection.InsertOne(ctx, mongoAttachment)
+	if err != nil {
+		return nil, err
+	}
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		mongoAttachment.ID = oid
+	}
+	return ar.toDomainAttachment(*mongoAttachment), nil
+}
+
+func (ar *MongoAttachmentRepository) GetByID(attachmentID string) (*attachment.Attachment, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(attachmentID)
+	if err != nil {
+		return nil, errors.New("invalid attachment ID format")
+	}
+
+	var mongoAttachment MongoAttachment
+	err = ar.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&mongoAttachment)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ar.toDomainAttachment(mongoAttachment), nil
+}
+
+func (ar *MongoAttachmentRepository) GetByIDs(attachmentIDs []string) ([]*attachment.Attachment, error) {
+	if len(attachmentIDs) == 0 {
+		return nil, nil
+	}
+	return ar.find(bson.M{"_id": bson.M{"$in": toObjectIDs(attachmentIDs)}})
+}
+
+func (ar *MongoAttachmentRepository) AttachToMessage(attachmentIDs []string, messageID string) (int, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	messageObjID, err := primitive.ObjectIDFromHex(messageID)
+	if err != nil {
+		return 0, errors.New("invalid message ID format")
+	}
+
+	filter := bson.M{
+		"_id":        bson.M{"$in": toObjectIDs(attachmentIDs)},
+		"message_id": bson.M{"$exists": false},
+	}
+	result, err := ar.collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"message_id": messageObjID}})
+	if err != nil {
+		return 0, err
+	}
+	return int(result.ModifiedCount), nil
+}
+
+func (ar *MongoAttachmentRepository) DetachFromMessage(messageID string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	messageObjID, err := primitive.ObjectIDFromHex(messageID)
+	if err != nil {
+		return errors.New("invalid message ID format")
+	}
+
+	_, err = ar.collection.UpdateMany(ctx, bson.M{"message_id": messageObjID}, bson.M{"$unset": bson.M{"message_id": ""}})
+	return err
+}
+
+func (ar *MongoAttachmentRepository) ListUnsentBefore(t time.Time) ([]*attachment.Attachment, error) {
+	return ar.find(bson.M{
+		"message_id": bson.M{"$exists": false},
+		"created_at": bson.M{"$lt": t.Unix()},
+	})
+}
+
+func (ar *MongoAttachmentRepository) ListByConversation(conversationID string) ([]*attachment.Attachment, error) {
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return nil, errors.New("invalid conversation ID format")
+	}
+	return ar.find(bson.M{"conversation_id": convObjID})
+}
+
+func (ar *MongoAttachmentRepository) ListByUploader(uploaderID string) ([]*attachment.Attachment, error) {
+	uploaderObjID, err := primitive.ObjectIDFromHex(uploaderID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+	return ar.find(bson.M{"uploader_id": uploaderObjID})
+}
+
+func (ar *MongoAttachmentRepository) Delete(attachmentID string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(attachmentID)
+	if err != nil {
+		return errors.New("invalid attachment ID format")
+	}
+	_, err = ar.collection.DeleteOne(ctx, bson.M{"_id": objID})
+	return err
+}
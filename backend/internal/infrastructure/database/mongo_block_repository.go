@@ -0,0 +1,203 @@
+package database
+
+import (
+	"backend-chat-app/internal/domain/contact"
+	"backend-chat-app/internal/infrastructure/database/registry"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	blockIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "blocker_id", Value: 1}, {Key: "blocked_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "blocked_id", Value: 1}},
+		},
+	}
+
+	registry.RegisterCollection("blocks", blockIndexes)
+}
+
+type MongoBlockRepository struct {
+	client     *mongo.Client
+	database   string
+	collection *mongo.Collection
+}
+
+func NewMongoBlockRepository(client *mongo.Client, database string) *MongoBlockRepository {
+	collection := client.Database(database).Collection("blocks")
+	return &MongoBlockRepository{
+		client:     client,
+		database:   database,
+		collection: collection,
+	}
+}
+
+func (br *MongoBlockRepository) Block(blockerID string, blockedID string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	blockerObjID, blockedObjID, err := userPairObjectIDs(blockerID, blockedID)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"blocker_id": blockerObjID, "blocked_id": blockedObjID}
+	update := bson.M{"$setOnInsert": bson.M{"created_at": time.Now().Unix()}}
+	_, err = br.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (br *MongoBlockRepository) Unblock(blockerID string, blockedID string) (bool, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	blockerObjID, blockedObjID, err := userPairObjectIDs(blockerID, blockedID)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := br.collection.DeleteOne(ctx, bson.M{"blocker_id": blockerObjID, "blocked_id": blockedObjID})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount == 1, nil
+}
+
+func (br *MongoBlockRepository) List(blockerID string) ([]*contact.Block, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	blockerObjID, err := primitive.ObjectIDFromHex(blockerID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := br.collection.Find(ctx, bson.M{"blocker_id": blockerObjID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var mongoBlocks []MongoBlock
+	if err = cursor.All(ctx, &mongoBlocks); err != nil {
+		return nil, err
+	}
+
+	blocks := make([]*contact.Block, len(mongoBlocks))
+	for i, b := range mongoBlocks {
+		blocks[i] = &contact.Block{
+			BlockerID: b.BlockerID.Hex(),
+			BlockedID: b.BlockedID.Hex(),
+			CreatedAt: timeFromUnix(b.CreatedAt),
+		}
+	}
+	return blocks, nil
+}
+
+func (br *MongoBlockRepository) IsBlocked(blockerID string, blockedID string) (bool, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	blockerObjID, blockedObjID, err := userPairObjectIDs(blockerID, blockedID)
+	if err != nil {
+		return false, err
+	}
+
+	filter := bson.M{"blocker_id": blockerObjID, "blocked_id": blockedObjID}
+	count, err := br.collection.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (br *MongoBlockRepository) IsBlockedEither(userA string, userB string) (bool, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	userAObjID, userBObjID, err := userPairObjectIDs(userA, userB)
+	if err != nil {
+		return false, err
+	}
+
+	filter := bson.M{"$or": bson.A{
+		bson.M{"blocker_id": userAObjID, "blocked_id": userBObjID},
+		bson.M{"blocker_id": userBObjID, "blocked_id": userAObjID},
+	}}
+	count, err := br.collection.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (br *MongoBlockRepository) ListRelated(userID string) ([]string, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	filter := bson.M{"$or": bson.A{
+		bson.M{"blocker_id": userObjID},
+		bson.M{"blocked_id": userObjID},
+	}}
+	cursor, err := br.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var mongoBlocks []MongoBlock
+	if err = cursor.All(ctx, &mongoBlocks); err != nil {
+		return nil, err
+	}
+
+	related := make([]string, 0, len(mongoBlocks))
+	for _, b := range mongoBlocks {
+		if b.BlockerID == userObjID {
+			related = append(related, b.BlockedID.Hex())
+		} else {
+			related = append(related, b.BlockerID.Hex())
+		}
+	}
+	return related, nil
+}
+
+func (br *MongoBlockRepository) DeleteByUser(userID string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user ID format")
+	}
+
+	filter := bson.M{"$or": bson.A{bson.M{"blocker_id": userObjID}, bson.M{"blocked_id": userObjID}}}
+	_, err = br.collection.DeleteMany(ctx, filter)
+	return err
+}
+
+func userPairObjectIDs(userA string, userB string) (primitive.ObjectID, primitive.ObjectID, error) {
+	userAObjID, err := primitive.ObjectIDFromHex(userA)
+	if err != nil {
+		return primitive.NilObjectID, primitive.NilObjectID, errors.New("invalid user ID format")
+	}
+	userBObjID, err := primitive.ObjectIDFromHex(userB)
+	if err != nil {
+		return primitive.NilObjectID, primitive.NilObjectID, errors.New("invalid user ID format")
+	}
+	return userAObjID, userBObjID, nil
+}
@@ -0,0 +1,151 @@
+package database
+
+import (
+	"backend-chat-app/internal/domain/contact"
+	"backend-chat-app/internal/infrastructure/database/registry"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	contactIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "contact_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+
+	registry.RegisterCollection("contacts", contactIndexes)
+}
+
+type MongoContactRepository struct {
+	client     *mongo.Client
+	database   string
+	collection *mongo.Collection
+}
+
+func NewMongoContactRepository(client *mongo.Client, database string) *MongoContactRepository {
+	collection := client.Database(database).Collection("contacts")
+	return &MongoContactRepository{
+		client:     client,
+		database:   database,
+		collection: collection,
+	}
+}
+
+// Add stores the relationship in both directions.
+func (cr *MongoContactRepository) Add(userID string, contactID string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user ID format")
+	}
+	contactObjID, err := primitive.ObjectIDFromHex(contactID)
+	if err != nil {
+		return errors.New("invalid user ID format")
+	}
+
+	now := time.Now().Unix()
+	for _, pair := range [][2]primitive.ObjectID{{userObjID, contactObjID}, {contactObjID, userObjID}} {
+		filter := bson.M{"user_id": pair[0], "contact_id": pair[1]}
+		update := bson.M{"$setOnInsert": bson.M{"created_at": now}}
+		if _, err := cr.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remove deletes the relationship in both directions.
+func (cr *MongoContactRepository) Remove(userID string, contactID string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user ID format")
+	}
+	contactObjID, err := primitive.ObjectIDFromHex(contactID)
+	if err != nil {
+		return errors.New("invalid user ID format")
+	}
+
+	filter := bson.M{"$or": bson.A{
+		bson.M{"user_id": userObjID, "contact_id": contactObjID},
+		bson.M{"user_id": contactObjID, "contact_id": userObjID},
+	}}
+	_, err = cr.collection.DeleteMany(ctx, filter)
+	return err
+}
+
+func (cr *MongoContactRepository) List(userID string) ([]*contact.Contact, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	cursor, err := cr.collection.Find(ctx, bson.M{"user_id": userObjID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var mongoContacts []MongoContact
+	if err = cursor.All(ctx, &mongoContacts); err != nil {
+		return nil, err
+	}
+
+	contacts := make([]*contact.Contact, len(mongoContacts))
+	for i, c := range mongoContacts {
+		contacts[i] = &contact.Contact{
+			UserID:    c.UserID.Hex(),
+			ContactID: c.ContactID.Hex(),
+			CreatedAt: timeFromUnix(c.CreatedAt),
+		}
+	}
+	return contacts, nil
+}
+
+func (cr *MongoContactRepository) IsContact(userID string, contactID string) (bool, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, errors.New("invalid user ID format")
+	}
+	contactObjID, err := primitive.ObjectIDFromHex(contactID)
+	if err != nil {
+		return false, errors.New("invalid user ID format")
+	}
+
+	count, err := cr.collection.CountDocuments(ctx, bson.M{"user_id": userObjID, "contact_id": contactObjID}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (cr *MongoContactRepository) RemoveAll(userID string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user ID format")
+	}
+
+	filter := bson.M{"$or": bson.A{bson.M{"user_id": userObjID}, bson.M{"contact_id": userObjID}}}
+	_, err = cr.collection.DeleteMany(ctx, filter)
+	return err
+}
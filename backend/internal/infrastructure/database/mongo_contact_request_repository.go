@@ -0,0 +1,198 @@
+package database
+
+import (
+	"backend-chat-app/internal/domain/contact"
+	"backend-chat-app/internal/infrastructure/database/registry"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	requestIndexes := []mongo.IndexModel{
+		{
+			// At most one pending request per direction
+			Keys: bson.D{{Key: "from_id", Value: 1}, {Key: "to_id", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"status": contact.StatusPending}),
+		},
+		{
+			Keys: bson.D{{Key: "to_id", Value: 1}, {Key: "status", Value: 1}},
+		},
+	}
+
+	registry.RegisterCollection("contact_requests", requestIndexes)
+}
+
+type MongoContactRequestRepository struct {
+	client     *mongo.Client
+	database   string
+	collection *mongo.Collection
+}
+
+func NewMongoContactRequestRepository(client *mongo.Client, database string) *MongoContactRequestRepository {
+	collection := client.Database(database).Collection("contact_requests")
+	return &MongoContactRequestRepository{
+		client:     client,
+		database:   database,
+		collection: collection,
+	}
+}
+
+func (rr *MongoContactRequestRepository) Create(request contact.Request) (*contact.Request, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	fromObjID, err := primitive.ObjectIDFromHex(request.FromID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+	toObjID, err := primitive.ObjectIDFromHex(request.ToID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	mongoRequest := &MongoContactRequest{
+		FromID:    fromObjID,
+		ToID:      toObjID,
+		Status:    request.Status,
+		CreatedAt: request.CreatedAt.Unix(),
+		UpdateAt:  request.UpdateAt.Unix(),
+	}
+	result, err := rr.collection.InsertOne(ctx, mongoRequest)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, errors.New("a contact request is already pending")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		mongoRequest.ID = oid
+	}
+	return rr.toDomainRequest(*mongoRequest), nil
+}
+
+func (rr *MongoContactRequestRepository) toDomainRequest(mongoRequest MongoContactRequest) *contact.Request {
+	return &contact.Request{
+		ID:        mongoRequest.ID.Hex(),
+		FromID:    mongoRequest.FromID.Hex(),
+		ToID:      mongoRequest.ToID.Hex(),
+		Status:    mongoRequest.Status,
+		CreatedAt: timeFromUnix(mongoRequest.CreatedAt),
+		UpdateAt:  timeFromUnix(mongoRequest.UpdateAt),
+	}
+}
+
+func (rr *MongoContactRequestRepository) GetByID(requestID string) (*contact.Request, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(requestID)
+	if err != nil {
+		return nil, errors.New("invalid request ID format")
+	}
+
+	var mongoRequest MongoContactRequest
+	err = rr.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&mongoRequest)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rr.toDomainRequest(mongoRequest), nil
+}
+
+func (rr *MongoContactRequestRepository) GetPending(fromID string, toID string) (*contact.Request, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	fromObjID, err := primitive.ObjectIDFromHex(fromID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+	toObjID, err := primitive.ObjectIDFromHex(toID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	var mongoRequest MongoContactRequest
+	filter := bson.M{"from_id": fromObjID, "to_id": toObjID, "status": contact.StatusPending}
+	err = rr.collection.FindOne(ctx, filter).Decode(&mongoRequest)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rr.toDomainRequest(mongoRequest), nil
+}
+
+func (rr *MongoContactRequestRepository) UpdateStatus(requestID string, status string) (bool, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(requestID)
+	if err != nil {
+		return false, errors.New("invalid request ID format")
+	}
+
+	filter := bson.M{"_id": objectID, "status": contact.StatusPending}
+	update := bson.M{"$set": bson.M{"status": status, "update_at": time.Now().Unix()}}
+	result, err := rr.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (rr *MongoContactRequestRepository) ListPending(userID string, incoming bool) ([]*contact.Request, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	field := "from_id"
+	if incoming {
+		field = "to_id"
+	}
+	filter := bson.M{field: userObjID, "status": contact.StatusPending}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := rr.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var mongoRequests []MongoContactRequest
+	if err = cursor.All(ctx, &mongoRequests); err != nil {
+		return nil, err
+	}
+
+	requests := make([]*contact.Request, len(mongoRequests))
+	for i, mongoRequest := range mongoRequests {
+		requests[i] = rr.toDomainRequest(mongoRequest)
+	}
+	return requests, nil
+}
+
+func (rr *MongoContactRequestRepository) DeleteByUser(userID string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user ID format")
+	}
+
+	filter := bson.M{"$or": bson.A{bson.M{"from_id": userObjID}, bson.M{"to_id": userObjID}}}
+	_, err = rr.collection.DeleteMany(ctx, filter)
+	return err
+}
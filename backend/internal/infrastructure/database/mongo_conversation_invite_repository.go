@@ -0,0 +1,196 @@
+package database
+
+import (
+	"backend-chat-app/internal/domain/conversation"
+	"backend-chat-app/internal/infrastructure/database/registry"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	inviteIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "conversation_id", Value: 1}},
+		},
+	}
+
+	registry.RegisterCollection("conversation_invites", inviteIndexes)
+}
+
+type MongoConversationInviteRepository struct {
+	client     *mongo.Client
+	database   string
+	collection *mongo.Collection
+}
+
+func NewMongoConversationInviteRepository(client *mongo.Client, database string) *MongoConversationInviteRepository {
+	collection := client.Database(database).Collection("conversation_invites")
+	return &MongoConversationInviteRepository{
+		client:     client,
+		database:   database,
+		collection: collection,
+	}
+}
+
+func (ir *MongoConversationInviteRepository) toDomainInvite(mongoInvite MongoConversationInvite) *conversation.Invite {
+	return &conversation.Invite{
+		ID:             mongoInvite.ID.Hex(),
+		ConversationID: mongoInvite.ConversationID.Hex(),
+		CreatorID:      mongoInvite.CreatorID,
+		Token:          mongoInvite.Token,
+		MaxUses:        mongoInvite.MaxUses,
+		Uses:           mongoInvite.Uses,
+		ExpiresAt:      optionalTimeFromUnix(mongoInvite.ExpiresAt),
+		RevokedAt:      optionalTimeFromUnix(mongoInvite.RevokedAt),
+		CreatedAt:      timeFromUnix(mongoInvite.CreatedAt),
+	}
+}
+
+func (ir *MongoConversationInviteRepository) Create(invite conversation.Invite) (*conversation.Invite, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	convObjID, err := primitive.ObjectIDFromHex(invite.ConversationID)
+	if err != nil {
+		return nil, errors.New("invalid conversation ID format")
+	}
+
+	mongoInvite := &MongoConversationInvite{
+		ConversationID: convObjID,
+		CreatorID:      invite.CreatorID,
+		Token:          invite.Token,
+		MaxUses:        invite.MaxUses,
+		ExpiresAt:      optionalUnix(invite.ExpiresAt),
+		CreatedAt:      invite.CreatedAt.Unix(),
+	}
+	result, err := ir.collection.InsertOne(ctx, mongoInvite)
+	if err != nil {
+		return nil, err
+	}
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		mongoInvite.ID = oid
+	}
+	return ir.toDomainInvite(*mongoInvite), nil
+}
+
+func (ir *MongoConversationInviteRepository) GetByToken(token string) (*conversation.Invite, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	var mongoInvite MongoConversationInvite
+	err := ir.collection.FindOne(ctx, bson.M{"token": token}).Decode(&mongoInvite)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ir.toDomainInvite(mongoInvite), nil
+}
+
+func (ir *MongoConversationInviteRepository) ListByConversation(conversationID string) ([]*conversation.Invite, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return nil, errors.New("invalid conversation ID format")
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := ir.collection.Find(ctx, bson.M{"conversation_id": convObjID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var mongoInvites []MongoConversationInvite
+	if err = cursor.All(ctx, &mongoInvites); err != nil {
+		return nil, err
+	}
+
+	invites := make([]*conversation.Invite, len(mongoInvites))
+	for i, mongoInvite := range mongoInvites {
+		invites[i] = ir.toDomainInvite(mongoInvite)
+	}
+	return invites, nil
+}
+
+func (ir *MongoConversationInviteRepository) Revoke(inviteID string, conversationID string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	inviteObjID, err := primitive.ObjectIDFromHex(inviteID)
+	if err != nil {
+		return errors.New("invalid invite ID format")
+	}
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return errors.New("invalid conversation ID format")
+	}
+
+	filter := bson.M{"_id": inviteObjID, "conversation_id": convObjID, "revoked_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revoked_at": time.Now().Unix()}}
+	result, err := ir.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("invite not found")
+	}
+	return nil
+}
+
+func (ir *MongoConversationInviteRepository) Use(inviteID string, now time.Time) (bool, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	inviteObjID, err := primitive.ObjectIDFromHex(inviteID)
+	if err != nil {
+		return false, errors.New("invalid invite ID format")
+	}
+
+	// The checks of Invite.IsActive, repeated here so two joins can't both
+	// take the last use
+	filter := bson.M{
+		"_id":        inviteObjID,
+		"revoked_at": bson.M{"$exists": false},
+		"$and": bson.A{
+			bson.M{"$or": bson.A{
+				bson.M{"max_uses": 0},
+				bson.M{"$expr": bson.M{"$lt": bson.A{"$uses", "$max_uses"}}},
+			}},
+			bson.M{"$or": bson.A{
+				bson.M{"expires_at": bson.M{"$exists": false}},
+				bson.M{"expires_at": bson.M{"$gt": now.Unix()}},
+			}},
+		},
+	}
+	result, err := ir.collection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"uses": 1}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (ir *MongoConversationInviteRepository) DeleteByConversation(conversationID string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return errors.New("invalid conversation ID format")
+	}
+
+	_, err = ir.collection.DeleteMany(ctx, bson.M{"conversation_id": convObjID})
+	return err
+}
@@ -2,13 +2,31 @@ package database
 
 import (
 	"backend-chat-app/internal/domain/conversation"
+	"backend-chat-app/internal/infrastructure/database/registry"
+	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+func init() {
+	conversationIndexes := []mongo.IndexModel{
+		{
+			// Conversation lists, most recently active first
+			Keys: bson.D{{Key: "participant._id", Value: 1}, {Key: "update_at", Value: -1}, {Key: "_id", Value: -1}},
+		},
+	}
+
+	registry.RegisterCollection("conversations", conversationIndexes)
+	registry.RegisterMigration("conversations_last_message", backfillLastMessages)
+	registry.RegisterMigration("conversations_direct", markDirectConversations)
+}
+
 type MongoConversationRepository struct {
 	client     *mongo.Client
 	database   string
@@ -42,11 +60,21 @@ func (cr *MongoConversationRepository) Create(conversation conversation.Conversa
 	}
 
 	mongoConversation := &MongoConversation{
+		Name:        conversation.Name,
+		Direct:      conversation.Direct,
 		Participant: mongoParticipants,
 		CreatedAt:   conversation.CreatedAt.Unix(),
 		UpdateAt:    conversation.UpdateAt.Unix(),
 	}
 
+	if conversation.OwnerID != "" {
+		ownerObjID, err := primitive.ObjectIDFromHex(conversation.OwnerID)
+		if err != nil {
+			return nil, err
+		}
+		mongoConversation.OwnerID = ownerObjID
+	}
+
 	result, err := cr.collection.InsertOne(ctx, mongoConversation)
 	if err != nil {
 		return nil, err
@@ -72,9 +100,29 @@ func (cr *MongoConversationRepository) toDomainConversation(mongoConversation Mo
 		}
 	}
 
+	var lastMessage *conversation.LastMessage
+	if last := mongoConversation.LastMessage; last != nil {
+		lastMessage = &conversation.LastMessage{
+			ID:        last.ID.Hex(),
+			SenderID:  last.SenderID.Hex(),
+			Text:      last.Message,
+			CreatedAt: timeFromUnix(last.CreatedAt),
+		}
+	}
+
+	var ownerID string
+	if !mongoConversation.OwnerID.IsZero() {
+		ownerID = mongoConversation.OwnerID.Hex()
+	}
+
 	return &conversation.Conversation{
 		ID:          conversationID,
+		Name:        mongoConversation.Name,
+		OwnerID:     ownerID,
+		Direct:      mongoConversation.Direct,
 		Participant: domainParticipants,
+		LastMessage: lastMessage,
+		MessageTTL:  time.Duration(mongoConversation.MessageTTL) * time.Second,
 		CreatedAt:   timeFromUnix(mongoConversation.CreatedAt),
 		UpdateAt:    timeFromUnix(mongoConversation.UpdateAt),
 	}
@@ -112,10 +160,12 @@ func (cr *MongoConversationRepository) IsCommunicate(participant1ID string, part
 	if err != nil {
 		return false, err
 	}
+	// Groups joined through invite links don't count as talking to each other
 	filter := bson.M{
 		"participant._id": bson.M{
 			"$all": []primitive.ObjectID{object1ID, object2ID},
 		},
+		"direct": true,
 	}
 	var mongoConversation MongoConversation
 	err = cr.collection.FindOne(ctx, filter).Decode(&mongoConversation)
@@ -130,3 +180,434 @@ func (cr *MongoConversationRepository) IsCommunicate(participant1ID string, part
 
 	return true, nil
 }
+
+func (cr *MongoConversationRepository) AddParticipant(conversationID string, participant conversation.Participant) (bool, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return false, err
+	}
+	userObjID, err := primitive.ObjectIDFromHex(participant.ID)
+	if err != nil {
+		return false, err
+	}
+
+	// Filter on the participant being absent so adding twice is a no-op
+	filter := bson.M{"_id": convObjID, "participant._id": bson.M{"$ne": userObjID}}
+	update := bson.M{
+		"$push": bson.M{
+			"participant": Participant{ID: userObjID, Name: participant.Name},
+		},
+		"$set": bson.M{
+			"update_at": time.Now().Unix(),
+		},
+	}
+	result, err := cr.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (cr *MongoConversationRepository) RemoveParticipant(conversationID string, userID string) (*conversation.Conversation, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Filter on the participant being present, so of two concurrent removals
+	// only one gets the conversation back
+	filter := bson.M{"_id": convObjID, "participant._id": userObjID}
+	update := bson.M{
+		"$pull": bson.M{
+			"participant": bson.M{"_id": userObjID},
+		},
+		"$set": bson.M{
+			"update_at": time.Now().Unix(),
+		},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var mongoConversation MongoConversation
+	err = cr.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&mongoConversation)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cr.toDomainConversation(mongoConversation), nil
+}
+
+func (cr *MongoConversationRepository) Rename(conversationID string, name string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return err
+	}
+
+	var update bson.M
+	if name == "" {
+		update = bson.M{"$unset": bson.M{"name": ""}, "$set": bson.M{"update_at": time.Now().Unix()}}
+	} else {
+		update = bson.M{"$set": bson.M{"name": name, "update_at": time.Now().Unix()}}
+	}
+	_, err = cr.collection.UpdateOne(ctx, bson.M{"_id": convObjID}, update)
+	return err
+}
+
+func (cr *MongoConversationRepository) SetOwner(conversationID string, ownerID string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return err
+	}
+	ownerObjID, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return err
+	}
+
+	_, err = cr.collection.UpdateOne(ctx, bson.M{"_id": convObjID}, bson.M{"$set": bson.M{"owner_id": ownerObjID}})
+	return err
+}
+
+func (cr *MongoConversationRepository) Delete(conversationID string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return err
+	}
+
+	_, err = cr.collection.DeleteOne(ctx, bson.M{"_id": convObjID})
+	return err
+}
+
+func (cr *MongoConversationRepository) DeleteIfEmpty(conversationID string) (bool, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := cr.collection.DeleteOne(ctx, bson.M{"_id": convObjID, "participant": bson.M{"$size": 0}})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount == 1, nil
+}
+
+func (cr *MongoConversationRepository) UpdateParticipantName(userID string, name string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"participant._id": userObjID}
+	update := bson.M{
+		"$set": bson.M{
+			"participant.$[p].name": name,
+		},
+	}
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{bson.M{"p._id": userObjID}},
+	})
+	_, err = cr.collection.UpdateMany(ctx, filter, update, opts)
+	return err
+}
+
+func (cr *MongoConversationRepository) ListByParticipant(query conversation.ListQuery) ([]*conversation.Conversation, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(query.UserID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	filter := bson.M{"participant._id": userObjID}
+	idFilter := bson.M{}
+	if query.IDs != nil {
+		idFilter["$in"] = toObjectIDs(query.IDs)
+	}
+	if len(query.ExcludeIDs) > 0 {
+		idFilter["$nin"] = toObjectIDs(query.ExcludeIDs)
+	}
+	if len(idFilter) > 0 {
+		filter["_id"] = idFilter
+	}
+	if query.After != nil {
+		afterID, err := primitive.ObjectIDFromHex(query.After.ID)
+		if err != nil {
+			return nil, errors.New("invalid cursor")
+		}
+		updateAt := query.After.UpdateAt.Unix()
+		filter["$or"] = bson.A{
+			bson.M{"update_at": bson.M{"$lt": updateAt}},
+			bson.M{"update_at": updateAt, "_id": bson.M{"$lt": afterID}},
+		}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "update_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(query.Limit))
+	cursor, err := cr.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var mongoConversations []MongoConversation
+	if err = cursor.All(ctx, &mongoConversations); err != nil {
+		return nil, err
+	}
+
+	conversations := make([]*conversation.Conversation, len(mongoConversations))
+	for i, mongoConversation := range mongoConversations {
+		conversations[i] = cr.toDomainConversation(mongoConversation)
+	}
+	return conversations, nil
+}
+
+func (cr *MongoConversationRepository) SetLastMessage(conversationID string, last conversation.LastMessage) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return err
+	}
+	messageObjID, err := primitive.ObjectIDFromHex(last.ID)
+	if err != nil {
+		return err
+	}
+	senderObjID, err := primitive.ObjectIDFromHex(last.SenderID)
+	if err != nil {
+		return err
+	}
+
+	createdAt := last.CreatedAt.Unix()
+	// Messages sent at the same time may be saved out of order
+	filter := bson.M{
+		"_id": convObjID,
+		"$or": bson.A{
+			bson.M{"last_message": bson.M{"$exists": false}},
+			bson.M{"last_message.created_at": bson.M{"$lte": createdAt}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"last_message": MongoLastMessage{
+				ID:        messageObjID,
+				SenderID:  senderObjID,
+				Message:   last.Text,
+				CreatedAt: createdAt,
+			},
+		},
+		"$max": bson.M{"update_at": createdAt},
+	}
+	_, err = cr.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (cr *MongoConversationRepository) RedactLastMessages(senderID string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	senderObjID, err := primitive.ObjectIDFromHex(senderID)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{"$set": bson.M{"last_message.message": ""}}
+	_, err = cr.collection.UpdateMany(ctx, bson.M{"last_message.sender_id": senderObjID}, update)
+	return err
+}
+
+func (cr *MongoConversationRepository) ReplaceLastMessage(conversationID string, messageID string, last *conversation.LastMessage) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return err
+	}
+	messageObjID, err := primitive.ObjectIDFromHex(messageID)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{"$unset": bson.M{"last_message": ""}}
+	if last != nil {
+		lastObjID, err := primitive.ObjectIDFromHex(last.ID)
+		if err != nil {
+			return err
+		}
+		senderObjID, err := primitive.ObjectIDFromHex(last.SenderID)
+		if err != nil {
+			return err
+		}
+		update = bson.M{"$set": bson.M{"last_message": MongoLastMessage{
+			ID:        lastObjID,
+			SenderID:  senderObjID,
+			Message:   last.Text,
+			CreatedAt: last.CreatedAt.Unix(),
+		}}}
+	}
+	_, err = cr.collection.UpdateOne(ctx, bson.M{"_id": convObjID, "last_message._id": messageObjID}, update)
+	return err
+}
+
+func (cr *MongoConversationRepository) SetMessageTTL(conversationID string, ttl time.Duration) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return err
+	}
+
+	var update bson.M
+	if ttl == 0 {
+		update = bson.M{"$unset": bson.M{"message_ttl": ""}, "$set": bson.M{"update_at": time.Now().Unix()}}
+	} else {
+		update = bson.M{"$set": bson.M{"message_ttl": int64(ttl / time.Second), "update_at": time.Now().Unix()}}
+	}
+	_, err = cr.collection.UpdateOne(ctx, bson.M{"_id": convObjID}, update)
+	return err
+}
+
+func (cr *MongoConversationRepository) ReservePin(conversationID string, limit int) (bool, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return false, err
+	}
+
+	// The limit is part of the filter so concurrent pins can't both take the last slot
+	filter := bson.M{
+		"_id": convObjID,
+		"$or": bson.A{
+			bson.M{"pin_count": bson.M{"$lt": limit}},
+			bson.M{"pin_count": bson.M{"$exists": false}},
+		},
+	}
+	result, err := cr.collection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"pin_count": 1}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (cr *MongoConversationRepository) ReleasePins(conversationID string, n int) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return err
+	}
+
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"pin_count": bson.M{"$max": bson.A{0, bson.M{"$subtract": bson.A{bson.M{"$ifNull": bson.A{"$pin_count", 0}}, n}}}},
+		}}},
+	}
+	_, err = cr.collection.UpdateOne(ctx, bson.M{"_id": convObjID}, update)
+	return err
+}
+
+// markDirectConversations marks conversations created before the flag. They
+// all started one-to-one; those that grew past two participants were treated
+// as groups until now and stay groups.
+func markDirectConversations(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("conversations").UpdateMany(ctx,
+		bson.M{"direct": bson.M{"$exists": false}, "participant.2": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"direct": true}})
+	return err
+}
+
+// backfillLastMessages fills last_message of conversations created before
+// it was kept on the conversation.
+func backfillLastMessages(ctx context.Context, db *mongo.Database) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":        "$conversation_id",
+			"message_id": bson.M{"$first": "$_id"},
+			"sender_id":  bson.M{"$first": "$sender_id"},
+			"message":    bson.M{"$first": "$message"},
+			"created_at": bson.M{"$first": "$created_at"},
+		}}},
+	}
+	cursor, err := db.Collection("messages").Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	conversations := db.Collection("conversations")
+	models := make([]mongo.WriteModel, 0, 500)
+	flush := func() error {
+		if len(models) == 0 {
+			return nil
+		}
+		_, err := conversations.BulkWrite(ctx, models)
+		models = models[:0]
+		return err
+	}
+
+	for cursor.Next(ctx) {
+		var last struct {
+			ConversationID primitive.ObjectID `bson:"_id"`
+			MessageID      primitive.ObjectID `bson:"message_id"`
+			SenderID       primitive.ObjectID `bson:"sender_id"`
+			Message        string             `bson:"message"`
+			CreatedAt      int64              `bson:"created_at"`
+		}
+		if err := cursor.Decode(&last); err != nil {
+			return err
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": last.ConversationID, "last_message": bson.M{"$exists": false}}).
+			SetUpdate(bson.M{
+				"$set": bson.M{"last_message": MongoLastMessage{
+					ID:        last.MessageID,
+					SenderID:  last.SenderID,
+					Message:   last.Message,
+					CreatedAt: last.CreatedAt,
+				}},
+				"$max": bson.M{"update_at": last.CreatedAt},
+			}))
+		if len(models) == cap(models) {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
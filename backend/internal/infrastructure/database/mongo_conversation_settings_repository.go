@@ -0,0 +1,195 @@
+package database
+
+import (
+	"backend-chat-app/internal/domain/conversation"
+	"backend-chat-app/internal/infrastructure/database/registry"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	settingsIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "conversation_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			// Muted users of a conversation when a message is sent
+			Keys: bson.D{{Key: "conversation_id", Value: 1}, {Key: "muted_until", Value: 1}},
+		},
+	}
+
+	registry.RegisterCollection("conversation_settings", settingsIndexes)
+}
+
+// MongoConversationSettingsRepository keeps per-user settings apart from the
+// conversation document, which is shared by all participants.
+type MongoConversationSettingsRepository struct {
+	client     *mongo.Client
+	database   string
+	collection *mongo.Collection
+}
+
+func NewMongoConversationSettingsRepository(client *mongo.Client, database string) *MongoConversationSettingsRepository {
+	collection := client.Database(database).Collection("conversation_settings")
+	return &MongoConversationSettingsRepository{
+		client:     client,
+		database:   database,
+		collection: collection,
+	}
+}
+
+func (sr *MongoConversationSettingsRepository) toDomainSettings(mongoSettings MongoConversationSettings) *conversation.Settings {
+	return &conversation.Settings{
+		UserID:         mongoSettings.UserID.Hex(),
+		ConversationID: mongoSettings.ConversationID.Hex(),
+		MutedUntil:     optionalTimeFromUnix(mongoSettings.MutedUntil),
+		Pinned:         mongoSettings.Pinned,
+		PinnedAt:       optionalTimeFromUnix(mongoSettings.PinnedAt),
+		Archived:       mongoSettings.Archived,
+		UpdateAt:       timeFromUnix(mongoSettings.UpdateAt),
+	}
+}
+
+func (sr *MongoConversationSettingsRepository) Get(userID string, conversationID string) (*conversation.Settings, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return nil, errors.New("invalid conversation ID format")
+	}
+
+	var mongoSettings MongoConversationSettings
+	filter := bson.M{"user_id": userObjID, "conversation_id": convObjID}
+	err = sr.collection.FindOne(ctx, filter).Decode(&mongoSettings)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sr.toDomainSettings(mongoSettings), nil
+}
+
+func (sr *MongoConversationSettingsRepository) ListByUser(userID string) ([]*conversation.Settings, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	cursor, err := sr.collection.Find(ctx, bson.M{"user_id": userObjID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var mongoSettings []MongoConversationSettings
+	if err = cursor.All(ctx, &mongoSettings); err != nil {
+		return nil, err
+	}
+
+	settings := make([]*conversation.Settings, len(mongoSettings))
+	for i, s := range mongoSettings {
+		settings[i] = sr.toDomainSettings(s)
+	}
+	return settings, nil
+}
+
+func (sr *MongoConversationSettingsRepository) Save(settings conversation.Settings) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(settings.UserID)
+	if err != nil {
+		return errors.New("invalid user ID format")
+	}
+	convObjID, err := primitive.ObjectIDFromHex(settings.ConversationID)
+	if err != nil {
+		return errors.New("invalid conversation ID format")
+	}
+
+	filter := bson.M{"user_id": userObjID, "conversation_id": convObjID}
+	update := bson.M{
+		"$set": bson.M{
+			"muted_until": optionalUnix(settings.MutedUntil),
+			"pinned":      settings.Pinned,
+			"pinned_at":   optionalUnix(settings.PinnedAt),
+			"archived":    settings.Archived,
+			"update_at":   settings.UpdateAt.Unix(),
+		},
+	}
+	_, err = sr.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (sr *MongoConversationSettingsRepository) ListMutedUsers(conversationID string, now time.Time) ([]string, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return nil, errors.New("invalid conversation ID format")
+	}
+
+	filter := bson.M{"conversation_id": convObjID, "muted_until": bson.M{"$gt": now.Unix()}}
+	opts := options.Find().SetProjection(bson.M{"user_id": 1})
+	cursor, err := sr.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var mongoSettings []MongoConversationSettings
+	if err = cursor.All(ctx, &mongoSettings); err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]string, len(mongoSettings))
+	for i, s := range mongoSettings {
+		userIDs[i] = s.UserID.Hex()
+	}
+	return userIDs, nil
+}
+
+func (sr *MongoConversationSettingsRepository) Delete(userID string, conversationID string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user ID format")
+	}
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return errors.New("invalid conversation ID format")
+	}
+
+	_, err = sr.collection.DeleteOne(ctx, bson.M{"user_id": userObjID, "conversation_id": convObjID})
+	return err
+}
+
+func (sr *MongoConversationSettingsRepository) DeleteByConversation(conversationID string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return errors.New("invalid conversation ID format")
+	}
+
+	_, err = sr.collection.DeleteMany(ctx, bson.M{"conversation_id": convObjID})
+	return err
+}
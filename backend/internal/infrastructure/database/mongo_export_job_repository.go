@@ -0,0 +1,269 @@
+package database
+
+import (
+	"backend-chat-app/internal/domain/account"
+	"backend-chat-app/internal/infrastructure/database/registry"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	exportIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+		{
+			// At most one pending or running export per user
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+			Options: options.Index().SetName("user_in_progress_unique").SetUnique(true).
+				SetPartialFilterExpression(bson.M{"in_progress": true}),
+		},
+	}
+
+	registry.RegisterCollection("export_jobs", exportIndexes)
+	registry.RegisterMigration("export_jobs_in_progress", markExportsInProgress)
+}
+
+type MongoExportJobRepository struct {
+	client     *mongo.Client
+	database   string
+	collection *mongo.Collection
+}
+
+func NewMongoExportJobRepository(client *mongo.Client, database string) *MongoExportJobRepository {
+	collection := client.Database(database).Collection("export_jobs")
+	return &MongoExportJobRepository{
+		client:     client,
+		database:   database,
+		collection: collection,
+	}
+}
+
+func (er *MongoExportJobRepository) Create(job account.ExportJob) (*account.ExportJob, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(job.UserID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	mongoJob := &MongoExportJob{
+		UserID:     userObjID,
+		Status:     job.Status,
+		InProgress: true,
+		CreatedAt:  job.CreatedAt.Unix(),
+	}
+	result, err := er.collection.InsertOne(ctx, mongoJob)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, account.ErrExportInProgress
+	}
+	if err != nil {
+		return nil, err
+	}
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		mongoJob.ID = oid
+	}
+	return er.toDomainJob(*mongoJob), nil
+}
+
+func (er *MongoExportJobRepository) toDomainJob(mongoJob MongoExportJob) *account.ExportJob {
+	return &account.ExportJob{
+		ID:          mongoJob.ID.Hex(),
+		UserID:      mongoJob.UserID.Hex(),
+		Status:      mongoJob.Status,
+		BlobKey:     mongoJob.BlobKey,
+		Size:        mongoJob.Size,
+		Error:       mongoJob.Error,
+		CreatedAt:   timeFromUnix(mongoJob.CreatedAt),
+		CompletedAt: optionalTimeFromUnix(mongoJob.CompletedAt),
+		ExpiresAt:   optionalTimeFromUnix(mongoJob.ExpiresAt),
+	}
+}
+
+func (er *MongoExportJobRepository) GetByID(jobID string) (*account.ExportJob, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		return nil, errors.New("invalid export ID format")
+	}
+
+	var mongoJob MongoExportJob
+	err = er.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&mongoJob)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return er.toDomainJob(mongoJob), nil
+}
+
+func (er *MongoExportJobRepository) ListByUser(userID string) ([]*account.ExportJob, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(20)
+	return er.find(ctx, bson.M{"user_id": userObjID}, opts)
+}
+
+func (er *MongoExportJobRepository) ClaimPending() (*account.ExportJob, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "created_at", Value: 1}}).
+		SetReturnDocument(options.After)
+	update := bson.M{"$set": bson.M{"status": account.ExportRunning}}
+
+	var mongoJob MongoExportJob
+	err := er.collection.FindOneAndUpdate(ctx, bson.M{"status": account.ExportPending}, update, opts).Decode(&mongoJob)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return er.toDomainJob(mongoJob), nil
+}
+
+func (er *MongoExportJobRepository) Complete(jobID string, blobKey string, size int64, expiresAt time.Time) error {
+	return er.finish(jobID, bson.M{
+		"status":       account.ExportReady,
+		"blob_key":     blobKey,
+		"size":         size,
+		"completed_at": time.Now().Unix(),
+		"expires_at":   expiresAt.Unix(),
+	})
+}
+
+func (er *MongoExportJobRepository) Fail(jobID string, reason string) error {
+	return er.finish(jobID, bson.M{
+		"status":       account.ExportFailed,
+		"error":        reason,
+		"completed_at": time.Now().Unix(),
+	})
+}
+
+func (er *MongoExportJobRepository) ResetRunning() error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"status": account.ExportPending}}
+	_, err := er.collection.UpdateMany(ctx, bson.M{"status": account.ExportRunning}, update)
+	return err
+}
+
+func (er *MongoExportJobRepository) ListExpired(now time.Time) ([]*account.ExportJob, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	filter := bson.M{"status": account.ExportReady, "expires_at": bson.M{"$lte": now.Unix()}}
+	return er.find(ctx, filter, options.Find().SetLimit(100))
+}
+
+func (er *MongoExportJobRepository) MarkExpired(jobID string) error {
+	return er.update(jobID, bson.M{"status": account.ExportExpired})
+}
+
+func (er *MongoExportJobRepository) DeleteByUser(userID string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user ID format")
+	}
+
+	_, err = er.collection.DeleteMany(ctx, bson.M{"user_id": userObjID})
+	return err
+}
+
+func (er *MongoExportJobRepository) update(jobID string, fields bson.M) error {
+	return er.updateOne(jobID, bson.M{"$set": fields})
+}
+
+// finish sets fields of a job that is done and lets the user request the
+// next export.
+func (er *MongoExportJobRepository) finish(jobID string, fields bson.M) error {
+	return er.updateOne(jobID, bson.M{"$set": fields, "$unset": bson.M{"in_progress": ""}})
+}
+
+func (er *MongoExportJobRepository) updateOne(jobID string, update bson.M) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		return errors.New("invalid export ID format")
+	}
+
+	_, err = er.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	return err
+}
+
+// markExportsInProgress flags jobs queued before in_progress existed. Only
+// the newest job of each user is flagged, so users who already got two
+// through the old check don't break the unique index; the older one still
+// runs.
+func markExportsInProgress(ctx context.Context, db *mongo.Database) error {
+	collection := db.Collection("export_jobs")
+	filter := bson.M{"status": bson.M{"$in": bson.A{account.ExportPending, account.ExportRunning}}}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetProjection(bson.M{"user_id": 1})
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	flagged := make(map[primitive.ObjectID]bool)
+	for cursor.Next(ctx) {
+		var mongoJob MongoExportJob
+		if err := cursor.Decode(&mongoJob); err != nil {
+			return err
+		}
+		if flagged[mongoJob.UserID] {
+			continue
+		}
+		flagged[mongoJob.UserID] = true
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": mongoJob.ID}, bson.M{"$set": bson.M{"in_progress": true}}); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+func (er *MongoExportJobRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*account.ExportJob, error) {
+	cursor, err := er.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var mongoJobs []MongoExportJob
+	if err = cursor.All(ctx, &mongoJobs); err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*account.ExportJob, len(mongoJobs))
+	for i, mongoJob := range mongoJobs {
+		jobs[i] = er.toDomainJob(mongoJob)
+	}
+	return jobs, nil
+}
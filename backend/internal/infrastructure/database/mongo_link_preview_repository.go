@@ -0,0 +1,88 @@
+package database
+
+import (
+	"backend-chat-app/internal/domain/linkpreview"
+	"backend-chat-app/internal/infrastructure/database/registry"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	previewIndexes := []mongo.IndexModel{
+		{
+			// Expired entries are swept by age
+			Keys: bson.D{{Key: "fetched_at", Value: 1}},
+		},
+	}
+
+	registry.RegisterCollection("link_previews", previewIndexes)
+}
+
+// MongoLinkPreviewRepository caches fetched pages by URL, so a link shared in
+// many conversations is fetched once.
+type MongoLinkPreviewRepository struct {
+	client     *mongo.Client
+	database   string
+	collection *mongo.Collection
+}
+
+func NewMongoLinkPreviewRepository(client *mongo.Client, database string) *MongoLinkPreviewRepository {
+	collection := client.Database(database).Collection("link_previews")
+	return &MongoLinkPreviewRepository{
+		client:     client,
+		database:   database,
+		collection: collection,
+	}
+}
+
+func (pr *MongoLinkPreviewRepository) Get(url string) (*linkpreview.Preview, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	var cached MongoCachedLinkPreview
+	err := pr.collection.FindOne(ctx, bson.M{"_id": url}).Decode(&cached)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &linkpreview.Preview{
+		URL:         cached.URL,
+		Title:       cached.Title,
+		Description: cached.Description,
+		ImageURL:    cached.ImageURL,
+		SiteName:    cached.SiteName,
+		Failed:      cached.Failed,
+		FetchedAt:   timeFromUnix(cached.FetchedAt),
+	}, nil
+}
+
+func (pr *MongoLinkPreviewRepository) Save(preview linkpreview.Preview) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	cached := MongoCachedLinkPreview{
+		URL:         preview.URL,
+		Title:       preview.Title,
+		Description: preview.Description,
+		ImageURL:    preview.ImageURL,
+		SiteName:    preview.SiteName,
+		Failed:      preview.Failed,
+		FetchedAt:   preview.FetchedAt.Unix(),
+	}
+	opts := options.Replace().SetUpsert(true)
+	_, err := pr.collection.ReplaceOne(ctx, bson.M{"_id": preview.URL}, cached, opts)
+	return err
+}
+
+func (pr *MongoLinkPreviewRepository) DeleteFetchedBefore(t time.Time) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	_, err := pr.collection.DeleteMany(ctx, bson.M{"fetched_at": bson.M{"$lt": t.Unix()}})
+	return err
+}
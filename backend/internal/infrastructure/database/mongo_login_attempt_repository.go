@@ -0,0 +1,119 @@
+package database
+
+import (
+	"backend-chat-app/internal/domain/security"
+	"backend-chat-app/internal/infrastructure/database/registry"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	attemptIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	registry.RegisterCollection("login_attempts", attemptIndexes)
+}
+
+type MongoLoginAttemptRepository struct {
+	client     *mongo.Client
+	database   string
+	collection *mongo.Collection
+}
+
+func NewMongoLoginAttemptRepository(client *mongo.Client, database string) *MongoLoginAttemptRepository {
+	collection := client.Database(database).Collection("login_attempts")
+	return &MongoLoginAttemptRepository{
+		client:     client,
+		database:   database,
+		collection: collection,
+	}
+}
+
+func (ar *MongoLoginAttemptRepository) toDomainAttempt(mongoAttempt MongoLoginAttempt) *security.LoginAttempt {
+	return &security.LoginAttempt{
+		Key:           mongoAttempt.Key,
+		Failures:      mongoAttempt.Failures,
+		LastFailureAt: timeFromUnix(mongoAttempt.LastFailureAt),
+		Allowed:       mongoAttempt.Allowed,
+	}
+}
+
+func (ar *MongoLoginAttemptRepository) RecordAttempt(key string, at time.Time, resetAfter time.Duration, waits []time.Duration) (*security.LoginAttempt, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	// Whole seconds, rounded up, to match last_failure_at
+	waitSeconds := bson.A{int64(0)}
+	keep := resetAfter
+	if len(waits) > 0 {
+		waitSeconds = bson.A{}
+		for _, wait := range waits {
+			waitSeconds = append(waitSeconds, int64((wait+time.Second-1)/time.Second))
+			if wait > keep {
+				keep = wait
+			}
+		}
+	}
+	now := at.Unix()
+	lastFailureAt := bson.M{"$ifNull": bson.A{"$last_failure_at", 0}}
+
+	// One pipeline update so that parallel attempts are counted one after
+	// another and each one is checked against those before it
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"prior": bson.M{
+				"$cond": bson.A{
+					bson.M{"$lt": bson.A{lastFailureAt, at.Add(-resetAfter).Unix()}},
+					0,
+					"$failures",
+				},
+			},
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"allowed": bson.M{
+				"$gte": bson.A{now, bson.M{"$add": bson.A{
+					lastFailureAt,
+					bson.M{"$arrayElemAt": bson.A{waitSeconds, bson.M{"$min": bson.A{"$prior", len(waitSeconds) - 1}}}},
+				}}},
+			},
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"failures":        bson.M{"$cond": bson.A{"$allowed", bson.M{"$add": bson.A{"$prior", 1}}, "$failures"}},
+			"last_failure_at": bson.M{"$cond": bson.A{"$allowed", now, "$last_failure_at"}},
+			"expires_at":      bson.M{"$cond": bson.A{"$allowed", at.Add(keep), "$expires_at"}},
+		}}},
+		{{Key: "$unset", Value: bson.A{"prior", "locked_until"}}},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var mongoAttempt MongoLoginAttempt
+	err := ar.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&mongoAttempt)
+	if err != nil {
+		return nil, err
+	}
+	return ar.toDomainAttempt(mongoAttempt), nil
+}
+
+func (ar *MongoLoginAttemptRepository) Release(key string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	filter := bson.M{"_id": key, "failures": bson.M{"$gt": 0}}
+	_, err := ar.collection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"failures": -1}})
+	return err
+}
+
+func (ar *MongoLoginAttemptRepository) Delete(key string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	_, err := ar.collection.DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
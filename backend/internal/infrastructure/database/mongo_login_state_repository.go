@@ -0,0 +1,71 @@
+package database
+
+import (
+	"backend-chat-app/internal/domain/sso"
+	"backend-chat-app/internal/infrastructure/database/registry"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	stateIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	registry.RegisterCollection("sso_login_states", stateIndexes)
+}
+
+type MongoLoginStateRepository struct {
+	client     *mongo.Client
+	database   string
+	collection *mongo.Collection
+}
+
+func NewMongoLoginStateRepository(client *mongo.Client, database string) *MongoLoginStateRepository {
+	collection := client.Database(database).Collection("sso_login_states")
+	return &MongoLoginStateRepository{
+		client:     client,
+		database:   database,
+		collection: collection,
+	}
+}
+
+func (sr *MongoLoginStateRepository) Create(state sso.LoginState) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	_, err := sr.collection.InsertOne(ctx, &MongoLoginState{
+		State:        state.State,
+		Nonce:        state.Nonce,
+		CodeVerifier: state.CodeVerifier,
+		ExpiresAt:    state.ExpiresAt,
+		CreatedAt:    state.CreatedAt.Unix(),
+	})
+	return err
+}
+
+func (sr *MongoLoginStateRepository) Consume(state string) (*sso.LoginState, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	var mongoState MongoLoginState
+	err := sr.collection.FindOneAndDelete(ctx, bson.M{"_id": state}).Decode(&mongoState)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sso.LoginState{
+		State:        mongoState.State,
+		Nonce:        mongoState.Nonce,
+		CodeVerifier: mongoState.CodeVerifier,
+		ExpiresAt:    mongoState.ExpiresAt,
+		CreatedAt:    timeFromUnix(mongoState.CreatedAt),
+	}, nil
+}
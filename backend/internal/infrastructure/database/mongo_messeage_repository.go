@@ -2,12 +2,43 @@ package database
 
 import (
 	"backend-chat-app/internal/domain/message"
+	"backend-chat-app/internal/domain/user"
+	"backend-chat-app/internal/infrastructure/database/registry"
+	"context"
+	"errors"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+func init() {
+	messageIndexes := []mongo.IndexModel{
+		{
+			// History of a conversation
+			Keys: bson.D{{Key: "conversation_id", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+		{
+			// Mentions feed of a user, newest first
+			Keys: bson.D{{Key: "mentioned_ids", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			// Disappearing messages, for the sweeper
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			// Open polls with a closing time, for the closing worker
+			Keys:    bson.D{{Key: "poll.close_due_at", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+	}
+
+	registry.RegisterCollection("messages", messageIndexes)
+}
+
 type MongoMessageRepository struct {
 	client     *mongo.Client
 	database   string
@@ -36,11 +67,90 @@ func (mm *MongoMessageRepository) Create(message message.Message) (*message.Mess
 	if err != nil {
 		return nil, err
 	}
+	attachmentObjectIDs := make([]primitive.ObjectID, 0, len(message.AttachmentIDs))
+	for _, id := range message.AttachmentIDs {
+		objectID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, err
+		}
+		attachmentObjectIDs = append(attachmentObjectIDs, objectID)
+	}
+	var mentions []MongoMention
+	for _, m := range message.Mentions {
+		mention := MongoMention{Offset: m.Offset, Length: m.Length}
+		if !m.IsAll() {
+			mention.UserID, err = primitive.ObjectIDFromHex(m.UserID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		mentions = append(mentions, mention)
+	}
+	var mentionedObjectIDs []primitive.ObjectID
+	for _, id := range message.MentionedIDs {
+		objectID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, err
+		}
+		mentionedObjectIDs = append(mentionedObjectIDs, objectID)
+	}
+	var entities []MongoEntity
+	for _, e := range message.Entities {
+		entities = append(entities, MongoEntity{Type: e.Type, Offset: e.Offset, Length: e.Length, URL: e.URL, Language: e.Language})
+	}
+	var forward *MongoForward
+	if message.ForwardedFrom != nil {
+		forwardSenderID, err := primitive.ObjectIDFromHex(message.ForwardedFrom.SenderID)
+		if err != nil {
+			return nil, err
+		}
+		forward = &MongoForward{
+			SenderID:   forwardSenderID,
+			SenderName: message.ForwardedFrom.SenderName,
+			CreatedAt:  message.ForwardedFrom.CreatedAt.Unix(),
+		}
+	}
+	var poll *MongoPoll
+	if p := message.Poll; p != nil {
+		poll = &MongoPoll{
+			Options:    p.Options,
+			Multiple:   p.Multiple,
+			Anonymous:  p.Anonymous,
+			ClosesAt:   optionalUnix(p.ClosesAt),
+			CloseDueAt: optionalUnix(p.ClosesAt),
+			Votes:      []MongoPollVote{},
+		}
+	}
+	var previews []MongoLinkPreview
+	for _, p := range message.LinkPreviews {
+		previews = append(previews, MongoLinkPreview{
+			URL:         p.URL,
+			Title:       p.Title,
+			Description: p.Description,
+			ImageURL:    p.ImageURL,
+			SiteName:    p.SiteName,
+		})
+	}
 	mongoMess := &MongoMessage{
 		ConversationID: convObjectID,
 		Sender:         senderObjectID,
+		Type:           message.Type,
 		Message:        message.Message,
+		AttachmentIDs:  attachmentObjectIDs,
+		Mentions:       mentions,
+		MentionedIDs:   mentionedObjectIDs,
+		Entities:       entities,
+		LinkPreviews:   previews,
+		ForwardedFrom:  forward,
+		Poll:           poll,
 		CreatedAt:      message.CreatedAt.Unix(),
+		ExpiresAt:      optionalUnix(message.ExpiresAt),
+	}
+	if message.ID != "" {
+		mongoMess.ID, err = primitive.ObjectIDFromHex(message.ID)
+		if err != nil {
+			return nil, errors.New("invalid message ID format")
+		}
 	}
 	result, err := mm.collection.InsertOne(ctx, mongoMess)
 	if err != nil {
@@ -53,12 +163,112 @@ func (mm *MongoMessageRepository) Create(message message.Message) (*message.Mess
 }
 
 func (mm *MongoMessageRepository) toDomainMessage(mongoMessage MongoMessage) *message.Message {
+	var attachmentIDs []string
+	for _, id := range mongoMessage.AttachmentIDs {
+		attachmentIDs = append(attachmentIDs, id.Hex())
+	}
+	var previews []message.LinkPreview
+	for _, p := range mongoMessage.LinkPreviews {
+		previews = append(previews, message.LinkPreview{
+			URL:         p.URL,
+			Title:       p.Title,
+			Description: p.Description,
+			ImageURL:    p.ImageURL,
+			SiteName:    p.SiteName,
+		})
+	}
+	var mentions []message.Mention
+	for _, m := range mongoMessage.Mentions {
+		mention := message.Mention{Offset: m.Offset, Length: m.Length}
+		if !m.UserID.IsZero() {
+			mention.UserID = m.UserID.Hex()
+		}
+		mentions = append(mentions, mention)
+	}
+	var mentionedIDs []string
+	for _, id := range mongoMessage.MentionedIDs {
+		mentionedIDs = append(mentionedIDs, id.Hex())
+	}
+	var entities []message.Entity
+	for _, e := range mongoMessage.Entities {
+		entities = append(entities, message.Entity{Type: e.Type, Offset: e.Offset, Length: e.Length, URL: e.URL, Language: e.Language})
+	}
+	var forward *message.Forward
+	if f := mongoMessage.ForwardedFrom; f != nil {
+		forward = &message.Forward{SenderID: f.SenderID.Hex(), SenderName: f.SenderName, CreatedAt: timeFromUnix(f.CreatedAt)}
+	}
+	var poll *message.Poll
+	if p := mongoMessage.Poll; p != nil {
+		poll = &message.Poll{
+			Options:   p.Options,
+			Multiple:  p.Multiple,
+			Anonymous: p.Anonymous,
+			ClosesAt:  optionalTimeFromUnix(p.ClosesAt),
+			ClosedAt:  optionalTimeFromUnix(p.ClosedAt),
+		}
+		for _, v := range p.Votes {
+			poll.Votes = append(poll.Votes, message.PollVote{UserID: v.UserID.Hex(), OptionIDs: v.OptionIDs, VotedAt: timeFromUnix(v.VotedAt)})
+		}
+	}
 	return &message.Message{
+		ID:             mongoMessage.ID.Hex(),
 		ConversationID: mongoMessage.ConversationID.Hex(),
 		SenderID:       mongoMessage.Sender.Hex(),
+		Type:           mongoMessage.Type,
 		Message:        mongoMessage.Message,
+		AttachmentIDs:  attachmentIDs,
+		LinkPreviews:   previews,
+		Mentions:       mentions,
+		MentionedIDs:   mentionedIDs,
+		Entities:       entities,
+		ForwardedFrom:  forward,
+		Poll:           poll,
+		Redacted:       mongoMessage.Redacted,
 		CreatedAt:      timeFromUnix(mongoMessage.CreatedAt),
+		ExpiresAt:      optionalTimeFromUnix(mongoMessage.ExpiresAt),
+	}
+}
+
+func (mm *MongoMessageRepository) GetByID(messageID string) (*message.Message, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(messageID)
+	if err != nil {
+		return nil, errors.New("invalid message ID format")
 	}
+
+	var mongoMessage MongoMessage
+	err = mm.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&mongoMessage)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mm.toDomainMessage(mongoMessage), nil
+}
+
+func (mm *MongoMessageRepository) GetByIDs(messageIDs []string) ([]*message.Message, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	cursor, err := mm.collection.Find(ctx, bson.M{"_id": bson.M{"$in": toObjectIDs(messageIDs)}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var mongoMessages []MongoMessage
+	if err = cursor.All(ctx, &mongoMessages); err != nil {
+		return nil, err
+	}
+
+	messages := make([]*message.Message, len(mongoMessages))
+	for i, mongoMess := range mongoMessages {
+		messages[i] = mm.toDomainMessage(mongoMess)
+	}
+	return messages, nil
 }
 
 func (mm *MongoMessageRepository) GetMessagesByConversationID(conversationID string) ([]*message.Message, error) {
@@ -88,3 +298,279 @@ func (mm *MongoMessageRepository) GetMessagesByConversationID(conversationID str
 
 	return messages, nil
 }
+
+func (mm *MongoMessageRepository) ListMentions(query message.MentionQuery) ([]*message.Message, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(query.UserID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	filter := bson.M{
+		"mentioned_ids":   userObjID,
+		"conversation_id": bson.M{"$in": toObjectIDs(query.ConversationIDs)},
+	}
+	if query.After != nil {
+		afterID, err := primitive.ObjectIDFromHex(query.After.ID)
+		if err != nil {
+			return nil, errors.New("invalid cursor")
+		}
+		createdAt := query.After.CreatedAt.Unix()
+		filter["$or"] = bson.A{
+			bson.M{"created_at": bson.M{"$lt": createdAt}},
+			bson.M{"created_at": createdAt, "_id": bson.M{"$lt": afterID}},
+		}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(query.Limit))
+	cursor, err := mm.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var mongoMessages []MongoMessage
+	if err = cursor.All(ctx, &mongoMessages); err != nil {
+		return nil, err
+	}
+
+	messages := make([]*message.Message, len(mongoMessages))
+	for i, mongoMess := range mongoMessages {
+		messages[i] = mm.toDomainMessage(mongoMess)
+	}
+	return messages, nil
+}
+
+func (mm *MongoMessageRepository) RedactBySender(senderID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	senderObjectID, err := primitive.ObjectIDFromHex(senderID)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{
+		"$set":   bson.M{"message": "", "redacted": true},
+		"$unset": bson.M{"attachment_ids": "", "link_previews": "", "mentions": "", "mentioned_ids": "", "entities": "", "forwarded_from": "", "poll": ""},
+	}
+	if _, err = mm.collection.UpdateMany(ctx, bson.M{"sender_id": senderObjectID}, update); err != nil {
+		return err
+	}
+	// Forwarded copies keep the text, which others chose to share, but not the name
+	forwarded := bson.M{"$set": bson.M{"forwarded_from.sender_name": user.DeletedUserName}}
+	_, err = mm.collection.UpdateMany(ctx, bson.M{"forwarded_from.sender_id": senderObjectID}, forwarded)
+	return err
+}
+
+func (mm *MongoMessageRepository) SetLinkPreviews(messageID string, previews []message.LinkPreview) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(messageID)
+	if err != nil {
+		return errors.New("invalid message ID format")
+	}
+
+	mongoPreviews := make([]MongoLinkPreview, 0, len(previews))
+	for _, p := range previews {
+		mongoPreviews = append(mongoPreviews, MongoLinkPreview{
+			URL:         p.URL,
+			Title:       p.Title,
+			Description: p.Description,
+			ImageURL:    p.ImageURL,
+			SiteName:    p.SiteName,
+		})
+	}
+	// Redacted messages keep no content
+	filter := bson.M{"_id": objectID, "redacted": bson.M{"$ne": true}}
+	_, err = mm.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"link_previews": mongoPreviews}})
+	return err
+}
+
+// openPollFilter matches the poll while it takes votes at now.
+func openPollFilter(messageID primitive.ObjectID, now time.Time) bson.M {
+	return bson.M{
+		"_id":            messageID,
+		"type":           message.TypePoll,
+		"poll.closed_at": bson.M{"$exists": false},
+		"$or": bson.A{
+			bson.M{"poll.closes_at": bson.M{"$exists": false}},
+			bson.M{"poll.closes_at": bson.M{"$gt": now.Unix()}},
+		},
+	}
+}
+
+// votesWithout is the poll's votes minus the user's, as an aggregation
+// expression.
+func votesWithout(userID primitive.ObjectID) bson.M {
+	return bson.M{"$filter": bson.M{
+		"input": "$poll.votes",
+		"cond":  bson.M{"$ne": bson.A{"$$this.user_id", userID}},
+	}}
+}
+
+// updatePoll applies a pipeline update to an open poll and returns it.
+func (mm *MongoMessageRepository) updatePoll(messageID string, now time.Time, update mongo.Pipeline) (*message.Message, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(messageID)
+	if err != nil {
+		return nil, errors.New("invalid message ID format")
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var mongoMessage MongoMessage
+	err = mm.collection.FindOneAndUpdate(ctx, openPollFilter(objectID, now), update, opts).Decode(&mongoMessage)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mm.toDomainMessage(mongoMessage), nil
+}
+
+func (mm *MongoMessageRepository) VotePoll(messageID string, vote message.PollVote) (*message.Message, error) {
+	userObjID, err := primitive.ObjectIDFromHex(vote.UserID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	// Replacing the vote in one update keeps concurrent votes of the user
+	// from both being counted
+	newVote := bson.M{"user_id": userObjID, "option_ids": vote.OptionIDs, "voted_at": vote.VotedAt.Unix()}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"poll.votes": bson.M{"$concatArrays": bson.A{votesWithout(userObjID), bson.A{newVote}}},
+	}}}}
+	return mm.updatePoll(messageID, vote.VotedAt, update)
+}
+
+func (mm *MongoMessageRepository) RetractPollVote(messageID string, userID string, now time.Time) (*message.Message, error) {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{"poll.votes": votesWithout(userObjID)}}}}
+	return mm.updatePoll(messageID, now, update)
+}
+
+func (mm *MongoMessageRepository) ClosePoll(messageID string, closedAt time.Time) (*message.Message, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(messageID)
+	if err != nil {
+		return nil, errors.New("invalid message ID format")
+	}
+
+	filter := bson.M{"_id": objectID, "type": message.TypePoll, "poll.closed_at": bson.M{"$exists": false}}
+	update := bson.M{
+		"$set":   bson.M{"poll.closed_at": closedAt.Unix()},
+		"$unset": bson.M{"poll.close_due_at": ""},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var mongoMessage MongoMessage
+	err = mm.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&mongoMessage)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mm.toDomainMessage(mongoMessage), nil
+}
+
+func (mm *MongoMessageRepository) ListPollsToClose(now time.Time, limit int) ([]*message.Message, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "poll.close_due_at", Value: 1}}).SetLimit(int64(limit))
+	cursor, err := mm.collection.Find(ctx, bson.M{"poll.close_due_at": bson.M{"$lte": now.Unix()}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var mongoMessages []MongoMessage
+	if err = cursor.All(ctx, &mongoMessages); err != nil {
+		return nil, err
+	}
+
+	messages := make([]*message.Message, len(mongoMessages))
+	for i, mongoMess := range mongoMessages {
+		messages[i] = mm.toDomainMessage(mongoMess)
+	}
+	return messages, nil
+}
+
+func (mm *MongoMessageRepository) GetLatest(conversationID string) (*message.Message, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return nil, errors.New("invalid conversation ID format")
+	}
+
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	var mongoMessage MongoMessage
+	err = mm.collection.FindOne(ctx, bson.M{"conversation_id": objectID}, opts).Decode(&mongoMessage)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mm.toDomainMessage(mongoMessage), nil
+}
+
+func (mm *MongoMessageRepository) ListExpired(now time.Time, limit int) ([]*message.Message, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "expires_at", Value: 1}}).SetLimit(int64(limit))
+	cursor, err := mm.collection.Find(ctx, bson.M{"expires_at": bson.M{"$lte": now.Unix()}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var mongoMessages []MongoMessage
+	if err = cursor.All(ctx, &mongoMessages); err != nil {
+		return nil, err
+	}
+
+	messages := make([]*message.Message, len(mongoMessages))
+	for i, mongoMess := range mongoMessages {
+		messages[i] = mm.toDomainMessage(mongoMess)
+	}
+	return messages, nil
+}
+
+func (mm *MongoMessageRepository) DeleteByIDs(messageIDs []string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	_, err := mm.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": toObjectIDs(messageIDs)}})
+	return err
+}
+
+func (mm *MongoMessageRepository) DeleteByConversation(conversationID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return err
+	}
+
+	_, err = mm.collection.DeleteMany(ctx, bson.M{"conversation_id": objectID})
+	return err
+}
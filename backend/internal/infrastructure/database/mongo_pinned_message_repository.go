@@ -0,0 +1,219 @@
+package database
+
+import (
+	"backend-chat-app/internal/domain/message"
+	"backend-chat-app/internal/infrastructure/database/registry"
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	pinIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "conversation_id", Value: 1}, {Key: "message_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			// Pins of a conversation, most recent first
+			Keys: bson.D{{Key: "conversation_id", Value: 1}, {Key: "pinned_at", Value: -1}},
+		},
+	}
+
+	registry.RegisterCollection("pinned_messages", pinIndexes)
+	registry.RegisterMigration("conversations_pin_count", backfillPinCounts)
+}
+
+type MongoPinnedMessageRepository struct {
+	client     *mongo.Client
+	database   string
+	collection *mongo.Collection
+}
+
+func NewMongoPinnedMessageRepository(client *mongo.Client, database string) *MongoPinnedMessageRepository {
+	collection := client.Database(database).Collection("pinned_messages")
+	return &MongoPinnedMessageRepository{
+		client:     client,
+		database:   database,
+		collection: collection,
+	}
+}
+
+func (pr *MongoPinnedMessageRepository) toDomainPin(mongoPin MongoPinnedMessage) *message.Pin {
+	return &message.Pin{
+		ConversationID: mongoPin.ConversationID.Hex(),
+		MessageID:      mongoPin.MessageID.Hex(),
+		PinnedBy:       mongoPin.PinnedBy.Hex(),
+		PinnedAt:       timeFromUnix(mongoPin.PinnedAt),
+	}
+}
+
+func (pr *MongoPinnedMessageRepository) Create(pin message.Pin) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	convObjID, err := primitive.ObjectIDFromHex(pin.ConversationID)
+	if err != nil {
+		return errors.New("invalid conversation ID format")
+	}
+	msgObjID, err := primitive.ObjectIDFromHex(pin.MessageID)
+	if err != nil {
+		return errors.New("invalid message ID format")
+	}
+	userObjID, err := primitive.ObjectIDFromHex(pin.PinnedBy)
+	if err != nil {
+		return errors.New("invalid user ID format")
+	}
+
+	_, err = pr.collection.InsertOne(ctx, &MongoPinnedMessage{
+		ConversationID: convObjID,
+		MessageID:      msgObjID,
+		PinnedBy:       userObjID,
+		PinnedAt:       pin.PinnedAt.Unix(),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return errors.New("message is already pinned")
+	}
+	return err
+}
+
+func (pr *MongoPinnedMessageRepository) Get(conversationID string, messageID string) (*message.Pin, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return nil, errors.New("invalid conversation ID format")
+	}
+	msgObjID, err := primitive.ObjectIDFromHex(messageID)
+	if err != nil {
+		return nil, errors.New("invalid message ID format")
+	}
+
+	var mongoPin MongoPinnedMessage
+	err = pr.collection.FindOne(ctx, bson.M{"conversation_id": convObjID, "message_id": msgObjID}).Decode(&mongoPin)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pr.toDomainPin(mongoPin), nil
+}
+
+func (pr *MongoPinnedMessageRepository) ListByConversation(conversationID string) ([]*message.Pin, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return nil, errors.New("invalid conversation ID format")
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "pinned_at", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := pr.collection.Find(ctx, bson.M{"conversation_id": convObjID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var mongoPins []MongoPinnedMessage
+	if err = cursor.All(ctx, &mongoPins); err != nil {
+		return nil, err
+	}
+
+	pins := make([]*message.Pin, len(mongoPins))
+	for i, mongoPin := range mongoPins {
+		pins[i] = pr.toDomainPin(mongoPin)
+	}
+	return pins, nil
+}
+
+func (pr *MongoPinnedMessageRepository) Delete(conversationID string, messageID string) (bool, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return false, errors.New("invalid conversation ID format")
+	}
+	msgObjID, err := primitive.ObjectIDFromHex(messageID)
+	if err != nil {
+		return false, errors.New("invalid message ID format")
+	}
+
+	result, err := pr.collection.DeleteOne(ctx, bson.M{"conversation_id": convObjID, "message_id": msgObjID})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount == 1, nil
+}
+
+func (pr *MongoPinnedMessageRepository) DeleteByMessages(conversationID string, messageIDs []string) (int, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return 0, errors.New("invalid conversation ID format")
+	}
+
+	filter := bson.M{"conversation_id": convObjID, "message_id": bson.M{"$in": toObjectIDs(messageIDs)}}
+	result, err := pr.collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.DeletedCount), nil
+}
+
+func (pr *MongoPinnedMessageRepository) DeleteByConversation(conversationID string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return errors.New("invalid conversation ID format")
+	}
+
+	_, err = pr.collection.DeleteMany(ctx, bson.M{"conversation_id": convObjID})
+	return err
+}
+
+// backfillPinCounts sets pin_count of conversations pinned before the count
+// was kept on the conversation.
+func backfillPinCounts(ctx context.Context, db *mongo.Database) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": "$conversation_id", "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := db.Collection("pinned_messages").Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var models []mongo.WriteModel
+	for cursor.Next(ctx) {
+		var pinned struct {
+			ConversationID primitive.ObjectID `bson:"_id"`
+			Count          int                `bson:"count"`
+		}
+		if err := cursor.Decode(&pinned); err != nil {
+			return err
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": pinned.ConversationID, "pin_count": bson.M{"$exists": false}}).
+			SetUpdate(bson.M{"$set": bson.M{"pin_count": pinned.Count}}))
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if len(models) == 0 {
+		return nil
+	}
+	_, err = db.Collection("conversations").BulkWrite(ctx, models)
+	return err
+}
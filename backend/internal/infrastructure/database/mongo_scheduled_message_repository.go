@@ -0,0 +1,316 @@
+package database
+
+import (
+	"backend-chat-app/internal/domain/message"
+	"backend-chat-app/internal/infrastructure/database/registry"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	scheduledIndexes := []mongo.IndexModel{
+		{
+			// Due messages, for the scheduler
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "send_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "sender_id", Value: 1}, {Key: "send_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "conversation_id", Value: 1}},
+		},
+	}
+
+	registry.RegisterCollection("scheduled_messages", scheduledIndexes)
+}
+
+type MongoScheduledMessageRepository struct {
+	client     *mongo.Client
+	database   string
+	collection *mongo.Collection
+}
+
+func NewMongoScheduledMessageRepository(client *mongo.Client, database string) *MongoScheduledMessageRepository {
+	collection := client.Database(database).Collection("scheduled_messages")
+	return &MongoScheduledMessageRepository{
+		client:     client,
+		database:   database,
+		collection: collection,
+	}
+}
+
+// scheduleEditable matches messages whose delivery has not started
+var scheduleEditable = bson.M{"$in": bson.A{message.SchedulePending, message.ScheduleFailed}}
+
+func (sr *MongoScheduledMessageRepository) toDomainScheduled(mongoMessage MongoScheduledMessage) *message.ScheduledMessage {
+	return &message.ScheduledMessage{
+		ID:             mongoMessage.ID.Hex(),
+		ConversationID: mongoMessage.ConversationID.Hex(),
+		SenderID:       mongoMessage.SenderID.Hex(),
+		Message:        mongoMessage.Message,
+		Format:         mongoMessage.Format,
+		Status:         mongoMessage.Status,
+		Error:          mongoMessage.Error,
+		SendAt:         timeFromUnix(mongoMessage.SendAt),
+		CreatedAt:      timeFromUnix(mongoMessage.CreatedAt),
+		UpdatedAt:      timeFromUnix(mongoMessage.UpdatedAt),
+	}
+}
+
+func (sr *MongoScheduledMessageRepository) Create(m message.ScheduledMessage) (*message.ScheduledMessage, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	convObjID, err := primitive.ObjectIDFromHex(m.ConversationID)
+	if err != nil {
+		return nil, errors.New("invalid conversation ID format")
+	}
+	senderObjID, err := primitive.ObjectIDFromHex(m.SenderID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	mongoMessage := &MongoScheduledMessage{
+		ConversationID: convObjID,
+		SenderID:       senderObjID,
+		Message:        m.Message,
+		Format:         m.Format,
+		Status:         m.Status,
+		SendAt:         m.SendAt.Unix(),
+		CreatedAt:      m.CreatedAt.Unix(),
+		UpdatedAt:      m.UpdatedAt.Unix(),
+	}
+	result, err := sr.collection.InsertOne(ctx, mongoMessage)
+	if err != nil {
+		return nil, err
+	}
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		mongoMessage.ID = oid
+	}
+	return sr.toDomainScheduled(*mongoMessage), nil
+}
+
+func (sr *MongoScheduledMessageRepository) GetByID(id string) (*message.ScheduledMessage, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid scheduled message ID format")
+	}
+
+	var mongoMessage MongoScheduledMessage
+	err = sr.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&mongoMessage)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sr.toDomainScheduled(mongoMessage), nil
+}
+
+func (sr *MongoScheduledMessageRepository) ListBySender(senderID string, conversationID string) ([]*message.ScheduledMessage, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	senderObjID, err := primitive.ObjectIDFromHex(senderID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+	filter := bson.M{"sender_id": senderObjID}
+	if conversationID != "" {
+		convObjID, err := primitive.ObjectIDFromHex(conversationID)
+		if err != nil {
+			return nil, errors.New("invalid conversation ID format")
+		}
+		filter["conversation_id"] = convObjID
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "send_at", Value: 1}})
+	return sr.find(ctx, filter, opts)
+}
+
+func (sr *MongoScheduledMessageRepository) CountBySender(senderID string) (int, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	senderObjID, err := primitive.ObjectIDFromHex(senderID)
+	if err != nil {
+		return 0, errors.New("invalid user ID format")
+	}
+
+	count, err := sr.collection.CountDocuments(ctx, bson.M{"sender_id": senderObjID})
+	return int(count), err
+}
+
+func (sr *MongoScheduledMessageRepository) Update(m message.ScheduledMessage) (bool, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(m.ID)
+	if err != nil {
+		return false, errors.New("invalid scheduled message ID format")
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"message":    m.Message,
+			"format":     m.Format,
+			"status":     message.SchedulePending,
+			"send_at":    m.SendAt.Unix(),
+			"updated_at": m.UpdatedAt.Unix(),
+		},
+		"$unset": bson.M{"error": ""},
+	}
+	result, err := sr.collection.UpdateOne(ctx, bson.M{"_id": objectID, "status": scheduleEditable}, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+func (sr *MongoScheduledMessageRepository) Cancel(id string) (bool, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, errors.New("invalid scheduled message ID format")
+	}
+
+	result, err := sr.collection.DeleteOne(ctx, bson.M{"_id": objectID, "status": scheduleEditable})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+func (sr *MongoScheduledMessageRepository) ClaimDue(now time.Time) (*message.ScheduledMessage, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	filter := bson.M{"status": message.SchedulePending, "send_at": bson.M{"$lte": now.Unix()}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "send_at", Value: 1}}).
+		SetReturnDocument(options.After)
+	update := bson.M{"$set": bson.M{"status": message.ScheduleSending}}
+
+	var mongoMessage MongoScheduledMessage
+	err := sr.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&mongoMessage)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sr.toDomainScheduled(mongoMessage), nil
+}
+
+func (sr *MongoScheduledMessageRepository) NextSendAt() (time.Time, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	opts := options.FindOne().SetSort(bson.D{{Key: "send_at", Value: 1}})
+	var mongoMessage MongoScheduledMessage
+	err := sr.collection.FindOne(ctx, bson.M{"status": message.SchedulePending}, opts).Decode(&mongoMessage)
+	if err == mongo.ErrNoDocuments {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return timeFromUnix(mongoMessage.SendAt), nil
+}
+
+func (sr *MongoScheduledMessageRepository) ListSending() ([]*message.ScheduledMessage, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	return sr.find(ctx, bson.M{"status": message.ScheduleSending}, options.Find())
+}
+
+func (sr *MongoScheduledMessageRepository) Fail(id string, reason string) error {
+	return sr.update(id, bson.M{"status": message.ScheduleFailed, "error": reason})
+}
+
+func (sr *MongoScheduledMessageRepository) Requeue(id string) error {
+	return sr.update(id, bson.M{"status": message.SchedulePending})
+}
+
+func (sr *MongoScheduledMessageRepository) Delete(id string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid scheduled message ID format")
+	}
+
+	_, err = sr.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	return err
+}
+
+func (sr *MongoScheduledMessageRepository) DeleteByConversation(conversationID string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return errors.New("invalid conversation ID format")
+	}
+
+	_, err = sr.collection.DeleteMany(ctx, bson.M{"conversation_id": convObjID})
+	return err
+}
+
+func (sr *MongoScheduledMessageRepository) DeleteBySender(senderID string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	senderObjID, err := primitive.ObjectIDFromHex(senderID)
+	if err != nil {
+		return errors.New("invalid user ID format")
+	}
+
+	_, err = sr.collection.DeleteMany(ctx, bson.M{"sender_id": senderObjID})
+	return err
+}
+
+func (sr *MongoScheduledMessageRepository) update(id string, fields bson.M) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid scheduled message ID format")
+	}
+
+	_, err = sr.collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": fields})
+	return err
+}
+
+func (sr *MongoScheduledMessageRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*message.ScheduledMessage, error) {
+	cursor, err := sr.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var mongoMessages []MongoScheduledMessage
+	if err = cursor.All(ctx, &mongoMessages); err != nil {
+		return nil, err
+	}
+
+	messages := make([]*message.ScheduledMessage, len(mongoMessages))
+	for i, mongoMessage := range mongoMessages {
+		messages[i] = sr.toDomainScheduled(mongoMessage)
+	}
+	return messages, nil
+}
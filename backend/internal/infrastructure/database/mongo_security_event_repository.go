@@ -0,0 +1,60 @@
+package database
+
+import (
+	"backend-chat-app/internal/domain/security"
+	"backend-chat-app/internal/infrastructure/database/registry"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func init() {
+	eventIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "type", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+	}
+
+	registry.RegisterCollection("security_events", eventIndexes)
+}
+
+type MongoSecurityEventRepository struct {
+	client     *mongo.Client
+	database   string
+	collection *mongo.Collection
+}
+
+func NewMongoSecurityEventRepository(client *mongo.Client, database string) *MongoSecurityEventRepository {
+	collection := client.Database(database).Collection("security_events")
+	return &MongoSecurityEventRepository{
+		client:     client,
+		database:   database,
+		collection: collection,
+	}
+}
+
+func (er *MongoSecurityEventRepository) Create(event security.Event) (*security.Event, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	mongoEvent := &MongoSecurityEvent{
+		Type:      event.Type,
+		UserID:    event.UserID,
+		Username:  event.Username,
+		IP:        event.IP,
+		Detail:    event.Detail,
+		CreatedAt: event.CreatedAt.Unix(),
+	}
+	result, err := er.collection.InsertOne(ctx, mongoEvent)
+	if err != nil {
+		return nil, err
+	}
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		event.ID = oid.Hex()
+	}
+	return &event, nil
+}
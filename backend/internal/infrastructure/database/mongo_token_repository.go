@@ -0,0 +1,111 @@
+package database
+
+import (
+	"backend-chat-app/internal/domain/token"
+	"backend-chat-app/internal/infrastructure/database/registry"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	tokenIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "purpose", Value: 1}},
+		},
+		{
+			// MongoDB removes expired tokens on its own
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	registry.RegisterCollection("user_tokens", tokenIndexes)
+}
+
+type MongoTokenRepository struct {
+	client     *mongo.Client
+	database   string
+	collection *mongo.Collection
+}
+
+func NewMongoTokenRepository(client *mongo.Client, database string) *MongoTokenRepository {
+	collection := client.Database(database).Collection("user_tokens")
+	return &MongoTokenRepository{
+		client:     client,
+		database:   database,
+		collection: collection,
+	}
+}
+
+func (tr *MongoTokenRepository) Create(t token.Token) (*token.Token, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(t.UserID)
+	if err != nil {
+		return nil, errors.New("invalid user ID format")
+	}
+
+	mongoToken := &MongoToken{
+		UserID:    userObjID,
+		Purpose:   t.Purpose,
+		ExpiresAt: t.ExpiresAt,
+		CreatedAt: t.CreatedAt.Unix(),
+	}
+	result, err := tr.collection.InsertOne(ctx, mongoToken)
+	if err != nil {
+		return nil, err
+	}
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		mongoToken.ID = oid
+	}
+	return tr.toDomainToken(*mongoToken), nil
+}
+
+func (tr *MongoTokenRepository) toDomainToken(mongoToken MongoToken) *token.Token {
+	return &token.Token{
+		ID:        mongoToken.ID.Hex(),
+		UserID:    mongoToken.UserID.Hex(),
+		Purpose:   mongoToken.Purpose,
+		ExpiresAt: mongoToken.ExpiresAt,
+		CreatedAt: timeFromUnix(mongoToken.CreatedAt),
+	}
+}
+
+func (tr *MongoTokenRepository) Consume(tokenID string, purpose string) (*token.Token, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(tokenID)
+	if err != nil {
+		return nil, errors.New("invalid token ID format")
+	}
+
+	var mongoToken MongoToken
+	filter := bson.M{"_id": objectID, "purpose": purpose}
+	err = tr.collection.FindOneAndDelete(ctx, filter).Decode(&mongoToken)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tr.toDomainToken(mongoToken), nil
+}
+
+func (tr *MongoTokenRepository) DeleteByUser(userID string, purpose string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user ID format")
+	}
+
+	_, err = tr.collection.DeleteMany(ctx, bson.M{"user_id": userObjID, "purpose": purpose})
+	return err
+}
@@ -1,9 +1,12 @@
 package database
 
 import (
+	"backend-chat-app/internal/domain/phone"
 	auth "backend-chat-app/internal/domain/user"
 	"backend-chat-app/internal/infrastructure/database/registry"
+	"context"
 	"errors"
+	"log"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -21,23 +24,107 @@ func init() {
 			Options: options.Index().SetUnique(true),
 		},
 		{
+			// Sparse so bot accounts without an email don't collide
 			Keys:    bson.D{{Key: "email", Value: 1}},
-			Options: options.Index().SetUnique(true),
+			Options: options.Index().SetName("email_unique_sparse").SetUnique(true).SetSparse(true),
 		},
 		{
 			Keys:    bson.D{{Key: "refresh_token", Value: 1}},
 			Options: options.Index().SetUnique(true).SetSparse(true),
 		},
+		phoneIndex,
 		{
-			Keys:    bson.D{{Key: "phone", Value: 1}},
-			Options: options.Index().SetUnique(true),
+			Keys:    bson.D{{Key: "oidc_issuer", Value: 1}, {Key: "oidc_subject", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "owner_id", Value: 1}},
+			Options: options.Index().SetSparse(true),
 		},
 		{
 			Keys: bson.D{{Key: "create_at", Value: 1}},
 		},
+		{
+			Keys:    bson.D{{Key: "deletion_due_at", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			// Prefix search, see user_search.go
+			Keys: bson.D{{Key: "search_terms", Value: 1}},
+		},
+		{
+			// Fuzzy search, see user_search.go
+			Keys: bson.D{{Key: "search_grams", Value: 1}},
+		},
 	}
 
 	registry.RegisterCollection("users", userIndexes)
+	registry.RegisterDroppedIndexes("users", "phone_1", "email_1")
+	registry.RegisterMigration("users_search_terms", backfillUserSearchTerms)
+}
+
+// phoneIndex is sparse so users provisioned by SSO without a phone don't
+// collide. The phone migration creates it early, see normalizeUserPhones.
+var phoneIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "phone", Value: 1}},
+	Options: options.Index().SetName("phone_unique_sparse").SetUnique(true).SetSparse(true),
+}
+
+// RegisterPhoneMigration rewrites stored phone numbers to E.164 so the
+// unique phone index compares normalized values. Numbers that can't be
+// parsed or that collide with another user's number are moved to
+// phone_legacy for manual review.
+func RegisterPhoneMigration(phones *phone.Normalizer) {
+	registry.RegisterMigration("users_phone_e164", func(ctx context.Context, db *mongo.Database) error {
+		return normalizeUserPhones(ctx, db.Collection("users"), phones)
+	})
+}
+
+// normalizeUserPhones runs after the old phone_1 index is dropped. That index
+// was not sparse, so moving a second number to phone_legacy under it failed.
+// The sparse index is created first: stored numbers were unique under
+// phone_1, and with it in place two numbers normalizing to the same value are
+// caught as a duplicate key.
+func normalizeUserPhones(ctx context.Context, collection *mongo.Collection, phones *phone.Normalizer) error {
+	if _, err := collection.Indexes().CreateOne(ctx, phoneIndex); err != nil {
+		return err
+	}
+
+	opts := options.Find().SetProjection(bson.M{"phone": 1}).SetSort(bson.D{{Key: "create_at", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{"phone": bson.M{"$exists": true}}, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var mongoUser MongoUser
+		if err := cursor.Decode(&mongoUser); err != nil {
+			return err
+		}
+		normalized, err := phones.Normalize(mongoUser.Phone)
+		if err == nil && normalized == mongoUser.Phone {
+			continue
+		}
+
+		filter := bson.M{"_id": mongoUser.ID}
+		if err == nil {
+			_, err = collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"phone": normalized}})
+			if err == nil {
+				continue
+			}
+			if !mongo.IsDuplicateKeyError(err) {
+				return err
+			}
+		}
+
+		log.Printf("Phone %q of user %s is invalid or taken (%v), moved to phone_legacy", mongoUser.Phone, mongoUser.ID.Hex(), err)
+		update := bson.M{"$set": bson.M{"phone_legacy": mongoUser.Phone}, "$unset": bson.M{"phone": ""}}
+		if _, err := collection.UpdateOne(ctx, filter, update); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
 }
 
 type MongoUserRepository struct {
@@ -59,12 +146,20 @@ func (mr *MongoUserRepository) Create(user auth.User) (*auth.User, error) {
 	ctx, cancel := withContextTimeout()
 	defer cancel()
 
+	searchTerms, searchGrams := userSearchFields(user)
 	mongoUser := &MongoUser{
 		Username:      user.Username,
 		Password:      user.Password,
 		Email:         user.Email,
 		Name:          user.Name,
 		Phone:         user.Phone,
+		EmailVerified: user.EmailVerified,
+		OIDCIssuer:    user.OIDCIssuer,
+		OIDCSubject:   user.OIDCSubject,
+		IsBot:         user.IsBot,
+		OwnerID:       user.OwnerID,
+		SearchTerms:   searchTerms,
+		SearchGrams:   searchGrams,
 		CreatedAt:     user.CreatedAt.Unix(),
 		UpdateAt:      user.UpdateAt.Unix(),
 		Conversations: []primitive.ObjectID{},
@@ -114,9 +209,23 @@ func (mr *MongoUserRepository) toDomainUser(mongoUser MongoUser) *auth.User {
 		Password:           mongoUser.Password,
 		Email:              mongoUser.Email,
 		Name:               mongoUser.Name,
+		DisplayName:        mongoUser.DisplayName,
+		Bio:                mongoUser.Bio,
+		Timezone:           mongoUser.Timezone,
+		AvatarID:           mongoUser.AvatarID,
 		Phone:              mongoUser.Phone,
+		EmailVerified:      mongoUser.EmailVerified,
+		OIDCIssuer:         mongoUser.OIDCIssuer,
+		OIDCSubject:        mongoUser.OIDCSubject,
+		IsBot:              mongoUser.IsBot,
+		OwnerID:            mongoUser.OwnerID,
+		ContactsOnly:       mongoUser.ContactsOnly,
+		Discoverability:    mongoUser.Discoverability,
+		DeletionDueAt:      optionalTimeFromUnix(mongoUser.DeletionDueAt),
+		DeletedAt:          optionalTimeFromUnix(mongoUser.DeletedAt),
 		RefreshToken:       mongoUser.RefreshToken,
 		RefreshTokenExpiry: mongoUser.RefreshTokenExpiry,
+		TokenVersion:       mongoUser.TokenVersion,
 		Conversations:      conversations,
 		CreatedAt:          timeFromUnix(mongoUser.CreatedAt),
 		UpdateAt:           timeFromUnix(mongoUser.UpdateAt),
@@ -301,3 +410,341 @@ func (mr *MongoUserRepository) GetConversationList(userID string) ([]*string, er
 
 	return conversationIDPtrs, nil
 }
+
+func (mr *MongoUserRepository) GetByEmail(email string) (*auth.User, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	var mongoUser MongoUser
+	err := mr.collection.FindOne(ctx, bson.M{"email": email}).Decode(&mongoUser)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mr.toDomainUser(mongoUser), nil
+}
+
+func (mr *MongoUserRepository) MarkEmailVerified(userID string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user ID format")
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"email_verified": true,
+			"update_at":      time.Now().Unix(),
+		},
+	}
+	_, err = mr.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	return err
+}
+
+func (mr *MongoUserRepository) UpdatePassword(userID string, hashPassword string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user ID format")
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"password":  hashPassword,
+			"update_at": time.Now().Unix(),
+		},
+	}
+	_, err = mr.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	return err
+}
+
+func (mr *MongoUserRepository) GetByOIDCIdentity(issuer string, subject string) (*auth.User, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	var mongoUser MongoUser
+	filter := bson.M{"oidc_issuer": issuer, "oidc_subject": subject}
+	err := mr.collection.FindOne(ctx, filter).Decode(&mongoUser)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mr.toDomainUser(mongoUser), nil
+}
+
+func (mr *MongoUserRepository) LinkOIDCIdentity(userID string, issuer string, subject string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user ID format")
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"oidc_issuer":  issuer,
+			"oidc_subject": subject,
+			"update_at":    time.Now().Unix(),
+		},
+	}
+	_, err = mr.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	return err
+}
+
+func (mr *MongoUserRepository) GetBotsByOwner(ownerID string) ([]*auth.User, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	cursor, err := mr.collection.Find(ctx, bson.M{"owner_id": ownerID, "is_bot": true})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var mongoUsers []MongoUser
+	if err = cursor.All(ctx, &mongoUsers); err != nil {
+		return nil, err
+	}
+
+	users := make([]*auth.User, len(mongoUsers))
+	for i, mongoUser := range mongoUsers {
+		users[i] = mr.toDomainUser(mongoUser)
+	}
+	return users, nil
+}
+
+func (mr *MongoUserRepository) AddConversation(userID string, conversationID string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user ID format")
+	}
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return errors.New("Invalid conversation ID format: " + err.Error())
+	}
+
+	update := bson.M{
+		"$addToSet": bson.M{
+			"conversations": convObjID,
+		},
+		"$set": bson.M{
+			"update_at": time.Now().Unix(),
+		},
+	}
+	_, err = mr.collection.UpdateOne(ctx, bson.M{"_id": userObjID}, update)
+	return err
+}
+
+func (mr *MongoUserRepository) RemoveConversation(userID string, conversationID string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user ID format")
+	}
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return errors.New("Invalid conversation ID format: " + err.Error())
+	}
+
+	update := bson.M{
+		"$pull": bson.M{
+			"conversations": convObjID,
+		},
+		"$set": bson.M{
+			"update_at": time.Now().Unix(),
+		},
+	}
+	_, err = mr.collection.UpdateOne(ctx, bson.M{"_id": userObjID}, update)
+	return err
+}
+
+func (mr *MongoUserRepository) RevokeSessions(userID string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user ID format")
+	}
+
+	// Unset rather than blank the refresh token, it has a unique sparse index
+	update := bson.M{
+		"$unset": bson.M{
+			"refresh_token": "",
+		},
+		"$set": bson.M{
+			"refresh_token_expiry": 0,
+			"update_at":            time.Now().Unix(),
+		},
+		"$inc": bson.M{
+			"token_version": 1,
+		},
+	}
+	_, err = mr.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	return err
+}
+
+func (mr *MongoUserRepository) UpdateProfile(user auth.User) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(user.ID)
+	if err != nil {
+		return errors.New("invalid user ID format")
+	}
+
+	searchTerms, searchGrams := userSearchFields(user)
+	update := bson.M{
+		"$set": bson.M{
+			"name":         user.Name,
+			"display_name": user.DisplayName,
+			"bio":          user.Bio,
+			"timezone":     user.Timezone,
+			"search_terms": searchTerms,
+			"search_grams": searchGrams,
+			"update_at":    time.Now().Unix(),
+		},
+	}
+	_, err = mr.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	return err
+}
+
+func (mr *MongoUserRepository) SetAvatar(userID string, avatarID string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user ID format")
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"avatar_id": avatarID,
+			"update_at": time.Now().Unix(),
+		},
+	}
+	_, err = mr.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	return err
+}
+
+func (mr *MongoUserRepository) UpdatePrivacy(user auth.User) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(user.ID)
+	if err != nil {
+		return errors.New("invalid user ID format")
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"contacts_only":   user.ContactsOnly,
+			"discoverability": user.Discoverability,
+			"update_at":       time.Now().Unix(),
+		},
+	}
+	_, err = mr.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	return err
+}
+
+func (mr *MongoUserRepository) ScheduleDeletion(userID string, dueAt time.Time) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user ID format")
+	}
+
+	var update bson.M
+	if dueAt.IsZero() {
+		update = bson.M{"$unset": bson.M{"deletion_due_at": ""}, "$set": bson.M{"update_at": time.Now().Unix()}}
+	} else {
+		update = bson.M{"$set": bson.M{"deletion_due_at": dueAt.Unix(), "update_at": time.Now().Unix()}}
+	}
+	_, err = mr.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	return err
+}
+
+func (mr *MongoUserRepository) GetDueForDeletion(now time.Time) ([]*auth.User, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	filter := bson.M{"deletion_due_at": bson.M{"$lte": now.Unix()}, "deleted_at": bson.M{"$exists": false}}
+	cursor, err := mr.collection.Find(ctx, filter, options.Find().SetLimit(100))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var mongoUsers []MongoUser
+	if err = cursor.All(ctx, &mongoUsers); err != nil {
+		return nil, err
+	}
+
+	users := make([]*auth.User, len(mongoUsers))
+	for i, mongoUser := range mongoUsers {
+		users[i] = mr.toDomainUser(mongoUser)
+	}
+	return users, nil
+}
+
+// Anonymize keeps the document, so references from conversations and
+// messages stay valid, but removes everything that identifies the person.
+func (mr *MongoUserRepository) Anonymize(userID string) error {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user ID format")
+	}
+
+	now := time.Now().Unix()
+	update := bson.M{
+		"$set": bson.M{
+			"username":        "deleted-" + userID,
+			"name":            auth.DeletedUserName,
+			"email_verified":  false,
+			"discoverability": auth.DiscoverableNobody,
+			"conversations":   bson.A{},
+			"deleted_at":      now,
+			"update_at":       now,
+		},
+		"$unset": bson.M{
+			"password":             "",
+			"email":                "",
+			"phone":                "",
+			"phone_legacy":         "",
+			"display_name":         "",
+			"bio":                  "",
+			"timezone":             "",
+			"avatar_id":            "",
+			"oidc_issuer":          "",
+			"oidc_subject":         "",
+			"refresh_token":        "",
+			"refresh_token_expiry": "",
+			"search_terms":         "",
+			"search_grams":         "",
+			"deletion_due_at":      "",
+		},
+		"$inc": bson.M{"token_version": 1},
+	}
+	_, err = mr.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	return err
+}
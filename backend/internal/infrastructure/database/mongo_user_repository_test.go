@@ -0,0 +1,111 @@
+package database
+
+import (
+	"backend-chat-app/internal/domain/phone"
+	"backend-chat-app/internal/infrastructure/database/registry"
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testDatabase connects to MONGO_TEST_URL and returns an empty database that
+// is dropped after the test. Tests using it are skipped without a server.
+func testDatabase(t *testing.T) (*mongo.Client, string) {
+	t.Helper()
+	uri := os.Getenv("MONGO_TEST_URL")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URL not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	name := fmt.Sprintf("chat-app-test-%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		client.Database(name).Drop(ctx)
+		client.Disconnect(ctx)
+	})
+	return client, name
+}
+
+func TestPhoneMigrationUnderOldIndex(t *testing.T) {
+	client, name := testDatabase(t)
+	ctx := context.Background()
+	users := client.Database(name).Collection("users")
+
+	// The index of the first release: unique and not sparse
+	if _, err := users.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "phone", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	seed := []interface{}{
+		bson.M{"username": "national", "phone": "0901000001", "create_at": 1},
+		bson.M{"username": "e164", "phone": "+84901000001", "create_at": 2},
+		bson.M{"username": "garbage", "phone": "not a phone", "create_at": 3},
+		bson.M{"username": "short", "phone": "12", "create_at": 4},
+		bson.M{"username": "spaced", "phone": "090 100 0002", "create_at": 5},
+	}
+	if _, err := users.InsertMany(ctx, seed); err != nil {
+		t.Fatal(err)
+	}
+
+	phones, err := phone.NewNormalizer("VN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterPhoneMigration(phones)
+	if err := registry.SetupAllIndexes(client, name); err != nil {
+		t.Fatalf("startup failed: %v", err)
+	}
+
+	want := map[string]bson.M{
+		// Two numbers normalizing to the same value: one keeps it
+		"national": {"phone_legacy": "0901000001"},
+		"e164":     {"phone": "+84901000001"},
+		// Two invalid numbers: both moved aside, which the old index refused
+		"garbage": {"phone_legacy": "not a phone"},
+		"short":   {"phone_legacy": "12"},
+		"spaced":  {"phone": "+84901000002"},
+	}
+	for username, fields := range want {
+		var stored bson.M
+		if err := users.FindOne(ctx, bson.M{"username": username}).Decode(&stored); err != nil {
+			t.Fatalf("%s: %v", username, err)
+		}
+		for field, value := range fields {
+			if stored[field] != value {
+				t.Errorf("%s: %s = %v, want %v", username, field, stored[field], value)
+			}
+		}
+		if _, moved := fields["phone_legacy"]; moved && stored["phone"] != nil {
+			t.Errorf("%s: phone %v kept next to phone_legacy", username, stored["phone"])
+		}
+	}
+
+	specs, err := users.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]bool)
+	for _, spec := range specs {
+		names[spec.Name] = true
+	}
+	if names["phone_1"] || !names["phone_unique_sparse"] {
+		t.Fatalf("indexes after startup: %v", names)
+	}
+}
@@ -0,0 +1,230 @@
+package database
+
+import (
+	auth "backend-chat-app/internal/domain/user"
+	"context"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// User search works on two derived, indexed fields kept in sync on write:
+//
+//   - search_terms: the lowercased, accent-free words of the name, display
+//     name, username and email. An anchored regex on it is an index range
+//     scan, which gives prefix matching ("ngu" finds "Nguyễn").
+//   - search_grams: the padded trigrams of those words. Sharing enough of
+//     them with the query gives typo-tolerant matching ("ngyuen").
+//
+// Prefix matches rank first, then users by the number of shared trigrams.
+
+// fuzzyMinOverlap is the share of the query's trigrams a user must have to
+// be returned as a fuzzy match.
+const fuzzyMinOverlap = 0.3
+
+func normalizeSearchText(text string) string {
+	// Strip combining marks so "Nguyễn" is found by "nguyen"; đ has no
+	// decomposition and is mapped by hand
+	folder := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(folder, strings.ToLower(text))
+	if err != nil {
+		folded = strings.ToLower(text)
+	}
+	return strings.ReplaceAll(folded, "đ", "d")
+}
+
+func searchWords(text string) []string {
+	return strings.FieldsFunc(normalizeSearchText(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchFieldsOf returns the search_terms and search_grams of a user. Only
+// the local part of the email is split into words, otherwise every gmail
+// user would match "gmail".
+func searchFieldsOf(username string, email string, name string, displayName string) ([]string, []string) {
+	local, _, _ := strings.Cut(email, "@")
+	words := dedupe(searchWords(name + " " + displayName + " " + username + " " + local))
+
+	// Whole username and address too, so "john.doe" and "john@ex" match as typed
+	terms := dedupe(append(append([]string{}, words...), normalizeSearchText(username), normalizeSearchText(email)))
+	return terms, searchGrams(words)
+}
+
+func userSearchFields(user auth.User) ([]string, []string) {
+	return searchFieldsOf(user.Username, user.Email, user.Name, user.DisplayName)
+}
+
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if value != "" && !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+func searchGrams(words []string) []string {
+	seen := make(map[string]bool)
+	grams := make([]string, 0, len(words)*6)
+	for _, word := range words {
+		padded := []rune(" " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			gram := string(padded[i : i+3])
+			if !seen[gram] {
+				seen[gram] = true
+				grams = append(grams, gram)
+			}
+		}
+	}
+	return grams
+}
+
+func (mr *MongoUserRepository) Search(query auth.SearchQuery) ([]*auth.User, error) {
+	ctx, cancel := withContextTimeout()
+	defer cancel()
+
+	words := searchWords(query.Term)
+	if len(words) == 0 {
+		return []*auth.User{}, nil
+	}
+	grams := searchGrams(words)
+	minOverlap := int(math.Ceil(float64(len(grams)) * fuzzyMinOverlap))
+
+	// Every query word must prefix one of the user's terms, or the query as
+	// typed must prefix a whole username or email
+	whole := strings.TrimSpace(normalizeSearchText(query.Term))
+	wordFilters := bson.A{}
+	wordExprs := bson.A{}
+	for _, word := range words {
+		wordFilters = append(wordFilters, bson.M{"search_terms": bson.M{"$regex": "^" + regexp.QuoteMeta(word)}})
+		wordExprs = append(wordExprs, hasTermWithPrefix(word))
+	}
+	prefixFilter := bson.M{"$or": bson.A{
+		bson.M{"$and": wordFilters},
+		bson.M{"search_terms": bson.M{"$regex": "^" + regexp.QuoteMeta(whole)}},
+	}}
+	prefixExpr := bson.M{"$or": bson.A{
+		bson.M{"$and": wordExprs},
+		hasTermWithPrefix(whole),
+	}}
+
+	match := bson.M{"$and": bson.A{
+		bson.M{"$or": bson.A{
+			prefixFilter,
+			bson.M{"search_grams": bson.M{"$in": grams}},
+		}},
+		bson.M{"$or": bson.A{
+			bson.M{"discoverability": bson.M{"$in": bson.A{nil, auth.DiscoverableEveryone}}},
+			bson.M{"discoverability": auth.DiscoverableContacts, "_id": bson.M{"$in": toObjectIDs(query.ContactIDs)}},
+		}},
+		bson.M{"_id": bson.M{"$nin": toObjectIDs(query.ExcludeIDs)}},
+		bson.M{"is_bot": bson.M{"$ne": true}},
+	}}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$addFields", Value: bson.M{
+			"_prefix": prefixExpr,
+			"_overlap": bson.M{"$size": bson.M{"$setIntersection": bson.A{
+				bson.M{"$ifNull": bson.A{"$search_grams", bson.A{}}},
+				grams,
+			}}},
+		}}},
+		{{Key: "$match", Value: bson.M{"$or": bson.A{
+			bson.M{"_prefix": true},
+			bson.M{"_overlap": bson.M{"$gte": minOverlap}},
+		}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_prefix", Value: -1}, {Key: "_overlap", Value: -1}, {Key: "username", Value: 1}}}},
+		{{Key: "$skip", Value: query.Offset}},
+		{{Key: "$limit", Value: query.Limit}},
+	}
+
+	cursor, err := mr.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var mongoUsers []MongoUser
+	if err = cursor.All(ctx, &mongoUsers); err != nil {
+		return nil, err
+	}
+
+	users := make([]*auth.User, len(mongoUsers))
+	for i, mongoUser := range mongoUsers {
+		users[i] = mr.toDomainUser(mongoUser)
+	}
+	return users, nil
+}
+
+func hasTermWithPrefix(prefix string) bson.M {
+	return bson.M{"$anyElementTrue": bson.A{bson.M{"$map": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$search_terms", bson.A{}}},
+		"in":    bson.M{"$eq": bson.A{bson.M{"$indexOfCP": bson.A{"$$this", prefix}}, 0}},
+	}}}}
+}
+
+func toObjectIDs(ids []string) bson.A {
+	objectIDs := bson.A{}
+	for _, id := range ids {
+		if objectID, err := primitive.ObjectIDFromHex(id); err == nil {
+			objectIDs = append(objectIDs, objectID)
+		}
+	}
+	return objectIDs
+}
+
+// backfillUserSearchTerms fills the search fields of users created before
+// search existed.
+func backfillUserSearchTerms(ctx context.Context, db *mongo.Database) error {
+	collection := db.Collection("users")
+	opts := options.Find().SetProjection(bson.M{"username": 1, "email": 1, "name": 1, "display_name": 1})
+	cursor, err := collection.Find(ctx, bson.M{"search_terms": bson.M{"$exists": false}}, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	models := make([]mongo.WriteModel, 0, 500)
+	flush := func() error {
+		if len(models) == 0 {
+			return nil
+		}
+		_, err := collection.BulkWrite(ctx, models)
+		models = models[:0]
+		return err
+	}
+
+	for cursor.Next(ctx) {
+		var mongoUser MongoUser
+		if err := cursor.Decode(&mongoUser); err != nil {
+			return err
+		}
+		terms, grams := searchFieldsOf(mongoUser.Username, mongoUser.Email, mongoUser.Name, mongoUser.DisplayName)
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": mongoUser.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"search_terms": terms, "search_grams": grams}}))
+		if len(models) == cap(models) {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
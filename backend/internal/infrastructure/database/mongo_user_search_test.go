@@ -0,0 +1,104 @@
+package database
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestNormalizeSearchText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Nguyễn Văn Đức", "nguyen van duc"},
+		{"ĐẶNG Thị Ánh", "dang thi anh"},
+		{"José Müller", "jose muller"},
+		{"john.doe@Example.COM", "john.doe@example.com"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeSearchText(tt.in); got != tt.want {
+			t.Errorf("normalizeSearchText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSearchFieldsOf(t *testing.T) {
+	terms, grams := searchFieldsOf("John.Doe", "john.doe@gmail.com", "Nguyễn Văn A", "Johnny")
+
+	for _, want := range []string{"nguyen", "van", "a", "johnny", "john", "doe", "john.doe", "john.doe@gmail.com"} {
+		if !slices.Contains(terms, want) {
+			t.Errorf("terms %v miss %q", terms, want)
+		}
+	}
+	// The mail domain is not a word of the user
+	for _, unwanted := range []string{"gmail", "com", ""} {
+		if slices.Contains(terms, unwanted) {
+			t.Errorf("terms %v contain %q", terms, unwanted)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, term := range terms {
+		if seen[term] {
+			t.Errorf("term %q repeated", term)
+		}
+		seen[term] = true
+	}
+	if !slices.Contains(grams, " ng") || !slices.Contains(grams, "en ") {
+		t.Errorf("grams %v miss the padded ends of nguyen", grams)
+	}
+}
+
+func TestSearchFieldsOfWithoutEmail(t *testing.T) {
+	terms, _ := searchFieldsOf("alice", "", "Alice", "")
+	if !slices.Equal(terms, []string{"alice"}) {
+		t.Fatalf("terms = %v", terms)
+	}
+}
+
+func TestSearchGrams(t *testing.T) {
+	tests := []struct {
+		words []string
+		want  []string
+	}{
+		{[]string{"ab"}, []string{" ab", "ab "}},
+		{[]string{"abc"}, []string{" ab", "abc", "bc "}},
+		{[]string{"a"}, []string{" a "}},
+		// Shared grams are listed once
+		{[]string{"abc", "abc"}, []string{" ab", "abc", "bc "}},
+		// Grams are runes, not bytes
+		{[]string{"đi"}, []string{" đi", "đi "}},
+		{nil, []string{}},
+	}
+	for _, tt := range tests {
+		if got := searchGrams(tt.words); !slices.Equal(got, tt.want) {
+			t.Errorf("searchGrams(%q) = %q, want %q", tt.words, got, tt.want)
+		}
+	}
+}
+
+// overlap mirrors the fuzzy match of Search: the share of the query's grams
+// the user has.
+func overlap(query string, user []string) bool {
+	grams := searchGrams(searchWords(query))
+	shared := 0
+	for _, gram := range grams {
+		if slices.Contains(user, gram) {
+			shared++
+		}
+	}
+	return shared >= int(math.Ceil(float64(len(grams))*fuzzyMinOverlap))
+}
+
+func TestSearchGramsTolerateTypos(t *testing.T) {
+	_, grams := searchFieldsOf("nguyen.van", "", "Nguyễn Văn", "")
+	for _, query := range []string{"ngyuen", "nguyn", "Nguyen"} {
+		if !overlap(query, grams) {
+			t.Errorf("%q does not find Nguyễn", query)
+		}
+	}
+	if overlap("smith", grams) {
+		t.Error("unrelated query finds Nguyễn")
+	}
+}
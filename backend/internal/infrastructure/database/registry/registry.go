@@ -5,15 +5,29 @@ import (
 	"log"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// Migration rewrites existing documents. It runs once per database, after
+// replaced indexes are dropped and before the indexes are created, and is
+// recorded in the schema_migrations collection.
+type Migration func(ctx context.Context, db *mongo.Database) error
+
+type namedMigration struct {
+	name string
+	run  Migration
+}
+
 type IndexRegistry struct {
 	collections map[string][]mongo.IndexModel
+	dropped     map[string][]string
+	migrations  []namedMigration
 }
 
 var registry = &IndexRegistry{
 	collections: make(map[string][]mongo.IndexModel),
+	dropped:     make(map[string][]string),
 }
 
 // RegisterCollection registers indexes for a collection
@@ -22,13 +36,34 @@ func RegisterCollection(name string, indexes []mongo.IndexModel) {
 	log.Printf("Registered indexes for collection: %s", name)
 }
 
-// SetupAllIndexes creates indexes for all registered collections
+// RegisterDroppedIndexes registers index names that were replaced and must be
+// removed before migrations run and the current indexes are created
+func RegisterDroppedIndexes(name string, indexNames ...string) {
+	registry.dropped[name] = append(registry.dropped[name], indexNames...)
+}
+
+// RegisterMigration registers a data migration under a unique name.
+// Migrations run in the order they were registered.
+func RegisterMigration(name string, migration Migration) {
+	registry.migrations = append(registry.migrations, namedMigration{name: name, run: migration})
+}
+
+// SetupAllIndexes drops replaced indexes, runs pending migrations and creates
+// indexes for all registered collections. Replaced indexes go first, as a
+// migration may rewrite documents in a way they no longer allow.
 func SetupAllIndexes(client *mongo.Client, dbName string) error {
+	db := client.Database(dbName)
+
+	if err := dropReplacedIndexes(db); err != nil {
+		return err
+	}
+	if err := runMigrations(db); err != nil {
+		return err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	db := client.Database(dbName)
-
 	for collectionName, indexes := range registry.collections {
 		collection := db.Collection(collectionName)
 
@@ -45,3 +80,59 @@ func SetupAllIndexes(client *mongo.Client, dbName string) error {
 	}
 	return nil
 }
+
+func dropReplacedIndexes(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for collectionName, names := range registry.dropped {
+		if err := dropIndexes(ctx, db.Collection(collectionName), names); err != nil {
+			log.Printf("Warning: Failed to drop old indexes for %s: %v", collectionName, err)
+			return err
+		}
+	}
+	return nil
+}
+
+func dropIndexes(ctx context.Context, collection *mongo.Collection, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	existing, err := collection.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return err
+	}
+	for _, spec := range existing {
+		for _, name := range names {
+			if spec.Name != name {
+				continue
+			}
+			if _, err := collection.Indexes().DropOne(ctx, name); err != nil {
+				return err
+			}
+			log.Printf("Dropped old index %s on collection: %s", name, collection.Name())
+		}
+	}
+	return nil
+}
+
+func runMigrations(db *mongo.Database) error {
+	applied := db.Collection("schema_migrations")
+	for _, migration := range registry.migrations {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		count, err := applied.CountDocuments(ctx, bson.M{"_id": migration.name})
+		if err == nil && count == 0 {
+			log.Printf("Running migration %s", migration.name)
+			err = migration.run(ctx, db)
+			if err == nil {
+				_, err = applied.InsertOne(ctx, bson.M{"_id": migration.name, "applied_at": time.Now().Unix()})
+			}
+		}
+		cancel()
+		if err != nil {
+			log.Printf("Warning: Migration %s failed: %v", migration.name, err)
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Processor turns uploaded images into JPEG thumbnails.
+type Processor struct {
+	// maxPixels guards against decompression bombs: a tiny file that
+	// declares a huge canvas
+	maxPixels int
+	quality   int
+}
+
+func NewProcessor(maxPixels int) *Processor {
+	return &Processor{
+		maxPixels: maxPixels,
+		quality:   85,
+	}
+}
+
+func (p *Processor) Thumbnail(data []byte, maxSize int, square bool) ([]byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("unsupported image format")
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > p.maxPixels {
+		return nil, errors.New("image dimensions are too large")
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("failed to decode image: " + err.Error())
+	}
+
+	bounds := src.Bounds()
+	if square {
+		side := min(bounds.Dx(), bounds.Dy())
+		x0 := bounds.Min.X + (bounds.Dx()-side)/2
+		y0 := bounds.Min.Y + (bounds.Dy()-side)/2
+		bounds = image.Rect(x0, y0, x0+side, y0+side)
+	}
+
+	width, height := fit(bounds.Dx(), bounds.Dy(), maxSize)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	// JPEG has no alpha, so flatten transparent images onto white
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, dst, &jpeg.Options{Quality: p.quality}); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// fit scales width x height down to fit in a maxSize box, never up.
+func fit(width int, height int, maxSize int) (int, int) {
+	if width <= maxSize && height <= maxSize {
+		return width, height
+	}
+	if width >= height {
+		return maxSize, max(1, height*maxSize/width)
+	}
+	return max(1, width*maxSize/height), maxSize
+}
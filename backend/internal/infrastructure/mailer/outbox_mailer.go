@@ -0,0 +1,66 @@
+package mailer
+
+import (
+	"backend-chat-app/internal/domain/mail"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// OutboxMailer keeps sent emails in memory and, when dir is set, also writes
+// each one as an .eml file. It is used in development and tests instead of SMTP.
+type OutboxMailer struct {
+	dir    string
+	from   string
+	emails []mail.Email
+	mu     sync.RWMutex
+}
+
+func NewOutboxMailer(dir string, from string) *OutboxMailer {
+	return &OutboxMailer{
+		dir:  dir,
+		from: from,
+	}
+}
+
+func (m *OutboxMailer) Send(email mail.Email) error {
+	m.mu.Lock()
+	m.emails = append(m.emails, email)
+	count := len(m.emails)
+	m.mu.Unlock()
+
+	if m.dir == "" {
+		log.Printf("Outbox email to %s: %s", email.To, email.Subject)
+		return nil
+	}
+
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%04d.eml", email.CreatedAt.UnixNano(), count)
+	return os.WriteFile(filepath.Join(m.dir, name), buildMessage(m.from, email), 0o600)
+}
+
+// Messages returns a copy of every email sent so far.
+func (m *OutboxMailer) Messages() []mail.Email {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	emails := make([]mail.Email, len(m.emails))
+	copy(emails, m.emails)
+	return emails
+}
+
+// Last returns the most recent email sent to the given address.
+func (m *OutboxMailer) Last(to string) (*mail.Email, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for i := len(m.emails) - 1; i >= 0; i-- {
+		if m.emails[i].To == to {
+			email := m.emails[i]
+			return &email, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,233 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testIdP is a local stand-in for an OpenID Connect provider. It remembers
+// the PKCE challenge of each authorization request and only hands out an ID
+// token for a code whose verifier matches it.
+type testIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	// issuer is what discovery and ID tokens report, by default the server URL
+	issuer string
+	// claims are changed by tests to produce bad ID tokens
+	claims jwt.MapClaims
+
+	mu         sync.Mutex
+	challenges map[string]string // code -> code_challenge
+	nonces     map[string]string // code -> nonce
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &testIdP{
+		key:        key,
+		claims:     jwt.MapClaims{},
+		challenges: make(map[string]string),
+		nonces:     make(map[string]string),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.issuer,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test-key",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", idp.token)
+	idp.server = httptest.NewServer(mux)
+	idp.issuer = idp.server.URL
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// authorize plays the user signing in at the provider and returns the code
+// the provider would send back to the redirect URL.
+func (idp *testIdP) authorize(t *testing.T, authURL string) string {
+	t.Helper()
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("code_challenge_method = %q", query.Get("code_challenge_method"))
+	}
+	code := "code-" + query.Get("state")
+	idp.mu.Lock()
+	idp.challenges[code] = query.Get("code_challenge")
+	idp.nonces[code] = query.Get("nonce")
+	idp.mu.Unlock()
+	return code
+}
+
+func (idp *testIdP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	code := r.PostForm.Get("code")
+	idp.mu.Lock()
+	challenge, found := idp.challenges[code]
+	nonce := idp.nonces[code]
+	delete(idp.challenges, code)
+	idp.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !found || base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":            idp.issuer,
+		"sub":            "subject-1",
+		"aud":            "client-1",
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          nonce,
+		"email":          "alice@example.com",
+		"email_verified": "true",
+	}
+	for name, value := range idp.claims {
+		claims[name] = value
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test-key"
+	signed, err := token.SignedString(idp.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"id_token": signed, "token_type": "Bearer"})
+}
+
+func (idp *testIdP) provider(issuer string) *Provider {
+	return NewProvider(issuer, "client-1", "secret", "https://app.example.com/sso/callback", []string{"openid", "email"})
+}
+
+// login runs the browser side of the flow and returns the exchange result.
+func login(t *testing.T, idp *testIdP, p *Provider, verifier string) (string, error) {
+	t.Helper()
+	sum := sha256.Sum256([]byte("verifier-1"))
+	authURL, err := p.AuthCodeURL("state-1", "nonce-1", base64.RawURLEncoding.EncodeToString(sum[:]))
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	if !strings.HasPrefix(authURL, idp.server.URL+"/authorize?") {
+		t.Fatalf("unexpected authorization URL %s", authURL)
+	}
+	code := idp.authorize(t, authURL)
+	identity, err := p.Exchange(code, verifier, "nonce-1")
+	if err != nil {
+		return "", err
+	}
+	return identity.Subject, nil
+}
+
+func TestProviderExchange(t *testing.T) {
+	idp := newTestIdP(t)
+	p := idp.provider(idp.server.URL)
+
+	sum := sha256.Sum256([]byte("verifier-1"))
+	authURL, err := p.AuthCodeURL("state-1", "nonce-1", base64.RawURLEncoding.EncodeToString(sum[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity, err := p.Exchange(idp.authorize(t, authURL), "verifier-1", "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if identity.Subject != "subject-1" || identity.Email != "alice@example.com" || !identity.EmailVerified {
+		t.Fatalf("unexpected identity %+v", identity)
+	}
+	if identity.Issuer != idp.server.URL {
+		t.Fatalf("identity issuer = %q", identity.Issuer)
+	}
+}
+
+func TestProviderRejectsWrongCodeVerifier(t *testing.T) {
+	idp := newTestIdP(t)
+	if _, err := login(t, idp, idp.provider(idp.server.URL), "another-verifier"); err == nil {
+		t.Fatal("exchange succeeded with the wrong code verifier")
+	}
+}
+
+func TestProviderRejectsBadIDToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+	}{
+		{"nonce", jwt.MapClaims{"nonce": "replayed-nonce"}},
+		{"issuer", jwt.MapClaims{"iss": "https://evil.example.com"}},
+		{"audience", jwt.MapClaims{"aud": "another-client"}},
+		{"expired", jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}},
+		{"subject", jwt.MapClaims{"sub": ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newTestIdP(t)
+			idp.claims = tt.claims
+			if _, err := login(t, idp, idp.provider(idp.server.URL), "verifier-1"); err == nil {
+				t.Fatalf("accepted an ID token with a bad %s", tt.name)
+			}
+		})
+	}
+}
+
+func TestProviderIssuerWithTrailingSlash(t *testing.T) {
+	idp := newTestIdP(t)
+	idp.issuer = idp.server.URL + "/"
+
+	for _, configured := range []string{idp.server.URL, idp.server.URL + "/"} {
+		subject, err := login(t, idp, idp.provider(configured), "verifier-1")
+		if err != nil {
+			t.Fatalf("configured %q: %v", configured, err)
+		}
+		if subject != "subject-1" {
+			t.Fatalf("configured %q: subject %q", configured, subject)
+		}
+	}
+}
+
+func TestProviderRejectsMismatchedDiscovery(t *testing.T) {
+	idp := newTestIdP(t)
+	idp.issuer = "https://evil.example.com"
+	if _, err := idp.provider(idp.server.URL).AuthCodeURL("state-1", "nonce-1", "challenge"); err == nil {
+		t.Fatal("accepted a discovery document for another issuer")
+	}
+}
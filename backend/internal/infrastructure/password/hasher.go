@@ -0,0 +1,55 @@
+package password
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type algorithm interface {
+	Hash(password string) (string, error)
+	Verify(hash string, password string) (bool, error)
+	NeedsRehash(hash string) bool
+	Recognizes(hash string) bool
+}
+
+// Hasher hashes with the preferred algorithm and verifies with whichever
+// known algorithm produced the stored hash, so existing bcrypt hashes keep
+// working and can be upgraded on the next successful login.
+type Hasher struct {
+	preferred algorithm
+	known     []algorithm
+}
+
+func NewHasher(preferred string) (*Hasher, error) {
+	argon := NewArgon2idHasher(DefaultArgon2idParams())
+	bcryptHasher := NewBcryptHasher(bcrypt.DefaultCost)
+
+	hasher := &Hasher{known: []algorithm{argon, bcryptHasher}}
+	switch preferred {
+	case "", "argon2id":
+		hasher.preferred = argon
+	case "bcrypt":
+		hasher.preferred = bcryptHasher
+	default:
+		return nil, errors.New("unknown password hasher: " + preferred)
+	}
+	return hasher, nil
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	return h.preferred.Hash(password)
+}
+
+func (h *Hasher) Verify(hash string, password string) (bool, error) {
+	for _, alg := range h.known {
+		if alg.Recognizes(hash) {
+			return alg.Verify(hash, password)
+		}
+	}
+	return false, errors.New("unknown password hash format")
+}
+
+func (h *Hasher) NeedsRehash(hash string) bool {
+	return !h.preferred.Recognizes(hash) || h.preferred.NeedsRehash(hash)
+}
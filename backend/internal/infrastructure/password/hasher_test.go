@@ -0,0 +1,156 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Small parameters keep the tests fast, the format is the same.
+var testParams = Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestArgon2idHashFormat(t *testing.T) {
+	h := NewArgon2idHasher(testParams)
+	hash, err := h.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("unexpected hash %q", hash)
+	}
+
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if *params != testParams {
+		t.Fatalf("decoded params %+v, want %+v", *params, testParams)
+	}
+	if len(salt) != 16 || len(key) != 32 {
+		t.Fatalf("salt %d bytes, key %d bytes", len(salt), len(key))
+	}
+
+	other, err := h.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == hash {
+		t.Fatal("two hashes of the same password share a salt")
+	}
+}
+
+func TestArgon2idVerify(t *testing.T) {
+	h := NewArgon2idHasher(testParams)
+	hash, err := h.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := h.Verify(hash, "correct horse"); err != nil || !ok {
+		t.Fatalf("right password: ok=%v err=%v", ok, err)
+	}
+	if ok, err := h.Verify(hash, "wrong horse"); err != nil || ok {
+		t.Fatalf("wrong password: ok=%v err=%v", ok, err)
+	}
+}
+
+func TestDecodeArgon2idRejectsMalformedHashes(t *testing.T) {
+	for _, hash := range []string{
+		"",
+		"$argon2i$v=19$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$not base64!$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA",
+	} {
+		if _, _, _, err := decodeArgon2id(hash); err == nil {
+			t.Errorf("%q: decoded", hash)
+		}
+	}
+}
+
+func TestArgon2idNeedsRehash(t *testing.T) {
+	h := NewArgon2idHasher(testParams)
+	hash, err := h.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.NeedsRehash(hash) {
+		t.Fatal("fresh hash needs a rehash")
+	}
+
+	stronger := testParams
+	stronger.Iterations = 2
+	if !NewArgon2idHasher(stronger).NeedsRehash(hash) {
+		t.Fatal("hash with fewer iterations does not need a rehash")
+	}
+	if !h.NeedsRehash("garbage") {
+		t.Fatal("malformed hash does not need a rehash")
+	}
+}
+
+func TestHasherVerifiesLegacyBcrypt(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &Hasher{preferred: NewArgon2idHasher(testParams), known: []algorithm{NewArgon2idHasher(testParams), NewBcryptHasher(bcrypt.MinCost)}}
+
+	if ok, err := h.Verify(string(legacy), "correct horse"); err != nil || !ok {
+		t.Fatalf("right password: ok=%v err=%v", ok, err)
+	}
+	if ok, err := h.Verify(string(legacy), "wrong horse"); err != nil || ok {
+		t.Fatalf("wrong password: ok=%v err=%v", ok, err)
+	}
+	if !h.NeedsRehash(string(legacy)) {
+		t.Fatal("bcrypt hash is not upgraded to argon2id")
+	}
+
+	upgraded, err := h.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.NeedsRehash(upgraded) {
+		t.Fatal("upgraded hash needs another rehash")
+	}
+	if ok, err := h.Verify(upgraded, "correct horse"); err != nil || !ok {
+		t.Fatalf("upgraded hash: ok=%v err=%v", ok, err)
+	}
+}
+
+func TestHasherUnknownFormat(t *testing.T) {
+	h, err := NewHasher("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Verify("$1$md5crypt$hash", "password"); err == nil {
+		t.Fatal("verified an unknown hash format")
+	}
+	if !h.NeedsRehash("$1$md5crypt$hash") {
+		t.Fatal("unknown hash format does not need a rehash")
+	}
+}
+
+func TestNewHasher(t *testing.T) {
+	for _, name := range []string{"", "argon2id", "bcrypt"} {
+		if _, err := NewHasher(name); err != nil {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+	if _, err := NewHasher("md5"); err == nil {
+		t.Fatal("accepted an unknown hasher")
+	}
+}
+
+func TestBcryptNeedsRehashOnCostChange(t *testing.T) {
+	hash, err := NewBcryptHasher(bcrypt.MinCost).Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if NewBcryptHasher(bcrypt.MinCost).NeedsRehash(hash) {
+		t.Fatal("hash with the configured cost needs a rehash")
+	}
+	if !NewBcryptHasher(bcrypt.MinCost + 1).NeedsRehash(hash) {
+		t.Fatal("hash with a lower cost does not need a rehash")
+	}
+}
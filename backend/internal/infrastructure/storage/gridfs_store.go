@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"backend-chat-app/internal/domain/media"
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GridFSStore keeps blobs in MongoDB, so every server instance sees the same
+// files without a shared disk. The key is the GridFS filename.
+type GridFSStore struct {
+	bucket *gridfs.Bucket
+}
+
+func NewGridFSStore(client *mongo.Client, database string) (*GridFSStore, error) {
+	bucket, err := gridfs.NewBucket(client.Database(database), options.GridFSBucket().SetName("blobs"))
+	if err != nil {
+		return nil, err
+	}
+	return &GridFSStore{bucket: bucket}, nil
+}
+
+type gridFSMetadata struct {
+	ContentType string `bson:"content_type"`
+}
+
+func (s *GridFSStore) Put(key string, contentType string, content io.Reader) (*media.BlobInfo, error) {
+	opts := options.GridFSUpload().SetMetadata(gridFSMetadata{ContentType: contentType})
+	fileID, err := s.bucket.UploadFromStream(key, content, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Readers get the newest revision, older ones are dropped once the new
+	// one is complete
+	var size int64
+	var modTime time.Time
+	files, err := s.find(key)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if file.ID == fileID {
+			size, modTime = file.Length, file.UploadDate
+			continue
+		}
+		if err := s.deleteFile(file.ID); err != nil {
+			return nil, err
+		}
+	}
+	return &media.BlobInfo{
+		Key:         key,
+		ContentType: contentType,
+		Size:        size,
+		ModTime:     modTime,
+	}, nil
+}
+
+func (s *GridFSStore) Get(key string) (io.ReadCloser, *media.BlobInfo, error) {
+	stream, err := s.bucket.OpenDownloadStreamByName(key)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file := stream.GetFile()
+	var metadata gridFSMetadata
+	if file.Metadata != nil {
+		_ = bson.Unmarshal(file.Metadata, &metadata)
+	}
+	if metadata.ContentType == "" {
+		metadata.ContentType = "application/octet-stream"
+	}
+	return stream, &media.BlobInfo{
+		Key:         key,
+		ContentType: metadata.ContentType,
+		Size:        file.Length,
+		ModTime:     file.UploadDate,
+	}, nil
+}
+
+func (s *GridFSStore) Delete(key string) error {
+	files, err := s.find(key)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := s.deleteFile(file.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type gridFSFile struct {
+	ID         interface{} `bson:"_id"`
+	Length     int64       `bson:"length"`
+	UploadDate time.Time   `bson:"uploadDate"`
+}
+
+func (s *GridFSStore) find(key string) ([]gridFSFile, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := s.bucket.FindContext(ctx, bson.M{"filename": key})
+	if err != nil {
+		return nil, err
+	}
+	var files []gridFSFile
+	if err := cursor.All(ctx, &files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (s *GridFSStore) deleteFile(fileID interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := s.bucket.DeleteContext(ctx, fileID)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil
+	}
+	return err
+}
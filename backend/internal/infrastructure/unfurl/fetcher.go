@@ -0,0 +1,182 @@
+package unfurl
+
+import (
+	"backend-chat-app/internal/domain/linkpreview"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+const (
+	fetchTimeout = 5 * time.Second
+	// Metadata lives in <head>, the rest of the page is never read
+	maxBodySize  = 512 << 10
+	maxRedirects = 3
+	userAgent    = "ChatAppLinkPreview/1.0"
+)
+
+var errBlockedAddress = errors.New("address is not allowed")
+
+// Fetcher reads previews from the public web. Links in messages are chosen
+// by users, so every connection, redirects included, is checked against the
+// resolved IP to keep the server from being used to reach internal services.
+type Fetcher struct {
+	client *http.Client
+	// allowedAddrs are host:port pairs exempt from the address and port
+	// checks. Only tests set it, to reach their own server on localhost.
+	allowedAddrs map[string]bool
+}
+
+func NewFetcher() *Fetcher {
+	return newFetcher(fetchTimeout)
+}
+
+func newFetcher(timeout time.Duration, allowedAddrs ...string) *Fetcher {
+	f := &Fetcher{allowedAddrs: make(map[string]bool, len(allowedAddrs))}
+	for _, addr := range allowedAddrs {
+		f.allowedAddrs[addr] = true
+	}
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		// Control runs after DNS resolution, on the address actually dialed,
+		// so a name can't resolve to a public IP once and a private one later
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if f.allowedAddrs[address] {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !isPublicIP(ip) {
+				return errBlockedAddress
+			}
+			return nil
+		},
+	}
+	transport := &http.Transport{
+		// A proxy would make the dial check see the proxy's address instead
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+	f.client = &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return errors.New("too many redirects")
+			}
+			return f.checkURL(req.URL)
+		},
+	}
+	return f
+}
+
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*linkpreview.Preview, error) {
+	pageURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.checkURL(pageURL); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if contentType != "text/html" && contentType != "application/xhtml+xml" {
+		return nil, errors.New("not an HTML page: " + contentType)
+	}
+
+	// The final URL after redirects is what relative image links refer to
+	preview := parseHead(io.LimitReader(resp.Body, maxBodySize), resp.Request.URL)
+	preview.URL = rawURL
+	return preview, nil
+}
+
+// checkURL allows plain web links only: no other schemes, no credentials
+// and no unusual ports.
+func (f *Fetcher) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("only http and https links are fetched")
+	}
+	if u.User != nil {
+		return errors.New("links with credentials are not fetched")
+	}
+	if u.Hostname() == "" {
+		return errors.New("link has no host")
+	}
+	if f.allowedAddrs[u.Host] {
+		return nil
+	}
+	switch u.Port() {
+	case "", "80", "443", "8080", "8443":
+		return nil
+	default:
+		return errors.New("port is not allowed")
+	}
+}
+
+var blockedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",       // "this" network
+	"100.64.0.0/10",   // carrier-grade NAT
+	"192.0.0.0/24",    // IETF protocol assignments
+	"192.0.2.0/24",    // documentation
+	"198.18.0.0/15",   // benchmarking
+	"198.51.100.0/24", // documentation
+	"203.0.113.0/24",  // documentation
+	"240.0.0.0/4",     // reserved, and broadcast
+	"64:ff9b::/96",    // NAT64, can map to private IPv4
+	"2001:db8::/32",   // documentation
+)
+
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
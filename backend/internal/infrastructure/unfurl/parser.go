@@ -0,0 +1,108 @@
+package unfurl
+
+import (
+	"backend-chat-app/internal/domain/linkpreview"
+	"io"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	maxTitleLength       = 200
+	maxDescriptionLength = 500
+)
+
+// parseHead reads OpenGraph and Twitter card tags, falling back to <title>
+// and the description meta tag. OpenGraph wins when a page has both.
+func parseHead(r io.Reader, pageURL *url.URL) *linkpreview.Preview {
+	meta := make(map[string]string)
+	var title string
+	inTitle := false
+
+	tokenizer := html.NewTokenizer(r)
+loop:
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			break loop
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.DataAtom {
+			case atom.Body:
+				break loop
+			case atom.Title:
+				inTitle = title == ""
+			case atom.Meta:
+				var key, content string
+				for _, attr := range token.Attr {
+					switch strings.ToLower(attr.Key) {
+					case "property", "name":
+						key = strings.ToLower(strings.TrimSpace(attr.Val))
+					case "content":
+						content = attr.Val
+					}
+				}
+				if key != "" && meta[key] == "" {
+					meta[key] = content
+				}
+			}
+		case html.TextToken:
+			if inTitle {
+				title += string(tokenizer.Text())
+			}
+		case html.EndTagToken:
+			token := tokenizer.Token()
+			switch token.DataAtom {
+			case atom.Head:
+				break loop
+			case atom.Title:
+				inTitle = false
+			}
+		}
+	}
+
+	first := func(values ...string) string {
+		for _, v := range values {
+			if v = cleanText(v); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	return &linkpreview.Preview{
+		Title:       truncate(first(meta["og:title"], meta["twitter:title"], title), maxTitleLength),
+		Description: truncate(first(meta["og:description"], meta["twitter:description"], meta["description"]), maxDescriptionLength),
+		ImageURL:    resolveImage(pageURL, first(meta["og:image:secure_url"], meta["og:image"], meta["twitter:image"], meta["twitter:image:src"])),
+		SiteName:    truncate(first(meta["og:site_name"], pageURL.Hostname()), maxTitleLength),
+	}
+}
+
+// resolveImage makes the image link absolute. Clients load it themselves,
+// so only web links are kept.
+func resolveImage(pageURL *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	imageURL, err := pageURL.Parse(ref)
+	if err != nil || (imageURL.Scheme != "http" && imageURL.Scheme != "https") {
+		return ""
+	}
+	return imageURL.String()
+}
+
+func cleanText(s string) string {
+	s = strings.ToValidUTF8(s, "")
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func truncate(s string, maxRunes int) string {
+	if utf8.RuneCountInString(s) <= maxRunes {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:maxRunes-1])) + "…"
+}
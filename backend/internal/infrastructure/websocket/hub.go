@@ -1,6 +1,7 @@
 package websocket
 
 import (
+	"backend-chat-app/internal/domain/contact"
 	"encoding/json"
 	"log"
 	"sync"
@@ -14,6 +15,9 @@ type Client struct {
 	Conn *websocket.Conn
 	Send chan []byte
 	Hub  *Hub
+	// hidden holds the users this client blocked or was blocked by; neither
+	// side sees the other's presence. Guarded by Hub.mu.
+	hidden map[string]bool
 }
 
 type Hub struct {
@@ -22,19 +26,23 @@ type Hub struct {
 	Register      chan *Client
 	Unregister    chan *Client
 	Broadcast     chan *Message
+	blockRepo     contact.BlockRepository
 	mu            sync.RWMutex
 }
 
 type Message struct {
-	ConversationID string `json:"conversation_id"`
-	SenderID       string `json:"sender_id"`
-	Message        string `json:"message"`
-	CreatedAt      int64  `json:"created_at"`
-	Type           string `json:"type"`
+	ConversationID string      `json:"conversation_id"`
+	SenderID       string      `json:"sender_id"`
+	Message        string      `json:"message"`
+	CreatedAt      int64       `json:"created_at"`
+	Type           string      `json:"type"`
+	Format         string      `json:"format,omitempty"` // "markup" on new_message sent by clients
+	Data           interface{} `json:"data,omitempty"`   // payload of events that are not chat messages
 }
 
-func NewHub() *Hub {
+func NewHub(blockRepo contact.BlockRepository) *Hub {
 	return &Hub{
+		blockRepo:     blockRepo,
 		Clients:       make(map[string]*Client),
 		Conversations: make(map[string]map[string]bool),
 		Register:      make(chan *Client),
@@ -47,10 +55,15 @@ func (h *Hub) Run() {
 	for {
 		select {
 		case client := <-h.Register:
+			hidden := h.loadHidden(client.ID)
 			h.mu.Lock()
+			client.hidden = hidden
 
 			onlineUsersList := make([]string, 0, len(h.Clients))
 			for userID := range h.Clients {
+				if hidden[userID] {
+					continue
+				}
 				onlineUsersList = append(onlineUsersList, userID)
 			}
 
@@ -81,7 +94,7 @@ func (h *Hub) Run() {
 
 			h.mu.RLock()
 			for _, c := range h.Clients {
-				if c.ID == client.ID {
+				if c.ID == client.ID || client.hidden[c.ID] {
 					continue
 				}
 				select {
@@ -104,6 +117,9 @@ func (h *Hub) Run() {
 				offlineMsgJSON, _ := json.Marshal(offlineMsg)
 
 				for _, c := range h.Clients {
+					if client.hidden[c.ID] {
+						continue
+					}
 					select {
 					case c.Send <- offlineMsgJSON:
 					default:
@@ -164,6 +180,124 @@ func (h *Hub) JoinConversation(conversationID string, userID string) {
 	log.Printf("User %s joined conversation %s. Total participants: %d", userID, conversationID, len(h.Conversations[conversationID]))
 }
 
+// LeaveConversation stops broadcasts of a conversation to the user.
+func (h *Hub) LeaveConversation(conversationID string, userID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	participants, ok := h.Conversations[conversationID]
+	if !ok {
+		return
+	}
+	delete(participants, userID)
+	if len(participants) == 0 {
+		delete(h.Conversations, conversationID)
+	}
+	log.Printf("User %s left conversation %s", userID, conversationID)
+}
+
+// RemoveConversation forgets a deleted conversation.
+func (h *Hub) RemoveConversation(conversationID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.Conversations, conversationID)
+}
+
+// RefreshBlock re-reads whether two users block each other and, when both
+// are online, makes each appear offline or online to the other accordingly.
+func (h *Hub) RefreshBlock(userA string, userB string) {
+	if h.blockRepo == nil {
+		return
+	}
+	blocked, err := h.blockRepo.IsBlockedEither(userA, userB)
+	if err != nil {
+		log.Printf("Failed to refresh block between %s and %s: %v", userA, userB, err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.setHidden(userA, userB, blocked)
+	h.setHidden(userB, userA, blocked)
+
+	clientA, onlineA := h.Clients[userA]
+	clientB, onlineB := h.Clients[userB]
+	if !onlineA || !onlineB {
+		return
+	}
+	presenceType := "user_online"
+	if blocked {
+		presenceType = "user_offline"
+	}
+	sendPresence(clientA, userB, presenceType)
+	sendPresence(clientB, userA, presenceType)
+}
+
+// setHidden must be called with h.mu held.
+func (h *Hub) setHidden(userID string, otherID string, hidden bool) {
+	client, ok := h.Clients[userID]
+	if !ok {
+		return
+	}
+	if client.hidden == nil {
+		client.hidden = make(map[string]bool)
+	}
+	if hidden {
+		client.hidden[otherID] = true
+	} else {
+		delete(client.hidden, otherID)
+	}
+}
+
+func sendPresence(client *Client, userID string, presenceType string) {
+	presence, _ := json.Marshal(Message{
+		Type:      presenceType,
+		SenderID:  userID,
+		CreatedAt: time.Now().Unix(),
+	})
+	select {
+	case client.Send <- presence:
+	default:
+	}
+}
+
+// SendToUser delivers an event to a single user if they are connected,
+// independent of the conversations they joined.
+func (h *Hub) SendToUser(userID string, message *Message) {
+	messageJson, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error marshaling message: %v", err)
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	client, ok := h.Clients[userID]
+	if !ok {
+		return
+	}
+	select {
+	case client.Send <- messageJson:
+	default:
+		log.Printf("Send buffer full for user %s, dropping %s event", userID, message.Type)
+	}
+}
+
+func (h *Hub) loadHidden(userID string) map[string]bool {
+	hidden := make(map[string]bool)
+	if h.blockRepo == nil {
+		return hidden
+	}
+	related, err := h.blockRepo.ListRelated(userID)
+	if err != nil {
+		log.Printf("Failed to load blocks of user %s: %v", userID, err)
+		return hidden
+	}
+	for _, id := range related {
+		hidden[id] = true
+	}
+	return hidden
+}
+
 func (h *Hub) IsOnline(userID string) bool {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
@@ -0,0 +1,108 @@
+package http
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/application/account"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AccountHandle struct {
+	accountService *account.AccountService
+}
+
+func NewAccountHandle(accountService *account.AccountService) *AccountHandle {
+	return &AccountHandle{
+		accountService: accountService,
+	}
+}
+
+func (h *AccountHandle) RequestExport(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.accountService.RequestExport(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to request export: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusAccepted, SuccessResponse(res, "Export queued, it will be ready to download shortly"))
+}
+
+func (h *AccountHandle) ListExports(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.accountService.ListExports(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to get exports: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Exports retrieved successfully"))
+}
+
+func (h *AccountHandle) DownloadExport(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	content, info, err := h.accountService.DownloadExport(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, FailResponse(nil, err.Error()))
+		return
+	}
+	defer content.Close()
+
+	c.DataFromReader(http.StatusOK, info.Size, info.ContentType, content, map[string]string{
+		"Content-Disposition": `attachment; filename="chat-export.zip"`,
+	})
+}
+
+func (h *AccountHandle) GetDeletion(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.accountService.GetDeletion(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Deletion status retrieved successfully"))
+}
+
+func (h *AccountHandle) RequestDeletion(c *gin.Context) {
+	var req application.DeleteAccountRequest
+	// Accounts without a password may send no body at all
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, FailResponse(nil, "Can not get delete account request data with err: "+err.Error()))
+			return
+		}
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.accountService.RequestDeletion(userID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to delete account: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Account deletion scheduled"))
+}
+
+func (h *AccountHandle) CancelDeletion(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	if err := h.accountService.CancelDeletion(userID); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(nil, "Account deletion cancelled"))
+}
@@ -0,0 +1,91 @@
+package http
+
+import (
+	"backend-chat-app/internal/application/attachment"
+	"backend-chat-app/internal/domain/apikey"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AttachmentHandle struct {
+	attachmentService *attachment.AttachmentService
+}
+
+func NewAttachmentHandle(attachmentService *attachment.AttachmentService) *AttachmentHandle {
+	return &AttachmentHandle{
+		attachmentService: attachmentService,
+	}
+}
+
+func (h *AttachmentHandle) Upload(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	conversationID := c.Param("id")
+	if !scopeAllows(apiKeyFromContext(c), apikey.ActionMessagesWrite, conversationID) {
+		c.JSON(http.StatusForbidden, FailResponse(nil, "API key is not allowed to write to this conversation"))
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, attachment.MaxAttachmentSize+1<<20)
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Missing file: "+err.Error()))
+		return
+	}
+	content, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Can not read file: "+err.Error()))
+		return
+	}
+	defer content.Close()
+
+	res, err := h.attachmentService.Upload(userID, conversationID, file.Filename, content)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to upload file: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusCreated, SuccessResponse(res, "File uploaded successfully"))
+}
+
+func (h *AttachmentHandle) Download(c *gin.Context) {
+	h.download(c, false)
+}
+
+func (h *AttachmentHandle) DownloadThumbnail(c *gin.Context) {
+	h.download(c, true)
+}
+
+func (h *AttachmentHandle) download(c *gin.Context, thumbnail bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	conversationID := c.Param("id")
+	if !scopeAllows(apiKeyFromContext(c), apikey.ActionMessagesRead, conversationID) {
+		c.JSON(http.StatusForbidden, FailResponse(nil, "API key is not allowed to read this conversation"))
+		return
+	}
+	content, info, err := h.attachmentService.Open(userID, conversationID, c.Param("attachmentId"), thumbnail)
+	if err != nil {
+		c.JSON(http.StatusNotFound, FailResponse(nil, err.Error()))
+		return
+	}
+	defer content.Close()
+
+	// Only images and audio are played inline; anything else is saved,
+	// never rendered
+	disposition := "attachment"
+	if strings.HasPrefix(info.ContentType, "image/") || strings.HasPrefix(info.ContentType, "audio/") {
+		disposition = "inline"
+	}
+	c.DataFromReader(http.StatusOK, info.Size, info.ContentType, content, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType(disposition, map[string]string{"filename": info.FileName}),
+		"X-Content-Type-Options": "nosniff",
+		"Cache-Control":          "private, max-age=86400",
+	})
+}
@@ -3,6 +3,8 @@ package http
 import (
 	"backend-chat-app/internal/application"
 	"backend-chat-app/internal/application/auth"
+	"errors"
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -64,7 +66,13 @@ func (h *AuthHandle) Login(c *gin.Context) {
 		return
 	}
 
+	req.ClientIP = c.ClientIP()
+
 	res, resErr := h.authService.Login(req)
+	if errors.Is(resErr, auth.ErrTooManyAttempts) {
+		c.JSON(http.StatusTooManyRequests, FailResponse(nil, resErr.Error()))
+		return
+	}
 	if resErr != nil {
 		c.JSON(http.StatusBadRequest, FailResponse(nil, "Login fail with error: "+resErr.Error()))
 		return
@@ -100,3 +108,73 @@ func (h *AuthHandle) Logout(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, SuccessResponse(nil, "Logout successful"))
 }
+
+func (h *AuthHandle) RequestEmailVerification(c *gin.Context) {
+	var req application.EmailRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Can not get email verification request data with err: "+err.Error()))
+		return
+	}
+	if err := h.authService.RequestEmailVerification(req); err != nil {
+		log.Printf("Email verification request failed: %v", err)
+	}
+	c.JSON(http.StatusOK, SuccessResponse(nil, "If the email belongs to an unverified account, a verification link has been sent"))
+}
+
+func (h *AuthHandle) VerifyEmail(c *gin.Context) {
+	var req application.VerifyEmailRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Can not get verify email request data with err: "+err.Error()))
+		return
+	}
+	if err := h.authService.VerifyEmail(req); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(nil, "Email verified successfully"))
+}
+
+func (h *AuthHandle) RequestPasswordReset(c *gin.Context) {
+	var req application.EmailRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Can not get password reset request data with err: "+err.Error()))
+		return
+	}
+	if err := h.authService.RequestPasswordReset(req); err != nil {
+		log.Printf("Password reset request failed: %v", err)
+	}
+	c.JSON(http.StatusOK, SuccessResponse(nil, "If the email belongs to an account, a password reset link has been sent"))
+}
+
+func (h *AuthHandle) ResetPassword(c *gin.Context) {
+	var req application.ResetPasswordRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Can not get reset password request data with err: "+err.Error()))
+		return
+	}
+	if err := h.authService.ResetPassword(req); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(nil, "Password reset successful"))
+}
+
+func (h *AuthHandle) ChangePassword(c *gin.Context) {
+	var req application.ChangePasswordRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Can not get change password request data with err: "+err.Error()))
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	req.UserID = userID
+
+	res, err := h.authService.ChangePassword(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Password changed, other sessions have been signed out"))
+}
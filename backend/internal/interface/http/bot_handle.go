@@ -0,0 +1,95 @@
+package http
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/application/bot"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BotHandle struct {
+	botService *bot.BotService
+}
+
+func NewBotHandle(botService *bot.BotService) *BotHandle {
+	return &BotHandle{
+		botService: botService,
+	}
+}
+
+func (h *BotHandle) CreateBot(c *gin.Context) {
+	var req application.CreateBotRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Can not get create bot request data with err: "+err.Error()))
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	req.OwnerID = userID
+
+	res, err := h.botService.CreateBot(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to create bot: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusCreated, SuccessResponse(res, "Bot created successfully"))
+}
+
+func (h *BotHandle) ListBots(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.botService.ListBots(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to get bots: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Bots retrieved successfully"))
+}
+
+func (h *BotHandle) CreateAPIKey(c *gin.Context) {
+	var req application.CreateAPIKeyRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Can not get create api key request data with err: "+err.Error()))
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.botService.CreateAPIKey(userID, c.Param("id"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to create api key: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusCreated, SuccessResponse(res, "API key created, store it now as it won't be shown again"))
+}
+
+func (h *BotHandle) ListAPIKeys(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.botService.ListAPIKeys(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to get api keys: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "API keys retrieved successfully"))
+}
+
+func (h *BotHandle) RevokeAPIKey(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	if err := h.botService.RevokeAPIKey(userID, c.Param("id"), c.Param("keyId")); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to revoke api key: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(nil, "API key revoked"))
+}
@@ -3,6 +3,7 @@ package http
 import (
 	"backend-chat-app/internal/application"
 	"backend-chat-app/internal/application/chat"
+	"backend-chat-app/internal/domain/apikey"
 	ws "backend-chat-app/internal/infrastructure/websocket"
 	"encoding/json"
 	"log"
@@ -83,42 +84,517 @@ func (h *ChatHandle) SendMessage(c *gin.Context) {
 		return
 	}
 	req.SenderID = userIDStr
+	if !scopeAllows(apiKeyFromContext(c), apikey.ActionMessagesWrite, req.ConversationID) {
+		c.JSON(http.StatusForbidden, FailResponse(nil, "API key is not allowed to write to this conversation"))
+		return
+	}
 	res, err := h.chatService.SendMessage(req)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, FailResponse(nil, "Send message fail with err: "+err.Error()))
 		return
 	}
 	if h.hub != nil {
-		msgJSON, _ := json.Marshal(map[string]interface{}{
-			"type":            "new_message",
-			"conversation_id": req.ConversationID,
-			"sender_id":       userIDStr,
-			"message":         res.Message,
-			"created_at":      res.CreatedAt,
+		broadcastNewMessage(h.hub, userIDStr, res)
+	}
+	c.JSON(http.StatusCreated, SuccessResponse(res, "Message sent successfully"))
+}
+
+func (h *ChatHandle) ForwardMessages(c *gin.Context) {
+	var req application.ForwardMessagesRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Invalid request data: "+err.Error()))
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	req.UserID = userID
+	key := apiKeyFromContext(c)
+	if !scopeAllows(key, apikey.ActionMessagesRead, req.ConversationID) {
+		c.JSON(http.StatusForbidden, FailResponse(nil, "API key is not allowed to read this conversation"))
+		return
+	}
+	for _, target := range req.TargetConversationIDs {
+		if !scopeAllows(key, apikey.ActionMessagesWrite, target) {
+			c.JSON(http.StatusForbidden, FailResponse(nil, "API key is not allowed to write to conversation "+target))
+			return
+		}
+	}
+
+	res, err := h.chatService.ForwardMessages(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to forward messages: "+err.Error()))
+		return
+	}
+	if h.hub != nil {
+		for i := range res.Messages {
+			broadcastNewMessage(h.hub, userID, &res.Messages[i])
+		}
+	}
+	c.JSON(http.StatusCreated, SuccessResponse(res, "Messages forwarded successfully"))
+}
+
+// BroadcastNewMessage delivers messages the server sends on behalf of users,
+// such as scheduled ones.
+func BroadcastNewMessage(hub *ws.Hub) func(string, *application.SendMessageResponse) {
+	return func(senderID string, res *application.SendMessageResponse) {
+		broadcastNewMessage(hub, senderID, res)
+	}
+}
+
+// broadcastNewMessage delivers a message sent over HTTP to the conversation,
+// with the message encoded as JSON like clients do over the WebSocket.
+func broadcastNewMessage(hub *ws.Hub, senderID string, res *application.SendMessageResponse) {
+	msgJSON, _ := json.Marshal(map[string]interface{}{
+		"type":            "new_message",
+		"message_id":      res.MessageID,
+		"conversation_id": res.ConversationID,
+		"sender_id":       senderID,
+		"message_type":    res.Type,
+		"message":         res.Message,
+		"attachments":     res.Attachments,
+		"mentions":        res.Mentions,
+		"entities":        res.Entities,
+		"forwarded_from":  res.ForwardedFrom,
+		"poll":            res.Poll,
+		"created_at":      res.CreatedAt,
+	})
+
+	hub.Broadcast <- &ws.Message{
+		ConversationID: res.ConversationID,
+		SenderID:       senderID,
+		Message:        string(msgJSON),
+		CreatedAt:      res.CreatedAt,
+		Type:           "new_message",
+	}
+	sendNotifications(hub, res.Notification)
+}
+
+// sendNotifications alerts the participants who did not mute the
+// conversation, whether or not they have it open, and everyone mentioned.
+func sendNotifications(hub *ws.Hub, notification *application.MessageNotification) {
+	if notification == nil {
+		return
+	}
+	for _, userID := range notification.Recipients {
+		hub.SendToUser(userID, &ws.Message{
+			Type:           "notification",
+			ConversationID: notification.ConversationID,
+			SenderID:       notification.SenderID,
+			CreatedAt:      notification.CreatedAt,
+			Data:           notification,
 		})
+	}
+	for _, userID := range notification.Mentioned {
+		hub.SendToUser(userID, &ws.Message{
+			Type:           "mention",
+			ConversationID: notification.ConversationID,
+			SenderID:       notification.SenderID,
+			CreatedAt:      notification.CreatedAt,
+			Data:           notification,
+		})
+	}
+}
 
-		h.hub.Broadcast <- &ws.Message{
-			ConversationID: req.ConversationID,
-			SenderID:       userIDStr,
-			Message:        string(msgJSON),
-			CreatedAt:      res.CreatedAt,
-			Type:           "new_message",
+func (h *ChatHandle) GetMentions(c *gin.Context) {
+	var req application.GetMentionsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Invalid mentions parameters: "+err.Error()))
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	req.UserID = userID
+
+	res, err := h.chatService.GetMentions(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to get mentions: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Mentions retrieved successfully"))
+}
+
+// BroadcastLinkPreviews pushes previews to the conversation once the links
+// of a message were fetched.
+func BroadcastLinkPreviews(hub *ws.Hub) func(*application.MessagePreviews) {
+	return func(previews *application.MessagePreviews) {
+		hub.Broadcast <- &ws.Message{
+			Type:           "message_preview",
+			ConversationID: previews.ConversationID,
+			CreatedAt:      time.Now().Unix(),
+			Data:           previews,
+		}
+	}
+}
+
+// BroadcastMessagesExpired tells participants to remove messages that
+// disappeared.
+func BroadcastMessagesExpired(hub *ws.Hub) func(*application.MessagesExpired) {
+	return func(expired *application.MessagesExpired) {
+		hub.Broadcast <- &ws.Message{
+			Type:           "messages_expired",
+			ConversationID: expired.ConversationID,
+			CreatedAt:      time.Now().Unix(),
+			Data:           expired,
 		}
 	}
-	c.JSON(http.StatusCreated, SuccessResponse(res, "Message sent successfully"))
 }
 
 func (h *ChatHandle) GetConversation(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
 	conversationId := c.Param("id")
 	if conversationId == "" {
 		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to send Message"))
 		return
 	}
-	res, err := h.chatService.GetConversation(conversationId)
+	if !scopeAllows(apiKeyFromContext(c), apikey.ActionMessagesRead, conversationId) {
+		c.JSON(http.StatusForbidden, FailResponse(nil, "API key is not allowed to read this conversation"))
+		return
+	}
+	res, err := h.chatService.GetConversation(userID, conversationId)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to send Message"))
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to get conversation: "+err.Error()))
 		return
 	}
 	c.JSON(http.StatusCreated, SuccessResponse(res, "Conversation created successfully"))
 
 }
+
+func (h *ChatHandle) LeaveConversation(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.chatService.LeaveConversation(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to leave conversation: "+err.Error()))
+		return
+	}
+	if h.hub != nil {
+		h.hub.LeaveConversation(res.ConversationID, userID)
+		h.broadcastChange("participant_left", userID, res)
+	}
+	c.JSON(http.StatusOK, SuccessResponse(nil, "Left conversation successfully"))
+}
+
+func (h *ChatHandle) RenameConversation(c *gin.Context) {
+	var req application.RenameConversationRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Invalid request data: "+err.Error()))
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.chatService.RenameConversation(userID, c.Param("id"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to rename conversation: "+err.Error()))
+		return
+	}
+	if h.hub != nil {
+		h.broadcastChange("conversation_renamed", userID, res)
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Conversation renamed successfully"))
+}
+
+func (h *ChatHandle) SetMessageTTL(c *gin.Context) {
+	var req application.SetMessageTTLRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Invalid request data: "+err.Error()))
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.chatService.SetMessageTTL(userID, c.Param("id"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to set message timer: "+err.Error()))
+		return
+	}
+	if h.hub != nil {
+		h.broadcastChange("message_ttl_changed", userID, res)
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Message timer updated successfully"))
+}
+
+func (h *ChatHandle) DeleteConversation(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.chatService.DeleteConversation(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to delete conversation: "+err.Error()))
+		return
+	}
+	if h.hub != nil {
+		// Sent directly since the conversation no longer exists to broadcast to
+		now := time.Now().Unix()
+		for _, participantID := range res.Participants {
+			h.hub.SendToUser(participantID, &ws.Message{
+				Type:           "conversation_deleted",
+				ConversationID: res.ConversationID,
+				SenderID:       userID,
+				CreatedAt:      now,
+				Data:           res,
+			})
+		}
+		h.hub.RemoveConversation(res.ConversationID)
+	}
+	c.JSON(http.StatusOK, SuccessResponse(nil, "Conversation deleted successfully"))
+}
+
+func (h *ChatHandle) CreateInvite(c *gin.Context) {
+	var req application.CreateInviteRequest
+	// Both limits are optional, so an empty body is fine
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, FailResponse(nil, "Invalid request data: "+err.Error()))
+			return
+		}
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.chatService.CreateInvite(userID, c.Param("id"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to create invite: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusCreated, SuccessResponse(res, "Invite created successfully"))
+}
+
+func (h *ChatHandle) ListInvites(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.chatService.ListInvites(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to get invites: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Invites retrieved successfully"))
+}
+
+func (h *ChatHandle) RevokeInvite(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	if err := h.chatService.RevokeInvite(userID, c.Param("id"), c.Param("inviteId")); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to revoke invite: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(nil, "Invite revoked successfully"))
+}
+
+func (h *ChatHandle) PreviewInvite(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.chatService.PreviewInvite(userID, c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, FailResponse(nil, err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Invite retrieved successfully"))
+}
+
+func (h *ChatHandle) JoinByInvite(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.chatService.JoinByInvite(userID, c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to join conversation: "+err.Error()))
+		return
+	}
+	if h.hub != nil {
+		h.hub.JoinConversation(res.ConversationID, userID)
+		h.broadcastChange("participant_joined", userID, res)
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Joined conversation successfully"))
+}
+
+// PinMessage pins a message for everyone in the conversation, who get a
+// message_pinned event.
+func (h *ChatHandle) PinMessage(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.chatService.PinMessage(userID, c.Param("id"), c.Param("messageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to pin message: "+err.Error()))
+		return
+	}
+	if h.hub != nil {
+		h.broadcastPin("message_pinned", userID, res)
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Message pinned successfully"))
+}
+
+func (h *ChatHandle) UnpinMessage(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.chatService.UnpinMessage(userID, c.Param("id"), c.Param("messageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to unpin message: "+err.Error()))
+		return
+	}
+	if h.hub != nil {
+		h.broadcastPin("message_unpinned", userID, res)
+	}
+	c.JSON(http.StatusOK, SuccessResponse(nil, "Message unpinned successfully"))
+}
+
+func (h *ChatHandle) ListPinnedMessages(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.chatService.ListPinnedMessages(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to get pinned messages: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Pinned messages retrieved successfully"))
+}
+
+func (h *ChatHandle) GetPoll(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.chatService.GetPoll(userID, c.Param("id"), c.Param("messageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to get poll: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Poll retrieved successfully"))
+}
+
+func (h *ChatHandle) VotePoll(c *gin.Context) {
+	var req application.PollVoteRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Invalid request data: "+err.Error()))
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	req.UserID = userID
+	req.ConversationID = c.Param("id")
+	req.MessageID = c.Param("messageId")
+
+	res, err := h.chatService.VotePoll(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to vote: "+err.Error()))
+		return
+	}
+	if h.hub != nil {
+		broadcastPollUpdate(h.hub, userID, res)
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Vote recorded successfully"))
+}
+
+func (h *ChatHandle) RetractPollVote(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.chatService.RetractPollVote(userID, c.Param("id"), c.Param("messageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to retract vote: "+err.Error()))
+		return
+	}
+	if h.hub != nil {
+		broadcastPollUpdate(h.hub, userID, res)
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Vote retracted successfully"))
+}
+
+func (h *ChatHandle) ClosePoll(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.chatService.ClosePoll(userID, c.Param("id"), c.Param("messageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to close poll: "+err.Error()))
+		return
+	}
+	if h.hub != nil {
+		broadcastPollUpdate(h.hub, userID, res)
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Poll closed successfully"))
+}
+
+// BroadcastPollUpdates pushes polls the server closed when their time ran
+// out.
+func BroadcastPollUpdates(hub *ws.Hub) func(*application.PollUpdate) {
+	return func(update *application.PollUpdate) {
+		broadcastPollUpdate(hub, "", update)
+	}
+}
+
+// broadcastPollUpdate sends the new tally to the conversation. The user's
+// own vote stays in the response to them, and anonymous polls do not name
+// the voter.
+func broadcastPollUpdate(hub *ws.Hub, userID string, update *application.PollUpdate) {
+	shared := *update
+	shared.Poll.VotedOptionIDs = nil
+	msg := &ws.Message{
+		Type:           "poll_updated",
+		ConversationID: update.ConversationID,
+		CreatedAt:      time.Now().Unix(),
+		Data:           &shared,
+	}
+	if !update.Poll.Anonymous {
+		msg.SenderID = userID
+	}
+	hub.Broadcast <- msg
+}
+
+func (h *ChatHandle) broadcastPin(eventType string, userID string, pin *application.PinnedMessage) {
+	h.hub.Broadcast <- &ws.Message{
+		Type:           eventType,
+		ConversationID: pin.ConversationID,
+		SenderID:       userID,
+		CreatedAt:      time.Now().Unix(),
+		Data:           pin,
+	}
+}
+
+// broadcastChange tells the participants still in the conversation about a
+// join, leave, rename or timer change, along with the system message added to
+// the history.
+func (h *ChatHandle) broadcastChange(eventType string, userID string, change *application.ConversationChange) {
+	msg := &ws.Message{
+		Type:           eventType,
+		ConversationID: change.ConversationID,
+		SenderID:       userID,
+		CreatedAt:      time.Now().Unix(),
+		Data:           change,
+	}
+	if change.Message != nil {
+		msg.Message = change.Message.Message
+		msg.CreatedAt = change.Message.CreatedAt
+	}
+	h.hub.Broadcast <- msg
+}
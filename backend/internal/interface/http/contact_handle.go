@@ -0,0 +1,180 @@
+package http
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/application/contact"
+	ws "backend-chat-app/internal/infrastructure/websocket"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ContactHandle struct {
+	contactService *contact.ContactService
+	hub            *ws.Hub
+}
+
+func NewContactHandle(contactService *contact.ContactService, hub *ws.Hub) *ContactHandle {
+	return &ContactHandle{
+		contactService: contactService,
+		hub:            hub,
+	}
+}
+
+func (h *ContactHandle) ListContacts(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.contactService.ListContacts(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to get contacts: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Contacts retrieved successfully"))
+}
+
+func (h *ContactHandle) RemoveContact(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	if err := h.contactService.RemoveContact(userID, c.Param("id")); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to remove contact: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(nil, "Contact removed successfully"))
+}
+
+func (h *ContactHandle) SendRequest(c *gin.Context) {
+	var req application.SendContactRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Can not get contact request data with err: "+err.Error()))
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	req.FromID = userID
+
+	res, err := h.contactService.SendRequest(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to send contact request: "+err.Error()))
+		return
+	}
+	h.notify(userID, res)
+	c.JSON(http.StatusCreated, SuccessResponse(res, "Contact request sent successfully"))
+}
+
+func (h *ContactHandle) ListRequests(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	direction := c.DefaultQuery("direction", "incoming")
+	if direction != "incoming" && direction != "outgoing" {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "direction must be incoming or outgoing"))
+		return
+	}
+	res, err := h.contactService.ListRequests(userID, direction == "incoming")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to get contact requests: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Contact requests retrieved successfully"))
+}
+
+func (h *ContactHandle) AcceptRequest(c *gin.Context) {
+	h.answerRequest(c, h.contactService.AcceptRequest, "Contact request accepted")
+}
+
+func (h *ContactHandle) DeclineRequest(c *gin.Context) {
+	h.answerRequest(c, h.contactService.DeclineRequest, "Contact request declined")
+}
+
+func (h *ContactHandle) CancelRequest(c *gin.Context) {
+	h.answerRequest(c, h.contactService.CancelRequest, "Contact request cancelled")
+}
+
+func (h *ContactHandle) answerRequest(c *gin.Context, answer func(string, string) (*application.ContactRequestInfo, error), successMessage string) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := answer(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, err.Error()))
+		return
+	}
+	h.notify(userID, res)
+	c.JSON(http.StatusOK, SuccessResponse(res, successMessage))
+}
+
+func (h *ContactHandle) ListBlocked(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.contactService.ListBlocked(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to get blocked users: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Blocked users retrieved successfully"))
+}
+
+func (h *ContactHandle) BlockUser(c *gin.Context) {
+	var req application.BlockUserRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Can not get block request data with err: "+err.Error()))
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.contactService.BlockUser(userID, req.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to block user: "+err.Error()))
+		return
+	}
+	if h.hub != nil {
+		h.hub.RefreshBlock(userID, req.UserID)
+	}
+	c.JSON(http.StatusCreated, SuccessResponse(res, "User blocked successfully"))
+}
+
+func (h *ContactHandle) UnblockUser(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	blockedID := c.Param("id")
+	if err := h.contactService.UnblockUser(userID, blockedID); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to unblock user: "+err.Error()))
+		return
+	}
+	if h.hub != nil {
+		h.hub.RefreshBlock(userID, blockedID)
+	}
+	c.JSON(http.StatusOK, SuccessResponse(nil, "User unblocked successfully"))
+}
+
+// notify sends a contact_request event to the other side of the request.
+func (h *ContactHandle) notify(actorID string, request *application.ContactRequestInfo) {
+	if h.hub == nil {
+		return
+	}
+	recipient := request.FromID
+	if recipient == actorID {
+		recipient = request.ToID
+	}
+	h.hub.SendToUser(recipient, &ws.Message{
+		Type:      "contact_request",
+		SenderID:  actorID,
+		CreatedAt: time.Now().Unix(),
+		Data:      request,
+	})
+}
@@ -0,0 +1,41 @@
+package http
+
+import (
+	"backend-chat-app/internal/domain/apikey"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeyFromContext returns the API key a bot authenticated with, or nil for
+// a regular user.
+func apiKeyFromContext(c *gin.Context) *apikey.APIKey {
+	value, exists := c.Get("api_key")
+	if !exists {
+		return nil
+	}
+	key, _ := value.(*apikey.APIKey)
+	return key
+}
+
+// scopeAllows is true for regular users and for bots whose key grants action
+// in the conversation.
+func scopeAllows(key *apikey.APIKey, action string, conversationID string) bool {
+	return key == nil || key.Allows(action, conversationID)
+}
+
+// currentUserID reads the authenticated user set by the auth middleware and
+// writes the error response itself when it is missing.
+func currentUserID(c *gin.Context) (string, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, FailResponse(nil, "Unauthorized"))
+		return "", false
+	}
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, FailResponse(nil, "Invalid user ID format"))
+		return "", false
+	}
+	return userIDStr, true
+}
@@ -0,0 +1,32 @@
+package http
+
+import (
+	"backend-chat-app/internal/domain/apikey"
+	"testing"
+)
+
+func TestScopeAllows(t *testing.T) {
+	key := &apikey.APIKey{Scopes: []apikey.Scope{
+		{Action: apikey.ActionMessagesWrite, ConversationID: "c1"},
+		{Action: apikey.ActionMessagesRead, ConversationID: "c2"},
+	}}
+	tests := []struct {
+		name           string
+		key            *apikey.APIKey
+		action         string
+		conversationID string
+		want           bool
+	}{
+		{"user", nil, apikey.ActionMessagesWrite, "c3", true},
+		{"write scope writes", key, apikey.ActionMessagesWrite, "c1", true},
+		{"write scope reads", key, apikey.ActionMessagesRead, "c1", true},
+		{"read scope reads", key, apikey.ActionMessagesRead, "c2", true},
+		{"read scope can not write", key, apikey.ActionMessagesWrite, "c2", false},
+		{"other conversation", key, apikey.ActionMessagesRead, "c3", false},
+	}
+	for _, tt := range tests {
+		if got := scopeAllows(tt.key, tt.action, tt.conversationID); got != tt.want {
+			t.Errorf("%s: scopeAllows = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
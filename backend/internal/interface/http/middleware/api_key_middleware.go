@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"backend-chat-app/internal/application/auth"
+	"backend-chat-app/internal/application/bot"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthOrAPIKeyMiddleware accepts either a user's JWT or a bot API key. API
+// keys are long lived, so they are only read from the Authorization or
+// X-API-Key header and never from the token query param, which ends up in
+// access logs. For API keys the key is stored under "api_key" so handlers can
+// check scopes.
+func AuthOrAPIKeyMiddleware(authService auth.Service, botService *bot.BotService) gin.HandlerFunc {
+	jwtMiddleware := AuthMiddleware(authService)
+	return func(c *gin.Context) {
+		if bot.IsAPIKey(c.Query("token")) {
+			abortUnauthorized(c, "api keys must be sent in the Authorization or X-API-Key header")
+			return
+		}
+
+		token := c.GetHeader("X-API-Key")
+		if token == "" {
+			token = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+
+		if !bot.IsAPIKey(token) {
+			jwtMiddleware(c)
+			return
+		}
+
+		key, err := botService.Authenticate(token)
+		if err != nil {
+			abortUnauthorized(c, err.Error())
+			return
+		}
+
+		c.Set("user_id", key.BotID)
+		c.Set("api_key", key)
+		c.Next()
+	}
+}
+
+func abortUnauthorized(c *gin.Context, reason string) {
+	if c.GetHeader("Upgrade") == "websocket" {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + reason})
+	c.Abort()
+}
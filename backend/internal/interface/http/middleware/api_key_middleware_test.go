@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"backend-chat-app/internal/application/auth"
+	"backend-chat-app/internal/application/bot"
+	"backend-chat-app/internal/domain/apikey"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// noKeys has no API keys, so any key that reaches it is refused.
+type noKeys struct{ apikey.APIKeyRepository }
+
+func (noKeys) GetByPrefix(prefix string) (*apikey.APIKey, error) { return nil, nil }
+
+func (noKeys) TouchLastUsed(keyID string, at time.Time) error { return nil }
+
+func serve(t *testing.T, req *http.Request) (int, bool) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	reached := false
+	r.GET("/ws", AuthOrAPIKeyMiddleware(auth.Service{}, bot.NewBotService(nil, nil, noKeys{})), func(c *gin.Context) {
+		reached = true
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code, reached
+}
+
+func TestAPIKeyInQueryIsRefused(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/ws?token=ck_0123456789abcdef_secret", nil)
+	code, reached := serve(t, req)
+	if code != http.StatusUnauthorized || reached {
+		t.Fatalf("api key in query: status %d, handler reached %v", code, reached)
+	}
+}
+
+func TestUnknownAPIKeyInHeaderIsRefused(t *testing.T) {
+	for _, header := range []string{"X-API-Key", "Authorization"} {
+		req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+		value := "ck_0123456789abcdef_secret"
+		if header == "Authorization" {
+			value = "Bearer " + value
+		}
+		req.Header.Set(header, value)
+		code, reached := serve(t, req)
+		if code != http.StatusUnauthorized || reached {
+			t.Fatalf("%s: status %d, handler reached %v", header, code, reached)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/application/chat"
+	ws "backend-chat-app/internal/infrastructure/websocket"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ScheduleHandle struct {
+	scheduleService *chat.ScheduleService
+}
+
+func NewScheduleHandle(scheduleService *chat.ScheduleService) *ScheduleHandle {
+	return &ScheduleHandle{
+		scheduleService: scheduleService,
+	}
+}
+
+func (h *ScheduleHandle) ScheduleMessage(c *gin.Context) {
+	var req application.ScheduleMessageRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Invalid request data: "+err.Error()))
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	req.SenderID = userID
+
+	res, err := h.scheduleService.ScheduleMessage(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to schedule message: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusCreated, SuccessResponse(res, "Message scheduled successfully"))
+}
+
+func (h *ScheduleHandle) ListScheduledMessages(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.scheduleService.ListScheduledMessages(userID, c.Query("conversation_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to get scheduled messages: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Scheduled messages retrieved successfully"))
+}
+
+func (h *ScheduleHandle) UpdateScheduledMessage(c *gin.Context) {
+	var req application.UpdateScheduledMessageRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Invalid request data: "+err.Error()))
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	req.SenderID = userID
+	req.ID = c.Param("id")
+
+	res, err := h.scheduleService.UpdateScheduledMessage(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to update scheduled message: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Scheduled message updated successfully"))
+}
+
+func (h *ScheduleHandle) CancelScheduledMessage(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	if err := h.scheduleService.CancelScheduledMessage(userID, c.Param("id")); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to cancel scheduled message: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(nil, "Scheduled message canceled successfully"))
+}
+
+// NotifyScheduleFailed tells the sender a scheduled message could not be
+// sent. It stays in their list to be rescheduled or canceled.
+func NotifyScheduleFailed(hub *ws.Hub) func(*application.ScheduledMessage) {
+	return func(m *application.ScheduledMessage) {
+		hub.SendToUser(m.SenderID, &ws.Message{
+			Type:           "scheduled_message_failed",
+			ConversationID: m.ConversationID,
+			SenderID:       m.SenderID,
+			CreatedAt:      time.Now().Unix(),
+			Data:           m,
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package http
+
+import (
+	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/application/auth"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ssoStateCookie ties a single sign-on callback to the browser that started it.
+const ssoStateCookie = "sso_state"
+
+type SSOHandle struct {
+	ssoService *auth.SSOService
+}
+
+func NewSSOHandle(ssoService *auth.SSOService) *SSOHandle {
+	return &SSOHandle{
+		ssoService: ssoService,
+	}
+}
+
+// Login redirects the browser to the identity provider. Clients that would
+// rather navigate themselves can pass ?redirect=false to get the URL as JSON.
+func (h *SSOHandle) Login(c *gin.Context) {
+	authURL, binding, err := h.ssoService.Start()
+	if err != nil {
+		c.JSON(http.StatusBadGateway, FailResponse(nil, "Failed to start single sign-on: "+err.Error()))
+		return
+	}
+	setSSOStateCookie(c, binding, int(auth.SSOLoginStateTTL.Seconds()))
+	if c.Query("redirect") == "false" {
+		c.JSON(http.StatusOK, SuccessResponse(application.SSOLoginResponse{AuthorizationURL: authURL}, "Single sign-on started"))
+		return
+	}
+	c.Redirect(http.StatusFound, authURL)
+}
+
+func (h *SSOHandle) Callback(c *gin.Context) {
+	var req application.SSOCallbackRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Can not get SSO callback request data with err: "+err.Error()))
+		return
+	}
+	req.Binding, _ = c.Cookie(ssoStateCookie)
+	// Single use either way, a new attempt starts with a new login
+	setSSOStateCookie(c, "", -1)
+
+	res, err := h.ssoService.Callback(req)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, FailResponse(nil, "Single sign-on failed: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusCreated, SuccessResponse(res, "Login successful"))
+}
+
+// setSSOStateCookie sets the binding cookie. The callback is posted by the
+// frontend from another site, and a Lax cookie would not be sent with it, so
+// the cookie is SameSite=None, which browsers only accept when Secure.
+// Browsers treat http://localhost as secure, so local development still works.
+func setSSOStateCookie(c *gin.Context, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteNoneMode)
+	c.SetCookie(ssoStateCookie, value, maxAge, "/auth/oidc", "", true, true)
+}
@@ -0,0 +1,31 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSSOStateCookieIsSentCrossSite(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "http://api.example.com/auth/oidc/login", nil)
+
+	setSSOStateCookie(c, "binding", 600)
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("got %d cookies", len(cookies))
+	}
+	cookie := cookies[0]
+	if cookie.Name != ssoStateCookie || cookie.Value != "binding" {
+		t.Fatalf("unexpected cookie %s=%s", cookie.Name, cookie.Value)
+	}
+	// The frontend posts the callback from another site
+	if cookie.SameSite != http.SameSiteNoneMode || !cookie.Secure || !cookie.HttpOnly {
+		t.Fatalf("cookie would not reach the callback: %+v", cookie)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"backend-chat-app/internal/application"
 	"backend-chat-app/internal/application/user"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -24,6 +25,11 @@ func (h *UserHandle) FindUserByPhone(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, FailResponse(nil, "Can not get FindUserByPhone request data with err: "+err.Error()))
 		return
 	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	req.UserID = userID
 	res, resErr := h.userService.FindUserByPhone(req)
 	if resErr != nil {
 		c.JSON(http.StatusBadRequest, FailResponse(nil, resErr.Error()))
@@ -33,20 +39,197 @@ func (h *UserHandle) FindUserByPhone(c *gin.Context) {
 }
 
 func (h *UserHandle) GetConversationList(c *gin.Context) {
-	userId, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusBadRequest, FailResponse(nil, "Unauthorized"))
+	var req application.GetConversationListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Invalid conversation list parameters: "+err.Error()))
 		return
 	}
-	userIdStr, ok := userId.(string)
+	userID, ok := currentUserID(c)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, FailResponse(nil, "Invalid user ID format"))
 		return
 	}
-	res, err := h.userService.GetConversationList(userIdStr)
+	req.UserID = userID
+
+	res, err := h.userService.GetConversationList(req)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, FailResponse(nil, "Failed to get conversation list: "+err.Error()))
 		return
 	}
 	c.JSON(http.StatusOK, SuccessResponse(res, "Conversation list retrieved successfully"))
 }
+
+func (h *UserHandle) GetConversationSettings(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.userService.GetConversationSettings(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Conversation settings retrieved successfully"))
+}
+
+func (h *UserHandle) UpdateConversationSettings(c *gin.Context) {
+	var req application.UpdateConversationSettingsRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Can not get conversation settings data with err: "+err.Error()))
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.userService.UpdateConversationSettings(userID, c.Param("id"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Conversation settings updated successfully"))
+}
+
+func (h *UserHandle) SearchUsers(c *gin.Context) {
+	var req application.SearchUsersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Invalid search parameters: "+err.Error()))
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	req.ViewerID = userID
+
+	res, err := h.userService.SearchUsers(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Users retrieved successfully"))
+}
+
+func (h *UserHandle) GetMyProfile(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.userService.GetMyProfile(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, FailResponse(nil, err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Profile retrieved successfully"))
+}
+
+func (h *UserHandle) UpdateMyProfile(c *gin.Context) {
+	var req application.UpdateProfileRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Can not get update profile request data with err: "+err.Error()))
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.userService.UpdateProfile(userID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Profile updated successfully"))
+}
+
+func (h *UserHandle) GetPrivacy(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.userService.GetPrivacy(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, FailResponse(nil, err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Privacy settings retrieved successfully"))
+}
+
+func (h *UserHandle) UpdatePrivacy(c *gin.Context) {
+	var req application.UpdatePrivacyRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Can not get privacy request data with err: "+err.Error()))
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	res, err := h.userService.UpdatePrivacy(userID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Privacy settings updated successfully"))
+}
+
+func (h *UserHandle) GetProfile(c *gin.Context) {
+	res, err := h.userService.GetProfile(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, FailResponse(nil, err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Profile retrieved successfully"))
+}
+
+func (h *UserHandle) UploadAvatar(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, user.MaxAvatarSize+1<<20)
+	file, err := c.FormFile("avatar")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Missing avatar file: "+err.Error()))
+		return
+	}
+	content, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, "Can not read avatar file: "+err.Error()))
+		return
+	}
+	defer content.Close()
+
+	res, err := h.userService.UploadAvatar(userID, content)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(res, "Avatar updated successfully"))
+}
+
+func (h *UserHandle) DeleteAvatar(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	if err := h.userService.DeleteAvatar(userID); err != nil {
+		c.JSON(http.StatusBadRequest, FailResponse(nil, err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(nil, "Avatar removed"))
+}
+
+func (h *UserHandle) GetAvatar(c *gin.Context) {
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "128"))
+	content, info, err := h.userService.GetAvatar(c.Param("id"), size)
+	if err != nil {
+		c.JSON(http.StatusNotFound, FailResponse(nil, err.Error()))
+		return
+	}
+	defer content.Close()
+
+	if c.Query("v") != "" {
+		// Versioned URLs never change content
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	}
+	c.DataFromReader(http.StatusOK, info.Size, info.ContentType, content, nil)
+}
@@ -2,7 +2,9 @@ package http
 
 import (
 	"backend-chat-app/internal/application"
+	"backend-chat-app/internal/application/bot"
 	"backend-chat-app/internal/application/chat"
+	"backend-chat-app/internal/domain/apikey"
 	ws "backend-chat-app/internal/infrastructure/websocket"
 	"encoding/json"
 	"log"
@@ -24,12 +26,14 @@ var upgrader = websocket.Upgrader{
 type WebSocketHandle struct {
 	hub         *ws.Hub
 	chatService *chat.ChatService
+	botService  *bot.BotService
 }
 
-func NewWebSocketHandle(hub *ws.Hub, chatService *chat.ChatService) *WebSocketHandle {
+func NewWebSocketHandle(hub *ws.Hub, chatService *chat.ChatService, botService *bot.BotService) *WebSocketHandle {
 	return &WebSocketHandle{
 		hub:         hub,
 		chatService: chatService,
+		botService:  botService,
 	}
 }
 
@@ -62,8 +66,9 @@ func (h *WebSocketHandle) HandleWebSocket(c *gin.Context) {
 
 	h.hub.Register <- client
 
-	go h.writePump(client)
-	go h.readPump(client)
+	key := apiKeyFromContext(c)
+	go h.writePump(client, key)
+	go h.readPump(client, key)
 }
 
 const (
@@ -73,7 +78,7 @@ const (
 	maxMessageSize = 512
 )
 
-func (h *WebSocketHandle) readPump(client *ws.Client) {
+func (h *WebSocketHandle) readPump(client *ws.Client, key *apikey.APIKey) {
 	defer func() {
 		h.hub.Unregister <- client
 		client.Conn.Close()
@@ -105,8 +110,28 @@ func (h *WebSocketHandle) readPump(client *ws.Client) {
 		log.Printf("Parsed WebSocket message - Type: %s, ConversationID: %s, SenderID: %s",
 			msg.Type, msg.ConversationID, msg.SenderID)
 
+		// The connection is already authenticated, never trust the sender in the frame
+		msg.SenderID = client.ID
+
+		// A bot's key may have been revoked or expired since the upgrade
+		if key != nil {
+			if key, err = h.botService.Recheck(key); err != nil {
+				log.Printf("Closing connection of %s, api key no longer valid: %v", client.ID, err)
+				break
+			}
+		}
+
 		switch msg.Type {
 		case "join_conversation":
+			if !scopeAllows(key, apikey.ActionMessagesRead, msg.ConversationID) {
+				log.Printf("API key of %s may not join conversation %s", client.ID, msg.ConversationID)
+				continue
+			}
+			// Users who left or were never added get nothing from the conversation
+			if err := h.chatService.CheckParticipant(client.ID, msg.ConversationID); err != nil {
+				log.Printf("User %s may not join conversation %s: %v", client.ID, msg.ConversationID, err)
+				continue
+			}
 			log.Printf("User %s joining conversation %s", client.ID, msg.ConversationID)
 			h.hub.JoinConversation(msg.ConversationID, client.ID)
 
@@ -125,31 +150,81 @@ func (h *WebSocketHandle) readPump(client *ws.Client) {
 				log.Printf("Failed to send join confirmation to user %s", client.ID)
 			}
 		case "new_conversation":
+			if key != nil {
+				continue
+			}
 			log.Printf("Broadcasting new conversation %s notification", msg.ConversationID)
 			h.hub.Broadcast <- &msg
 		case "new_message":
+			if !scopeAllows(key, apikey.ActionMessagesWrite, msg.ConversationID) {
+				log.Printf("API key of %s may not write to conversation %s", client.ID, msg.ConversationID)
+				continue
+			}
 			log.Printf("Processing new message from %s in conversation %s: %s",
 				msg.SenderID, msg.ConversationID, msg.Message)
 			req := &application.SendMessageRequest{
 				ConversationID: msg.ConversationID,
 				SenderID:       msg.SenderID,
 				Message:        msg.Message,
+				Format:         msg.Format,
 			}
 			res, err := h.chatService.SendMessage(*req)
 			if err != nil {
+				// Rejected messages (not a participant, blocked) must not be delivered
 				log.Printf("Failed to save message to DB: %v", err)
-			} else {
-				log.Printf("Message saved to DB successfully. Created at: %d", res.CreatedAt)
+				continue
 			}
+			log.Printf("Message saved to DB successfully. Created at: %d", res.CreatedAt)
 			log.Printf("Broadcasting message to Hub")
+			// Lets clients match the message_preview that may follow
+			msg.Data = map[string]interface{}{"message_id": res.MessageID, "mentions": res.Mentions, "entities": res.Entities}
+			// Markup is delivered as the plain text the entities refer to
+			msg.Message = res.Message
+			msg.Format = ""
 			h.hub.Broadcast <- &msg
+			sendNotifications(h.hub, res.Notification)
+		case "poll_vote", "poll_retract":
+			if !scopeAllows(key, apikey.ActionMessagesWrite, msg.ConversationID) {
+				log.Printf("API key of %s may not vote in conversation %s", client.ID, msg.ConversationID)
+				continue
+			}
+			var req application.PollVoteRequest
+			if err := decodeFrameData(msg.Data, &req); err != nil {
+				log.Printf("Invalid %s data: %v", msg.Type, err)
+				continue
+			}
+			var res *application.PollUpdate
+			var err error
+			if msg.Type == "poll_vote" {
+				req.UserID = client.ID
+				req.ConversationID = msg.ConversationID
+				res, err = h.chatService.VotePoll(req)
+			} else {
+				res, err = h.chatService.RetractPollVote(client.ID, msg.ConversationID, req.MessageID)
+			}
+			if err != nil {
+				log.Printf("Failed to %s for %s: %v", msg.Type, client.ID, err)
+				continue
+			}
+			broadcastPollUpdate(h.hub, client.ID, res)
 		default:
 			log.Printf("Unknown message type: %s", msg.Type)
 		}
 	}
 }
 
-func (h *WebSocketHandle) writePump(client *ws.Client) {
+// decodeFrameData reads the data of a frame sent by a client into v.
+func decodeFrameData(data interface{}, v interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// writePump also re-checks a bot's key on every ping, so a revoked key stops
+// receiving messages even when the bot never sends a frame.
+func (h *WebSocketHandle) writePump(client *ws.Client, key *apikey.APIKey) {
 	ticker := time.NewTicker(pingPeriod)
 	defer func() {
 		ticker.Stop()
@@ -182,6 +257,15 @@ func (h *WebSocketHandle) writePump(client *ws.Client) {
 			}
 
 		case <-ticker.C:
+			if key != nil {
+				var err error
+				if key, err = h.botService.Recheck(key); err != nil {
+					log.Printf("Closing connection of %s, api key no longer valid: %v", client.ID, err)
+					client.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+					client.Conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "api key revoked or expired"))
+					return
+				}
+			}
 			client.Conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if err := client.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return